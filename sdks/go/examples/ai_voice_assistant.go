@@ -1,11 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -16,27 +13,27 @@ import (
 	"github.com/rustpbx/go-sdk/rustpbx"
 )
 
-// ConversationHistory tracks the conversation for context
-type ConversationHistory struct {
-	Messages []Message `json:"messages"`
-}
-
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// ChatCompletion represents OpenAI-compatible chat completion request
-type ChatCompletion struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
-}
-
-// ChatResponse represents the response from chat completion
-type ChatResponse struct {
-	Choices []struct {
-		Message Message `json:"message"`
-	} `json:"choices"`
+// personas are the assistant roles the caller can switch between with DTMF
+var personas = []rustpbx.Persona{
+	{
+		Name:         "main",
+		SystemPrompt: "You are a helpful AI voice assistant. Keep responses concise and conversational, suitable for voice interaction. Be friendly and helpful.",
+		Speaker:      "101002",
+		Greeting:     "Returning to main assistant mode.",
+	},
+	{
+		Name:         "customer_service",
+		SystemPrompt: "You are a customer service assistant. Be extra helpful and professional. Keep responses concise, suitable for voice interaction.",
+		Speaker:      "101002",
+		Greeting:     "Switching to customer service mode.",
+	},
+	{
+		Name:         "tech_support",
+		SystemPrompt: "You are a technical support assistant. Focus on troubleshooting and technical solutions. Keep responses concise, suitable for voice interaction.",
+		Speaker:      "101001",
+		Tools:        []string{"check_service_status"},
+		Greeting:     "Switching to technical support mode.",
+	},
 }
 
 func main() {
@@ -56,15 +53,15 @@ func main() {
 
 	log.Println("Connected to RustPBX AI Voice Assistant")
 
-	// Initialize conversation history
-	conversation := &ConversationHistory{
-		Messages: []Message{
-			{
-				Role:    "system",
-				Content: "You are a helpful AI voice assistant. Keep responses concise and conversational, suitable for voice interaction. Be friendly and helpful.",
-			},
+	// The assistant keeps the conversation history and answers each turn
+	assistant := rustpbx.NewAssistant(conn, &rustpbx.AssistantOptions{
+		LLM: &rustpbx.ProxyLLM{
+			Client: client,
+			Model:  "gpt-3.5-turbo",
 		},
-	}
+		Greeting: "Hello! I'm your AI voice assistant. How can I help you today?",
+		Personas: personas,
+	})
 
 	// Track call state
 	callActive := false
@@ -112,19 +109,7 @@ func main() {
 		case "answer":
 			log.Println("AI Assistant call connected")
 			callActive = true
-
-			// Send welcome message
-			welcomeMsg := "Hello! I'm your AI voice assistant. How can I help you today?"
-			if err := conn.TTSSimple(welcomeMsg); err != nil {
-				log.Printf("Failed to send welcome message: %v", err)
-			}
-
-			// Add assistant's welcome to conversation history
-			conn.History("assistant", welcomeMsg)
-			conversation.Messages = append(conversation.Messages, Message{
-				Role:    "assistant",
-				Content: welcomeMsg,
-			})
+			assistant.HandleEvent(event)
 
 		case "ringing":
 			log.Println("AI Assistant call is ringing")
@@ -132,9 +117,10 @@ func main() {
 		case "hangup":
 			log.Printf("AI Assistant call ended: %s (initiated by %s)", event.Reason, event.Initiator)
 			callActive = false
-			
+			assistant.HandleEvent(event)
+
 			// Save conversation summary
-			saveFinalSummary(conversation)
+			saveFinalSummary(assistant.History())
 
 		case "asrFinal":
 			if !callActive {
@@ -144,49 +130,21 @@ func main() {
 			userInput := strings.TrimSpace(event.Text)
 			log.Printf("User said: %s", userInput)
 
-			// Add user input to conversation history
-			conn.History("user", userInput)
-			conversation.Messages = append(conversation.Messages, Message{
-				Role:    "user",
-				Content: userInput,
-			})
-
 			// Check for special commands
 			if handleSpecialCommands(conn, userInput) {
 				return
 			}
 
-			// Get AI response
-			aiResponse, err := getAIResponse(client, conversation)
-			if err != nil {
-				log.Printf("Failed to get AI response: %v", err)
-				fallbackResponse := "I'm sorry, I'm having trouble processing that right now. Could you please repeat?"
-				conn.TTSSimple(fallbackResponse)
-				return
-			}
-
-			// Send AI response via TTS
-			if err := conn.TTS(aiResponse, "101002", "", &rustpbx.TTSOptions{
-				Streaming:   true,
-				AutoHangup:  false,
-				EndOfStream: false,
-			}); err != nil {
-				log.Printf("Failed to send AI response via TTS: %v", err)
-			}
-
-			// Add AI response to conversation history
-			conn.History("assistant", aiResponse)
-			conversation.Messages = append(conversation.Messages, Message{
-				Role:    "assistant",
-				Content: aiResponse,
-			})
+			assistant.HandleEvent(event)
 
 		case "asrDelta":
 			// Log partial transcription for debugging
 			log.Printf("Partial speech: %s", event.Text)
+			assistant.HandleEvent(event)
 
 		case "speaking":
 			log.Printf("Speech activity detected on track %s", event.TrackID)
+			assistant.HandleEvent(event)
 
 		case "silence":
 			log.Printf("Silence detected on track %s (duration: %dms)", event.TrackID, event.Duration)
@@ -198,7 +156,7 @@ func main() {
 
 		case "dtmf":
 			log.Printf("DTMF digit: %s", event.Digit)
-			handleDTMFCommands(conn, event.Digit, conversation)
+			handleDTMFCommands(conn, assistant, event.Digit)
 
 		case "error":
 			log.Printf("AI Assistant Error from %s: %s (code: %d)", event.Sender, event.Error, event.Code)
@@ -279,8 +237,8 @@ func handleSpecialCommands(conn *rustpbx.Connection, input string) bool {
 	switch {
 	case strings.Contains(input, "goodbye") || strings.Contains(input, "bye") || strings.Contains(input, "end call"):
 		conn.TTSSimple("Thank you for using the AI assistant. Have a wonderful day! Goodbye!")
-		time.AfterFunc(3*time.Second, func() {
-			conn.HangupSimple()
+		conn.After(3*time.Second, func(ctx context.Context) error {
+			return conn.HangupSimple()
 		})
 		return true
 
@@ -308,22 +266,13 @@ func handleSpecialCommands(conn *rustpbx.Connection, input string) bool {
 }
 
 // handleDTMFCommands processes DTMF commands for the AI assistant
-func handleDTMFCommands(conn *rustpbx.Connection, digit string, conversation *ConversationHistory) {
+func handleDTMFCommands(conn *rustpbx.Connection, assistant *rustpbx.Assistant, digit string) {
 	switch digit {
 	case "1":
-		conn.TTSSimple("Switching to customer service mode.")
-		// Add system message to change behavior
-		conversation.Messages = append(conversation.Messages, Message{
-			Role:    "system",
-			Content: "You are now in customer service mode. Be extra helpful and professional.",
-		})
+		switchPersona(assistant, "customer_service")
 
 	case "2":
-		conn.TTSSimple("Switching to technical support mode.")
-		conversation.Messages = append(conversation.Messages, Message{
-			Role:    "system",
-			Content: "You are now in technical support mode. Focus on troubleshooting and technical solutions.",
-		})
+		switchPersona(assistant, "tech_support")
 
 	case "3":
 		conn.TTSSimple("Playing hold music while I process your request.")
@@ -331,82 +280,34 @@ func handleDTMFCommands(conn *rustpbx.Connection, digit string, conversation *Co
 
 	case "9":
 		conn.TTSSimple("Ending our conversation. Thank you!")
-		time.AfterFunc(2*time.Second, func() {
-			conn.HangupSimple()
+		conn.After(2*time.Second, func(ctx context.Context) error {
+			return conn.HangupSimple()
 		})
 
 	case "0":
-		conn.TTSSimple("Returning to main assistant mode.")
-		// Reset to original system prompt
-		for i, msg := range conversation.Messages {
-			if msg.Role == "system" && i == 0 {
-				conversation.Messages[0] = Message{
-					Role:    "system",
-					Content: "You are a helpful AI voice assistant. Keep responses concise and conversational, suitable for voice interaction. Be friendly and helpful.",
-				}
-				break
-			}
-		}
+		switchPersona(assistant, "main")
 
 	default:
 		conn.TTSSimple(fmt.Sprintf("You pressed %s. Press 1 for customer service, 2 for technical support, or 9 to end the call.", digit))
 	}
 }
 
-// getAIResponse calls the LLM to get an AI response
-func getAIResponse(client *rustpbx.Client, conversation *ConversationHistory) (string, error) {
-	// Prepare chat completion request
-	request := ChatCompletion{
-		Model:    "gpt-3.5-turbo",
-		Messages: conversation.Messages,
-	}
-
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Make request to LLM proxy
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	headers := map[string]string{
-		"Content-Type": "application/json",
-	}
-
-	resp, err := client.ProxyLLMRequest(ctx, "chat/completions", "POST", bytes.NewReader(requestBody), headers)
-	if err != nil {
-		return "", fmt.Errorf("failed to call LLM: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("LLM request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var chatResponse ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
-		return "", fmt.Errorf("failed to parse LLM response: %w", err)
-	}
-
-	if len(chatResponse.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
+// switchPersona changes the assistant's prompt and voice
+func switchPersona(assistant *rustpbx.Assistant, name string) {
+	if err := assistant.SwitchPersona(name); err != nil {
+		log.Printf("Failed to switch persona: %v", err)
 	}
-
-	return chatResponse.Choices[0].Message.Content, nil
 }
 
 // saveFinalSummary saves a summary of the conversation
-func saveFinalSummary(conversation *ConversationHistory) {
+func saveFinalSummary(history []rustpbx.ChatMessage) {
 	log.Println("Conversation Summary:")
-	log.Printf("Total messages: %d", len(conversation.Messages))
-	
+	log.Printf("Total messages: %d", len(history))
+
 	userMessages := 0
 	assistantMessages := 0
-	
-	for _, msg := range conversation.Messages {
+
+	for _, msg := range history {
 		switch msg.Role {
 		case "user":
 			userMessages++
@@ -414,8 +315,8 @@ func saveFinalSummary(conversation *ConversationHistory) {
 			assistantMessages++
 		}
 	}
-	
+
 	log.Printf("User messages: %d", userMessages)
 	log.Printf("Assistant messages: %d", assistantMessages)
 	log.Println("Conversation ended successfully")
-}
\ No newline at end of file
+}
@@ -173,7 +173,7 @@ func main() {
 			SampleRate:   16000,
 			PTime:        "20ms",
 		},
-		HandshakeTimeout: "30s",
+		HandshakeTimeout: "30",
 		EnableIPv6:       false,
 		Extra: map[string]interface{}{
 			"sip_integration": true,
@@ -189,7 +189,7 @@ func main() {
 	}
 
 	// Demonstrate advanced SIP features after call setup
-	time.AfterFunc(10*time.Second, func() {
+	conn.After(10*time.Second, func(ctx context.Context) error {
 		if callActive {
 			log.Println("Demonstrating SIP call features...")
 			
@@ -205,6 +205,7 @@ func main() {
 			// Example: SIP-specific audio playback
 			conn.TTSSimple("This demonstrates SIP protocol integration with advanced telephony features.")
 		}
+		return nil
 	})
 
 	// Wait for interrupt signal or timeout
@@ -89,10 +89,11 @@ func main() {
 			}
 
 			// Start the conversation
-			time.AfterFunc(2*time.Second, func() {
+			conn.After(2*time.Second, func(ctx context.Context) error {
 				if err := conn.TTSSimple("Hello! This is a WebRTC call. How can I assist you today?"); err != nil {
 					log.Printf("Failed to send initial TTS: %v", err)
 				}
+				return nil
 			})
 
 		case "ringing":
@@ -243,8 +244,8 @@ func handleDTMF(conn *rustpbx.Connection, digit string) {
 		conn.Play("https://example.com/info.wav", false)
 	case "9":
 		conn.TTSSimple("You pressed 9. Ending call.")
-		time.AfterFunc(2*time.Second, func() {
-			conn.HangupSimple()
+		conn.After(2*time.Second, func(ctx context.Context) error {
+			return conn.HangupSimple()
 		})
 	case "0":
 		conn.TTSSimple("You pressed 0. Returning to main menu.")
@@ -0,0 +1,139 @@
+package rustpbx
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// AddressFamily represents the IP address families used for call media
+type AddressFamily string
+
+const (
+	// AddressIPv4Only offers and accepts IPv4 media addresses only
+	AddressIPv4Only AddressFamily = "ipv4"
+	// AddressDualStack offers IPv4 and IPv6 candidates, letting ICE connectivity checks
+	// fall back from one family to the other
+	AddressDualStack AddressFamily = "dualStack"
+	// AddressPreferIPv6 is like AddressDualStack with the IPv6 candidates offered first
+	AddressPreferIPv6 AddressFamily = "preferIpv6"
+)
+
+// MediaAddress is a media address of a session description
+type MediaAddress struct {
+	// Media is the media type, e.g. "audio"
+	Media string
+	// Network is "IP4" or "IP6"
+	Network string
+	Address string
+	Port    int
+}
+
+// String formats the address as host:port
+func (a MediaAddress) String() string {
+	return net.JoinHostPort(a.Address, strconv.Itoa(a.Port))
+}
+
+// ParseMediaAddresses returns the address of each media section of an SDP, from its
+// connection line or the session's. Sections without an address are skipped.
+func ParseMediaAddresses(sdp string) []MediaAddress {
+	var addresses []MediaAddress
+	var session, current *MediaAddress
+	flush := func() {
+		if current != nil && current.Address != "" {
+			addresses = append(addresses, *current)
+		}
+	}
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "m="):
+			flush()
+			fields := strings.Fields(line[2:])
+			current = &MediaAddress{}
+			if len(fields) > 1 {
+				current.Media = fields[0]
+				current.Port, _ = strconv.Atoi(fields[1])
+			}
+			if session != nil {
+				current.Network, current.Address = session.Network, session.Address
+			}
+		case strings.HasPrefix(line, "c="):
+			// c=IN IP4 192.0.2.1, with an optional TTL or count after a slash
+			fields := strings.Fields(line[2:])
+			if len(fields) < 3 {
+				continue
+			}
+			address := MediaAddress{Network: fields[1], Address: strings.SplitN(fields[2], "/", 2)[0]}
+			if current == nil {
+				session = &address
+			} else {
+				current.Network, current.Address = address.Network, address.Address
+			}
+		}
+	}
+	flush()
+	return addresses
+}
+
+// applyAddressFamily returns a copy of a call option with its offer filtered or reordered
+// for the address family, and EnableIPv6 set as the server's only address setting; the option
+// is returned as is without an address family
+func applyAddressFamily(option *CallOption) *CallOption {
+	if option == nil || option.AddressFamily == "" {
+		return option
+	}
+	applied := *option
+	applied.EnableIPv6 = option.AddressFamily != AddressIPv4Only
+	if option.Offer != "" {
+		applied.Offer = arrangeCandidates(option.Offer, option.AddressFamily)
+	}
+	return &applied
+}
+
+// arrangeCandidates removes the IPv6 ICE candidates of an SDP for AddressIPv4Only, and
+// moves them before the IPv4 candidates of their media section for AddressPreferIPv6
+func arrangeCandidates(sdp string, family AddressFamily) string {
+	if family == AddressDualStack {
+		return sdp
+	}
+	newline := "\n"
+	if strings.Contains(sdp, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := strings.Split(sdp, newline)
+	arranged := make([]string, 0, len(lines))
+	// ipv4 holds the IPv4 candidates of a run of candidates, placed after its IPv6 ones
+	var ipv4 []string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			arranged = append(arranged, ipv4...)
+			ipv4 = nil
+			arranged = append(arranged, line)
+			continue
+		}
+		switch {
+		case !ipv6Candidate(line):
+			if family == AddressPreferIPv6 {
+				ipv4 = append(ipv4, line)
+			} else {
+				arranged = append(arranged, line)
+			}
+		case family == AddressPreferIPv6:
+			arranged = append(arranged, line)
+		}
+	}
+	arranged = append(arranged, ipv4...)
+	return strings.Join(arranged, newline)
+}
+
+// ipv6Candidate reports whether an ICE candidate line has an IPv6 address:
+// a=candidate:<foundation> <component> <transport> <priority> <address> <port> typ <type>
+func ipv6Candidate(line string) bool {
+	fields := strings.Fields(strings.TrimPrefix(line, "a=candidate:"))
+	if len(fields) < 5 {
+		return false
+	}
+	ip := net.ParseIP(fields[4])
+	return ip != nil && ip.To4() == nil
+}
@@ -0,0 +1,72 @@
+package rustpbx
+
+import (
+	"strings"
+	"testing"
+)
+
+const dualStackOffer = "v=0\r\n" +
+	"o=- 1 1 IN IP4 192.0.2.1\r\n" +
+	"c=IN IP4 192.0.2.1\r\n" +
+	"m=audio 4000 UDP/TLS/RTP/SAVPF 0\r\n" +
+	"a=candidate:1 1 udp 2130706431 192.0.2.1 4000 typ host\r\n" +
+	"a=candidate:2 1 udp 2130706430 2001:db8::1 4002 typ host\r\n" +
+	"a=rtcp-mux\r\n" +
+	"m=video 5000 RTP/AVP 96\r\n" +
+	"c=IN IP6 2001:db8::2\r\n"
+
+func TestParseMediaAddresses(t *testing.T) {
+	addresses := ParseMediaAddresses(dualStackOffer)
+	if len(addresses) != 2 {
+		t.Fatalf("Expected 2 media addresses, got %v", addresses)
+	}
+	if got := addresses[0]; got.Media != "audio" || got.Network != "IP4" || got.String() != "192.0.2.1:4000" {
+		t.Errorf("Unexpected audio address %+v", got)
+	}
+	if got := addresses[1]; got.Media != "video" || got.Network != "IP6" || got.String() != "[2001:db8::2]:5000" {
+		t.Errorf("Unexpected video address %+v", got)
+	}
+	if addresses := ParseMediaAddresses("m=audio 4000 RTP/AVP 0"); addresses != nil {
+		t.Errorf("Expected no addresses without a connection line, got %v", addresses)
+	}
+}
+
+func TestApplyAddressFamily(t *testing.T) {
+	option := applyAddressFamily(&CallOption{Offer: dualStackOffer, AddressFamily: AddressIPv4Only})
+	if option.EnableIPv6 || strings.Contains(option.Offer, "2001:db8::1") {
+		t.Errorf("Expected IPv4 only offer, got %+v", option)
+	}
+	if !strings.Contains(option.Offer, "a=candidate:1 1 udp 2130706431 192.0.2.1 4000 typ host\r\na=rtcp-mux") {
+		t.Errorf("Expected the IPv4 candidate to be kept, got %q", option.Offer)
+	}
+
+	option = applyAddressFamily(&CallOption{Offer: dualStackOffer, AddressFamily: AddressPreferIPv6})
+	if !option.EnableIPv6 {
+		t.Error("Expected EnableIPv6 for preferIpv6")
+	}
+	if v6, v4 := strings.Index(option.Offer, "2001:db8::1"), strings.Index(option.Offer, "192.0.2.1 4000"); v6 > v4 {
+		t.Errorf("Expected the IPv6 candidate first, got %q", option.Offer)
+	}
+
+	option = applyAddressFamily(&CallOption{Offer: dualStackOffer, AddressFamily: AddressDualStack})
+	if !option.EnableIPv6 || option.Offer != dualStackOffer {
+		t.Errorf("Expected dual stack offer unchanged, got %+v", option)
+	}
+
+	original := &CallOption{EnableIPv6: true}
+	if applyAddressFamily(original) != original {
+		t.Error("Expected option without an address family to be returned as is")
+	}
+}
+
+func TestAddressFamilyValidation(t *testing.T) {
+	if err := (&CallOption{AddressFamily: "ipv5"}).Validate(); err == nil || !strings.Contains(err.Error(), "addressFamily") {
+		t.Errorf("Expected an unknown address family error, got %v", err)
+	}
+	if err := (&CallOption{AddressFamily: AddressIPv4Only, EnableIPv6: true}).Validate(); err == nil || !strings.Contains(err.Error(), "enableIpv6") {
+		t.Errorf("Expected a conflict error, got %v", err)
+	}
+	if err := (&CallOption{AddressFamily: AddressPreferIPv6}).Validate(); err != nil {
+		t.Errorf("Expected preferIpv6 to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package adminhttp serves an administrative HTTP API for applications built on the
+// RustPBX SDK, so operators can inspect and manage a running instance:
+//
+//	GET  /status                   draining flag, active sessions and config version
+//	GET  /sessions                 active sessions
+//	GET  /sessions/{id}            state of a session
+//	POST /sessions/{id}/kill       hang up a session, with an optional ?reason=
+//	POST /drain                    stop accepting new calls
+//	POST /resume                   accept new calls again
+//	POST /config/reload            reload the config file
+//
+// Responses are JSON; errors are returned as {"error": "..."}. Without a token, only
+// requests from the loopback interface are served.
+package adminhttp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rustpbx/go-sdk/rustpbx"
+)
+
+// Options represents the admin API configuration
+type Options struct {
+	// Registry tracks the sessions to manage; required
+	Registry *rustpbx.SessionRegistry
+	// Config is reloaded by /config/reload; the endpoint is disabled when nil
+	Config *rustpbx.ConfigWatcher
+	// Token is required as a bearer token when set. Without it, requests are only served
+	// from loopback addresses and ListenAndServe refuses other addresses.
+	Token string
+}
+
+// ErrTokenRequired is returned by ListenAndServe for an address other than loopback
+// without a token
+var ErrTokenRequired = errors.New("an admin token is required unless listening on loopback")
+
+// Status reports the state of the instance
+type Status struct {
+	Draining      bool `json:"draining"`
+	Active        int  `json:"active"`
+	ConfigVersion int  `json:"configVersion,omitempty"`
+}
+
+// NewHandler creates the admin API handler
+func NewHandler(options Options) http.Handler {
+	h := &handler{options: options}
+	return http.HandlerFunc(h.serve)
+}
+
+// handler routes admin API requests
+type handler struct {
+	options Options
+}
+
+func (h *handler) serve(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "status":
+		if allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, h.status())
+		}
+	case path == "sessions":
+		if allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, h.options.Registry.Sessions())
+		}
+	case strings.HasPrefix(path, "sessions/"):
+		h.session(w, r, strings.TrimPrefix(path, "sessions/"))
+	case path == "drain":
+		if allow(w, r, http.MethodPost) {
+			h.options.Registry.Drain()
+			writeJSON(w, http.StatusOK, h.status())
+		}
+	case path == "resume":
+		if allow(w, r, http.MethodPost) {
+			h.options.Registry.Resume()
+			writeJSON(w, http.StatusOK, h.status())
+		}
+	case path == "config/reload":
+		if allow(w, r, http.MethodPost) {
+			h.reload(w)
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint: %s", r.URL.Path))
+	}
+}
+
+// authorized checks the bearer token of a request, or that it comes from loopback
+// without a token
+func (h *handler) authorized(r *http.Request) bool {
+	if h.options.Token == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		return err == nil && isLoopback(host)
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.options.Token)) == 1
+}
+
+// status returns the state of the instance
+func (h *handler) status() Status {
+	status := Status{
+		Draining: h.options.Registry.Draining(),
+		Active:   h.options.Registry.Active(),
+	}
+	if h.options.Config != nil {
+		status.ConfigVersion = h.options.Config.Current().Version
+	}
+	return status
+}
+
+// session serves /sessions/{id} and /sessions/{id}/kill
+func (h *handler) session(w http.ResponseWriter, r *http.Request, path string) {
+	id, action, _ := strings.Cut(path, "/")
+	switch action {
+	case "":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		info, ok := h.options.Registry.Session(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, rustpbx.ErrSessionNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	case "kill":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		err := h.options.Registry.Kill(id, r.URL.Query().Get("reason"))
+		switch {
+		case errors.Is(err, rustpbx.ErrSessionNotFound):
+			writeError(w, http.StatusNotFound, err)
+		case err != nil:
+			writeError(w, http.StatusBadGateway, err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint: %s", r.URL.Path))
+	}
+}
+
+// reload serves /config/reload
+func (h *handler) reload(w http.ResponseWriter) {
+	if h.options.Config == nil {
+		writeError(w, http.StatusNotFound, errors.New("no config watcher configured"))
+		return
+	}
+	if err := h.options.Config.Reload(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.status())
+}
+
+// allow checks the method of a request, answering 405 for other methods
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// isLoopback reports whether a host is a loopback IP address or localhost
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ListenAndServe serves the admin API on addr until ctx is done, then shuts the server
+// down, giving in-flight requests 5 seconds to finish. Without a token, addr must be a
+// loopback address such as 127.0.0.1:9090.
+func ListenAndServe(ctx context.Context, addr string, options Options) error {
+	if options.Token == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid address %s: %w", addr, err)
+		}
+		if !isLoopback(host) {
+			return ErrTokenRequired
+		}
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	server := &http.Server{
+		Handler:           NewHandler(options),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	served := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+		case <-served:
+			return
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	err = server.Serve(listener)
+	close(served)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+		return nil
+	}
+	return err
+}
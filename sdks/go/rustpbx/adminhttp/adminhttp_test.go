@@ -0,0 +1,138 @@
+package adminhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rustpbx/go-sdk/rustpbx"
+)
+
+// newPBX starts a server accepting calls and discarding their commands
+func newPBX(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func request(t *testing.T, admin *httptest.Server, method, path, token string, out interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, admin.URL+path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestAdminAPI(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{"prompts": {"system": "v1"}}`), 0o644)
+	config, err := rustpbx.NewConfigWatcher(configPath)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+	registry := rustpbx.NewSessionRegistry()
+	admin := httptest.NewServer(NewHandler(Options{Registry: registry, Config: config, Token: "secret"}))
+	defer admin.Close()
+
+	client := rustpbx.NewClient(newPBX(t).URL)
+	conn, err := client.ConnectCall(context.Background(), &rustpbx.ConnectionOptions{SessionID: "call-1", Registry: registry})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	if status := request(t, admin, "GET", "/sessions", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the token, got %d", status)
+	}
+
+	var sessions []rustpbx.SessionInfo
+	if status := request(t, admin, "GET", "/sessions", "secret", &sessions); status != http.StatusOK || len(sessions) != 1 || sessions[0].SessionID != "call-1" {
+		t.Errorf("Expected the active session, got %d %+v", status, sessions)
+	}
+	var info rustpbx.SessionInfo
+	if status := request(t, admin, "GET", "/sessions/call-1", "secret", &info); status != http.StatusOK || info.State != "connected" {
+		t.Errorf("Expected the session state, got %d %+v", status, info)
+	}
+	if status := request(t, admin, "GET", "/sessions/unknown", "secret", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown session, got %d", status)
+	}
+	if status := request(t, admin, "GET", "/drain", "secret", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", status)
+	}
+
+	var status Status
+	request(t, admin, "POST", "/drain", "secret", &status)
+	if !status.Draining || status.Active != 1 {
+		t.Errorf("Expected draining with one active call, got %+v", status)
+	}
+	if code := request(t, admin, "POST", "/sessions/call-1/kill", "secret", nil); code != http.StatusNoContent {
+		t.Errorf("Expected the session to be killed, got %d", code)
+	}
+	deadline := time.Now().Add(time.Second)
+	for registry.Active() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	request(t, admin, "POST", "/resume", "secret", &status)
+	if status.Draining || status.Active != 0 {
+		t.Errorf("Expected resumed with no active call, got %+v", status)
+	}
+
+	os.WriteFile(configPath, []byte(`{"prompts": {"system": "v2"}}`), 0o644)
+	if code := request(t, admin, "POST", "/config/reload", "secret", &status); code != http.StatusOK || status.ConfigVersion != 2 {
+		t.Errorf("Expected the config to be reloaded, got %d %+v", code, status)
+	}
+	os.WriteFile(configPath, []byte(`{`), 0o644)
+	var failure map[string]string
+	if code := request(t, admin, "POST", "/config/reload", "secret", &failure); code != http.StatusUnprocessableEntity || failure["error"] == "" {
+		t.Errorf("Expected an invalid config to be rejected, got %d %v", code, failure)
+	}
+}
+
+func TestAdminAPIWithoutToken(t *testing.T) {
+	options := Options{Registry: rustpbx.NewSessionRegistry()}
+	if err := ListenAndServe(context.Background(), ":0", options); !errors.Is(err, ErrTokenRequired) {
+		t.Errorf("Expected ErrTokenRequired on all interfaces, got %v", err)
+	}
+
+	admin := httptest.NewServer(NewHandler(options))
+	defer admin.Close()
+	if status := request(t, admin, "GET", "/status", "", nil); status != http.StatusOK {
+		t.Errorf("Expected loopback requests to be served, got %d", status)
+	}
+
+	req := httptest.NewRequest("POST", "/drain", nil)
+	req.RemoteAddr = "192.0.2.10:40000"
+	recorder := httptest.NewRecorder()
+	NewHandler(options).ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusUnauthorized || options.Registry.Draining() {
+		t.Errorf("Expected a remote request to be refused, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,354 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MetadataQueue is the call context metadata key naming the queue a call belongs to
+const MetadataQueue = "queue"
+
+// BusyTreatment is applied to incoming calls that exceed the admission limits
+type BusyTreatment int
+
+const (
+	// BusyReject rejects excess calls immediately
+	BusyReject BusyTreatment = iota
+	// BusyQueue holds excess calls unanswered until a slot frees up or the queue timeout expires.
+	// Queued calls are admitted by priority, then in arrival order.
+	BusyQueue
+)
+
+// AdmissionOptions represents admission control configuration. Zero limits are unlimited.
+type AdmissionOptions struct {
+	MaxCalls int
+	// TenantLimits and QueueLimits cap the concurrent calls of each tenant and queue
+	TenantLimits map[string]int
+	QueueLimits  map[string]int
+	Treatment    BusyTreatment
+	// QueueTimeout is how long a queued call waits for a slot; 30s when zero
+	QueueTimeout time.Duration
+	// MaxQueued caps the calls waiting for a slot; excess calls are rejected
+	MaxQueued    int
+	RejectReason string
+	RejectCode   int
+}
+
+// AdmissionController limits the concurrent calls admitted across connections so that
+// downstream ASR and LLM capacity is not overrun. Share one controller between all
+// connections it protects.
+type AdmissionController struct {
+	options AdmissionOptions
+	mu      sync.Mutex
+	active  int
+	tenants map[string]int
+	queues  map[string]int
+	waiters []*admissionWaiter
+	seq     uint64
+	// freed is closed and replaced whenever a slot is released
+	freed    chan struct{}
+	admitted map[Priority]int
+	rejected map[Priority]int
+}
+
+// admissionWaiter is a call queued for a slot
+type admissionWaiter struct {
+	tenant   string
+	queue    string
+	priority Priority
+	seq      uint64
+}
+
+// AdmissionStats reports the admission counters, by call priority
+type AdmissionStats struct {
+	Active   int
+	Queued   int
+	Admitted map[Priority]int
+	Rejected map[Priority]int
+}
+
+// NewAdmissionController creates an admission controller
+func NewAdmissionController(options AdmissionOptions) *AdmissionController {
+	if options.QueueTimeout == 0 {
+		options.QueueTimeout = 30 * time.Second
+	}
+	if options.RejectReason == "" {
+		options.RejectReason = "busy"
+	}
+	if options.RejectCode == 0 {
+		options.RejectCode = 486
+	}
+	return &AdmissionController{
+		options:  options,
+		tenants:  make(map[string]int),
+		queues:   make(map[string]int),
+		freed:    make(chan struct{}),
+		admitted: make(map[Priority]int),
+		rejected: make(map[Priority]int),
+	}
+}
+
+// Active returns the number of admitted calls
+func (a *AdmissionController) Active() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+// Queued returns the number of calls waiting for a slot
+func (a *AdmissionController) Queued() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.waiters)
+}
+
+// Stats returns a snapshot of the admission counters
+func (a *AdmissionController) Stats() AdmissionStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := AdmissionStats{
+		Active:   a.active,
+		Queued:   len(a.waiters),
+		Admitted: make(map[Priority]int, len(a.admitted)),
+		Rejected: make(map[Priority]int, len(a.rejected)),
+	}
+	for p, n := range a.admitted {
+		stats.Admitted[p] = n
+	}
+	for p, n := range a.rejected {
+		stats.Rejected[p] = n
+	}
+	return stats
+}
+
+// TryAcquire admits a call if a slot is free and no queued call of the same or higher
+// priority is waiting for it, returning the function that releases the slot
+func (a *AdmissionController) TryAcquire(tenant, queue string, priority Priority) (func(), bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.tryAcquireLocked(&admissionWaiter{tenant: tenant, queue: queue, priority: priority, seq: ^uint64(0)})
+}
+
+// Acquire waits until a slot is free or ctx is done
+func (a *AdmissionController) Acquire(ctx context.Context, tenant, queue string, priority Priority) (func(), error) {
+	a.mu.Lock()
+	a.seq++
+	waiter := &admissionWaiter{tenant: tenant, queue: queue, priority: priority, seq: a.seq}
+	if release, ok := a.tryAcquireLocked(waiter); ok {
+		a.mu.Unlock()
+		return release, nil
+	}
+	a.waiters = append(a.waiters, waiter)
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		for i, w := range a.waiters {
+			if w == waiter {
+				a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+				break
+			}
+		}
+		// A waiter leaving may unblock those behind it
+		a.wakeLocked()
+		a.mu.Unlock()
+	}()
+
+	for {
+		a.mu.Lock()
+		if release, ok := a.tryAcquireLocked(waiter); ok {
+			a.mu.Unlock()
+			return release, nil
+		}
+		freed := a.freed
+		a.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// tryAcquireLocked must be called with a.mu held
+func (a *AdmissionController) tryAcquireLocked(waiter *admissionWaiter) (func(), bool) {
+	if a.options.MaxCalls > 0 && a.active >= a.options.MaxCalls {
+		return nil, false
+	}
+	if !a.withinLimitsLocked(waiter) {
+		return nil, false
+	}
+	for _, w := range a.waiters {
+		// Calls held back by their own tenant or queue limit do not block the others
+		ahead := w.priority > waiter.priority || (w.priority == waiter.priority && w.seq < waiter.seq)
+		if w != waiter && ahead && a.withinLimitsLocked(w) {
+			return nil, false
+		}
+	}
+
+	tenant, queue := waiter.tenant, waiter.queue
+
+	a.active++
+	a.tenants[tenant]++
+	a.queues[queue]++
+	a.admitted[waiter.priority]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { a.release(tenant, queue) })
+	}, true
+}
+
+// withinLimitsLocked reports whether the tenant and queue of a call have a free slot
+func (a *AdmissionController) withinLimitsLocked(w *admissionWaiter) bool {
+	if limit := a.options.TenantLimits[w.tenant]; limit > 0 && a.tenants[w.tenant] >= limit {
+		return false
+	}
+	if limit := a.options.QueueLimits[w.queue]; limit > 0 && a.queues[w.queue] >= limit {
+		return false
+	}
+	return true
+}
+
+// release frees a slot and wakes the queued calls
+func (a *AdmissionController) release(tenant, queue string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.active--
+	if a.tenants[tenant]--; a.tenants[tenant] <= 0 {
+		delete(a.tenants, tenant)
+	}
+	if a.queues[queue]--; a.queues[queue] <= 0 {
+		delete(a.queues, queue)
+	}
+	a.wakeLocked()
+}
+
+// wakeLocked wakes the queued calls to retry; it must be called with a.mu held
+func (a *AdmissionController) wakeLocked() {
+	close(a.freed)
+	a.freed = make(chan struct{})
+}
+
+// queueFull reports whether another call may wait for a slot
+func (a *AdmissionController) queueFull() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.options.MaxQueued > 0 && len(a.waiters) >= a.options.MaxQueued
+}
+
+// admitIncoming applies admission control to an incoming call. It returns false if the
+// call was rejected or queued; a queued call's incoming event is dispatched once admitted.
+func (c *Connection) admitIncoming(event *Event) bool {
+	admission := c.admission
+	if admission == nil {
+		return true
+	}
+
+	var tenant, queue string
+	priority := PriorityNormal
+	if c.callContext != nil {
+		c.mu.RLock()
+		tenant, queue = c.callContext.Tenant, c.callContext.Metadata[MetadataQueue]
+		priority = c.callContext.Priority
+		c.mu.RUnlock()
+	}
+
+	if release, ok := admission.TryAcquire(tenant, queue, priority); ok {
+		c.setAdmission(release, nil)
+		return true
+	}
+	if admission.options.Treatment != BusyQueue || admission.queueFull() {
+		c.rejectBusy(event, "limit", priority)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, admission.options.QueueTimeout)
+	c.setAdmission(nil, cancel)
+	data, _ := json.Marshal(map[string]interface{}{
+		"priority": priority.String(),
+	})
+	c.dispatch(&Event{
+		Event:     "queued",
+		Timestamp: time.Now().UnixMilli(),
+		Caller:    event.Caller,
+		Callee:    event.Callee,
+		Data:      data,
+	})
+
+	go func() {
+		defer cancel()
+		release, err := admission.Acquire(ctx, tenant, queue, priority)
+		if err != nil {
+			if c.ctx.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+				c.rejectBusy(event, "queue_timeout", priority)
+			}
+			return
+		}
+		if !c.setAdmission(release, nil) {
+			// The call ended while it was queued
+			release()
+			return
+		}
+		c.dispatch(event)
+	}()
+	return false
+}
+
+// setAdmission records the admission slot or queue wait of the call.
+// It returns false if the call has already ended.
+func (c *Connection) setAdmission(release func(), cancel context.CancelFunc) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.admissionEnded {
+		return false
+	}
+	c.admissionRelease, c.admissionCancel = release, cancel
+	return true
+}
+
+// releaseAdmission frees the call's slot, or stops waiting for one, when the call ends
+func (c *Connection) releaseAdmission() {
+	c.mu.Lock()
+	release, cancel := c.admissionRelease, c.admissionCancel
+	c.admissionRelease, c.admissionCancel = nil, nil
+	c.admissionEnded = true
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if release != nil {
+		release()
+	}
+}
+
+// rejectBusy applies the busy treatment to a call that could not be admitted
+func (c *Connection) rejectBusy(event *Event, cause string, priority Priority) {
+	c.admission.mu.Lock()
+	c.admission.rejected[priority]++
+	c.admission.mu.Unlock()
+
+	options := c.admission.options
+	data, _ := json.Marshal(map[string]interface{}{
+		"cause":    cause,
+		"active":   c.admission.Active(),
+		"priority": priority.String(),
+	})
+	c.dispatch(&Event{
+		Event:     "busy",
+		Timestamp: time.Now().UnixMilli(),
+		Caller:    event.Caller,
+		Callee:    event.Callee,
+		Reason:    options.RejectReason,
+		Code:      options.RejectCode,
+		Data:      data,
+	})
+
+	if err := c.Reject(options.RejectReason, options.RejectCode); err != nil {
+		c.handleError(err)
+	}
+}
@@ -0,0 +1,141 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAdmissionLimits(t *testing.T) {
+	admission := NewAdmissionController(AdmissionOptions{
+		MaxCalls:     3,
+		TenantLimits: map[string]int{"acme": 1},
+	})
+
+	release, ok := admission.TryAcquire("acme", "", PriorityNormal)
+	if !ok {
+		t.Fatal("Expected first acme call to be admitted")
+	}
+	if _, ok := admission.TryAcquire("acme", "", PriorityNormal); ok {
+		t.Error("Expected second acme call to exceed the tenant limit")
+	}
+	admission.TryAcquire("globex", "", PriorityNormal)
+	admission.TryAcquire("globex", "", PriorityNormal)
+	if _, ok := admission.TryAcquire("initech", "", PriorityNormal); ok {
+		t.Error("Expected fourth call to exceed the overall limit")
+	}
+
+	release()
+	release()
+	if admission.Active() != 2 {
+		t.Errorf("Expected 2 active calls after a release, got %d", admission.Active())
+	}
+	if _, ok := admission.TryAcquire("acme", "", PriorityNormal); !ok {
+		t.Error("Expected acme call to be admitted after the release")
+	}
+}
+
+func TestAdmissionQueue(t *testing.T) {
+	admission := NewAdmissionController(AdmissionOptions{MaxCalls: 1})
+	release, _ := admission.TryAcquire("", "", PriorityNormal)
+
+	admitted := make(chan error, 1)
+	go func() {
+		_, err := admission.Acquire(context.Background(), "", "", PriorityNormal)
+		admitted <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if admission.Queued() != 1 {
+		t.Errorf("Expected 1 queued call, got %d", admission.Queued())
+	}
+	release()
+
+	select {
+	case err := <-admitted:
+		if err != nil {
+			t.Errorf("Expected queued call to be admitted, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected queued call to be admitted")
+	}
+}
+
+func TestAdmissionRejectsBusy(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		// Wait for the client to install its handler
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100"})
+	})
+
+	admission := NewAdmissionController(AdmissionOptions{MaxCalls: 1})
+	release, _ := admission.TryAcquire("", "", PriorityNormal)
+	defer release()
+
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{Admission: admission})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case cmd := <-commands:
+		if cmd["command"] != "reject" || cmd["code"] != float64(486) {
+			t.Errorf("Expected reject with code 486, got %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected reject command")
+	}
+	if event := <-events; event.Event != "busy" {
+		t.Errorf("Expected 'busy' event instead of the incoming call, got '%s'", event.Event)
+	}
+}
+
+func TestAdmissionQueuesIncoming(t *testing.T) {
+	hangup := make(chan struct{})
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100"})
+		<-hangup
+		conn.WriteJSON(Event{Event: "hangup"})
+	})
+
+	admission := NewAdmissionController(AdmissionOptions{MaxCalls: 1, Treatment: BusyQueue})
+	release, _ := admission.TryAcquire("", "", PriorityNormal)
+
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{Admission: admission})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	if event := <-events; event.Event != "queued" {
+		t.Fatalf("Expected 'queued' event, got '%s'", event.Event)
+	}
+	release()
+
+	select {
+	case event := <-events:
+		if event.Event != "incoming" {
+			t.Errorf("Expected incoming event once admitted, got '%s'", event.Event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected incoming event once admitted")
+	}
+
+	close(hangup)
+	<-events
+	if admission.Active() != 0 {
+		t.Errorf("Expected the slot to be released on hangup, got %d active", admission.Active())
+	}
+}
@@ -0,0 +1,215 @@
+package rustpbx
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrAgentUnauthorized is returned by agent authenticators for unknown credentials
+var ErrAgentUnauthorized = errors.New("agent unauthorized")
+
+// AgentEvent types published to agent desktops
+const (
+	AgentEventTranscript = "transcript"
+	AgentEventSentiment  = "sentiment"
+	AgentEventSuggestion = "suggestion"
+	AgentEventAssigned   = "assigned"
+	AgentEventEnded      = "ended"
+)
+
+// AgentEvent represents a call event streamed to an agent-assist UI
+type AgentEvent struct {
+	CallID    string  `json:"callId"`
+	Type      string  `json:"type"`
+	Speaker   string  `json:"speaker,omitempty"`
+	Text      string  `json:"text,omitempty"`
+	Score     float64 `json:"score,omitempty"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// AgentAuthenticator resolves the agent making a desktop request
+type AgentAuthenticator func(r *http.Request) (agentID string, err error)
+
+// BearerTokenAuthenticator authenticates agents by bearer token, mapping each token to an agent ID
+func BearerTokenAuthenticator(tokens map[string]string) AgentAuthenticator {
+	return func(r *http.Request) (string, error) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			// Browsers cannot set headers on WebSocket requests
+			token = r.URL.Query().Get("token")
+		}
+		for known, agentID := range tokens {
+			if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+				return agentID, nil
+			}
+		}
+		return "", ErrAgentUnauthorized
+	}
+}
+
+// AgentBridgeOptions represents agent desktop bridge configuration
+type AgentBridgeOptions struct {
+	// Authenticate is required; every request is rejected when nil
+	Authenticate AgentAuthenticator
+	// CheckOrigin validates the Origin of WebSocket requests; same-origin only when nil
+	CheckOrigin func(r *http.Request) bool
+	// Backlog is the number of events buffered per agent before new events are dropped
+	Backlog int
+}
+
+// agentSubscriber is a connected agent desktop
+type agentSubscriber struct {
+	agentID string
+	events  chan *AgentEvent
+}
+
+// AgentBridge streams per-call events to agent-assist UIs over WebSocket.
+// Agents only receive events of the calls assigned to them.
+type AgentBridge struct {
+	options  AgentBridgeOptions
+	upgrader websocket.Upgrader
+
+	mu          sync.RWMutex
+	assignments map[string]string
+	subscribers map[*agentSubscriber]struct{}
+}
+
+// NewAgentBridge creates an agent desktop bridge
+func NewAgentBridge(options *AgentBridgeOptions) *AgentBridge {
+	opts := *options
+	if opts.Backlog <= 0 {
+		opts.Backlog = 64
+	}
+	if opts.Authenticate == nil {
+		opts.Authenticate = func(*http.Request) (string, error) {
+			return "", ErrAgentUnauthorized
+		}
+	}
+	return &AgentBridge{
+		options:     opts,
+		upgrader:    websocket.Upgrader{CheckOrigin: opts.CheckOrigin},
+		assignments: make(map[string]string),
+		subscribers: make(map[*agentSubscriber]struct{}),
+	}
+}
+
+// Assign scopes a call to an agent
+func (b *AgentBridge) Assign(callID, agentID string) {
+	b.mu.Lock()
+	b.assignments[callID] = agentID
+	b.mu.Unlock()
+	b.Publish(&AgentEvent{CallID: callID, Type: AgentEventAssigned})
+}
+
+// Unassign ends the agent's view of a call
+func (b *AgentBridge) Unassign(callID string) {
+	b.Publish(&AgentEvent{CallID: callID, Type: AgentEventEnded})
+	b.mu.Lock()
+	delete(b.assignments, callID)
+	b.mu.Unlock()
+}
+
+// Publish sends an event to the agent the call is assigned to
+func (b *AgentBridge) Publish(event *AgentEvent) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	agentID, ok := b.assignments[event.CallID]
+	if !ok {
+		return
+	}
+	for sub := range b.subscribers {
+		if sub.agentID != agentID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// A stalled desktop must not block the call
+		}
+	}
+}
+
+// PublishTranscript sends a transcript line of a call
+func (b *AgentBridge) PublishTranscript(callID, speaker, text string) {
+	b.Publish(&AgentEvent{CallID: callID, Type: AgentEventTranscript, Speaker: speaker, Text: text})
+}
+
+// PublishSentiment sends the caller's sentiment score, from -1 (negative) to 1 (positive)
+func (b *AgentBridge) PublishSentiment(callID string, score float64) {
+	b.Publish(&AgentEvent{CallID: callID, Type: AgentEventSentiment, Score: score})
+}
+
+// PublishSuggestion sends a suggested response for the agent
+func (b *AgentBridge) PublishSuggestion(callID, text string) {
+	b.Publish(&AgentEvent{CallID: callID, Type: AgentEventSuggestion, Text: text})
+}
+
+// Observe publishes the transcript of a connection event
+func (b *AgentBridge) Observe(event *Event) {
+	if event.Context == nil {
+		return
+	}
+	switch event.Event {
+	case "asrFinal":
+		b.PublishTranscript(event.Context.CallID, "caller", event.Text)
+	case "hangup":
+		b.Unassign(event.Context.CallID)
+	}
+}
+
+// ServeHTTP upgrades an authenticated agent desktop request to a WebSocket event stream
+func (b *AgentBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	agentID, err := b.options.Authenticate(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ws, err := b.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	sub := &agentSubscriber{agentID: agentID, events: make(chan *AgentEvent, b.options.Backlog)}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		b.mu.Unlock()
+	}()
+
+	// The desktop only reads; a read error means it went away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-sub.events:
+			if err := ws.WriteJSON(event); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
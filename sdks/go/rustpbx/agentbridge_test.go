@@ -0,0 +1,69 @@
+package rustpbx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialAgent connects an agent desktop to a bridge test server
+func dialAgent(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	t.Helper()
+	header := http.Header{"Authorization": []string{"Bearer " + token}}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	return ws
+}
+
+func TestAgentBridgeScopesEventsToAgent(t *testing.T) {
+	bridge := NewAgentBridge(&AgentBridgeOptions{
+		Authenticate: BearerTokenAuthenticator(map[string]string{"t-alice": "alice", "t-bob": "bob"}),
+	})
+	server := httptest.NewServer(bridge)
+	defer server.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected unauthenticated request to be rejected, got %v", err)
+	}
+
+	alice := dialAgent(t, server, "t-alice")
+	defer alice.Close()
+	bob := dialAgent(t, server, "t-bob")
+	defer bob.Close()
+
+	// Wait for both subscriptions to be registered
+	for i := 0; i < 100; i++ {
+		bridge.mu.RLock()
+		n := len(bridge.subscribers)
+		bridge.mu.RUnlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	bridge.Assign("call-1", "alice")
+	bridge.Observe(&Event{Event: "asrFinal", Text: "I need help", Context: &CallContext{CallID: "call-1"}})
+	bridge.PublishTranscript("call-2", "caller", "unassigned call")
+
+	var event AgentEvent
+	alice.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := alice.ReadJSON(&event); err != nil || event.Type != AgentEventAssigned {
+		t.Fatalf("Expected assignment event, got %+v (%v)", event, err)
+	}
+	if err := alice.ReadJSON(&event); err != nil || event.Type != AgentEventTranscript || event.Text != "I need help" {
+		t.Errorf("Expected transcript event, got %+v (%v)", event, err)
+	}
+
+	bob.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if err := bob.ReadJSON(&event); err == nil {
+		t.Errorf("Expected no events for another agent's call, got %+v", event)
+	}
+}
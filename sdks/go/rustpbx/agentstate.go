@@ -0,0 +1,175 @@
+package rustpbx
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AgentState is the availability of an agent for routing
+type AgentState string
+
+const (
+	// AgentAvailable agents are offered queued calls
+	AgentAvailable AgentState = "available"
+	// AgentBusy agents are on a call
+	AgentBusy AgentState = "busy"
+	// AgentWrapUp agents are finishing after-call work and become available when the wrap-up timer ends
+	AgentWrapUp AgentState = "wrap_up"
+	// AgentOffline agents are logged out or on a break
+	AgentOffline AgentState = "offline"
+)
+
+// valid reports whether the state is one of the known states
+func (s AgentState) valid() bool {
+	switch s {
+	case AgentAvailable, AgentBusy, AgentWrapUp, AgentOffline:
+		return true
+	}
+	return false
+}
+
+// AgentStateChange represents an agent moving from one state to another
+type AgentStateChange struct {
+	AgentID string
+	From    AgentState
+	To      AgentState
+	// CallID is the call that made the agent busy or put them in wrap-up
+	CallID string
+	At     time.Time
+}
+
+// AgentStatus reports the current state of an agent
+type AgentStatus struct {
+	Agent  *Agent
+	State  AgentState
+	Since  time.Time
+	CallID string
+}
+
+// SetState changes the state of an agent. Setting an agent available ends their wrap-up early.
+func (p *AgentPool) SetState(id string, state AgentState) error {
+	if !state.valid() {
+		return fmt.Errorf("invalid agent state: %s", state)
+	}
+	p.mu.Lock()
+	defer p.unlock()
+	agent, ok := p.agents[id]
+	if !ok {
+		return fmt.Errorf("unknown agent: %s", id)
+	}
+	callID := ""
+	if state == AgentBusy || state == AgentWrapUp {
+		callID = agent.callID
+	}
+	p.setStateLocked(agent, state, callID)
+	if state == AgentAvailable {
+		p.dispatchLocked()
+	}
+	return nil
+}
+
+// Agent returns the status of an agent
+func (p *AgentPool) Agent(id string) (AgentStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	agent, ok := p.agents[id]
+	if !ok {
+		return AgentStatus{}, false
+	}
+	return agent.status(), true
+}
+
+// Agents returns the status of all agents, in the order they were added
+func (p *AgentPool) Agents() []AgentStatus {
+	p.mu.Lock()
+	agents := make([]*poolAgent, 0, len(p.agents))
+	for _, agent := range p.agents {
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].order < agents[j].order })
+	statuses := make([]AgentStatus, len(agents))
+	for i, agent := range agents {
+		statuses[i] = agent.status()
+	}
+	p.mu.Unlock()
+	return statuses
+}
+
+// status returns the public status of the agent
+func (a *poolAgent) status() AgentStatus {
+	return AgentStatus{Agent: a.agent, State: a.state, Since: a.since, CallID: a.callID}
+}
+
+// stopWrapUp cancels the agent's wrap-up timer
+func (a *poolAgent) stopWrapUp() {
+	if a.wrapUp != nil {
+		a.wrapUp.Stop()
+		a.wrapUp = nil
+	}
+}
+
+// setStateLocked moves an agent to a state and queues the change notification.
+// It must be called with p.mu held.
+func (p *AgentPool) setStateLocked(agent *poolAgent, state AgentState, callID string) {
+	from := agent.state
+	agent.stopWrapUp()
+	now := time.Now()
+	agent.state, agent.callID, agent.since = state, callID, now
+	if from == AgentBusy || (state == AgentAvailable && from != AgentAvailable) {
+		agent.idleSince = now
+	}
+
+	if state == AgentWrapUp && p.options.WrapUp > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(p.options.WrapUp, func() {
+			p.mu.Lock()
+			defer p.unlock()
+			// Ignore a timer stopped after it fired
+			if agent.wrapUp != timer {
+				return
+			}
+			p.setStateLocked(agent, AgentAvailable, "")
+			p.dispatchLocked()
+		})
+		agent.wrapUp = timer
+	}
+
+	if call := agent.call; call != nil {
+		if from == AgentBusy && state != AgentBusy {
+			call.ended = now
+		}
+		if state != AgentBusy && state != AgentWrapUp {
+			p.records = append(p.records, call.record(agent.agent.ID, false))
+			agent.call = nil
+		}
+	}
+
+	if from != state {
+		p.changes = append(p.changes, AgentStateChange{
+			AgentID: agent.agent.ID,
+			From:    from,
+			To:      state,
+			CallID:  callID,
+			At:      now,
+		})
+	}
+}
+
+// unlock releases p.mu and notifies the state changes and call records made while it was held
+func (p *AgentPool) unlock() {
+	changes, records := p.changes, p.records
+	p.changes, p.records = nil, nil
+	p.mu.Unlock()
+
+	if p.options.OnStateChange != nil {
+		for _, change := range changes {
+			p.options.OnStateChange(change)
+		}
+	}
+	if p.options.OnCallRecord != nil {
+		for _, record := range records {
+			p.options.OnCallRecord(record)
+		}
+	}
+}
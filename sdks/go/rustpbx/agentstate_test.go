@@ -0,0 +1,91 @@
+package rustpbx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentWrapUp(t *testing.T) {
+	var mu sync.Mutex
+	var changes []AgentStateChange
+	pool := NewAgentPool(&AgentPoolOptions{
+		WrapUp: 50 * time.Millisecond,
+		OnStateChange: func(change AgentStateChange) {
+			mu.Lock()
+			changes = append(changes, change)
+			mu.Unlock()
+		},
+	})
+	pool.AddAgent(&Agent{ID: "ann"}, AgentAvailable)
+	queue := pool.NewQueue(QueueOptions{})
+
+	agent, _ := queue.Enqueue(context.Background(), &QueuedCall{ID: "call-1"})
+	pool.Release(agent.ID)
+	if status, _ := pool.Agent("ann"); status.State != AgentWrapUp || status.CallID != "call-1" {
+		t.Errorf("Expected ann in wrap-up for call-1, got %+v", status)
+	}
+
+	start := time.Now()
+	if _, err := queue.Enqueue(context.Background(), &QueuedCall{ID: "call-2"}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("Expected the next call to wait for the wrap-up, waited %v", waited)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []AgentState{AgentAvailable, AgentBusy, AgentWrapUp, AgentAvailable, AgentBusy}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d state changes, got %+v", len(expected), changes)
+	}
+	for i, state := range expected {
+		if changes[i].To != state {
+			t.Errorf("Change %d: expected %s, got %s", i, state, changes[i].To)
+		}
+	}
+	if changes[1].CallID != "call-1" {
+		t.Errorf("Expected busy change to carry the call ID, got '%s'", changes[1].CallID)
+	}
+}
+
+func TestAgentStateRouting(t *testing.T) {
+	pool := NewAgentPool(&AgentPoolOptions{WrapUp: time.Hour})
+	pool.AddAgent(&Agent{ID: "ann"}, AgentOffline)
+	queue := pool.NewQueue(QueueOptions{})
+
+	assigned := make(chan *Agent, 1)
+	go func() {
+		agent, _ := queue.Enqueue(context.Background(), &QueuedCall{ID: "call-1"})
+		assigned <- agent
+	}()
+
+	select {
+	case <-assigned:
+		t.Fatal("Expected offline agent not to be offered calls")
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	if err := pool.SetState("ann", AgentAvailable); err != nil {
+		t.Fatalf("SetState failed: %v", err)
+	}
+	if agent := <-assigned; agent.ID != "ann" {
+		t.Errorf("Expected ann to take the call, got %+v", agent)
+	}
+
+	// Ending wrap-up early makes the agent available again
+	pool.Release("ann")
+	pool.SetState("ann", AgentAvailable)
+	if statuses := pool.Agents(); len(statuses) != 1 || statuses[0].State != AgentAvailable {
+		t.Errorf("Expected ann available, got %+v", statuses)
+	}
+
+	if err := pool.SetState("ann", "lunch"); err == nil {
+		t.Error("Expected error for an unknown state")
+	}
+	if err := pool.SetState("bob", AgentOffline); err == nil {
+		t.Error("Expected error for an unknown agent")
+	}
+}
@@ -0,0 +1,124 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCallNotAnswered is wrapped by the errors of the call helpers when the call ended or
+// failed before it was answered
+var ErrCallNotAnswered = errors.New("call not answered")
+
+// CallFailedError reports the hangup, reject or error event that ended a call before it
+// was answered
+type CallFailedError struct {
+	Event *Event
+}
+
+func (e *CallFailedError) Error() string {
+	switch e.Event.Event {
+	case "error":
+		return fmt.Sprintf("call failed before answer: %s", e.Event.Error)
+	case "reject":
+		return fmt.Sprintf("call rejected with code %d: %s", e.Event.Code, e.Event.Reason)
+	case "unencryptedMedia":
+		return "call refused: unencrypted media"
+	}
+	return fmt.Sprintf("call hung up before answer: %s", e.Event.Reason)
+}
+
+// Unwrap makes the error match ErrCallNotAnswered, and the *CommandError of a reject or
+// error event or ErrUnencryptedMedia
+func (e *CallFailedError) Unwrap() []error {
+	if e.Event.Event == "unencryptedMedia" {
+		return []error{ErrCallNotAnswered, ErrUnencryptedMedia}
+	}
+	if err := e.Event.Err(); err != nil {
+		return []error{ErrCallNotAnswered, err}
+	}
+	return []error{ErrCallNotAnswered}
+}
+
+// AnswerResult describes an answered call
+type AnswerResult struct {
+	// SDP is the remote SDP of the answer and MediaAddresses its media addresses
+	SDP            string
+	MediaAddresses []MediaAddress
+	// Encryption is the protection of the media negotiated by the answer
+	Encryption MediaEncryption
+	Answer     *Event
+	// Ringing is the first ringing event, or nil if the call was answered without ringing
+	Ringing    *Event
+	EarlyMedia bool
+	// RingDelay is the time from the command to the first ringing event and AnswerDelay
+	// the time from the command to the answer
+	RingDelay   time.Duration
+	AnswerDelay time.Duration
+}
+
+// InviteAndWaitAnswer sends an invite and waits until the call is answered. It fails with
+// a *CallFailedError if the call is rejected, hangs up or fails first, or with the error of ctx, which
+// should bound the wait.
+func (c *Connection) InviteAndWaitAnswer(ctx context.Context, option *CallOption) (*AnswerResult, error) {
+	return c.waitAnswer(ctx, func() error {
+		return c.InviteContext(ctx, option)
+	})
+}
+
+// AcceptAndWaitAnswer accepts an incoming call and waits until it is answered, like
+// InviteAndWaitAnswer
+func (c *Connection) AcceptAndWaitAnswer(ctx context.Context, option *CallOption) (*AnswerResult, error) {
+	return c.waitAnswer(ctx, func() error {
+		return c.AcceptContext(ctx, option)
+	})
+}
+
+// waitAnswer sends a command and waits for the call to be answered or fail
+func (c *Connection) waitAnswer(ctx context.Context, send func() error) (*AnswerResult, error) {
+	events, unsubscribe := c.subscribe(func(event *Event) bool {
+		switch event.Event {
+		case "ringing", "answer", "reject", "hangup", "unencryptedMedia":
+			return true
+		case "error":
+			// Errors of the application's own handlers and goroutines do not fail the call
+			return event.Sender != "handler" && event.Sender != "task"
+		}
+		return false
+	})
+	defer unsubscribe()
+
+	start := time.Now()
+	if err := send(); err != nil {
+		return nil, err
+	}
+
+	result := &AnswerResult{}
+	for {
+		select {
+		case event := <-events:
+			switch event.Event {
+			case "ringing":
+				if result.Ringing == nil {
+					result.Ringing = event
+					result.RingDelay = time.Since(start)
+				}
+				result.EarlyMedia = result.EarlyMedia || event.EarlyMedia
+			case "answer":
+				result.Answer = event
+				result.SDP = event.SDP
+				result.MediaAddresses = ParseMediaAddresses(event.SDP)
+				result.Encryption = ParseMediaEncryption(event.SDP)
+				result.AnswerDelay = time.Since(start)
+				return result, nil
+			default:
+				return nil, &CallFailedError{Event: event}
+			}
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for answer: %w", ctx.Err())
+		case <-c.done:
+			return nil, fmt.Errorf("%w: %w", ErrCallNotAnswered, ErrConnectionClosed)
+		}
+	}
+}
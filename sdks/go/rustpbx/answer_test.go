@@ -0,0 +1,83 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// answerServer replies to invites with the events given for each callee
+func answerServer(t *testing.T, replies map[string][]map[string]interface{}) string {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd struct {
+				Command string      `json:"command"`
+				Option  *CallOption `json:"option"`
+			}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			for _, event := range replies[cmd.Option.Callee] {
+				time.Sleep(10 * time.Millisecond)
+				conn.WriteJSON(event)
+			}
+		}
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestInviteAndWaitAnswer(t *testing.T) {
+	wsURL := answerServer(t, map[string][]map[string]interface{}{
+		"sip:alice@example.com": {
+			{"event": "ringing", "earlyMedia": true},
+			{"event": "dtmf", "digit": "1"},
+			{"event": "answer", "sdp": "v=0"},
+		},
+		"sip:bob@example.com":   {{"event": "ringing"}, {"event": "hangup", "reason": "busy"}},
+		"sip:carol@example.com": {{"event": "error", "error": "no route"}},
+		"sip:dave@example.com":  {{"event": "ringing"}},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	conn, err := NewConnection(ctx, wsURL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	result, err := conn.InviteAndWaitAnswer(ctx, &CallOption{Callee: "sip:alice@example.com"})
+	if err != nil {
+		t.Fatalf("Expected the call to be answered, got %v", err)
+	}
+	if result.SDP != "v=0" || !result.EarlyMedia || result.Ringing == nil || result.RingDelay <= 0 || result.AnswerDelay < result.RingDelay {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	for callee, expected := range map[string]string{"sip:bob@example.com": "busy", "sip:carol@example.com": "no route"} {
+		conn, err := NewConnection(ctx, wsURL)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		_, err = conn.InviteAndWaitAnswer(ctx, &CallOption{Callee: callee})
+		var failed *CallFailedError
+		if !errors.As(err, &failed) || !errors.Is(err, ErrCallNotAnswered) || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %s to fail with %s, got %v", callee, expected, err)
+		}
+		conn.Close()
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelShort()
+	conn, err = NewConnection(ctx, wsURL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.InviteAndWaitAnswer(short, &CallOption{Callee: "sip:dave@example.com"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package rustpbx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrArtifactCorrupt is returned when an encrypted artifact cannot be decrypted, because
+// it was altered, truncated or encrypted with another key
+var ErrArtifactCorrupt = errors.New("artifact corrupt or encrypted with another key")
+
+// maxArtifactRecord bounds the records read back, so a corrupt length cannot exhaust memory
+const maxArtifactRecord = 64 << 20
+
+// KeyProvider returns the 256-bit data key artifacts are encrypted with, e.g. by
+// unwrapping it with a KMS
+type KeyProvider func(ctx context.Context) ([]byte, error)
+
+// EnvKey provides the base64 encoded key held in an environment variable
+func EnvKey(name string) KeyProvider {
+	return func(ctx context.Context) ([]byte, error) {
+		value := os.Getenv(name)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key from %s: %w", name, err)
+		}
+		return key, nil
+	}
+}
+
+// ArtifactCipher encrypts the artifacts an application writes to local disk, such as
+// wire dumps, recordings and response caches, with AES-256-GCM. An artifact is a random
+// ID followed by a sequence of records, each sealed with its own random nonce and
+// authenticated with the artifact ID, its index and whether it is the last record, so
+// records moved between artifacts, reordered, dropped or cut off are detected. Audio and
+// logs can be written as they are produced; a closed artifact cannot be appended to.
+type ArtifactCipher struct {
+	aead cipher.AEAD
+}
+
+// artifactIDSize is the size of the random ID that starts an artifact
+const artifactIDSize = 16
+
+// NewArtifactCipher creates a cipher with the key supplied by provider
+func NewArtifactCipher(ctx context.Context, provider KeyProvider) (*ArtifactCipher, error) {
+	key, err := provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("artifact key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &ArtifactCipher{aead: aead}, nil
+}
+
+// recordAAD returns the additional data of a record: the artifact ID, the record index
+// and the last record flag
+func recordAAD(id []byte, index uint64, final bool) []byte {
+	aad := make([]byte, len(id)+9)
+	copy(aad, id)
+	binary.BigEndian.PutUint64(aad[len(id):], index)
+	if final {
+		aad[len(aad)-1] = 1
+	}
+	return aad
+}
+
+// seal encrypts a record: its length, then the nonce and the sealed data
+func (c *ArtifactCipher) seal(id []byte, index uint64, final bool, data []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	size := nonceSize + len(data) + c.aead.Overhead()
+	record := make([]byte, 4+nonceSize, 4+size)
+	binary.BigEndian.PutUint32(record, uint32(size))
+	if _, err := rand.Read(record[4:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(record, record[4:], data, recordAAD(id, index, final)), nil
+}
+
+// open reads and decrypts the record at index and reports whether it is the last one;
+// io.EOF when there is none
+func (c *ArtifactCipher) open(r *bufio.Reader, id []byte, index uint64) ([]byte, bool, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, false, ErrArtifactCorrupt
+		}
+		return nil, false, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	nonceSize := c.aead.NonceSize()
+	if size < uint32(nonceSize+c.aead.Overhead()) || size > maxArtifactRecord {
+		return nil, false, ErrArtifactCorrupt
+	}
+	record := make([]byte, size)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, false, ErrArtifactCorrupt
+	}
+	for _, final := range []bool{false, true} {
+		data, err := c.aead.Open(nil, record[:nonceSize], record[nonceSize:], recordAAD(id, index, final))
+		if err == nil {
+			return data, final, nil
+		}
+	}
+	return nil, false, ErrArtifactCorrupt
+}
+
+// WriteFile writes data encrypted to a file, replacing it
+func (c *ArtifactCipher) WriteFile(path string, data []byte, perm os.FileMode) error {
+	var artifact bytes.Buffer
+	w := c.NewWriter(&artifact)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, artifact.Bytes(), perm); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return nil
+}
+
+// ReadFile reads and decrypts a file written by WriteFile or a writer
+func (c *ArtifactCipher) ReadFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	defer file.Close()
+
+	var data bytes.Buffer
+	if _, err := io.Copy(&data, c.NewReader(file)); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// CreateFile creates or truncates a file and returns a writer encrypting to it. Close
+// completes the artifact and closes the file.
+func (c *ArtifactCipher) CreateFile(path string, perm os.FileMode) (io.WriteCloser, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artifact: %w", err)
+	}
+	return &artifactFile{WriteCloser: c.NewWriter(file), file: file}, nil
+}
+
+// NewWriter returns a writer encrypting each write as a record to w. Close writes the
+// last record, without which the artifact reads as truncated; it does not close w.
+func (c *ArtifactCipher) NewWriter(w io.Writer) io.WriteCloser {
+	return &artifactWriter{cipher: c, w: w}
+}
+
+// NewReader returns a reader decrypting the records read from r. It fails with
+// ErrArtifactCorrupt when the records were altered, reordered or cut off.
+func (c *ArtifactCipher) NewReader(r io.Reader) io.Reader {
+	return &artifactReader{cipher: c, r: bufio.NewReader(r)}
+}
+
+// artifactWriter encrypts writes as records
+type artifactWriter struct {
+	cipher *ArtifactCipher
+	w      io.Writer
+	id     []byte
+	index  uint64
+	closed bool
+}
+
+func (w *artifactWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("artifact writer is closed")
+	}
+	if err := w.writeRecord(p, false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *artifactWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.writeRecord(nil, true)
+}
+
+// writeRecord seals and writes the next record, starting the artifact with its ID
+func (w *artifactWriter) writeRecord(data []byte, final bool) error {
+	if w.id == nil {
+		id := make([]byte, artifactIDSize)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("failed to generate artifact ID: %w", err)
+		}
+		if _, err := w.w.Write(id); err != nil {
+			return fmt.Errorf("failed to write artifact: %w", err)
+		}
+		w.id = id
+	}
+	record, err := w.cipher.seal(w.id, w.index, final, data)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write artifact: %w", err)
+	}
+	w.index++
+	return nil
+}
+
+// artifactFile is an artifact writer closing its file
+type artifactFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (f *artifactFile) Close() error {
+	err := f.WriteCloser.Close()
+	if closeErr := f.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close artifact: %w", closeErr)
+	}
+	return err
+}
+
+// artifactReader decrypts records
+type artifactReader struct {
+	cipher  *ArtifactCipher
+	r       *bufio.Reader
+	id      []byte
+	index   uint64
+	done    bool
+	pending []byte
+}
+
+func (r *artifactReader) Read(p []byte) (int, error) {
+	if r.id == nil {
+		id := make([]byte, artifactIDSize)
+		if _, err := io.ReadFull(r.r, id); err != nil {
+			return 0, ErrArtifactCorrupt
+		}
+		r.id = id
+	}
+	for len(r.pending) == 0 {
+		if r.done {
+			// Nothing may follow the last record
+			if _, err := r.r.ReadByte(); err != io.EOF {
+				return 0, ErrArtifactCorrupt
+			}
+			return 0, io.EOF
+		}
+		data, final, err := r.cipher.open(r.r, r.id, r.index)
+		if err == io.EOF {
+			// The last record is missing
+			return 0, ErrArtifactCorrupt
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.index++
+		r.pending, r.done = data, final
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
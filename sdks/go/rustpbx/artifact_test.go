@@ -0,0 +1,99 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactCipher(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	t.Setenv("RUSTPBX_ARTIFACT_KEY", base64.StdEncoding.EncodeToString(key))
+	cipher, err := NewArtifactCipher(context.Background(), EnvKey("RUSTPBX_ARTIFACT_KEY"))
+	if err != nil {
+		t.Fatalf("NewArtifactCipher failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "recording.wav")
+	audio := bytes.Repeat([]byte("RIFF audio "), 1000)
+	if err := cipher.WriteFile(path, audio, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("RIFF")) {
+		t.Error("Expected the artifact to be encrypted")
+	}
+	if data, err := cipher.ReadFile(path); err != nil || !bytes.Equal(data, audio) {
+		t.Errorf("Expected the audio back, got %d bytes, %v", len(data), err)
+	}
+
+	// Audit logs are written record by record
+	var log bytes.Buffer
+	w := cipher.NewWriter(&log)
+	w.Write([]byte("call-1 payment started\n"))
+	w.Write([]byte("call-1 payment completed\n"))
+	w.Close()
+	data, err := io.ReadAll(cipher.NewReader(bytes.NewReader(log.Bytes())))
+	if err != nil || string(data) != "call-1 payment started\ncall-1 payment completed\n" {
+		t.Errorf("Unexpected log: '%s', %v", data, err)
+	}
+
+	tampered := append([]byte(nil), log.Bytes()...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := io.ReadAll(cipher.NewReader(bytes.NewReader(tampered))); !errors.Is(err, ErrArtifactCorrupt) {
+		t.Errorf("Expected ErrArtifactCorrupt for altered data, got %v", err)
+	}
+	if _, err := io.ReadAll(cipher.NewReader(bytes.NewReader(log.Bytes()[:10]))); !errors.Is(err, ErrArtifactCorrupt) {
+		t.Errorf("Expected ErrArtifactCorrupt for truncated data, got %v", err)
+	}
+
+	id, records := splitArtifact(t, log.Bytes())
+	var another bytes.Buffer
+	w = cipher.NewWriter(&another)
+	w.Write([]byte("call-2 payment started\n"))
+	w.Close()
+	_, anotherRecords := splitArtifact(t, another.Bytes())
+	for name, altered := range map[string][][]byte{
+		"reordered": {id, records[1], records[0], records[2]},
+		"dropped":   {id, records[1], records[2]},
+		"truncated": {id, records[0], records[1]},
+		"appended":  {id, records[0], records[1], records[2], records[2]},
+		"spliced":   {id, anotherRecords[0], records[1], records[2]},
+	} {
+		_, err := io.ReadAll(cipher.NewReader(bytes.NewReader(bytes.Join(altered, nil))))
+		if !errors.Is(err, ErrArtifactCorrupt) {
+			t.Errorf("Expected ErrArtifactCorrupt for %s records, got %v", name, err)
+		}
+	}
+
+	other, _ := NewArtifactCipher(context.Background(), func(ctx context.Context) ([]byte, error) {
+		return make([]byte, 32), nil
+	})
+	if _, err := other.ReadFile(path); !errors.Is(err, ErrArtifactCorrupt) {
+		t.Errorf("Expected another key to fail, got %v", err)
+	}
+	if _, err := NewArtifactCipher(context.Background(), EnvKey("RUSTPBX_MISSING_KEY")); err == nil {
+		t.Error("Expected a missing key to fail")
+	}
+}
+
+// splitArtifact returns the ID and the records of an artifact
+func splitArtifact(t *testing.T, artifact []byte) ([]byte, [][]byte) {
+	t.Helper()
+	id, rest := artifact[:artifactIDSize], artifact[artifactIDSize:]
+	var records [][]byte
+	for len(rest) > 0 {
+		size := 4 + int(binary.BigEndian.Uint32(rest))
+		records = append(records, rest[:size])
+		rest = rest[size:]
+	}
+	return id, records
+}
@@ -0,0 +1,397 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// PatiencePolicy controls how long the assistant waits after the caller stops
+// speaking before it answers
+type PatiencePolicy struct {
+	// Base is the fixed wait used when Adaptive is off
+	Base time.Duration
+	// Adaptive answers as soon as the reply is ready, but not before Min. Until the
+	// reply is spoken the caller may keep talking, which extends the turn; with a
+	// fixed patience the turn is committed once Base has elapsed.
+	Adaptive bool
+	Min      time.Duration
+	// Max is how long to wait for a slow reply before speaking Filler, if set
+	Max    time.Duration
+	Filler string
+}
+
+// DefaultPatiencePolicy answers adaptively between 300ms and 2s after the caller stops speaking
+var DefaultPatiencePolicy = PatiencePolicy{
+	Base:     800 * time.Millisecond,
+	Adaptive: true,
+	Min:      300 * time.Millisecond,
+	Max:      2 * time.Second,
+}
+
+// AssistantOptions represents voice assistant configuration
+type AssistantOptions struct {
+	LLM          LLM
+	SystemPrompt string
+	Speaker      string
+	// Greeting is spoken when the call is answered
+	Greeting string
+	// FallbackText is spoken when the LLM fails
+	FallbackText string
+	// Patience defaults to DefaultPatiencePolicy
+	Patience *PatiencePolicy
+	// SpeculativePartials starts preparing a reply from asrDelta partials before the
+	// final transcript arrives. A revised partial cancels the speculation; the reply is
+	// only spoken if the final transcript matches. Experimental.
+	SpeculativePartials bool
+	// Cache answers repeated questions without calling the LLM
+	Cache *ResponseCache
+	// Guardrails filter every reply before it is spoken
+	Guardrails *GuardrailChain
+	// Prose converts markdown replies into speakable prose; the history keeps the replies as written
+	Prose *MarkdownProse
+	// Verbosity bounds the length of spoken replies, offering the rest as more detail
+	Verbosity *VerbosityPolicy
+	// Personas are the roles the assistant can switch between with SwitchPersona.
+	// The active persona's prompt and voice replace SystemPrompt and Speaker.
+	Personas []Persona
+	// Persona names the initial persona; defaults to the first of Personas
+	Persona string
+}
+
+// assistantTurn tracks the preparation of one reply
+type assistantTurn struct {
+	text        string
+	persona     *Persona
+	speculative bool
+	cancel      context.CancelFunc
+	reply       string
+	err         error
+	ready       bool
+	cached      bool
+	waited      bool
+	spoken      bool
+	filled      bool
+	timers      []*time.Timer
+}
+
+// stop cancels the turn's reply preparation and timers
+func (t *assistantTurn) stop() {
+	t.cancel()
+	for _, timer := range t.timers {
+		timer.Stop()
+	}
+}
+
+// Assistant runs an LLM-driven voice conversation over a connection.
+// Feed it every connection event with HandleEvent.
+type Assistant struct {
+	conn     *Connection
+	options  AssistantOptions
+	patience PatiencePolicy
+	personas map[string]*Persona
+
+	mu        sync.Mutex
+	persona   *Persona
+	history   []ChatMessage
+	utterance []string
+	turn      *assistantTurn
+	// more is the rest of a reply cut by the verbosity policy
+	more string
+}
+
+// NewAssistant creates an assistant for a connection
+func NewAssistant(conn *Connection, options *AssistantOptions) *Assistant {
+	if options == nil {
+		options = &AssistantOptions{}
+	}
+	opts := *options
+	conn.config.applyAssistant(&opts)
+	if opts.FallbackText == "" {
+		opts.FallbackText = "I'm sorry, I'm having trouble processing that right now. Could you please repeat?"
+	}
+	patience := DefaultPatiencePolicy
+	if opts.Patience != nil {
+		patience = *opts.Patience
+	}
+
+	a := &Assistant{
+		conn:     conn,
+		options:  opts,
+		patience: patience,
+		personas: make(map[string]*Persona),
+	}
+	a.applyFlags()
+	for i := range opts.Personas {
+		persona := &opts.Personas[i]
+		a.personas[persona.Name] = persona
+		if a.persona == nil || persona.Name == opts.Persona {
+			a.persona = persona
+		}
+	}
+
+	prompt := opts.SystemPrompt
+	if a.persona != nil {
+		prompt = a.persona.SystemPrompt
+	}
+	a.setSystemPrompt(prompt)
+	return a
+}
+
+// History returns a copy of the conversation so far
+func (a *Assistant) History() []ChatMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]ChatMessage(nil), a.history...)
+}
+
+// HandleEvent drives the conversation from a connection event
+func (a *Assistant) HandleEvent(event *Event) {
+	switch event.Event {
+	case "answer":
+		if a.options.Greeting != "" {
+			a.mu.Lock()
+			a.history = append(a.history, ChatMessage{Role: "assistant", Content: a.options.Greeting})
+			a.mu.Unlock()
+			a.speak(a.options.Greeting)
+			a.conn.History("assistant", a.options.Greeting)
+		}
+	case "asrDelta":
+		if text := strings.TrimSpace(event.Text); text != "" && a.options.SpeculativePartials {
+			a.onPartial(text)
+		}
+	case "asrFinal":
+		if text := strings.TrimSpace(event.Text); text != "" {
+			a.onFinal(text)
+		}
+	case "speaking":
+		a.onSpeaking()
+	case "hangup":
+		a.mu.Lock()
+		if a.turn != nil {
+			a.turn.stop()
+			a.turn = nil
+		}
+		a.mu.Unlock()
+	}
+}
+
+// onPartial speculatively prepares a reply to a partial transcript
+func (a *Assistant) onPartial(text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A committed turn is left alone; the caller speaking again abandons it
+	if a.turn != nil && !a.turn.speculative {
+		return
+	}
+
+	full := strings.Join(append(append([]string(nil), a.utterance...), text), " ")
+	if a.turn != nil {
+		if normalizeUtterance(a.turn.text) == normalizeUtterance(full) {
+			return
+		}
+		// The hypothesis was revised
+		a.turn.stop()
+	}
+	a.turn = a.startTurn(full)
+	a.turn.speculative = true
+}
+
+// onFinal commits the caller's turn, adopting a matching speculative reply
+func (a *Assistant) onFinal(text string) {
+	if a.options.Verbosity != nil && a.continueReply(text) {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.utterance = append(a.utterance, text)
+	full := strings.Join(a.utterance, " ")
+
+	turn := a.turn
+	if turn == nil || !turn.speculative || normalizeUtterance(turn.text) != normalizeUtterance(full) {
+		if turn != nil {
+			turn.stop()
+		}
+		turn = a.startTurn(full)
+		a.turn = turn
+	}
+	turn.speculative = false
+
+	wait := a.patience.Base
+	if a.patience.Adaptive {
+		wait = a.patience.Min
+	}
+	turn.timers = append(turn.timers, time.AfterFunc(wait, func() {
+		a.mu.Lock()
+		turn.waited = true
+		a.mu.Unlock()
+		a.respond(turn)
+	}))
+	if a.patience.Max > 0 && a.patience.Filler != "" {
+		turn.timers = append(turn.timers, time.AfterFunc(a.patience.Max, func() {
+			a.mu.Lock()
+			filler := a.turn == turn && !turn.ready && !turn.filled
+			turn.filled = true
+			a.mu.Unlock()
+			if filler {
+				a.speak(a.patience.Filler)
+			}
+		}))
+	}
+}
+
+// startTurn starts preparing a reply to text; the caller must hold a.mu
+func (a *Assistant) startTurn(text string) *assistantTurn {
+	turn := &assistantTurn{text: text, persona: a.persona, cancel: func() {}}
+
+	if a.options.Cache != nil {
+		if reply, ok := a.options.Cache.Get(cacheKey(turn.persona, text)); ok {
+			turn.reply, turn.ready, turn.cached = reply, true, true
+			return turn
+		}
+	}
+
+	a.prepareTurn(turn)
+	return turn
+}
+
+// prepareTurn starts asking the LLM for the reply of a turn with the active persona;
+// the caller must hold a.mu
+func (a *Assistant) prepareTurn(turn *assistantTurn) {
+	ctx, cancel := context.WithCancel(WithCallContext(a.conn.ctx, a.conn.callContext))
+	if a.persona != nil {
+		ctx = withPersona(ctx, a.persona)
+	}
+	turn.persona, turn.cancel = a.persona, cancel
+	turn.reply, turn.err, turn.ready, turn.cached = "", nil, false, false
+
+	messages := append(append([]ChatMessage(nil), a.history...), ChatMessage{
+		Role:    "user",
+		Content: turn.text,
+	})
+	go a.prepare(ctx, turn, messages)
+}
+
+// cacheKey keeps the cached answers of each persona apart
+func cacheKey(persona *Persona, text string) string {
+	if persona == nil {
+		return text
+	}
+	return persona.Name + " " + text
+}
+
+// normalizeUtterance reduces a transcript to lower-case words for comparison
+func normalizeUtterance(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+	return strings.Join(words, " ")
+}
+
+// onSpeaking abandons an unspoken reply because the caller kept talking
+func (a *Assistant) onSpeaking() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.turn == nil || a.turn.spoken {
+		return
+	}
+	// With a fixed patience the turn is committed once the wait has elapsed
+	if !a.patience.Adaptive && a.turn.waited {
+		return
+	}
+	a.turn.stop()
+	a.turn = nil
+}
+
+// prepare asks the LLM for the reply of a turn
+func (a *Assistant) prepare(ctx context.Context, turn *assistantTurn, messages []ChatMessage) {
+	reply, err := a.options.LLM.Complete(ctx, messages)
+
+	a.mu.Lock()
+	if ctx.Err() != nil {
+		// The turn was abandoned or is prepared again
+		a.mu.Unlock()
+		return
+	}
+	turn.reply, turn.err, turn.ready = reply, err, true
+	a.mu.Unlock()
+
+	if a.conn.profiler != nil {
+		a.conn.profiler.Mark(StageLLMFirstToken)
+	}
+	a.respond(turn)
+}
+
+// respond speaks the reply of a turn once it is ready and the patience has elapsed
+func (a *Assistant) respond(turn *assistantTurn) {
+	a.mu.Lock()
+	if a.turn != turn || !turn.ready || !turn.waited || turn.spoken {
+		a.mu.Unlock()
+		return
+	}
+	turn.spoken = true
+	turn.stop()
+	a.turn = nil
+
+	utterance := turn.text
+	a.utterance = nil
+	speaker := a.speaker()
+	a.mu.Unlock()
+
+	if turn.err != nil {
+		a.conn.handleError(fmt.Errorf("assistant LLM error: %w", turn.err))
+		a.speakAs(a.options.FallbackText, speaker)
+		return
+	}
+
+	reply, verdict := turn.reply, GuardrailAllow
+	if a.options.Guardrails != nil && !turn.cached {
+		ctx := WithCallContext(a.conn.ctx, a.conn.callContext)
+		reply, verdict = a.conn.applyGuardrails(ctx, a.options.Guardrails, reply)
+	}
+
+	a.mu.Lock()
+	a.history = append(a.history,
+		ChatMessage{Role: "user", Content: utterance},
+		ChatMessage{Role: "assistant", Content: reply},
+	)
+	a.mu.Unlock()
+
+	// Cached answers have already passed the guardrails
+	if a.options.Cache != nil && !turn.cached && verdict != GuardrailBlock {
+		a.options.Cache.Put(cacheKey(turn.persona, utterance), reply)
+	}
+
+	a.conn.History("user", utterance)
+	spoken := reply
+	if a.options.Prose != nil {
+		spoken = a.options.Prose.Convert(reply)
+	}
+	a.mu.Lock()
+	spoken = a.governReply(spoken)
+	a.mu.Unlock()
+	a.speakAs(spoken, speaker)
+	a.conn.History("assistant", reply)
+}
+
+// speak sends text to TTS with the voice of the active persona
+func (a *Assistant) speak(text string) {
+	a.mu.Lock()
+	speaker := a.speaker()
+	a.mu.Unlock()
+	a.speakAs(text, speaker)
+}
+
+// speakAs sends text to TTS with the given speaker
+func (a *Assistant) speakAs(text, speaker string) {
+	if err := a.conn.TTS(text, speaker, "", nil); err != nil {
+		a.conn.handleError(fmt.Errorf("assistant TTS error: %w", err))
+	}
+}
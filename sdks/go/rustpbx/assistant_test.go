@@ -0,0 +1,149 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextTTSCommand waits for the next TTS command received by a test server
+func nextTTSCommand(t *testing.T, commands <-chan map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case cmd := <-commands:
+			if cmd["command"] == "tts" {
+				return cmd
+			}
+		case <-timeout:
+			t.Fatal("Expected a TTS command")
+			return nil
+		}
+	}
+}
+
+// nextTTS waits for the text of the next TTS command received by a test server
+func nextTTS(t *testing.T, commands <-chan map[string]interface{}) string {
+	t.Helper()
+	text, _ := nextTTSCommand(t, commands)["text"].(string)
+	return text
+}
+
+func TestAssistantAnswersTurn(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	assistant := NewAssistant(conn, &AssistantOptions{
+		SystemPrompt: "You are terse.",
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return "You said: " + messages[len(messages)-1].Content, nil
+		}),
+		Patience: &PatiencePolicy{Adaptive: true, Min: 10 * time.Millisecond},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "hello"})
+	if text := nextTTS(t, commands); text != "You said: hello" {
+		t.Errorf("Expected reply to the caller, got '%s'", text)
+	}
+
+	history := assistant.History()
+	if len(history) != 3 || history[1].Content != "hello" {
+		t.Errorf("Expected system, user and assistant messages, got %+v", history)
+	}
+}
+
+func TestAssistantDefaultOptions(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	assistant := NewAssistant(conn, nil)
+	if assistant.options.FallbackText == "" || assistant.patience != DefaultPatiencePolicy {
+		t.Errorf("Expected default options, got %+v", assistant.options)
+	}
+}
+
+func TestAssistantCallerContinuesTurn(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	release := make(chan struct{})
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			content := messages[len(messages)-1].Content
+			if content == "book a table" {
+				// The first reply is still being prepared when the caller goes on
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			}
+			return "Reply to: " + content, nil
+		}),
+		Patience: &PatiencePolicy{Adaptive: true, Min: 10 * time.Millisecond},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "book a table"})
+	assistant.HandleEvent(&Event{Event: "speaking"})
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "for two"})
+	close(release)
+
+	if text := nextTTS(t, commands); text != "Reply to: book a table for two" {
+		t.Errorf("Expected a single reply to the whole turn, got '%s'", text)
+	}
+}
+
+func TestAssistantSpeculativePartials(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	prompts := make(chan string, 8)
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			prompts <- messages[len(messages)-1].Content
+			return "It is noon", nil
+		}),
+		Patience:            &PatiencePolicy{Adaptive: true, Min: 10 * time.Millisecond},
+		SpeculativePartials: true,
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrDelta", Text: "what tim"})
+	assistant.HandleEvent(&Event{Event: "asrDelta", Text: "what time is it"})
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "What time is it?"})
+
+	if text := nextTTS(t, commands); text != "It is noon" {
+		t.Errorf("Expected speculative reply to be spoken, got '%s'", text)
+	}
+
+	// The final transcript matches the speculation and must not trigger another LLM call
+	var calls []string
+	for len(calls) < 3 {
+		select {
+		case prompt := <-prompts:
+			calls = append(calls, prompt)
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if len(calls) != 2 {
+		t.Errorf("Expected one speculative call per hypothesis, got %v", calls)
+	}
+}
@@ -0,0 +1,117 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrInvalidAudioFrame is returned for frames that do not fit the negotiated audio format
+var ErrInvalidAudioFrame = errors.New("invalid audio frame")
+
+// AudioFormat describes the audio exchanged in binary WebSocket messages
+type AudioFormat struct {
+	Codec      Codec
+	SampleRate int
+	// BytesPerSecond is the data rate of the codec
+	BytesPerSecond int
+	// FrameAlign is the byte multiple frames must have, e.g. 2 for 16-bit linear PCM
+	FrameAlign int
+}
+
+// AudioFormatFor returns the audio format of a codec; 16kHz linear PCM when empty or
+// unknown, as RustPBX streams it
+func AudioFormatFor(codec Codec) AudioFormat {
+	switch codec {
+	case CodecPCMA:
+		return AudioFormat{Codec: CodecPCMA, SampleRate: 8000, BytesPerSecond: 8000, FrameAlign: 1}
+	case CodecG722:
+		return AudioFormat{Codec: CodecG722, SampleRate: 16000, BytesPerSecond: 8000, FrameAlign: 1}
+	case CodecPCMU:
+		return AudioFormat{Codec: CodecPCMU, SampleRate: 8000, BytesPerSecond: 8000, FrameAlign: 1}
+	default:
+		return AudioFormat{Codec: CodecPCM, SampleRate: 16000, BytesPerSecond: 32000, FrameAlign: 2}
+	}
+}
+
+// Duration returns the playing time of a frame of n bytes
+func (f AudioFormat) Duration(n int) time.Duration {
+	if f.BytesPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(f.BytesPerSecond)
+}
+
+// rtpHeaderBytes is the IPv4, UDP and RTP header size of an audio packet
+const rtpHeaderBytes = 20 + 8 + 12
+
+// Bitrate returns the bandwidth of a direction of audio in bits per second when sent in
+// packets of ptime, IP, UDP and RTP headers included; 20ms packets when zero
+func (f AudioFormat) Bitrate(ptime time.Duration) int {
+	if ptime <= 0 {
+		ptime = 20 * time.Millisecond
+	}
+	packetsPerSecond := float64(time.Second) / float64(ptime)
+	return f.BytesPerSecond*8 + int(math.Ceil(packetsPerSecond*rtpHeaderBytes*8))
+}
+
+// AudioFrame is a frame of the remote party's audio
+type AudioFrame struct {
+	Data   []byte
+	Format AudioFormat
+	// Duration is the playing time of the frame
+	Duration time.Duration
+}
+
+// AudioFormat returns the audio format negotiated with the codec of the call option sent
+// with Invite or Accept; 16kHz linear PCM before either is sent or when the option has
+// no codec
+func (c *Connection) AudioFormat() AudioFormat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.audioFormat.Codec == "" {
+		return AudioFormatFor(CodecPCM)
+	}
+	return c.audioFormat
+}
+
+// negotiateAudio records the audio format of the call option sent to the server
+func (c *Connection) negotiateAudio(option *CallOption) {
+	if option == nil || option.Codec == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioFormat = AudioFormatFor(option.Codec)
+}
+
+// WriteAudio sends a frame of audio into the call, in the negotiated audio format, e.g.
+// from a softphone or a local TTS engine
+func (c *Connection) WriteAudio(frame []byte) error {
+	return c.WriteAudioContext(context.Background(), frame)
+}
+
+// WriteAudioContext is like WriteAudio but bounded by ctx
+func (c *Connection) WriteAudioContext(ctx context.Context, frame []byte) error {
+	format := c.AudioFormat()
+	if len(frame) == 0 || len(frame)%format.FrameAlign != 0 {
+		return fmt.Errorf("%w: %d bytes of %s audio", ErrInvalidAudioFrame, len(frame), format.Codec)
+	}
+	if err := c.writeMessageContext(ctx, websocket.BinaryMessage, frame); err != nil {
+		return fmt.Errorf("failed to send audio: %w", err)
+	}
+	c.observeMediaFrame(MediaOutbound, frame)
+	return nil
+}
+
+// OnAudioFrame sets the handler of the remote party's audio, received as binary messages;
+// a nil handler removes it. It runs alongside a bridged VoiceGateway.
+func (c *Connection) OnAudioFrame(handler func(frame *AudioFrame)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frameHandler = handler
+}
@@ -0,0 +1,73 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAudioFrames(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType == websocket.BinaryMessage {
+				conn.WriteMessage(messageType, data)
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	if format := conn.AudioFormat(); format.Codec != CodecPCM || format.SampleRate != 16000 {
+		t.Errorf("Expected 16kHz linear PCM by default, got %+v", format)
+	}
+	if format := AudioFormatFor(CodecPCMU); format.SampleRate != 8000 || format.FrameAlign != 1 {
+		t.Errorf("Expected 8kHz G.711 μ-law, got %+v", format)
+	}
+	if err := conn.Invite(&CallOption{Callee: "1000", Codec: CodecPCM}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	if format := conn.AudioFormat(); format.Codec != CodecPCM || format.SampleRate != 16000 {
+		t.Errorf("Expected linear PCM from the call option, got %+v", format)
+	}
+
+	frames := make(chan *AudioFrame, 1)
+	conn.OnAudioFrame(func(frame *AudioFrame) { frames <- frame })
+
+	if err := conn.WriteAudio([]byte{1, 2, 3}); !errors.Is(err, ErrInvalidAudioFrame) {
+		t.Errorf("Expected an odd-sized PCM frame to be rejected, got %v", err)
+	}
+	frame := make([]byte, 640)
+	frame[0] = 7
+	if err := conn.WriteAudio(frame); err != nil {
+		t.Fatalf("WriteAudio failed: %v", err)
+	}
+	select {
+	case received := <-frames:
+		if !bytes.Equal(received.Data, frame) || received.Duration != 20*time.Millisecond {
+			t.Errorf("Expected the 20ms frame back, got %d bytes of %v", len(received.Data), received.Duration)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the audio frame")
+	}
+}
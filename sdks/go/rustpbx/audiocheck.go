@@ -0,0 +1,116 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SampleRateMismatch reports a component of a call option whose sample rate differs from
+// the rate of the codec, or from the other components when the option has no codec
+type SampleRateMismatch struct {
+	// Component is "recorder", "asr" or "tts"
+	Component  string
+	SampleRate int
+	// Expected is the rate of the codec, or of the first component setting one
+	Expected int
+}
+
+func (m SampleRateMismatch) String() string {
+	return fmt.Sprintf("%s sample rate %d does not match %d", m.Component, m.SampleRate, m.Expected)
+}
+
+// CheckAudioConfig checks that the sample rates of the recorder, ASR and TTS of a call
+// option match the codec, as mismatched rates play audio too fast or too slow. Without a
+// codec the rates must match each other; unset rates are not checked.
+func CheckAudioConfig(option *CallOption) []SampleRateMismatch {
+	if option == nil {
+		return nil
+	}
+	expected := 0
+	if option.Codec != "" {
+		expected = AudioFormatFor(option.Codec).SampleRate
+	}
+	var mismatches []SampleRateMismatch
+	for _, rate := range sampleRates(option) {
+		switch {
+		case rate.value == 0:
+		case expected == 0:
+			expected = rate.value
+		case rate.value != expected:
+			mismatches = append(mismatches, SampleRateMismatch{Component: rate.component, SampleRate: rate.value, Expected: expected})
+		}
+	}
+	return mismatches
+}
+
+// componentRate is the sample rate of a component of a call option
+type componentRate struct {
+	component string
+	value     int
+}
+
+// sampleRates returns the sample rates of the components of a call option, in order
+func sampleRates(option *CallOption) []componentRate {
+	var rates []componentRate
+	if option.Recorder != nil {
+		rates = append(rates, componentRate{"recorder", option.Recorder.SampleRate})
+	}
+	if option.ASR != nil {
+		rates = append(rates, componentRate{"asr", option.ASR.SampleRate})
+	}
+	if option.TTS != nil {
+		rates = append(rates, componentRate{"tts", option.TTS.SampleRate})
+	}
+	return rates
+}
+
+// alignSampleRates returns a copy of a call option with the sample rates of its components
+// set to the rate of the codec; the option is returned as is without a codec
+func alignSampleRates(option *CallOption) *CallOption {
+	if option == nil || option.Codec == "" {
+		return option
+	}
+	rate := AudioFormatFor(option.Codec).SampleRate
+	aligned := *option
+	if option.Recorder != nil {
+		recorder := *option.Recorder
+		recorder.SampleRate = rate
+		aligned.Recorder = &recorder
+	}
+	if option.ASR != nil {
+		asr := *option.ASR
+		asr.SampleRate = rate
+		aligned.ASR = &asr
+	}
+	if option.TTS != nil {
+		tts := *option.TTS
+		tts.SampleRate = rate
+		aligned.TTS = &tts
+	}
+	return &aligned
+}
+
+// checkAudio aligns the sample rates of a call option when AlignSampleRates is set, and
+// otherwise reports its mismatches as "sampleRateMismatch" events
+func (c *Connection) checkAudio(option *CallOption) *CallOption {
+	if c.alignSampleRates {
+		option = alignSampleRates(option)
+	}
+	for _, mismatch := range CheckAudioConfig(option) {
+		c.log().Warn("sample rate mismatch", "component", mismatch.Component,
+			"sampleRate", mismatch.SampleRate, "expected", mismatch.Expected)
+		data, _ := json.Marshal(map[string]interface{}{
+			"component":  mismatch.Component,
+			"sampleRate": mismatch.SampleRate,
+			"expected":   mismatch.Expected,
+		})
+		c.dispatch(&Event{
+			Event:     "sampleRateMismatch",
+			Timestamp: time.Now().UnixMilli(),
+			Error:     mismatch.String(),
+			Data:      data,
+		})
+	}
+	return option
+}
@@ -0,0 +1,101 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCheckAudioConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   *CallOption
+		expected []SampleRateMismatch
+	}{
+		{"nil", nil, nil},
+		{"unset rates", &CallOption{Codec: CodecPCM, ASR: &TranscriptionOption{}, TTS: &SynthesisOption{}}, nil},
+		{"matching codec", &CallOption{Codec: CodecG722, Recorder: &RecorderOption{SampleRate: 16000}, TTS: &SynthesisOption{SampleRate: 16000}}, nil},
+		{
+			"codec mismatch",
+			&CallOption{Codec: CodecPCMU, ASR: &TranscriptionOption{SampleRate: 8000}, TTS: &SynthesisOption{SampleRate: 16000}},
+			[]SampleRateMismatch{{Component: "tts", SampleRate: 16000, Expected: 8000}},
+		},
+		{
+			"no codec",
+			&CallOption{Recorder: &RecorderOption{SampleRate: 16000}, ASR: &TranscriptionOption{SampleRate: 8000}, TTS: &SynthesisOption{SampleRate: 16000}},
+			[]SampleRateMismatch{{Component: "asr", SampleRate: 8000, Expected: 16000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatches := CheckAudioConfig(tt.option)
+			if len(mismatches) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, mismatches)
+			}
+			for i := range mismatches {
+				if mismatches[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected[i], mismatches[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSampleRateMismatchEvent(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	received := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) { received <- event })
+
+	option := &CallOption{Callee: "1000", Codec: CodecPCMA, TTS: &SynthesisOption{SampleRate: 16000}}
+	if err := conn.Invite(option); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	select {
+	case event := <-received:
+		if event.Event != "sampleRateMismatch" || event.Error != "tts sample rate 16000 does not match 8000" {
+			t.Errorf("Expected a sampleRateMismatch event, got %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a sampleRateMismatch event")
+	}
+	cmd := <-commands
+	if rate := cmd["option"].(map[string]interface{})["tts"].(map[string]interface{})["samplerate"]; rate != float64(16000) {
+		t.Errorf("Expected the sample rate to be sent as is, got %v", rate)
+	}
+}
+
+func TestAlignSampleRates(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{AlignSampleRates: true})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	received := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) { received <- event })
+
+	option := &CallOption{Codec: CodecG722, ASR: &TranscriptionOption{SampleRate: 8000}, TTS: &SynthesisOption{}}
+	if err := conn.Accept(option); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	cmd := <-commands
+	sent := cmd["option"].(map[string]interface{})
+	for _, component := range []string{"asr", "tts"} {
+		if rate := sent[component].(map[string]interface{})["samplerate"]; rate != float64(16000) {
+			t.Errorf("Expected %s sample rate 16000, got %v", component, rate)
+		}
+	}
+	if option.ASR.SampleRate != 8000 || option.TTS.SampleRate != 0 {
+		t.Error("Expected the caller's option to be left unchanged")
+	}
+	select {
+	case event := <-received:
+		t.Errorf("Unexpected event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,92 @@
+package rustpbx
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// TokenProvider supplies bearer tokens that expire, such as OAuth access tokens. It is
+// asked for a token on every WebSocket dial and HTTP request, so it should cache tokens
+// and refresh them before they expire.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenFunc adapts a function to the TokenProvider interface
+type TokenFunc func(ctx context.Context) (string, error)
+
+// Token implements TokenProvider
+func (f TokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// ClientOptions represents client configuration, such as the credentials required by an
+// auth gateway in front of RustPBX. Credentials are sent on the WebSocket handshakes of
+// ConnectCall, ConnectWebRTC, ConnectSIP and reconnects, and on every HTTP request.
+type ClientOptions struct {
+	// HTTPClient makes the HTTP requests; a new http.Client using TLS when nil
+	HTTPClient *http.Client
+	// TLS configures wss:// handshakes and, unless HTTPClient is set, https:// requests,
+	// e.g. to trust a private CA or present a client certificate; see TLSOptions
+	TLS *tls.Config
+	// BearerToken is sent in the Authorization header
+	BearerToken string
+	// TokenProvider supplies the bearer token instead of BearerToken
+	TokenProvider TokenProvider
+	// APIKey is sent in the APIKeyHeader header
+	APIKey string
+	// APIKeyHeader names the API key header; "X-API-Key" when empty
+	APIKeyHeader string
+	// Headers are sent as is, e.g. for gateways with their own scheme
+	Headers map[string]string
+	// Signer signs every HTTP request with HMAC; WebSocket handshakes are not signed
+	Signer *RequestSigner
+	// Logger logs the requests of the client and, unless they have their own, its connections
+	Logger *slog.Logger
+}
+
+// NewClientWithOptions creates a new RustPBX client with credentials and other options
+func NewClientWithOptions(baseURL string, options ClientOptions) *Client {
+	client := NewClient(baseURL)
+	if options.HTTPClient != nil {
+		client.httpClient = options.HTTPClient
+	} else if options.TLS != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = options.TLS.Clone()
+		client.httpClient = &http.Client{Transport: transport}
+	}
+	client.options = options
+	return client
+}
+
+// authorize sets the credentials of the client on a request or handshake header
+func (c *Client) authorize(ctx context.Context, header http.Header) error {
+	options := &c.options
+	for key, value := range options.Headers {
+		header.Set(key, value)
+	}
+	if options.APIKey != "" {
+		name := options.APIKeyHeader
+		if name == "" {
+			name = "X-API-Key"
+		}
+		header.Set(name, options.APIKey)
+	}
+
+	token := options.BearerToken
+	if options.TokenProvider != nil {
+		var err error
+		token, err = options.TokenProvider.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+	}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+strings.TrimPrefix(token, "Bearer "))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientCredentials(t *testing.T) {
+	var tokens atomic.Int32
+	headers := make(chan http.Header, 4)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		if r.Header.Get("Authorization") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(`{"calls":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClientWithOptions(server.URL, ClientOptions{
+		TokenProvider: TokenFunc(func(ctx context.Context) (string, error) {
+			return fmt.Sprintf("token-%d", tokens.Add(1)), nil
+		}),
+		APIKey:  "key",
+		Headers: map[string]string{"X-Gateway": "edge"},
+	})
+
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	conn.Close()
+	header := <-headers
+	if header.Get("Authorization") != "Bearer token-1" || header.Get("X-API-Key") != "key" || header.Get("X-Gateway") != "edge" {
+		t.Errorf("Expected the credentials on the handshake, got %v", header)
+	}
+
+	if _, err := client.GetActiveCalls(context.Background()); err != nil {
+		t.Fatalf("GetActiveCalls failed: %v", err)
+	}
+	if header := <-headers; header.Get("Authorization") != "Bearer token-2" {
+		t.Errorf("Expected a fresh token on the request, got %v", header)
+	}
+
+	failing := NewClientWithOptions(server.URL, ClientOptions{
+		TokenProvider: TokenFunc(func(ctx context.Context) (string, error) {
+			return "", errors.New("identity provider down")
+		}),
+	})
+	if err := failing.KillCall(context.Background(), "call-1"); err == nil {
+		t.Error("Expected the token error to fail the request")
+	}
+	if _, err := failing.ConnectCall(context.Background(), nil); err == nil {
+		t.Error("Expected the token error to fail the dial")
+	}
+}
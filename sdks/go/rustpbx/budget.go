@@ -0,0 +1,190 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// BudgetResource identifies a metered per-call resource
+type BudgetResource string
+
+const (
+	BudgetTTSCharacters BudgetResource = "tts_characters"
+	BudgetLLMTokens     BudgetResource = "llm_tokens"
+	BudgetRecording     BudgetResource = "recording_duration"
+)
+
+// CallBudget represents per-call resource limits enforced by the SDK.
+// A zero limit means unlimited.
+type CallBudget struct {
+	MaxTTSCharacters     int
+	MaxLLMTokens         int
+	MaxRecordingDuration time.Duration
+
+	// AutoWrapUp ends the call when a budget is exceeded, speaking WrapUpText first if set
+	AutoWrapUp bool
+	WrapUpText string
+}
+
+// BudgetExceededError is returned by commands that would exceed a call budget
+type BudgetExceededError struct {
+	Resource BudgetResource
+	Limit    int64
+	Used     int64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("call budget exceeded for %s: used %d of %d", e.Resource, e.Used, e.Limit)
+}
+
+// budgetTracker accounts resource usage against a CallBudget
+type budgetTracker struct {
+	budget         CallBudget
+	mu             sync.Mutex
+	ttsCharacters  int64
+	llmTokens      int64
+	recording      bool
+	recordingStart time.Time
+	recordingTimer *time.Timer
+	exceeded       map[BudgetResource]bool
+}
+
+func newBudgetTracker(budget CallBudget) *budgetTracker {
+	return &budgetTracker{
+		budget:   budget,
+		exceeded: make(map[BudgetResource]bool),
+	}
+}
+
+// charge adds usage and returns an error if it would go over the limit.
+// The first time a resource is exceeded, first is true.
+func (b *budgetTracker) charge(resource BudgetResource, used *int64, limit int, amount int64) (err *BudgetExceededError, first bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit > 0 && *used+amount > int64(limit) {
+		first = !b.exceeded[resource]
+		b.exceeded[resource] = true
+		return &BudgetExceededError{Resource: resource, Limit: int64(limit), Used: *used}, first
+	}
+	*used += amount
+	return nil, false
+}
+
+// chargeTTS accounts the characters of a TTS command
+func (c *Connection) chargeTTS(text string) error {
+	if c.budget == nil {
+		return nil
+	}
+	b := c.budget
+	if err, first := b.charge(BudgetTTSCharacters, &b.ttsCharacters, b.budget.MaxTTSCharacters, int64(utf8.RuneCountInString(text))); err != nil {
+		if first {
+			c.budgetExceeded(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ChargeLLMTokens reports LLM tokens consumed on behalf of the call.
+// It returns a *BudgetExceededError once the call's token budget is used up.
+func (c *Connection) ChargeLLMTokens(tokens int) error {
+	if c.budget == nil {
+		return nil
+	}
+	b := c.budget
+	if err, first := b.charge(BudgetLLMTokens, &b.llmTokens, b.budget.MaxLLMTokens, int64(tokens)); err != nil {
+		if first {
+			c.budgetExceeded(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// trackRecording notes whether the call is being recorded
+func (c *Connection) trackRecording(option *CallOption) {
+	if c.budget == nil || option == nil || option.Recorder == nil {
+		return
+	}
+	c.budget.mu.Lock()
+	c.budget.recording = true
+	c.budget.mu.Unlock()
+}
+
+// startRecordingBudget starts the recording timer once the call is answered
+func (c *Connection) startRecordingBudget() {
+	if c.budget == nil {
+		return
+	}
+	b := c.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limit := b.budget.MaxRecordingDuration
+	if !b.recording || limit <= 0 || b.recordingTimer != nil {
+		return
+	}
+	b.recordingStart = time.Now()
+	b.recordingTimer = time.AfterFunc(limit, func() {
+		b.mu.Lock()
+		b.exceeded[BudgetRecording] = true
+		used := time.Since(b.recordingStart)
+		b.mu.Unlock()
+
+		c.budgetExceeded(&BudgetExceededError{
+			Resource: BudgetRecording,
+			Limit:    int64(limit / time.Second),
+			Used:     int64(used / time.Second),
+		})
+	})
+}
+
+// stopRecordingBudget stops the recording timer when the call ends
+func (c *Connection) stopRecordingBudget() {
+	if c.budget == nil {
+		return
+	}
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	if c.budget.recordingTimer != nil {
+		c.budget.recordingTimer.Stop()
+	}
+}
+
+// budgetExceeded emits a budgetExceeded event and wraps up the call if configured
+func (c *Connection) budgetExceeded(err *BudgetExceededError) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"resource": err.Resource,
+		"limit":    err.Limit,
+		"used":     err.Used,
+	})
+	c.dispatch(&Event{
+		Event:     "budgetExceeded",
+		Timestamp: time.Now().UnixMilli(),
+		Reason:    string(err.Resource),
+		Error:     err.Error(),
+		Data:      data,
+	})
+
+	if !c.budget.budget.AutoWrapUp || c.isClosed() {
+		return
+	}
+	// Wrap-up commands bypass the budget
+	if text := c.budget.budget.WrapUpText; text != "" {
+		c.sendCommand(TTSCommand{
+			Command:    "tts",
+			Text:       text,
+			AutoHangup: true,
+		})
+		return
+	}
+	c.sendCommand(HangupCommand{
+		Command:   "hangup",
+		Reason:    "budget_exceeded",
+		Initiator: "system",
+	})
+}
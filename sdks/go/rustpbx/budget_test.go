@@ -0,0 +1,68 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTTSBudgetEnforcement(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+
+	client := NewClient(server.URL)
+	conn, err := client.ConnectCall(context.Background(), &ConnectionOptions{
+		Budget: &CallBudget{MaxTTSCharacters: 10, AutoWrapUp: true, WrapUpText: "Goodbye"},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	exceeded := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "budgetExceeded" {
+			exceeded <- event
+		}
+	})
+
+	if err := conn.TTSSimple("Hello"); err != nil {
+		t.Fatalf("Expected TTS within budget to succeed, got %v", err)
+	}
+	<-commands
+
+	err = conn.TTSSimple("Hello again")
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Resource != BudgetTTSCharacters {
+		t.Fatalf("Expected BudgetExceededError, got %v", err)
+	}
+
+	select {
+	case event := <-exceeded:
+		if event.Reason != string(BudgetTTSCharacters) {
+			t.Errorf("Expected reason '%s', got '%s'", BudgetTTSCharacters, event.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected budgetExceeded event")
+	}
+
+	select {
+	case cmd := <-commands:
+		if cmd["text"] != "Goodbye" || cmd["autoHangup"] != true {
+			t.Errorf("Expected wrap-up TTS with auto hangup, got %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected wrap-up command")
+	}
+}
+
+func TestLLMTokenBudget(t *testing.T) {
+	conn := &Connection{budget: newBudgetTracker(CallBudget{MaxLLMTokens: 100})}
+
+	if err := conn.ChargeLLMTokens(60); err != nil {
+		t.Fatalf("Expected charge within budget to succeed, got %v", err)
+	}
+	if err := conn.ChargeLLMTokens(60); err == nil {
+		t.Error("Expected charge over budget to fail")
+	}
+}
@@ -0,0 +1,160 @@
+package rustpbx
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ResponseCache remembers recent LLM answers keyed by the normalized question,
+// so repeated questions are answered without another LLM round trip. Give each
+// assistant its own cache for per-call caching, or share one across calls for FAQ bots.
+type ResponseCache struct {
+	// Key maps a question to its cache key; it defaults to lower-cased words without punctuation.
+	// Replace it to group questions semantically.
+	Key func(question string) string
+
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key     string
+	answer  string
+	expires time.Time
+}
+
+// NewResponseCache creates a cache holding up to size answers for ttl; a zero ttl never expires
+func NewResponseCache(size int, ttl time.Duration) *ResponseCache {
+	if size <= 0 {
+		size = 100
+	}
+	return &ResponseCache{
+		Key:     normalizeUtterance,
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the cached answer to a question
+func (c *ResponseCache) Get(question string) (string, bool) {
+	key := c.Key(question)
+	if key == "" {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.answer, true
+}
+
+// Put stores the answer to a question, evicting the least recently used answer when full
+func (c *ResponseCache) Put(question, answer string) {
+	key := c.Key(question)
+	if key == "" {
+		return
+	}
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(key, answer, expires)
+}
+
+// put stores an answer under its key; the caller holds the lock
+func (c *ResponseCache) put(key, answer string, expires time.Time) {
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.answer, entry.expires = answer, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, answer: answer, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached answers
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// savedAnswer is a cached answer in a file written by SaveFile
+type savedAnswer struct {
+	Key     string    `json:"key"`
+	Answer  string    `json:"answer"`
+	Expires time.Time `json:"expires"`
+}
+
+// SaveFile writes the unexpired answers to a file encrypted with cipher, replacing it, so
+// a restarted application answers from the cache again
+func (c *ResponseCache) SaveFile(path string, cipher *ArtifactCipher) error {
+	now := time.Now()
+	c.mu.Lock()
+	answers := make([]savedAnswer, 0, c.order.Len())
+	// Oldest first, so loading restores the recency order
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*cacheEntry)
+		if entry.expires.IsZero() || now.Before(entry.expires) {
+			answers = append(answers, savedAnswer{Key: entry.key, Answer: entry.answer, Expires: entry.expires})
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(answers)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+	return cipher.WriteFile(path, data, 0o600)
+}
+
+// LoadFile adds the answers of a file written by SaveFile, keeping their expiry
+func (c *ResponseCache) LoadFile(path string, cipher *ArtifactCipher) error {
+	data, err := cipher.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var answers []savedAnswer
+	if err := json.Unmarshal(data, &answers); err != nil {
+		return fmt.Errorf("failed to decode cache: %w", err)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, answer := range answers {
+		if answer.Expires.IsZero() || now.Before(answer.Expires) {
+			c.put(answer.Key, answer.Answer, answer.Expires)
+		}
+	}
+	return nil
+}
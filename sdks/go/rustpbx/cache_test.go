@@ -0,0 +1,65 @@
+package rustpbx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheNormalizesQuestions(t *testing.T) {
+	cache := NewResponseCache(2, 0)
+	cache.Put("What are your opening hours?", "9 to 5")
+
+	if answer, ok := cache.Get("what are your opening hours"); !ok || answer != "9 to 5" {
+		t.Errorf("Expected cached answer for normalized question, got '%s' (%t)", answer, ok)
+	}
+
+	cache.Put("Where are you?", "Downtown")
+	cache.Get("What are your opening hours?")
+	cache.Put("Do you deliver?", "Yes")
+	if cache.Len() != 2 {
+		t.Errorf("Expected cache to hold 2 answers, got %d", cache.Len())
+	}
+	if _, ok := cache.Get("Where are you?"); ok {
+		t.Error("Expected least recently used answer to be evicted")
+	}
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	cache := NewResponseCache(10, time.Millisecond)
+	cache.Put("hello", "hi")
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := cache.Get("hello"); ok {
+		t.Error("Expected expired answer to be dropped")
+	}
+}
+
+func TestResponseCacheFile(t *testing.T) {
+	cipher, err := NewArtifactCipher(context.Background(), func(ctx context.Context) ([]byte, error) {
+		return make([]byte, 32), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewResponseCache(10, time.Hour)
+	cache.Put("What are your opening hours?", "9 to 5")
+	path := filepath.Join(t.TempDir(), "answers.enc")
+	if err := cache.SaveFile(path, cipher); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	if raw, _ := os.ReadFile(path); strings.Contains(string(raw), "9 to 5") {
+		t.Error("Expected the cache file to be encrypted")
+	}
+
+	restored := NewResponseCache(10, time.Hour)
+	if err := restored.LoadFile(path, cipher); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if answer, ok := restored.Get("what are your opening hours"); !ok || answer != "9 to 5" {
+		t.Errorf("Expected the saved answer, got '%s' (%t)", answer, ok)
+	}
+}
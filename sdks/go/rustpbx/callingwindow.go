@@ -0,0 +1,183 @@
+package rustpbx
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownCountry is returned for international numbers whose country has no known time zone
+var ErrUnknownCountry = errors.New("unknown country")
+
+// CallingWindow represents the local hours during which a destination may be called.
+// A window whose End is before its Start spans midnight, e.g. 20:00 to 02:00.
+type CallingWindow struct {
+	Start string // HH:MM local time, inclusive
+	End   string // HH:MM local time, exclusive
+	// Days restricts calling to the given weekdays; empty means every day. The hours
+	// after midnight of a window spanning midnight belong to the day it opened.
+	Days []time.Weekday
+}
+
+// DefaultCallingWindow allows calls from 8am to 9pm in the destination's time zone
+var DefaultCallingWindow = CallingWindow{Start: "08:00", End: "21:00"}
+
+// Allows reports whether t is inside the window in every one of the given time zones.
+// Requiring all zones keeps calls compliant when the destination's zone is ambiguous.
+func (w CallingWindow) Allows(t time.Time, zones []*time.Location) (bool, error) {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+
+	for _, loc := range zones {
+		local := t.In(loc)
+		minute := local.Hour()*60 + local.Minute()
+		day := local.Weekday()
+		switch {
+		case start <= end:
+			if minute < start || minute >= end {
+				return false, nil
+			}
+		case minute < end:
+			// The window opened the day before
+			day = (day + 6) % 7
+		case minute < start:
+			return false, nil
+		}
+		if len(w.Days) > 0 && !containsWeekday(w.Days, day) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// NextOpening returns the first minute at or after t that the window allows in every zone,
+// searching up to a week ahead
+func (w CallingWindow) NextOpening(t time.Time, zones []*time.Location) (time.Time, error) {
+	cursor := t.Truncate(time.Minute)
+	limit := cursor.Add(7 * 24 * time.Hour)
+	for cursor.Before(limit) {
+		ok, err := w.Allows(cursor, zones)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok {
+			return cursor, nil
+		}
+		cursor = cursor.Add(time.Minute)
+	}
+	return time.Time{}, fmt.Errorf("calling window %s-%s never opens", w.Start, w.End)
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Country calling codes of single time zone countries
+var countryTimezones = map[string]string{
+	"27":  "Africa/Johannesburg",
+	"31":  "Europe/Amsterdam",
+	"32":  "Europe/Brussels",
+	"33":  "Europe/Paris",
+	"34":  "Europe/Madrid",
+	"39":  "Europe/Rome",
+	"41":  "Europe/Zurich",
+	"44":  "Europe/London",
+	"46":  "Europe/Stockholm",
+	"47":  "Europe/Oslo",
+	"48":  "Europe/Warsaw",
+	"49":  "Europe/Berlin",
+	"64":  "Pacific/Auckland",
+	"65":  "Asia/Singapore",
+	"81":  "Asia/Tokyo",
+	"82":  "Asia/Seoul",
+	"86":  "Asia/Shanghai",
+	"91":  "Asia/Kolkata",
+	"353": "Europe/Dublin",
+	"852": "Asia/Hong_Kong",
+	"886": "Asia/Taipei",
+	"971": "Asia/Dubai",
+}
+
+// North American area codes by time zone
+var nanpTimezones = map[string]string{
+	"201": "America/New_York",
+	"202": "America/New_York",
+	"206": "America/Los_Angeles",
+	"212": "America/New_York",
+	"213": "America/Los_Angeles",
+	"214": "America/Chicago",
+	"303": "America/Denver",
+	"305": "America/New_York",
+	"310": "America/Los_Angeles",
+	"312": "America/Chicago",
+	"404": "America/New_York",
+	"415": "America/Los_Angeles",
+	"416": "America/Toronto",
+	"512": "America/Chicago",
+	"602": "America/Phoenix",
+	"604": "America/Vancouver",
+	"617": "America/New_York",
+	"646": "America/New_York",
+	"702": "America/Los_Angeles",
+	"713": "America/Chicago",
+	"718": "America/New_York",
+	"801": "America/Denver",
+	"808": "Pacific/Honolulu",
+	"907": "America/Anchorage",
+}
+
+// North American zones assumed when an area code is unknown
+var nanpFallbackTimezones = []string{"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles"}
+
+// InferTimezones returns the IANA time zones a destination number may be in.
+// Several zones are returned when the number does not pin down a single zone.
+// Numbers in national format name no country and return no zones; international
+// numbers of a country without a known zone return ErrUnknownCountry.
+func InferTimezones(number string) ([]string, error) {
+	digits := dialedNumber(number)
+	// Only numbers in international format identify a country
+	if digits == "" || !strings.HasPrefix(dialedUser(number), "+") {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(digits, "1") && len(digits) == 11 {
+		if tz, ok := nanpTimezones[digits[1:4]]; ok {
+			return []string{tz}, nil
+		}
+		return nanpFallbackTimezones, nil
+	}
+
+	for n := 3; n >= 1; n-- {
+		if len(digits) > n {
+			if tz, ok := countryTimezones[digits[:n]]; ok {
+				return []string{tz}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: no time zone known for %s", ErrUnknownCountry, number)
+}
+
+// loadLocations resolves time zone names
+func loadLocations(names []string) ([]*time.Location, error) {
+	zones := make([]*time.Location, 0, len(names))
+	for _, name := range names {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone %s: %w", name, err)
+		}
+		zones = append(zones, loc)
+	}
+	return zones, nil
+}
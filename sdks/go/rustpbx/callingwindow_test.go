@@ -0,0 +1,96 @@
+package rustpbx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInferTimezones(t *testing.T) {
+	tests := []struct {
+		number   string
+		expected int
+		first    string
+	}{
+		{"+442071838750", 1, "Europe/London"},
+		{"sip:+12125550100@carrier", 1, "America/New_York"},
+		{"+15095550100", 4, "America/New_York"},
+		{"2125550100", 0, ""},
+	}
+
+	for _, test := range tests {
+		zones, err := InferTimezones(test.number)
+		if err != nil || len(zones) != test.expected || (test.expected > 0 && zones[0] != test.first) {
+			t.Errorf("InferTimezones(%q) = %v, %v", test.number, zones, err)
+		}
+	}
+}
+
+func TestInferTimezonesUnknownCountry(t *testing.T) {
+	for _, number := range []string{"+61255501000", "+5511955501000", "tel:+7-495-555-0100"} {
+		zones, err := InferTimezones(number)
+		if !errors.Is(err, ErrUnknownCountry) || zones != nil {
+			t.Errorf("InferTimezones(%q) = %v, %v, expected ErrUnknownCountry", number, zones, err)
+		}
+	}
+}
+
+func TestCallingWindowOvernight(t *testing.T) {
+	// Friday nights only, spanning midnight
+	window := CallingWindow{Start: "20:00", End: "02:00", Days: []time.Weekday{time.Friday}}
+	zones := []*time.Location{time.UTC}
+	tests := []struct {
+		at       time.Time
+		expected bool
+	}{
+		{time.Date(2026, 3, 6, 21, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 7, 1, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 6, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 6, 1, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if ok, err := window.Allows(test.at, zones); err != nil || ok != test.expected {
+			t.Errorf("Allows(%s) = %t, %v, expected %t", test.at.Format(time.RFC1123), ok, err, test.expected)
+		}
+	}
+}
+
+func TestCallingWindowDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	zones := []*time.Location{newYork}
+
+	tests := []struct {
+		window   CallingWindow
+		from     time.Time
+		expected time.Time
+	}{
+		// 08:00 is 13:00 UTC before the clocks spring forward on March 8 and 12:00 UTC after
+		{DefaultCallingWindow, time.Date(2026, 3, 7, 11, 0, 0, 0, time.UTC), time.Date(2026, 3, 7, 13, 0, 0, 0, time.UTC)},
+		{DefaultCallingWindow, time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)},
+		// and back to 13:00 UTC once they fall back on November 1
+		{DefaultCallingWindow, time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 13, 0, 0, 0, time.UTC)},
+		// A window opening in the skipped hour opens when the clocks reach 03:00
+		{CallingWindow{Start: "02:30", End: "05:00"}, time.Date(2026, 3, 8, 6, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		next, err := test.window.NextOpening(test.from, zones)
+		if err != nil || !next.Equal(test.expected) {
+			t.Errorf("NextOpening(%s) = %s, %v, expected %s", test.from, next, err, test.expected)
+		}
+	}
+
+	// Both passes through the repeated hour of the fall back are inside a window closing at 01:30
+	window := CallingWindow{Start: "00:00", End: "01:30"}
+	for _, at := range []time.Time{
+		time.Date(2026, 11, 1, 5, 15, 0, 0, time.UTC), // 01:15 EDT
+		time.Date(2026, 11, 1, 6, 15, 0, 0, time.UTC), // 01:15 EST
+	} {
+		if ok, err := window.Allows(at, zones); err != nil || !ok {
+			t.Errorf("Allows(%s) = %t, %v, expected true", at.In(newYork), ok, err)
+		}
+	}
+}
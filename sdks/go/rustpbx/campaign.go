@@ -0,0 +1,317 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CampaignOutcome is the result of one campaign call attempt
+type CampaignOutcome string
+
+const (
+	CampaignAnswered       CampaignOutcome = "answered"
+	CampaignNoAnswer       CampaignOutcome = "no_answer"
+	CampaignBusy           CampaignOutcome = "busy"
+	CampaignVoicemail      CampaignOutcome = "voicemail"
+	CampaignTransferFailed CampaignOutcome = "transfer_failed"
+	CampaignFailed         CampaignOutcome = "failed"
+	// CampaignSkipped is recorded when a target can never be called compliantly
+	CampaignSkipped CampaignOutcome = "skipped"
+)
+
+// CampaignTarget represents a destination of an outbound campaign
+type CampaignTarget struct {
+	ID     string
+	Number string
+	// Timezone overrides the time zone inferred from Number
+	Timezone string
+	Metadata map[string]string
+}
+
+// CampaignResult represents the final state of a campaign target
+type CampaignResult struct {
+	Target     CampaignTarget
+	Outcome    CampaignOutcome
+	Attempts   int
+	Deferrals  int
+	Error      error
+	FinishedAt time.Time
+
+	// Notified is set when the fallback notifier was invoked for the outcome
+	Notified    bool
+	NotifyError error
+
+	// Disposition is set when the last call to the target recorded one with SetDisposition
+	Disposition *Disposition
+}
+
+// CampaignDialer places one call to a campaign target and reports its outcome
+type CampaignDialer func(ctx context.Context, target *CampaignTarget) (CampaignOutcome, error)
+
+// CampaignOptions represents campaign scheduler configuration
+type CampaignOptions struct {
+	Concurrency int
+	// Window is the legal calling window in the destination's local time; DefaultCallingWindow when nil
+	Window *CallingWindow
+	// DefaultTimezone is used for destinations in national format, which name no country.
+	// International numbers of countries without a known zone are refused instead, so set
+	// CampaignTarget.Timezone for them.
+	DefaultTimezone string
+	// MaxAttempts bounds the attempts per target for retryable outcomes (no answer, busy)
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	// Notifier is invoked when a target finishes with one of the NotifyOn outcomes,
+	// e.g. to fall back to SMS or email
+	Notifier Notifier
+	// NotifyOn defaults to no answer, voicemail and failed transfers
+	NotifyOn []CampaignOutcome
+}
+
+// campaignEntry tracks the scheduling state of a target
+type campaignEntry struct {
+	target    CampaignTarget
+	zones     []*time.Location
+	notBefore time.Time
+	attempts  int
+	deferrals int
+}
+
+// Campaign schedules outbound calls to a list of targets, deferring attempts
+// that fall outside the destination's legal calling window
+type Campaign struct {
+	dialer  CampaignDialer
+	options CampaignOptions
+	window  CallingWindow
+
+	mu      sync.Mutex
+	pending []*campaignEntry
+	results []CampaignResult
+	now     func() time.Time
+}
+
+// NewCampaign creates a campaign that places calls with dialer
+func NewCampaign(dialer CampaignDialer, options *CampaignOptions) *Campaign {
+	if options == nil {
+		options = &CampaignOptions{}
+	}
+	opts := *options
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 30 * time.Minute
+	}
+	if opts.NotifyOn == nil {
+		opts.NotifyOn = []CampaignOutcome{CampaignNoAnswer, CampaignVoicemail, CampaignTransferFailed}
+	}
+
+	window := DefaultCallingWindow
+	if opts.Window != nil {
+		window = *opts.Window
+	}
+
+	return &Campaign{
+		dialer:  dialer,
+		options: opts,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+// Add queues targets for calling
+func (c *Campaign) Add(targets ...CampaignTarget) error {
+	entries := make([]*campaignEntry, 0, len(targets))
+	for _, target := range targets {
+		zones, err := c.targetZones(&target)
+		if err != nil {
+			return fmt.Errorf("target %s: %w", target.ID, err)
+		}
+		entries = append(entries, &campaignEntry{target: target, zones: zones})
+	}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, entries...)
+	c.mu.Unlock()
+	return nil
+}
+
+// targetZones resolves the time zones a target may be in
+func (c *Campaign) targetZones(target *CampaignTarget) ([]*time.Location, error) {
+	names := []string{target.Timezone}
+	if target.Timezone == "" {
+		var err error
+		if names, err = InferTimezones(target.Number); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		if c.options.DefaultTimezone == "" {
+			return nil, fmt.Errorf("cannot infer time zone of %s", target.Number)
+		}
+		names = []string{c.options.DefaultTimezone}
+	}
+	return loadLocations(names)
+}
+
+// Results returns the results of targets that finished so far
+func (c *Campaign) Results() []CampaignResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CampaignResult(nil), c.results...)
+}
+
+// Run calls every queued target and returns when all have finished or ctx is cancelled
+func (c *Campaign) Run(ctx context.Context) ([]CampaignResult, error) {
+	slots := make(chan struct{}, c.options.Concurrency)
+	finished := make(chan struct{}, c.options.Concurrency)
+	inflight := 0
+
+	for {
+		due, wait := c.nextDue()
+		for _, entry := range due {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return c.Results(), ctx.Err()
+			}
+			inflight++
+			go func(entry *campaignEntry) {
+				defer func() {
+					<-slots
+					// Run stops receiving once ctx is cancelled
+					select {
+					case finished <- struct{}{}:
+					case <-ctx.Done():
+					}
+				}()
+				c.attempt(ctx, entry)
+			}(entry)
+		}
+
+		c.mu.Lock()
+		idle := len(c.pending) == 0
+		c.mu.Unlock()
+		if idle && inflight == 0 {
+			return c.Results(), nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-finished:
+			inflight--
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return c.Results(), ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// nextDue removes the entries that may be called now and returns how long to wait for the next one.
+// Entries outside their calling window are deferred to the window's next opening.
+func (c *Campaign) nextDue() ([]*campaignEntry, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var due []*campaignEntry
+	remaining := c.pending[:0]
+	wait := time.Minute
+
+	for _, entry := range c.pending {
+		if now.Before(entry.notBefore) {
+			remaining = append(remaining, entry)
+			if d := entry.notBefore.Sub(now); d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		open, err := c.window.Allows(now, entry.zones)
+		if err == nil && open {
+			due = append(due, entry)
+			continue
+		}
+
+		next, nextErr := c.window.NextOpening(now, entry.zones)
+		if err == nil {
+			err = nextErr
+		}
+		if err != nil {
+			c.finish(entry, CampaignSkipped, err)
+			continue
+		}
+		entry.notBefore = next
+		entry.deferrals++
+		remaining = append(remaining, entry)
+	}
+	c.pending = remaining
+
+	return due, wait
+}
+
+// attempt dials an entry and either records its result or requeues it for a retry
+func (c *Campaign) attempt(ctx context.Context, entry *campaignEntry) {
+	entry.attempts++
+	dialCtx, slot := withDispositionSlot(ctx)
+	outcome, err := c.dialer(dialCtx, &entry.target)
+	if err != nil && outcome == "" {
+		outcome = CampaignFailed
+	}
+
+	retryable := outcome == CampaignNoAnswer || outcome == CampaignBusy
+	if retryable && entry.attempts < c.options.MaxAttempts {
+		c.mu.Lock()
+		entry.notBefore = c.now().Add(c.options.RetryDelay)
+		c.pending = append(c.pending, entry)
+		c.mu.Unlock()
+		return
+	}
+
+	notified, notifyErr := c.notify(ctx, entry, outcome)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finish(entry, outcome, err)
+	c.results[len(c.results)-1].Notified = notified
+	c.results[len(c.results)-1].NotifyError = notifyErr
+	slot.mu.Lock()
+	c.results[len(c.results)-1].Disposition = slot.disposition
+	slot.mu.Unlock()
+}
+
+// notify invokes the notifier if the outcome calls for a fallback
+func (c *Campaign) notify(ctx context.Context, entry *campaignEntry, outcome CampaignOutcome) (bool, error) {
+	if c.options.Notifier == nil {
+		return false, nil
+	}
+	for _, o := range c.options.NotifyOn {
+		if o == outcome {
+			return true, c.options.Notifier.Notify(ctx, &Notification{
+				Target:   entry.target,
+				Outcome:  outcome,
+				Attempts: entry.attempts,
+			})
+		}
+	}
+	return false, nil
+}
+
+// finish records the result of an entry; the caller must hold c.mu
+func (c *Campaign) finish(entry *campaignEntry, outcome CampaignOutcome, err error) {
+	c.results = append(c.results, CampaignResult{
+		Target:     entry.target,
+		Outcome:    outcome,
+		Attempts:   entry.attempts,
+		Deferrals:  entry.deferrals,
+		Error:      err,
+		FinishedAt: c.now(),
+	})
+}
@@ -0,0 +1,108 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCampaignDefersOutOfWindow(t *testing.T) {
+	campaign := NewCampaign(nil, nil)
+	// 06:00 in New York is before the default window opens
+	campaign.now = func() time.Time { return time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC) }
+
+	if err := campaign.Add(CampaignTarget{ID: "t1", Number: "+12125550100"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	due, _ := campaign.nextDue()
+	if len(due) != 0 {
+		t.Fatal("Expected out-of-window target to be deferred")
+	}
+
+	entry := campaign.pending[0]
+	expected := time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC)
+	if entry.deferrals != 1 || !entry.notBefore.Equal(expected) {
+		t.Errorf("Expected deferral until %s, got %s (%d deferrals)", expected, entry.notBefore, entry.deferrals)
+	}
+}
+
+func TestCampaignRunRetries(t *testing.T) {
+	var calls int32
+	campaign := NewCampaign(func(ctx context.Context, target *CampaignTarget) (CampaignOutcome, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return CampaignNoAnswer, nil
+		}
+		return CampaignAnswered, nil
+	}, &CampaignOptions{MaxAttempts: 2, RetryDelay: 10 * time.Millisecond})
+
+	// Noon in London, inside the default window
+	base, started := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), time.Now()
+	campaign.now = func() time.Time { return base.Add(time.Since(started)) }
+
+	if err := campaign.Add(CampaignTarget{ID: "t1", Number: "+442071838750"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := campaign.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Outcome != CampaignAnswered || results[0].Attempts != 2 {
+		t.Errorf("Expected one answered result after 2 attempts, got %+v", results)
+	}
+}
+
+func TestCampaignRejectsUnknownTimezone(t *testing.T) {
+	campaign := NewCampaign(nil, nil)
+	if err := campaign.Add(CampaignTarget{ID: "t1", Number: "5550100"}); err == nil {
+		t.Error("Expected error for a target without a known time zone")
+	}
+
+	// A default zone stands in for national numbers, not for unknown countries
+	campaign = NewCampaign(nil, &CampaignOptions{DefaultTimezone: "Europe/London"})
+	if err := campaign.Add(CampaignTarget{ID: "t2", Number: "5550100"}); err != nil {
+		t.Errorf("Expected the default time zone for a national number, got %v", err)
+	}
+	if err := campaign.Add(CampaignTarget{ID: "t3", Number: "+61255501000"}); !errors.Is(err, ErrUnknownCountry) {
+		t.Errorf("Expected ErrUnknownCountry, got %v", err)
+	}
+}
+
+func TestCampaignNotifiesFallback(t *testing.T) {
+	var notified []*Notification
+	campaign := NewCampaign(func(ctx context.Context, target *CampaignTarget) (CampaignOutcome, error) {
+		if target.ID == "voicemail" {
+			return CampaignVoicemail, nil
+		}
+		return CampaignAnswered, nil
+	}, &CampaignOptions{
+		Notifier: NotifierFunc(func(ctx context.Context, n *Notification) error {
+			notified = append(notified, n)
+			return nil
+		}),
+	})
+	campaign.now = func() time.Time { return time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC) }
+
+	campaign.Add(
+		CampaignTarget{ID: "voicemail", Number: "+442071838750"},
+		CampaignTarget{ID: "answered", Number: "+442071838751"},
+	)
+
+	results, err := campaign.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(notified) != 1 || notified[0].Target.ID != "voicemail" {
+		t.Fatalf("Expected one notification for the voicemail target, got %v", notified)
+	}
+	for _, result := range results {
+		if result.Notified != (result.Target.ID == "voicemail") {
+			t.Errorf("Unexpected Notified=%t for target %s", result.Notified, result.Target.ID)
+		}
+	}
+}
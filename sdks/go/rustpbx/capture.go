@@ -0,0 +1,491 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ErrCaptureFailed is returned when the caller could not give a valid value within the retries
+var ErrCaptureFailed = errors.New("capture failed")
+
+// natoAlphabet maps spelling alphabet words to letters
+var natoAlphabet = map[string]string{
+	"alpha": "a", "alfa": "a", "bravo": "b", "charlie": "c", "delta": "d", "echo": "e", "foxtrot": "f",
+	"golf": "g", "hotel": "h", "india": "i", "juliet": "j", "juliett": "j", "kilo": "k", "lima": "l",
+	"mike": "m", "november": "n", "oscar": "o", "papa": "p", "quebec": "q", "romeo": "r", "sierra": "s",
+	"tango": "t", "uniform": "u", "victor": "v", "whiskey": "w", "whisky": "w", "x-ray": "x", "xray": "x",
+	"yankee": "y", "zulu": "z",
+}
+
+// natoNames are spelling alphabet words that are also names; they are read as letters
+// only next to other spelled letters, so "mike at example dot com" stays mike
+var natoNames = map[string]bool{
+	"charlie": true, "india": true, "juliet": true, "juliett": true, "mike": true, "oscar": true,
+	"romeo": true, "victor": true,
+}
+
+// spokenEmailSymbols maps spoken words to the symbols of an email address
+var spokenEmailSymbols = map[string]string{
+	"dot": ".", "period": ".", "point": ".",
+	"underscore": "_", "dash": "-", "hyphen": "-", "minus": "-", "plus": "+",
+	"punto": ".", "guion": "-", "guión": "-", "más": "+",
+}
+
+// letterNames maps the names of letters to letters, for callers spelling out "e" as "ee"
+var letterNames = map[string]string{
+	"bee": "b", "cee": "c", "see": "c", "dee": "d", "ee": "e", "ef": "f", "gee": "g", "aitch": "h",
+	"jay": "j", "kay": "k", "el": "l", "em": "m", "en": "n", "pee": "p", "cue": "q", "queue": "q",
+	"ar": "r", "es": "s", "tee": "t", "you": "u", "vee": "v", "ex": "x", "why": "y", "zed": "z", "zee": "z",
+}
+
+// ParseSpokenEmail turns a transcript such as "john dot smith at gmail dot com" or
+// "juliet oscar hotel november at example dot org" into an email address. The symbols
+// may also be named in Spanish, as in "jose arroba ejemplo punto com".
+func ParseSpokenEmail(text string) string {
+	words := strings.Fields(strings.ToLower(strings.NewReplacer(",", " ", "'", "").Replace(text)))
+
+	// The last "at" separates the local part from the domain; ASR may also produce "@"
+	at := -1
+	for i, word := range words {
+		if word == "at" || word == "arroba" || word == "@" {
+			at = i
+		}
+	}
+
+	for i, word := range words {
+		words[i] = strings.TrimSuffix(word, ".")
+	}
+	spelled := spelledLetters(words)
+
+	var b strings.Builder
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case i == at:
+			b.WriteString("@")
+		case word == "double" && i+1 < len(words) && words[i+1] == "you":
+			// "double you" is the name of the letter w
+			b.WriteString("w")
+			i++
+		case (word == "guion" || word == "guión") && i+1 < len(words) && words[i+1] == "bajo":
+			b.WriteString("_")
+			i++
+		case word == "double" && i+1 < len(words):
+			// "double l" means "ll"
+			letter := spokenLetter(words[i+1])
+			b.WriteString(letter + letter)
+			i++
+		case word == "capital" || word == "uppercase" || word == "lowercase" || word == "letter":
+		case spokenEmailSymbols[word] != "":
+			b.WriteString(spokenEmailSymbols[word])
+		default:
+			if n, ok := spokenUnits[word]; ok && n < 10 {
+				b.WriteString(fmt.Sprint(n))
+			} else if natoNames[word] && !spelled[i] {
+				b.WriteString(word)
+			} else {
+				b.WriteString(spokenLetter(word))
+			}
+		}
+	}
+	return b.String()
+}
+
+// spelledLetters marks the words that spell a letter. Spelling alphabet words that are
+// also names only spell a letter in a run of spelled letters.
+func spelledLetters(words []string) []bool {
+	spelled := make([]bool, len(words))
+	for i, word := range words {
+		_, nato := natoAlphabet[word]
+		_, name := letterNames[word]
+		single := len(word) == 1 && word[0] >= 'a' && word[0] <= 'z'
+		spelled[i] = (nato || name || single) && !natoNames[word]
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, word := range words {
+			if !spelled[i] && natoNames[word] && (i > 0 && spelled[i-1] || i+1 < len(words) && spelled[i+1]) {
+				spelled[i], changed = true, true
+			}
+		}
+	}
+	return spelled
+}
+
+// spokenLetter returns the letter a spelling word stands for, or the word itself
+func spokenLetter(word string) string {
+	if letter, ok := natoAlphabet[word]; ok {
+		return letter
+	}
+	if letter, ok := letterNames[word]; ok {
+		return letter
+	}
+	return word
+}
+
+// ValidateEmail checks the syntax of an email address and its domain
+func ValidateEmail(address string) error {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return fmt.Errorf("invalid email address: %s", address)
+	}
+	return validateDomain(address[strings.LastIndex(address, "@")+1:])
+}
+
+// validateDomain checks that a domain has valid labels and a top-level domain
+func validateDomain(domain string) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("invalid domain: %s", domain)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid domain: %s", domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid domain: %s", domain)
+			}
+		}
+	}
+	if tld := labels[len(labels)-1]; len(tld) < 2 || strings.Trim(tld, "0123456789") == "" {
+		return fmt.Errorf("invalid domain: %s", domain)
+	}
+	return nil
+}
+
+// SpellEmail formats an email address for TTS in English, spelling the local part letter
+// by letter
+func SpellEmail(address string) string {
+	return spellEmail(address, EnglishLocale)
+}
+
+// spellEmail formats an email address for TTS, naming its symbols in the language of locale
+func spellEmail(address string, locale *LocaleBundle) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return spellOut(address, locale)
+	}
+	domain := strings.ReplaceAll(address[at+1:], ".", " "+capturePrompt(locale, "symbol_dot", nil)+" ")
+	return spellOut(address[:at], locale) + ", " + capturePrompt(locale, "symbol_at", nil) + " " + domain
+}
+
+// emailSymbolKeys maps the symbols of an email address to the prompts naming them
+var emailSymbolKeys = map[rune]string{
+	'.': "symbol_dot", '_': "symbol_underscore", '-': "symbol_dash", '+': "symbol_plus", '@': "symbol_at",
+}
+
+// spellOut spells text one character at a time, naming symbols
+func spellOut(text string, locale *LocaleBundle) string {
+	var parts []string
+	for _, r := range text {
+		if key, ok := emailSymbolKeys[r]; ok {
+			parts = append(parts, capturePrompt(locale, key, nil))
+		} else {
+			parts = append(parts, string(r))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// capturePrompt returns a capture prompt of a locale, in English when the locale lacks it
+func capturePrompt(locale *LocaleBundle, key string, args map[string]string) string {
+	if text := locale.Prompt(key, args); text != "" {
+		return text
+	}
+	return EnglishLocale.Prompt(key, args)
+}
+
+// CaptureOptions represents email and address capture configuration
+type CaptureOptions struct {
+	// Retries is the number of times the caller is asked again; 2 when zero, none when negative
+	Retries int
+	// Timeout is how long to wait for each answer; 15s when zero
+	Timeout time.Duration
+	Speaker string
+	// SkipConfirm disables reading the value back for confirmation
+	SkipConfirm bool
+	// LookupMX rejects email domains without mail exchangers
+	LookupMX bool
+	Resolver *net.Resolver
+	// ValidateAddress checks and normalizes a captured postal address, e.g. with a geocoder
+	ValidateAddress func(ctx context.Context, address *PostalAddress) error
+	// Locale provides the prompts and the yes and no grammar; EnglishLocale when nil
+	Locale *LocaleBundle
+}
+
+// defaults fills in the default capture options
+func (o CaptureOptions) defaults() CaptureOptions {
+	if o.Retries == 0 {
+		o.Retries = 2
+	} else if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 15 * time.Second
+	}
+	if o.Resolver == nil {
+		o.Resolver = net.DefaultResolver
+	}
+	if o.Locale == nil {
+		o.Locale = EnglishLocale
+	}
+	return o
+}
+
+// ask speaks a prompt and waits for the caller's transcript; "" means no input
+func (c *Connection) ask(ctx context.Context, answers <-chan *Event, prompt, speaker string, timeout time.Duration) (string, error) {
+	if err := c.TTS(prompt, speaker, "", nil); err != nil {
+		return "", fmt.Errorf("failed to ask caller: %w", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case event := <-answers:
+		return event.Text, nil
+	case <-timer.C:
+		return "", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-c.ctx.Done():
+		return "", fmt.Errorf("%w while waiting for caller", ErrConnectionClosed)
+	}
+}
+
+// drainEvents discards events that are already queued, such as the answer to a confirmation
+func drainEvents(events <-chan *Event) {
+	for {
+		select {
+		case <-events:
+		default:
+			return
+		}
+	}
+}
+
+// CaptureEmail asks the caller for an email address, accepting spelling alphabets,
+// validating the domain and reading the address back for confirmation
+func (c *Connection) CaptureEmail(ctx context.Context, prompt string, options *CaptureOptions) (string, error) {
+	opts := CaptureOptions{}
+	if options != nil {
+		opts = *options
+	}
+	opts = opts.defaults()
+
+	answers, unsubscribe := c.subscribe(func(event *Event) bool {
+		return event.Event == "asrFinal" && event.Text != ""
+	})
+	defer unsubscribe()
+
+	question := prompt
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		text, err := c.ask(ctx, answers, question, opts.Speaker, opts.Timeout)
+		if err != nil {
+			return "", err
+		}
+		question = capturePrompt(opts.Locale, "capture_retry", map[string]string{"prompt": prompt})
+		if text == "" {
+			continue
+		}
+
+		address := ParseSpokenEmail(text)
+		if err := ValidateEmail(address); err != nil {
+			question = capturePrompt(opts.Locale, "capture_email_invalid", nil)
+			continue
+		}
+		if opts.LookupMX {
+			domain := address[strings.LastIndex(address, "@")+1:]
+			if records, err := opts.Resolver.LookupMX(ctx, domain); err != nil || len(records) == 0 {
+				question = capturePrompt(opts.Locale, "capture_email_domain", map[string]string{
+					"domain": strings.ReplaceAll(domain, ".", " "+capturePrompt(opts.Locale, "symbol_dot", nil)+" "),
+					"prompt": prompt,
+				})
+				continue
+			}
+		}
+
+		if opts.SkipConfirm {
+			return address, nil
+		}
+		confirmed, err := c.Confirm(ctx, capturePrompt(opts.Locale, "capture_confirm", map[string]string{
+			"value": spellEmail(address, opts.Locale),
+		}), &ConfirmOptions{
+			Speaker: opts.Speaker,
+			Timeout: opts.Timeout,
+			Locale:  opts.Locale,
+		})
+		if err != nil {
+			return "", err
+		}
+		drainEvents(answers)
+		if confirmed.Confirmed() {
+			return address, nil
+		}
+		question = capturePrompt(opts.Locale, "capture_email_again", nil)
+	}
+	return "", ErrCaptureFailed
+}
+
+// PostalAddress represents a postal address captured by voice
+type PostalAddress struct {
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	PostalCode string `json:"postalCode"`
+	Country    string `json:"country,omitempty"`
+}
+
+// String formats the address on a single line
+func (a *PostalAddress) String() string {
+	parts := []string{a.Street, a.City, a.PostalCode, a.Country}
+	var filled []string
+	for _, part := range parts {
+		if part != "" {
+			filled = append(filled, part)
+		}
+	}
+	return strings.Join(filled, ", ")
+}
+
+// speakable formats the address for reading back, with numbers spelled digit by digit
+func (a *PostalAddress) speakable(locale *LocaleBundle) string {
+	spell := func(text string) string {
+		var words []string
+		for _, word := range strings.Fields(text) {
+			if strings.Trim(word, "0123456789") == "" {
+				word = locale.SpeakDigits(word)
+			}
+			words = append(words, word)
+		}
+		return strings.Join(words, " ")
+	}
+	return capturePrompt(locale, "capture_address_readback", map[string]string{
+		"street": spell(a.Street), "city": a.City, "postalCode": spell(a.PostalCode),
+	})
+}
+
+// CaptureAddress asks the caller for a postal address one part at a time, reads it
+// back for confirmation and validates it with the optional ValidateAddress hook
+func (c *Connection) CaptureAddress(ctx context.Context, options *CaptureOptions) (*PostalAddress, error) {
+	opts := CaptureOptions{}
+	if options != nil {
+		opts = *options
+	}
+	opts = opts.defaults()
+
+	steps := []addressStep{
+		{capturePrompt(opts.Locale, "capture_street", nil), func(a *PostalAddress, text string) bool {
+			a.Street = parseSpokenStreet(text)
+			return strings.IndexAny(a.Street, "0123456789") >= 0
+		}},
+		{capturePrompt(opts.Locale, "capture_city", nil), func(a *PostalAddress, text string) bool {
+			a.City = strings.TrimRight(strings.TrimSpace(text), ".")
+			return a.City != ""
+		}},
+		{capturePrompt(opts.Locale, "capture_postal_code", nil), func(a *PostalAddress, text string) bool {
+			a.PostalCode = parseSpokenPostalCode(text)
+			return len(a.PostalCode) >= 3
+		}},
+	}
+
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		address := &PostalAddress{}
+		if err := c.captureSteps(ctx, &opts, address, steps); err != nil {
+			return nil, err
+		}
+
+		if opts.ValidateAddress != nil {
+			if err := opts.ValidateAddress(ctx, address); err != nil {
+				c.TTS(capturePrompt(opts.Locale, "capture_address_unknown", nil), opts.Speaker, "", nil)
+				continue
+			}
+		}
+		if opts.SkipConfirm {
+			return address, nil
+		}
+
+		confirmed, err := c.Confirm(ctx, capturePrompt(opts.Locale, "capture_confirm", map[string]string{
+			"value": address.speakable(opts.Locale),
+		}), &ConfirmOptions{
+			Speaker: opts.Speaker,
+			Timeout: opts.Timeout,
+			Locale:  opts.Locale,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if confirmed.Confirmed() {
+			return address, nil
+		}
+	}
+	return nil, ErrCaptureFailed
+}
+
+// addressStep asks for one part of an address; set stores the answer and reports whether it is valid
+type addressStep struct {
+	prompt string
+	set    func(a *PostalAddress, text string) bool
+}
+
+// captureSteps asks for each part of an address, retrying parts that are missing or invalid
+func (c *Connection) captureSteps(ctx context.Context, opts *CaptureOptions, address *PostalAddress, steps []addressStep) error {
+	answers, unsubscribe := c.subscribe(func(event *Event) bool {
+		return event.Event == "asrFinal" && event.Text != ""
+	})
+	defer unsubscribe()
+
+	for _, step := range steps {
+		question := step.prompt
+		for attempt := 0; ; attempt++ {
+			if attempt > opts.Retries {
+				return ErrCaptureFailed
+			}
+			text, err := c.ask(ctx, answers, question, opts.Speaker, opts.Timeout)
+			if err != nil {
+				return err
+			}
+			if text != "" && step.set(address, text) {
+				break
+			}
+			question = capturePrompt(opts.Locale, "capture_retry", map[string]string{"prompt": step.prompt})
+		}
+	}
+	return nil
+}
+
+// parseSpokenPostalCode reads a postal code spoken digit by digit or letter by letter,
+// such as "nine four one oh five" or "S W one A, one A A"
+func parseSpokenPostalCode(text string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(strings.ToLower(strings.NewReplacer(",", " ", ".", " ", "-", " ").Replace(text))) {
+		switch n, ok := spokenUnits[word]; {
+		case word == "oh" || word == "o":
+			b.WriteString("0")
+		case ok:
+			b.WriteString(fmt.Sprint(n))
+		default:
+			b.WriteString(spokenLetter(word))
+		}
+	}
+	return strings.ToUpper(b.String())
+}
+
+// parseSpokenStreet writes the numbers of a street address as digits, joining a house
+// number said digit by digit: "one two three main street" becomes "123 main street"
+func parseSpokenStreet(text string) string {
+	words := strings.Fields(normalizeSpokenNumbers(text))
+	number := ""
+	for len(words) > 0 && strings.Trim(words[0], "0123456789") == "" {
+		number += words[0]
+		words = words[1:]
+	}
+	if number != "" {
+		words = append([]string{number}, words...)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,137 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSpokenEmail(t *testing.T) {
+	tests := map[string]string{
+		"john dot smith at gmail dot com":                   "john.smith@gmail.com",
+		"Juliet Oscar Hotel November at example dot org.":   "john@example.org",
+		"m a double t underscore two at mail dot co dot uk": "matt_2@mail.co.uk",
+		"meet me at the office at acme dot io":              "meetmeattheoffice@acme.io",
+		"double you a t t at example dot com":               "watt@example.com",
+		"mike at example dot com":                           "mike@example.com",
+		"victor smith at example dot com":                   "victorsmith@example.com",
+		"romeo oscar sierra alpha at example dot com":       "rosa@example.com",
+		"jose guion bajo luis arroba ejemplo punto com":     "jose_luis@ejemplo.com",
+	}
+	for input, expected := range tests {
+		if address := ParseSpokenEmail(input); address != expected {
+			t.Errorf("ParseSpokenEmail(%q) = %q, expected %q", input, address, expected)
+		}
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	valid := []string{"john.smith@gmail.com", "a+b@mail.co.uk"}
+	invalid := []string{"john@localhost", "john@-bad.com", "john@example.123", "johnexample.com", "john@exa_mple.com"}
+
+	for _, address := range valid {
+		if err := ValidateEmail(address); err != nil {
+			t.Errorf("Expected %s to be valid: %v", address, err)
+		}
+	}
+	for _, address := range invalid {
+		if err := ValidateEmail(address); err == nil {
+			t.Errorf("Expected %s to be invalid", address)
+		}
+	}
+}
+
+func TestSpellEmail(t *testing.T) {
+	if spoken := SpellEmail("jo.b@acme.com"); spoken != "j o dot b, at acme dot com" {
+		t.Errorf("Unexpected spelling: '%s'", spoken)
+	}
+	if spoken := spellEmail("jo.b@acme.com", SpanishLocale); spoken != "j o punto b, arroba acme punto com" {
+		t.Errorf("Unexpected Spanish spelling: '%s'", spoken)
+	}
+}
+
+func TestParseSpokenAddressParts(t *testing.T) {
+	if street := parseSpokenStreet("one two three Main Street"); street != "123 main street" {
+		t.Errorf("Unexpected street: '%s'", street)
+	}
+	if code := parseSpokenPostalCode("nine four one oh five"); code != "94105" {
+		t.Errorf("Unexpected postal code: '%s'", code)
+	}
+	if code := parseSpokenPostalCode("sierra whiskey one alpha, one alpha alpha"); code != "SW1A1AA" {
+		t.Errorf("Unexpected postal code: '%s'", code)
+	}
+}
+
+func TestCaptureEmail(t *testing.T) {
+	client := answeringServer(t,
+		map[string]interface{}{"event": "asrFinal", "text": "it's john at nowhere"},
+		map[string]interface{}{"event": "asrFinal", "text": "j o h n at example dot com"},
+		map[string]interface{}{"event": "asrFinal", "text": "yes"},
+	)
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	address, err := conn.CaptureEmail(context.Background(), "What is your email address?", &CaptureOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("CaptureEmail failed: %v", err)
+	}
+	if address != "john@example.com" {
+		t.Errorf("Expected john@example.com, got %s", address)
+	}
+}
+
+func TestCaptureAddress(t *testing.T) {
+	client := answeringServer(t,
+		map[string]interface{}{"event": "asrFinal", "text": "Main Street"},
+		map[string]interface{}{"event": "asrFinal", "text": "twelve Main Street"},
+		map[string]interface{}{"event": "asrFinal", "text": "Springfield."},
+		map[string]interface{}{"event": "asrFinal", "text": "six two seven oh one"},
+	)
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	var validated string
+	address, err := conn.CaptureAddress(context.Background(), &CaptureOptions{
+		Timeout:     time.Second,
+		SkipConfirm: true,
+		ValidateAddress: func(ctx context.Context, address *PostalAddress) error {
+			validated = address.String()
+			address.Country = "US"
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("CaptureAddress failed: %v", err)
+	}
+	if validated != "12 main street, Springfield, 62701" || address.Country != "US" {
+		t.Errorf("Unexpected address: %+v", address)
+	}
+}
+
+func TestCaptureLocale(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.CaptureAddress(context.Background(), &CaptureOptions{
+		Retries: -1,
+		Timeout: 10 * time.Millisecond,
+		Locale:  SpanishLocale,
+	})
+	if !errors.Is(err, ErrCaptureFailed) {
+		t.Fatalf("Expected ErrCaptureFailed without answers, got %v", err)
+	}
+	if cmd := <-commands; cmd["text"] != SpanishLocale.Prompt("capture_street", nil) {
+		t.Errorf("Expected the Spanish street prompt, got %v", cmd["text"])
+	}
+}
@@ -16,6 +16,7 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	options    ClientOptions
 }
 
 // NewClient creates a new RustPBX client
@@ -64,6 +65,8 @@ func (c *Client) connectWebSocket(ctx context.Context, endpoint string, options
 	sessionID := options.SessionID
 	if sessionID == "" {
 		sessionID = uuid.New().String()
+	} else if err := ValidateSessionID(sessionID); err != nil {
+		return nil, fmt.Errorf("invalid session ID: %w", err)
 	}
 
 	// Build WebSocket URL
@@ -73,7 +76,7 @@ func (c *Client) connectWebSocket(ctx context.Context, endpoint string, options
 	}
 
 	// Create and return connection
-	conn, err := NewConnection(ctx, wsURL)
+	conn, err := newConnection(ctx, wsURL, newCallContext(sessionID, options), options, c)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create WebSocket connection: %w", err)
 	}
@@ -111,17 +114,39 @@ func (c *Client) buildWebSocketURL(endpoint, sessionID string, dump bool) (strin
 	return u.String(), nil
 }
 
+// newRequest creates an HTTP request with the default headers and the call context carried by ctx
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.authorize(ctx, req.Header); err != nil {
+		return nil, err
+	}
+	if cc, ok := CallContextFromContext(ctx); ok {
+		cc.applyHeaders(req.Header)
+	}
+	if c.options.Signer != nil {
+		if err := c.options.Signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	c.log().Debug("sending request", "method", method, "url", url)
+
+	return req, nil
+}
+
 // GetActiveCalls retrieves a list of all currently active calls
 func (c *Client) GetActiveCalls(ctx context.Context) (*CallListResponse, error) {
 	url := c.baseURL + "/call/lists"
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := c.newRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 	
-	req.Header.Set("Content-Type", "application/json")
-	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -129,8 +154,7 @@ func (c *Client) GetActiveCalls(ctx context.Context) (*CallListResponse, error)
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 	
 	var result CallListResponse
@@ -141,30 +165,41 @@ func (c *Client) GetActiveCalls(ctx context.Context) (*CallListResponse, error)
 	return &result, nil
 }
 
+// findCall looks up an active call by ID
+func (c *Client) findCall(ctx context.Context, callID string) (*Call, error) {
+	calls, err := c.GetActiveCalls(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range calls.Calls {
+		if calls.Calls[i].ID == callID {
+			return &calls.Calls[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrCallNotFound, callID)
+}
+
 // KillCall forcefully terminates an active call by ID
 func (c *Client) KillCall(ctx context.Context, callID string) error {
 	url := c.baseURL + "/call/kill/" + callID
 	
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	req, err := c.newRequest(ctx, "POST", url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 	
-	req.Header.Set("Content-Type", "application/json")
-	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("call with ID %s not found", callID)
-	}
-	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		apiErr := newAPIError(resp)
+		if resp.StatusCode == http.StatusNotFound {
+			apiErr.Err = ErrCallNotFound
+		}
+		return apiErr
 	}
 	
 	return nil
@@ -174,13 +209,11 @@ func (c *Client) KillCall(ctx context.Context, callID string) error {
 func (c *Client) GetICEServers(ctx context.Context) ([]ICEServer, error) {
 	url := c.baseURL + "/iceservers"
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := c.newRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 	
-	req.Header.Set("Content-Type", "application/json")
-	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -188,8 +221,7 @@ func (c *Client) GetICEServers(ctx context.Context) ([]ICEServer, error) {
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp)
 	}
 	
 	var result []ICEServer
@@ -204,14 +236,11 @@ func (c *Client) GetICEServers(ctx context.Context) ([]ICEServer, error) {
 func (c *Client) ProxyLLMRequest(ctx context.Context, path string, method string, body io.Reader, headers map[string]string) (*http.Response, error) {
 	url := c.baseURL + "/llm/v1/" + strings.TrimPrefix(path, "/")
 	
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	req, err := c.newRequest(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 	
-	// Set default headers
-	req.Header.Set("Content-Type", "application/json")
-	
 	// Set custom headers
 	for key, value := range headers {
 		req.Header.Set(key, value)
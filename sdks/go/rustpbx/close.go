@@ -0,0 +1,209 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// CloseOptions represents graceful close configuration
+type CloseOptions struct {
+	// Hangup hangs up the call before closing, unless it has already ended, and waits for
+	// the hangup event
+	Hangup bool
+	// Reason and Initiator of the hangup; "normal_clearing" and "caller" when empty
+	Reason    string
+	Initiator string
+}
+
+// pendingSends counts the commands being sent, so a graceful close can wait for them
+type pendingSends struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed when the count drops to zero
+	idle chan struct{}
+}
+
+// begin counts a send
+func (p *pendingSends) begin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.count == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.count++
+}
+
+// end uncounts a send
+func (p *pendingSends) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count--
+	if p.count == 0 {
+		close(p.idle)
+	}
+}
+
+// wait waits until no send is pending or ctx is done
+func (p *pendingSends) wait(ctx context.Context) error {
+	p.mu.Lock()
+	if p.count == 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	idle := p.idle
+	p.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseGracefully closes the connection without cutting off commands in flight: it waits
+// for pending sends, such as a TTS streamed in chunks, optionally hangs up the call and
+// waits for the hangup event, then closes the socket. If ctx is done first, the
+// connection is closed right away and the error of ctx is returned.
+func (c *Connection) CloseGracefully(ctx context.Context, options *CloseOptions) error {
+	if options == nil {
+		options = &CloseOptions{}
+	}
+	err := c.drainAndHangup(ctx, options)
+	if closeErr := c.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// drainAndHangup waits for pending sends and hangs up the call if requested
+func (c *Connection) drainAndHangup(ctx context.Context, options *CloseOptions) error {
+	if err := c.sends.wait(ctx); err != nil {
+		return err
+	}
+	c.mu.RLock()
+	ended := c.hungUp || c.closed
+	c.mu.RUnlock()
+	if !options.Hangup || ended {
+		return nil
+	}
+
+	reason, initiator := options.Reason, options.Initiator
+	if reason == "" {
+		reason = "normal_clearing"
+	}
+	if initiator == "" {
+		initiator = "caller"
+	}
+	hangups, unsubscribe := c.subscribe(func(event *Event) bool {
+		return event.Event == "hangup"
+	})
+	defer unsubscribe()
+	if err := c.HangupContext(ctx, reason, initiator); err != nil {
+		return err
+	}
+
+	select {
+	case <-hangups:
+		return nil
+	case <-c.done:
+		// The server dropped the connection after hanging up
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseError reports the close frame the server ended the connection with. Code is one of
+// the websocket.Close* codes, e.g. websocket.ClosePolicyViolation.
+type CloseError struct {
+	Code int
+	Text string
+	err  error
+}
+
+func (e *CloseError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("connection closed by server with code %d", e.Code)
+	}
+	return fmt.Sprintf("connection closed by server with code %d: %s", e.Code, e.Text)
+}
+
+// Unwrap returns the *websocket.CloseError read from the socket
+func (e *CloseError) Unwrap() error {
+	return e.err
+}
+
+// Normal reports whether the server shut the connection down in order
+func (e *CloseError) Normal() bool {
+	return e.Code == websocket.CloseNormalClosure
+}
+
+// CloseEvent is delivered when the server closes the connection with a close frame, before
+// reconnecting if a reconnect policy is set
+type CloseEvent struct {
+	Timestamp int64
+	Code      int
+	Text      string
+	Raw       *Event
+}
+
+// OnClose sets the handler of close events, emitted when the server closes the connection
+// with a close frame, whatever its code
+func (c *Connection) OnClose(handler func(*CloseEvent)) {
+	c.on("close", typed(handler, newCloseEvent))
+}
+
+// newCloseEvent converts a close event
+func newCloseEvent(e *Event) *CloseEvent {
+	var data struct {
+		Text string `json:"text"`
+	}
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, &data)
+	}
+	return &CloseEvent{Timestamp: e.Timestamp, Code: e.Code, Text: data.Text, Raw: e}
+}
+
+// serverClosed converts the read error of a close frame into a *CloseError and emits a
+// close event; other errors are returned as is
+func (c *Connection) serverClosed(err error) error {
+	var frame *websocket.CloseError
+	if !errors.As(err, &frame) || frame.Code == websocket.CloseAbnormalClosure {
+		// Abnormal closures are reported by the WebSocket for drops without a close frame
+		return err
+	}
+	closeErr := &CloseError{Code: frame.Code, Text: frame.Text, err: err}
+	c.log().Info("closed by server", "code", frame.Code, "text", frame.Text)
+	data, _ := json.Marshal(map[string]interface{}{
+		"text": frame.Text,
+	})
+	c.dispatch(&Event{
+		Event:     "close",
+		Timestamp: time.Now().UnixMilli(),
+		Code:      frame.Code,
+		Data:      data,
+	})
+	return closeErr
+}
+
+// Err returns why the connection ended, once its read loop has: a *CloseError when the
+// server closed it with a close frame other than a normal closure, ErrConnectionDead,
+// ErrMessageTooLarge or the read error. It is nil while the connection is open, and after
+// Close or a normal closure by the server.
+func (c *Connection) Err() error {
+	select {
+	case <-c.done:
+	default:
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.disconnectErr
+}
@@ -0,0 +1,117 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCloseGracefully(t *testing.T) {
+	received := make(chan string, 8)
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			received <- cmd["command"].(string)
+			if cmd["command"] == "hangup" && cmd["reason"] != "silent" {
+				conn.WriteJSON(Event{Event: "hangup", Reason: "normal_clearing"})
+			}
+		}
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	conn.TTS("Goodbye", "", "", nil)
+	if err := conn.CloseGracefully(context.Background(), &CloseOptions{Hangup: true}); err != nil {
+		t.Fatalf("CloseGracefully failed: %v", err)
+	}
+	if cmd := <-received; cmd != "tts" {
+		t.Errorf("Expected the tts first, got %s", cmd)
+	}
+	if cmd := <-received; cmd != "hangup" {
+		t.Errorf("Expected a hangup, got %s", cmd)
+	}
+	if !conn.isClosed() {
+		t.Error("Expected the connection to be closed")
+	}
+
+	// A call that already ended is not hung up again
+	conn, _ = NewClient(server.URL).ConnectCall(context.Background(), nil)
+	conn.SendRawCommand(map[string]interface{}{"command": "hangup"})
+	<-received
+	if _, err := conn.WaitForEvent("hangup", time.Second); err != nil {
+		t.Fatalf("Expected the hangup event: %v", err)
+	}
+	conn.CloseGracefully(context.Background(), &CloseOptions{Hangup: true})
+	select {
+	case cmd := <-received:
+		t.Errorf("Expected no command, got %s", cmd)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The deadline bounds the wait for the hangup event
+	conn, _ = NewClient(server.URL).ConnectCall(context.Background(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := conn.CloseGracefully(ctx, &CloseOptions{Hangup: true, Reason: "silent"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if !conn.isClosed() {
+		t.Error("Expected the connection to be closed after the deadline")
+	}
+}
+
+func TestServerCloseCode(t *testing.T) {
+	tests := []struct {
+		code int
+		text string
+		err  bool
+	}{
+		{websocket.ClosePolicyViolation, "tenant suspended", true},
+		{websocket.CloseNormalClosure, "", false},
+	}
+	for _, test := range tests {
+		server, _ := newTestServer(t, func(conn *websocket.Conn) {
+			var ready map[string]interface{}
+			conn.ReadJSON(&ready)
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(test.code, test.text), time.Now().Add(time.Second))
+		})
+		conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("ConnectCall failed: %v", err)
+		}
+		closes := make(chan *CloseEvent, 1)
+		conn.OnClose(func(e *CloseEvent) { closes <- e })
+		conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+		select {
+		case e := <-closes:
+			if e.Code != test.code || e.Text != test.text {
+				t.Errorf("Expected close %d '%s', got %d '%s'", test.code, test.text, e.Code, e.Text)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a close event for code %d", test.code)
+		}
+		<-conn.done
+
+		var closeErr *CloseError
+		err = conn.Err()
+		if test.err {
+			if !errors.As(err, &closeErr) || closeErr.Code != test.code || closeErr.Normal() {
+				t.Errorf("Expected a *CloseError with code %d, got %v", test.code, err)
+			}
+		} else if err != nil {
+			t.Errorf("Expected no error after a normal closure, got %v", err)
+		}
+		if closeResult := conn.Close(); closeResult != err {
+			t.Errorf("Expected Close to return %v, got %v", err, closeResult)
+		}
+	}
+}
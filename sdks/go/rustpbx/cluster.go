@@ -0,0 +1,327 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BalanceStrategy selects the node that receives a new call
+type BalanceStrategy int
+
+const (
+	// BalanceRoundRobin cycles through the healthy nodes
+	BalanceRoundRobin BalanceStrategy = iota
+	// BalanceLeastLoaded picks the healthy node with the fewest active calls
+	BalanceLeastLoaded
+)
+
+// ClusterOptions represents ClusterClient configuration
+type ClusterOptions struct {
+	Strategy            BalanceStrategy
+	HealthCheckInterval time.Duration
+	HTTPClient          *http.Client
+	// Client holds the credentials and other options of the node clients; its HTTPClient
+	// defaults to HTTPClient
+	Client *ClientOptions
+}
+
+// NodeStatus reports the last known state of a cluster node
+type NodeStatus struct {
+	BaseURL     string
+	Healthy     bool
+	ActiveCalls int
+	LastError   error
+	LastChecked time.Time
+}
+
+// clusterNode tracks one RustPBX server of the cluster
+type clusterNode struct {
+	client *Client
+	status NodeStatus
+}
+
+// ClusterClient distributes calls across several RustPBX servers and routes
+// per-call operations to the server that owns the call
+type ClusterClient struct {
+	nodes    []*clusterNode
+	strategy BalanceStrategy
+	interval time.Duration
+	mu       sync.Mutex
+	next     int
+	owners   map[string]*clusterNode
+}
+
+// NewClusterClient creates a client for the given RustPBX base URLs
+func NewClusterClient(baseURLs []string, options *ClusterOptions) (*ClusterClient, error) {
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("at least one base URL is required")
+	}
+	if options == nil {
+		options = &ClusterOptions{}
+	}
+
+	interval := options.HealthCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	cc := &ClusterClient{
+		strategy: options.Strategy,
+		interval: interval,
+		owners:   make(map[string]*clusterNode),
+	}
+	for _, baseURL := range baseURLs {
+		var client *Client
+		if options.Client != nil {
+			clientOptions := *options.Client
+			if clientOptions.HTTPClient == nil {
+				clientOptions.HTTPClient = options.HTTPClient
+			}
+			client = NewClientWithOptions(baseURL, clientOptions)
+		} else if options.HTTPClient != nil {
+			client = NewClientWithHTTPClient(baseURL, options.HTTPClient)
+		} else {
+			client = NewClient(baseURL)
+		}
+		cc.nodes = append(cc.nodes, &clusterNode{
+			client: client,
+			// Nodes are assumed healthy until the first check says otherwise
+			status: NodeStatus{BaseURL: strings.TrimSuffix(baseURL, "/"), Healthy: true},
+		})
+	}
+
+	return cc, nil
+}
+
+// Start runs periodic health checks until ctx is cancelled
+func (cc *ClusterClient) Start(ctx context.Context) {
+	cc.CheckHealth(ctx)
+
+	go func() {
+		ticker := time.NewTicker(cc.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				cc.CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+// CheckHealth queries every node for its active calls and updates health and ownership
+func (cc *ClusterClient) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, node := range cc.nodes {
+		wg.Add(1)
+		go func(node *clusterNode) {
+			defer wg.Done()
+			calls, err := node.client.GetActiveCalls(ctx)
+
+			cc.mu.Lock()
+			defer cc.mu.Unlock()
+			node.status.LastChecked = time.Now()
+			node.status.LastError = err
+			node.status.Healthy = err == nil
+			if err != nil {
+				return
+			}
+			node.status.ActiveCalls = len(calls.Calls)
+
+			// Refresh ownership from what the node reports
+			live := make(map[string]bool, len(calls.Calls))
+			for _, call := range calls.Calls {
+				live[call.ID] = true
+				cc.owners[call.ID] = node
+			}
+			for callID, owner := range cc.owners {
+				if owner == node && !live[callID] {
+					delete(cc.owners, callID)
+				}
+			}
+		}(node)
+	}
+	wg.Wait()
+}
+
+// Nodes returns the status of every node
+func (cc *ClusterClient) Nodes() []NodeStatus {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	result := make([]NodeStatus, 0, len(cc.nodes))
+	for _, node := range cc.nodes {
+		result = append(result, node.status)
+	}
+	return result
+}
+
+// healthyNodes returns the nodes that passed their last health check, or every node
+// rather than none when all health checks failed
+func (cc *ClusterClient) healthyNodes() []*clusterNode {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.healthyNodesLocked()
+}
+
+// healthyNodesLocked is healthyNodes for callers holding cc.mu
+func (cc *ClusterClient) healthyNodesLocked() []*clusterNode {
+	candidates := make([]*clusterNode, 0, len(cc.nodes))
+	for _, node := range cc.nodes {
+		if node.status.Healthy {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) == 0 {
+		return cc.nodes
+	}
+	return candidates
+}
+
+// pickNode selects the node for a new call according to the balance strategy
+func (cc *ClusterClient) pickNode() *clusterNode {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	candidates := cc.healthyNodesLocked()
+
+	switch cc.strategy {
+	case BalanceLeastLoaded:
+		best := candidates[0]
+		for _, node := range candidates[1:] {
+			if node.status.ActiveCalls < best.status.ActiveCalls {
+				best = node
+			}
+		}
+		return best
+	default:
+		node := candidates[cc.next%len(candidates)]
+		cc.next++
+		return node
+	}
+}
+
+// connect opens a call on the selected node and records it as the owner
+func (cc *ClusterClient) connect(ctx context.Context, options *ConnectionOptions,
+	dial func(*Client, context.Context, *ConnectionOptions) (*Connection, error)) (*Connection, error) {
+	node := cc.pickNode()
+
+	conn, err := dial(node.client, ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", node.client.baseURL, err)
+	}
+
+	cc.mu.Lock()
+	node.status.ActiveCalls++
+	cc.mu.Unlock()
+	if conn.CallContext() != nil {
+		cc.trackOwner(conn, conn.CallContext().CallID, node)
+	}
+
+	return conn, nil
+}
+
+// trackOwner records the node owning the call of a connection until the call is hung up
+// or the connection closed, so ownership does not grow without health checks
+func (cc *ClusterClient) trackOwner(conn *Connection, callID string, node *clusterNode) {
+	cc.mu.Lock()
+	cc.owners[callID] = node
+	cc.mu.Unlock()
+
+	conn.AddEventHandler(func(event *Event) {
+		if event.Event == "hangup" {
+			cc.forgetOwner(callID, node)
+		}
+	})
+	go func() {
+		<-conn.ctx.Done()
+		cc.forgetOwner(callID, node)
+	}()
+}
+
+// forgetOwner drops the ownership of a call unless another node took it over since
+func (cc *ClusterClient) forgetOwner(callID string, node *clusterNode) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.owners[callID] == node {
+		delete(cc.owners, callID)
+	}
+}
+
+// ConnectCall establishes a WebSocket call on one of the cluster nodes
+func (cc *ClusterClient) ConnectCall(ctx context.Context, options *ConnectionOptions) (*Connection, error) {
+	return cc.connect(ctx, options, (*Client).ConnectCall)
+}
+
+// ConnectWebRTC establishes a WebRTC call on one of the cluster nodes
+func (cc *ClusterClient) ConnectWebRTC(ctx context.Context, options *ConnectionOptions) (*Connection, error) {
+	return cc.connect(ctx, options, (*Client).ConnectWebRTC)
+}
+
+// ConnectSIP establishes a SIP call on one of the cluster nodes
+func (cc *ClusterClient) ConnectSIP(ctx context.Context, options *ConnectionOptions) (*Connection, error) {
+	return cc.connect(ctx, options, (*Client).ConnectSIP)
+}
+
+// ClientFor returns the client of the node owning a call, discovering it if needed
+func (cc *ClusterClient) ClientFor(ctx context.Context, callID string) (*Client, error) {
+	cc.mu.Lock()
+	node, ok := cc.owners[callID]
+	cc.mu.Unlock()
+	if ok {
+		return node.client, nil
+	}
+
+	for _, node := range cc.nodes {
+		if _, err := node.client.findCall(ctx, callID); err == nil {
+			cc.mu.Lock()
+			cc.owners[callID] = node
+			cc.mu.Unlock()
+			return node.client, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrCallNotFound, callID)
+}
+
+// GetActiveCalls retrieves the active calls of every healthy node, or of every node when
+// none is known to be healthy, e.g. before the first health check
+func (cc *ClusterClient) GetActiveCalls(ctx context.Context) (*CallListResponse, error) {
+	result := &CallListResponse{}
+	var lastErr error
+	for _, node := range cc.healthyNodes() {
+		calls, err := node.client.GetActiveCalls(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result.Calls = append(result.Calls, calls.Calls...)
+	}
+	if len(result.Calls) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// KillCall terminates a call on the node that owns it
+func (cc *ClusterClient) KillCall(ctx context.Context, callID string) error {
+	client, err := cc.ClientFor(ctx, callID)
+	if err != nil {
+		return err
+	}
+	if err := client.KillCall(ctx, callID); err != nil {
+		return err
+	}
+
+	cc.mu.Lock()
+	delete(cc.owners, callID)
+	cc.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newCallListServer(calls ...Call) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(CallListResponse{Calls: calls})
+	}))
+}
+
+func TestClusterClientHealthAndRouting(t *testing.T) {
+	busy := newCallListServer(Call{ID: "call-1"}, Call{ID: "call-2"})
+	defer busy.Close()
+	idle := newCallListServer()
+	defer idle.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	cluster, err := NewClusterClient([]string{busy.URL, idle.URL, down.URL}, &ClusterOptions{Strategy: BalanceLeastLoaded})
+	if err != nil {
+		t.Fatalf("NewClusterClient failed: %v", err)
+	}
+	cluster.CheckHealth(context.Background())
+
+	nodes := cluster.Nodes()
+	if !nodes[0].Healthy || nodes[0].ActiveCalls != 2 {
+		t.Errorf("Expected first node healthy with 2 calls, got %+v", nodes[0])
+	}
+	if nodes[2].Healthy {
+		t.Error("Expected failing node to be unhealthy")
+	}
+
+	if node := cluster.pickNode(); node.client.baseURL != idle.URL {
+		t.Errorf("Expected least loaded node '%s', got '%s'", idle.URL, node.client.baseURL)
+	}
+
+	client, err := cluster.ClientFor(context.Background(), "call-2")
+	if err != nil || client.baseURL != busy.URL {
+		t.Errorf("Expected call-2 to be owned by '%s', got %v", busy.URL, err)
+	}
+}
+
+func TestClusterClientRoundRobin(t *testing.T) {
+	cluster, err := NewClusterClient([]string{"http://a", "http://b"}, nil)
+	if err != nil {
+		t.Fatalf("NewClusterClient failed: %v", err)
+	}
+
+	first := cluster.pickNode()
+	second := cluster.pickNode()
+	if first == second {
+		t.Error("Expected round robin to alternate nodes")
+	}
+	if cluster.pickNode() != first {
+		t.Error("Expected round robin to wrap around")
+	}
+}
+
+func TestClusterClientActiveCallsOfHealthyNodes(t *testing.T) {
+	up := newCallListServer(Call{ID: "call-1"})
+	defer up.Close()
+	var recovered atomic.Bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !recovered.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(CallListResponse{Calls: []Call{{ID: "call-2"}}})
+	}))
+	defer flaky.Close()
+
+	cluster, _ := NewClusterClient([]string{up.URL, flaky.URL}, nil)
+	cluster.CheckHealth(context.Background())
+	recovered.Store(true)
+
+	calls, err := cluster.GetActiveCalls(context.Background())
+	if err != nil || len(calls.Calls) != 1 || calls.Calls[0].ID != "call-1" {
+		t.Errorf("Expected the calls of the healthy node only, got %+v (%v)", calls, err)
+	}
+}
+
+func TestClusterClientForgetsEndedCalls(t *testing.T) {
+	hangup, _ := newTestServer(t, func(conn *websocket.Conn) {
+		time.Sleep(50 * time.Millisecond)
+		conn.WriteJSON(Event{Event: "hangup", Reason: "bye"})
+	})
+	idle, _ := newTestServer(t, nil)
+
+	for _, tc := range []struct {
+		name  string
+		url   string
+		close bool
+	}{
+		{"hangup", hangup.URL, false},
+		{"close", idle.URL, true},
+	} {
+		cluster, _ := NewClusterClient([]string{tc.url}, nil)
+		owned := func() int {
+			cluster.mu.Lock()
+			defer cluster.mu.Unlock()
+			return len(cluster.owners)
+		}
+
+		conn, err := cluster.ConnectCall(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("%s: ConnectCall failed: %v", tc.name, err)
+		}
+		if owned() != 1 {
+			t.Fatalf("%s: expected the call to be owned, got %d owners", tc.name, owned())
+		}
+		if tc.close {
+			conn.Close()
+		}
+		deadline := time.Now().Add(time.Second)
+		for owned() != 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if owned() != 0 {
+			t.Errorf("%s: expected the ended call to be forgotten", tc.name)
+		}
+		conn.Close()
+	}
+}
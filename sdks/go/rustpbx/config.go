@@ -0,0 +1,272 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CallConfig holds the settings applied to new calls: prompts, flows, provider settings
+// and routing rules. A config is a snapshot that must not be modified once loaded; calls
+// keep the snapshot they started with while newer configs are loaded.
+type CallConfig struct {
+	// Prompts holds prompts by name; "system" is the assistant's system prompt when its
+	// options have none
+	Prompts map[string]string `json:"prompts,omitempty"`
+	// Personas are used by assistants whose options have none
+	Personas []Persona `json:"personas,omitempty"`
+	// Flows holds application-defined flow definitions by name
+	Flows map[string]json.RawMessage `json:"flows,omitempty"`
+	// ASR and TTS are used by Invite and Accept when the call option has none
+	ASR *TranscriptionOption `json:"asr,omitempty"`
+	TTS *SynthesisOption     `json:"tts,omitempty"`
+	// Routes are matched in order by Route
+	Routes []RouteRule `json:"routes,omitempty"`
+	// Trunks holds media presets by trunk name, such as the bandwidth of a satellite link
+	// that the codec of a call must fit
+	Trunks map[string]MediaOption `json:"trunks,omitempty"`
+
+	// Version counts the configs loaded by a watcher, starting at 1
+	Version int `json:"-"`
+	// Loaded is when the config was loaded
+	Loaded time.Time `json:"-"`
+}
+
+// RouteRule routes calls matching the caller and callee patterns to a target, a queue
+// or a flow. Patterns use path.Match syntax, e.g. "+44*"; an empty pattern matches any number.
+type RouteRule struct {
+	Name   string `json:"name,omitempty"`
+	Caller string `json:"caller,omitempty"`
+	Callee string `json:"callee,omitempty"`
+	Target string `json:"target,omitempty"`
+	Queue  string `json:"queue,omitempty"`
+	Flow   string `json:"flow,omitempty"`
+	// Trunk names the media preset of Trunks used by Invite and Accept for matching calls
+	Trunk string `json:"trunk,omitempty"`
+}
+
+// matches reports whether the rule applies to a call
+func (r *RouteRule) matches(caller, callee string) bool {
+	return matchPattern(r.Caller, caller) && matchPattern(r.Callee, callee)
+}
+
+// matchPattern matches a number against a route pattern
+func matchPattern(pattern, number string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(pattern, number)
+	return ok
+}
+
+// Route returns the first rule matching a call, or nil when none matches
+func (c *CallConfig) Route(caller, callee string) *RouteRule {
+	for i := range c.Routes {
+		if c.Routes[i].matches(caller, callee) {
+			return &c.Routes[i]
+		}
+	}
+	return nil
+}
+
+// Prompt returns a prompt by name, or fallback when the config has none
+func (c *CallConfig) Prompt(name, fallback string) string {
+	if prompt, ok := c.Prompts[name]; ok {
+		return prompt
+	}
+	return fallback
+}
+
+// validate checks a config before it replaces the current one
+func (c *CallConfig) validate() error {
+	for i, route := range c.Routes {
+		if route.Target == "" && route.Queue == "" && route.Flow == "" {
+			return fmt.Errorf("route %d has no target, queue or flow", i)
+		}
+		for _, pattern := range []string{route.Caller, route.Callee} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("route %d has an invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+		if route.Flow != "" {
+			if _, ok := c.Flows[route.Flow]; !ok {
+				return fmt.Errorf("route %d refers to unknown flow %q", i, route.Flow)
+			}
+		}
+		if route.Trunk != "" {
+			if _, ok := c.Trunks[route.Trunk]; !ok {
+				return fmt.Errorf("route %d refers to unknown trunk %q", i, route.Trunk)
+			}
+		}
+	}
+	for name, trunk := range c.Trunks {
+		if err := trunk.Validate(); err != nil {
+			return fmt.Errorf("trunk %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ConfigWatcher loads a call config from a JSON file and reloads it when the file changes
+// or Reload is called, e.g. from an admin API. A config that fails to load or validate is
+// rejected and the current one is kept.
+type ConfigWatcher struct {
+	path    string
+	current atomic.Pointer[CallConfig]
+	// mu serializes reloads
+	mu      sync.Mutex
+	modTime time.Time
+
+	// OnReload is called with each newly loaded config
+	OnReload func(config *CallConfig)
+	// OnError is called when a reload by Watch fails
+	OnError func(err error)
+}
+
+// NewConfigWatcher creates a watcher and loads the config file at path
+func NewConfigWatcher(path string) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{path: path}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Current returns the current config
+func (w *ConfigWatcher) Current() *CallConfig {
+	return w.current.Load()
+}
+
+// Reload loads the config file, even if it has not changed. Calls started before keep
+// their config.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	return w.load(info.ModTime())
+}
+
+// load reads, validates and publishes the config file; the caller must hold w.mu
+func (w *ConfigWatcher) load(modTime time.Time) error {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	config := &CallConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	config.Version = 1
+	if previous := w.current.Load(); previous != nil {
+		config.Version = previous.Version + 1
+	}
+	config.Loaded = time.Now()
+	w.modTime = modTime
+	w.current.Store(config)
+	if w.OnReload != nil {
+		w.OnReload(config)
+	}
+	return nil
+}
+
+// Watch polls the config file every interval, 2s when zero, and reloads it when its
+// modification time changes, until ctx is done
+func (w *ConfigWatcher) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.reloadIfChanged(); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}
+}
+
+// reloadIfChanged reloads the config file if its modification time changed
+func (w *ConfigWatcher) reloadIfChanged() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+	if info.ModTime().Equal(w.modTime) {
+		return nil
+	}
+	if err := w.load(info.ModTime()); err != nil {
+		// Do not retry the same broken file on every poll
+		w.modTime = info.ModTime()
+		return err
+	}
+	return nil
+}
+
+// Config returns the config the call started with, or nil when the connection has no
+// config watcher
+func (c *Connection) Config() *CallConfig {
+	return c.config
+}
+
+// applyConfig fills a call option with the provider settings of the call's config, and
+// with the trunk media preset of the route matching its caller and callee, which the
+// option is validated against
+func (c *Connection) applyConfig(option *CallOption) *CallOption {
+	if c.config == nil || (c.config.ASR == nil && c.config.TTS == nil && len(c.config.Trunks) == 0) {
+		return option
+	}
+	applied := CallOption{}
+	if option != nil {
+		applied = *option
+	}
+	if applied.Media == nil {
+		if route := c.config.Route(applied.Caller, applied.Callee); route != nil && route.Trunk != "" {
+			if media, ok := c.config.Trunks[route.Trunk]; ok {
+				applied.Media = &media
+			}
+		}
+	}
+	if applied.ASR == nil && c.config.ASR != nil {
+		asr := *c.config.ASR
+		applied.ASR = &asr
+	}
+	if applied.TTS == nil && c.config.TTS != nil {
+		tts := *c.config.TTS
+		applied.TTS = &tts
+	}
+	return &applied
+}
+
+// applyAssistant fills the assistant options with the prompt and personas of the config
+func (c *CallConfig) applyAssistant(opts *AssistantOptions) {
+	if c == nil {
+		return
+	}
+	if opts.SystemPrompt == "" {
+		opts.SystemPrompt = c.Prompt("system", "")
+	}
+	if len(opts.Personas) == 0 && len(c.Personas) > 0 {
+		opts.Personas = append([]Persona(nil), c.Personas...)
+	}
+}
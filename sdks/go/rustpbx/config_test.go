@@ -0,0 +1,159 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	write(`{
+		"prompts": {"system": "You are a receptionist."},
+		"tts": {"provider": "aliyun", "speaker": "xiaoyun"},
+		"flows": {"sales": {"steps": ["greet"]}},
+		"routes": [
+			{"name": "uk", "callee": "+44*", "queue": "uk-support"},
+			{"callee": "1000", "flow": "sales"}
+		]
+	}`, time.Now().Add(-time.Minute))
+
+	watcher, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+	reloads := make(chan *CallConfig, 4)
+	watcher.OnReload = func(config *CallConfig) { reloads <- config }
+	errs := make(chan error, 4)
+	watcher.OnError = func(err error) { errs <- err }
+
+	first := watcher.Current()
+	if first.Version != 1 || first.Prompt("system", "") != "You are a receptionist." {
+		t.Errorf("Unexpected config: %+v", first)
+	}
+	if route := first.Route("+15550100", "+442071234567"); route == nil || route.Queue != "uk-support" {
+		t.Errorf("Expected the uk route, got %+v", route)
+	}
+	if route := first.Route("", "1000"); route == nil || route.Flow != "sales" {
+		t.Errorf("Expected the sales flow, got %+v", route)
+	}
+	if route := first.Route("", "2000"); route != nil {
+		t.Errorf("Expected no route, got %+v", route)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Watch(ctx, 5*time.Millisecond)
+
+	write(`{"prompts": {"system": "You are a sales agent."}}`, time.Now())
+	select {
+	case config := <-reloads:
+		if config.Version != 2 || config.Prompt("system", "") != "You are a sales agent." {
+			t.Errorf("Unexpected reloaded config: %+v", config)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the changed file to be reloaded")
+	}
+	if first.Prompt("system", "") != "You are a receptionist." {
+		t.Error("Expected the previous config to be left untouched")
+	}
+
+	write(`{"routes": [{"callee": "1000", "flow": "missing"}]}`, time.Now().Add(time.Second))
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the invalid config to be reported")
+	}
+	if err := watcher.Reload(); err == nil {
+		t.Error("Expected Reload to reject the invalid config")
+	}
+	if watcher.Current().Version != 2 {
+		t.Errorf("Expected the current config to be kept, got version %d", watcher.Current().Version)
+	}
+}
+
+func TestConnectionConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"prompts": {"system": "Be brief."}, "asr": {"provider": "tencent"}}`), 0o644)
+	watcher, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{Config: watcher})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	// A reload after the call started does not change it
+	os.WriteFile(path, []byte(`{"prompts": {"system": "Be chatty."}}`), 0o644)
+	if err := watcher.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	assistant := NewAssistant(conn, &AssistantOptions{})
+	if history := assistant.History(); len(history) != 1 || history[0].Content != "Be brief." {
+		t.Errorf("Expected the system prompt of the call's config, got %+v", history)
+	}
+
+	if err := conn.Accept(&CallOption{Caller: "1001"}); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	cmd := <-commands
+	option, _ := cmd["option"].(map[string]interface{})
+	asr, _ := option["asr"].(map[string]interface{})
+	if asr["provider"] != "tencent" || option["caller"] != "1001" {
+		t.Errorf("Expected the ASR settings of the config, got %v", cmd)
+	}
+}
+
+func TestConnectionConfigTrunk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{
+		"trunks": {"satellite": {"ptimeMs": 60, "maxBitrate": 72000}},
+		"routes": [{"callee": "+88*", "target": "sip:sat@gw", "trunk": "satellite"}]
+	}`), 0o644)
+	watcher, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatalf("NewConfigWatcher failed: %v", err)
+	}
+
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{Config: watcher})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Invite(&CallOption{Callee: "+8816123", Codec: CodecPCMU}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	cmd := <-commands
+	if option, _ := cmd["option"].(map[string]interface{}); option["media"] != nil {
+		t.Errorf("Expected the media option not to be sent, got %v", cmd)
+	}
+
+	// PCM needs far more than the satellite link offers
+	if err := conn.Invite(&CallOption{Callee: "+8816123", Codec: CodecPCM}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("Expected the codec to be rejected by the trunk's bitrate cap, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"routes": [{"target": "sip:gw", "trunk": "missing"}]}`), 0o644)
+	if err := watcher.Reload(); err == nil {
+		t.Errorf("Expected a route with an unknown trunk to be rejected")
+	}
+}
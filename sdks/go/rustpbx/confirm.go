@@ -0,0 +1,151 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConfirmationAnswer is the outcome of a yes/no question
+type ConfirmationAnswer int
+
+const (
+	ConfirmationYes ConfirmationAnswer = iota
+	ConfirmationNo
+	// ConfirmationNoInput means the caller said nothing on every attempt
+	ConfirmationNoInput
+	// ConfirmationNoMatch means the caller's last answer was neither yes nor no
+	ConfirmationNoMatch
+)
+
+// String returns the name of the answer
+func (a ConfirmationAnswer) String() string {
+	switch a {
+	case ConfirmationYes:
+		return "yes"
+	case ConfirmationNo:
+		return "no"
+	case ConfirmationNoInput:
+		return "no_input"
+	default:
+		return "no_match"
+	}
+}
+
+// ConfirmationResult represents the caller's answer to a yes/no question
+type ConfirmationResult struct {
+	Answer ConfirmationAnswer
+	// Input is the transcript or digit of the last answer
+	Input string
+	// DTMF is set when the caller answered with the keypad
+	DTMF     bool
+	Attempts int
+}
+
+// Confirmed reports whether the caller answered yes
+func (r *ConfirmationResult) Confirmed() bool {
+	return r.Answer == ConfirmationYes
+}
+
+// ConfirmOptions represents yes/no question configuration
+type ConfirmOptions struct {
+	// Locale provides the yes/no grammar and reprompt; EnglishLocale when nil
+	Locale *LocaleBundle
+	// Retries is the number of times the question is asked again; 2 when zero, none when negative
+	Retries int
+	// Timeout is how long to wait for an answer after asking; 10s when zero
+	Timeout time.Duration
+	// Reprompt is spoken before asking again; the locale's "confirm_reprompt" prompt when empty
+	Reprompt string
+	Speaker  string
+	// YesDigit and NoDigit are the keypad answers; 1 and 2 when empty
+	YesDigit string
+	NoDigit  string
+}
+
+// Confirm asks the caller a yes/no question and waits for a spoken or keypad answer,
+// asking again when the answer is missing or not understood
+func (c *Connection) Confirm(ctx context.Context, prompt string, options *ConfirmOptions) (*ConfirmationResult, error) {
+	opts := ConfirmOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Locale == nil {
+		opts.Locale = EnglishLocale
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 2
+	} else if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Reprompt == "" {
+		opts.Reprompt = opts.Locale.Prompt("confirm_reprompt", nil)
+	}
+	if opts.YesDigit == "" {
+		opts.YesDigit = "1"
+	}
+	if opts.NoDigit == "" {
+		opts.NoDigit = "2"
+	}
+
+	answers, unsubscribe := c.subscribe(func(event *Event) bool {
+		// Speech answers are ignored once recognition was degraded to the keypad
+		return (event.Event == "asrFinal" && event.Text != "" && !c.DTMFOnly()) || event.Event == "dtmf"
+	})
+	defer unsubscribe()
+
+	result := &ConfirmationResult{Answer: ConfirmationNoInput}
+	for result.Attempts <= opts.Retries {
+		text := prompt
+		if result.Attempts > 0 && opts.Reprompt != "" {
+			text = opts.Reprompt + " " + prompt
+		}
+		result.Attempts++
+		if err := c.TTS(text, opts.Speaker, "", nil); err != nil {
+			return nil, fmt.Errorf("failed to ask confirmation: %w", err)
+		}
+
+		timer := time.NewTimer(opts.Timeout)
+		select {
+		case event := <-answers:
+			timer.Stop()
+			result.Input, result.DTMF = event.Text, event.Event == "dtmf"
+			if result.DTMF {
+				result.Input = event.Digit
+			}
+			result.Answer = classifyConfirmation(result, &opts)
+			if result.Answer == ConfirmationYes || result.Answer == ConfirmationNo {
+				return result, nil
+			}
+		case <-timer.C:
+			result.Answer, result.Input, result.DTMF = ConfirmationNoInput, "", false
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-c.ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w while waiting for confirmation", ErrConnectionClosed)
+		}
+	}
+	return result, nil
+}
+
+// classifyConfirmation matches an answer against the keypad digits or the locale's grammar
+func classifyConfirmation(result *ConfirmationResult, opts *ConfirmOptions) ConfirmationAnswer {
+	switch {
+	case result.DTMF && result.Input == opts.YesDigit:
+		return ConfirmationYes
+	case result.DTMF && result.Input == opts.NoDigit:
+		return ConfirmationNo
+	case result.DTMF:
+		return ConfirmationNoMatch
+	case opts.Locale.IsYes(result.Input):
+		return ConfirmationYes
+	case opts.Locale.IsNo(result.Input):
+		return ConfirmationNo
+	}
+	return ConfirmationNoMatch
+}
@@ -0,0 +1,86 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// answeringServer replies to each TTS prompt with the next of the given events
+func answeringServer(t *testing.T, answers ...map[string]interface{}) *Client {
+	t.Helper()
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd["command"] != "tts" || len(answers) == 0 {
+				continue
+			}
+			answer := answers[0]
+			answers = answers[1:]
+			if answer != nil {
+				conn.WriteJSON(answer)
+			}
+		}
+	})
+	return NewClient(server.URL)
+}
+
+func TestConfirmRetriesUntilUnderstood(t *testing.T) {
+	client := answeringServer(t,
+		map[string]interface{}{"event": "asrFinal", "text": "yesterday maybe"},
+		nil,
+		map[string]interface{}{"event": "asrFinal", "text": "Yes, please."},
+	)
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Confirm(context.Background(), "Shall I book it?", &ConfirmOptions{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if !result.Confirmed() || result.Attempts != 3 || result.DTMF {
+		t.Errorf("Expected spoken yes on the third attempt, got %+v", result)
+	}
+}
+
+func TestConfirmDTMF(t *testing.T) {
+	client := answeringServer(t, map[string]interface{}{"event": "dtmf", "digit": "2"})
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Confirm(context.Background(), "¿Confirma la cita?", &ConfirmOptions{Locale: SpanishLocale})
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if result.Answer != ConfirmationNo || !result.DTMF || result.Input != "2" {
+		t.Errorf("Expected keypad no, got %+v", result)
+	}
+}
+
+func TestConfirmNoInput(t *testing.T) {
+	client := answeringServer(t)
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Confirm(context.Background(), "Are you there?", &ConfirmOptions{Retries: -1, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	if result.Answer != ConfirmationNoInput || result.Attempts != 1 {
+		t.Errorf("Expected no input after a single attempt, got %+v", result)
+	}
+}
@@ -1,16 +1,35 @@
 package rustpbx
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"net/url"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 )
 
+// ErrEventTooLarge is returned for event frames exceeding the maximum event size
+var ErrEventTooLarge = errors.New("event too large")
+
+// ErrMessageTooLarge is returned when a message exceeds the inbound or outbound message size limit
+var ErrMessageTooLarge = errors.New("message too large")
+
+// defaultMaxEventSize bounds event frames when ConnectionOptions.MaxEventSize is zero
+const defaultMaxEventSize = 1 << 20
+
+// Default message size limits; audio frames and SDPs are far smaller
+const (
+	defaultMaxInboundMessage  = 4 << 20
+	defaultMaxOutboundMessage = 1 << 20
+)
+
 // Connection represents a WebSocket connection to RustPBX
 type Connection struct {
 	conn         *websocket.Conn
@@ -20,96 +39,420 @@ type Connection struct {
 	mu           sync.RWMutex
 	closed       bool
 	done         chan struct{}
+	callContext  *CallContext
+	budget       *budgetTracker
+	screening    *ScreeningPolicy
+	emergency    *EmergencyPolicy
+	audioHandler func(frame []byte)
+	profiler     *LatencyProfiler
+	dispositions DispositionTaxonomy
+	disposition  *Disposition
+	sensitive    chan string
+	enricher     *IdentityEnricher
+	admission    *AdmissionController
+	// admissionRelease frees the call's admission slot; admissionCancel stops a queued wait
+	admissionRelease func()
+	admissionCancel  context.CancelFunc
+	admissionEnded   bool
+	degradation      *degradationTracker
+	faults           *FaultInjector
+	maxEventSize     int
+	quarantine       func(frame []byte, err error)
+	maxInbound       int64
+	maxOutbound      int
+	// wsURL and reconnect are used to re-dial the session when the connection drops
+	wsURL     string
+	reconnect *ReconnectPolicy
+	sanitizer *TextSanitizer
+	keepalive *keepalive
+	flags     *FeatureFlags
+	config    *CallConfig
+	// handlers are the typed event handlers set with OnIncoming, OnASRFinal and the like
+	handlers map[string]EventHandler
+	// subscriptions are the handlers added with AddEventHandler, in order
+	subscriptions []*Subscription
+	// store holds the call's values, created on first use with Store
+	store          *SessionStore
+	sessionBackend SessionBackend
+	// client supplies the credentials and TLS configuration of handshakes, including reconnects
+	client *Client
+	// memory remembers the caller; rememberedProfile is the profile as last saved for the call
+	memory            *CallerMemory
+	remembered        bool
+	rememberedProfile *CallerProfile
+	// sends counts the commands being sent and hungUp records the hangup event, for CloseGracefully
+	sends  pendingSends
+	hungUp bool
+	// audioFormat is negotiated by Invite and Accept; frameHandler is set with OnAudioFrame
+	audioFormat  AudioFormat
+	frameHandler func(frame *AudioFrame)
+	// queue holds the events waiting for the workers of an EventDispatcher
+	queue *dispatchQueue
+	// commandInterceptors and eventInterceptors wrap sends and deliveries, outermost first
+	commandInterceptors []CommandInterceptor
+	eventInterceptors   []EventInterceptor
+	// logger is nil when nothing is logged, see log
+	logger *slog.Logger
+	// wireDump records the raw frames sent and received
+	wireDump *WireDump
+	// tasks are the goroutines started with Go and After
+	tasks taskGroup
+	// playbacks are the pending playbacks, oldest first; endedTrack is the track of the
+	// last interruption, whose track end is not a playback's
+	playbacks  []*Playback
+	endedTrack string
+	// validation checks commands and events against the protocol schema
+	validation ProtocolValidation
+	// state is the connection state, reported to stateHandler on transitions
+	state        ConnectionState
+	stateHandler func(old, new ConnectionState)
+	// alignSampleRates sets the sample rates of call options to the rate of their codec
+	alignSampleRates bool
+	// writes queues the commands for writeLoop, written directly when nil
+	writes *writeQueue
+	// skipOptionValidation sends call options without validating them
+	skipOptionValidation bool
+	// recording follows the call recording, located after the call with recordingOptions
+	recording        recordingTracker
+	recordingOptions *RecordingOptions
+	// dial is used again to reconnect
+	dial *DialOptions
+	// disconnectErr is why the connection dropped for good, reported to disconnectHandler
+	disconnectErr     error
+	disconnectHandler func(err error)
+	// metrics counts the commands, events and reconnects, also reported to metricsRecorder
+	metrics         connectionMetrics
+	metricsRecorder MetricsRecorder
+	// media detects one-way audio when set
+	media *mediaWatch
+	// panicHook receives the panics recovered from event handlers
+	panicHook func(*HandlerPanic)
+	// oversized is the handling of frames exceeding maxInbound
+	oversized OversizedFrames
+	// replay keeps the unacknowledged commands to write again after reconnecting
+	replay *replayBuffer
+	// setup is the invite or accept of the call in progress, ended by a connection drop
+	setup *replayEntry
+	// requireEncryption refuses calls whose media is not SRTP protected
+	requireEncryption bool
 }
 
 // NewConnection creates a new WebSocket connection
 func NewConnection(ctx context.Context, wsURL string) (*Connection, error) {
+	return newConnection(ctx, wsURL, nil, nil, nil)
+}
+
+// NewConnectionWithOptions is like NewConnection with connection options, such as the
+// dial options of the handshake
+func NewConnectionWithOptions(ctx context.Context, wsURL string, options *ConnectionOptions) (*Connection, error) {
+	return newConnection(ctx, wsURL, nil, options, nil)
+}
+
+// newConnection creates a new WebSocket connection bound to a call context. The handshakes
+// use the credentials and TLS configuration of client, if not nil.
+func newConnection(ctx context.Context, wsURL string, callContext *CallContext, options *ConnectionOptions, client *Client) (*Connection, error) {
+	if options != nil && options.Registry != nil {
+		if err := options.Registry.admit(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create a cancellable context
 	connCtx, cancel := context.WithCancel(ctx)
 
-	// Set up WebSocket dialer
-	dialer := websocket.DefaultDialer
-	dialer.HandshakeTimeout = 30 * time.Second
-
 	// Establish WebSocket connection
-	conn, _, err := dialer.DialContext(connCtx, wsURL, http.Header{})
+	logger := connectionLogger(wsURL, callContext, options, client)
+	if logger != nil {
+		logger.Debug("dialing", "url", wsURL)
+	}
+	var dial *DialOptions
+	if options != nil {
+		dial = options.Dial
+	}
+	conn, err := dialWebSocket(connCtx, wsURL, callContext, client, dial)
 	if err != nil {
+		if logger != nil {
+			logger.Error("dial failed", "url", wsURL, "error", err)
+		}
 		cancel()
-		return nil, fmt.Errorf("failed to dial WebSocket: %w", err)
+		return nil, err
 	}
 
 	connection := &Connection{
-		conn:   conn,
-		ctx:    connCtx,
-		cancel: cancel,
-		done:   make(chan struct{}),
+		conn:         conn,
+		ctx:          connCtx,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		callContext:  callContext,
+		wsURL:        wsURL,
+		client:       client,
+		maxEventSize: defaultMaxEventSize,
+		maxInbound:   defaultMaxInboundMessage,
+		maxOutbound:  defaultMaxOutboundMessage,
+		logger:       logger,
+	}
+	connection.log().Info("connected", "url", wsURL)
+	if options != nil && options.Budget != nil {
+		connection.budget = newBudgetTracker(*options.Budget)
+	}
+	if options != nil {
+		connection.screening = options.Screening
+		connection.emergency = options.Emergency
+		connection.profiler = options.Profiler
+		connection.dispositions = options.Dispositions
+		connection.enricher = options.Enricher
+		connection.memory = options.Memory
+		connection.admission = options.Admission
+		if options.Degradation != nil {
+			connection.degradation = newDegradationTracker(*options.Degradation)
+		}
+		if options.MaxEventSize != 0 {
+			connection.maxEventSize = options.MaxEventSize
+		}
+		connection.quarantine = options.Quarantine
+		if options.MaxInboundMessage != 0 {
+			connection.maxInbound = int64(options.MaxInboundMessage)
+		}
+		connection.oversized = options.OversizedFrames
+		if options.MaxOutboundMessage != 0 {
+			connection.maxOutbound = options.MaxOutboundMessage
+		}
+		connection.sanitizer = options.TextSanitizer
+		connection.flags = options.Flags
+		connection.sessionBackend = options.SessionBackend
+		connection.wireDump = options.WireDump
+		connection.validation = options.Validation
+		connection.alignSampleRates = options.AlignSampleRates
+		connection.skipOptionValidation = options.SkipOptionValidation
+		connection.requireEncryption = options.RequireEncryption
+		connection.recordingOptions = options.Recording
+		connection.dial = options.Dial
+		connection.metricsRecorder = options.Metrics
+		if options.WriteQueue != nil {
+			connection.writes = newWriteQueue(*options.WriteQueue)
+		}
+		if options.Dispatcher != nil {
+			connection.queue = options.Dispatcher.newQueue(connection)
+		}
+		if options.Config != nil {
+			connection.config = options.Config.Current()
+		}
+		if options.Keepalive != nil {
+			connection.keepalive = newKeepalive(*options.Keepalive)
+		}
+		if options.MediaWatch != nil {
+			connection.media = newMediaWatch(*options.MediaWatch)
+		}
+		if options.Reconnect != nil {
+			policy := options.Reconnect.withDefaults()
+			connection.reconnect = &policy
+			if policy.Replay != nil {
+				connection.replay = newReplayBuffer(*policy.Replay)
+			}
+		}
+		if options.Faults != nil {
+			connection.faults = options.Faults
+			connection.faults.attach(connection)
+		}
+	}
+
+	connection.prepareConn(conn)
+	connection.state = ConnectionConnected
+	if options != nil && options.Registry != nil && callContext != nil {
+		options.Registry.register(connection)
 	}
 
 	// Start reading messages in a goroutine
 	go connection.readLoop()
+	if connection.writes != nil {
+		go connection.writeLoop()
+	}
+	if connection.keepalive != nil {
+		go connection.keepaliveLoop()
+	}
+	if connection.media != nil {
+		go connection.mediaWatchLoop()
+	}
 
 	return connection, nil
 }
 
-// OnEvent sets the event handler function
+// dialWebSocket establishes the WebSocket connection of a session
+func dialWebSocket(ctx context.Context, wsURL string, callContext *CallContext, client *Client, dial *DialOptions) (*websocket.Conn, error) {
+	dialer := dial.dialer()
+
+	header := callContext.Headers()
+	if client != nil {
+		if err := client.authorize(ctx, header); err != nil {
+			return nil, err
+		}
+		if client.options.TLS != nil {
+			dialer.TLSClientConfig = client.options.TLS.Clone()
+		}
+	}
+	conn, _, err := dialer.DialContext(ctx, wsURL, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial WebSocket: %w", err)
+	}
+	return conn, nil
+}
+
+// prepareConn applies the message size limit and liveness tracking to a dialed WebSocket
+func (c *Connection) prepareConn(conn *websocket.Conn) {
+	if c.maxInbound > 0 && c.oversized != OversizedSkip {
+		conn.SetReadLimit(c.maxInbound)
+	}
+	if c.keepalive != nil {
+		c.keepalive.seen()
+		conn.SetPongHandler(func(string) error {
+			c.keepalive.seen()
+			return nil
+		})
+	}
+}
+
+// sessionIDFromURL extracts the session ID query parameter from a WebSocket URL
+func sessionIDFromURL(wsURL string) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("id")
+}
+
+// OnEvent sets the event handler function, replacing the previous one. Use AddEventHandler
+// to add handlers alongside it.
 func (c *Connection) OnEvent(handler EventHandler) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.eventHandler = handler
 }
 
-// Close closes the WebSocket connection
+// CallContext returns the call context of the connection
+func (c *Connection) CallContext() *CallContext {
+	return c.callContext
+}
+
+// Close closes the WebSocket connection. If the server closed it first, it returns the
+// reason, as Err does.
 func (c *Connection) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.closed {
+		c.mu.Unlock()
 		return nil
 	}
 
 	c.closed = true
 	c.cancel()
+	c.log().Debug("closing")
+
+	conn := c.conn
+	select {
+	case <-c.done:
+		// The server closed the connection first
+		err := c.disconnectErr
+		c.mu.Unlock()
+		c.setState(ConnectionClosed)
+		conn.Close()
+		return err
+	default:
+	}
 
 	// Send close message
-	err := c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	// Release the lock so the read loop can observe the close and exit
+	c.mu.Unlock()
+	c.setState(ConnectionClosed)
 	if err != nil {
 		// If we can't send close message, just close the connection
-		c.conn.Close()
+		conn.Close()
 		return err
 	}
 
 	// Wait for close or timeout
 	select {
 	case <-c.done:
-		return c.conn.Close()
+		return conn.Close()
 	case <-time.After(5 * time.Second):
-		return c.conn.Close()
+		return conn.Close()
 	}
 }
 
 // readLoop continuously reads messages from the WebSocket
 func (c *Connection) readLoop() {
+	defer c.notifyDisconnect()
 	defer close(c.done)
+	defer c.setState(ConnectionClosed)
+	defer c.releaseAdmission()
+	defer func() {
+		c.mu.Lock()
+		c.abortPlaybacksLocked()
+		c.mu.Unlock()
+	}()
+	if c.faults != nil {
+		defer c.faults.detach(c)
+	}
 
 	for {
 		select {
 		case <-c.ctx.Done():
+			if !c.isClosed() {
+				c.setDisconnectErr(c.ctx.Err())
+			}
 			return
 		default:
-			// Set read deadline
-			c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			// Set read deadline, unless the keepalive watches the connection
+			if c.keepalive == nil {
+				c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			}
 
-			messageType, data, err := c.conn.ReadMessage()
+			messageType, data, err := c.readFrame()
 			if err != nil {
+				normal := websocket.IsCloseError(err, websocket.CloseNormalClosure)
 				if !c.isClosed() {
+					err = c.serverClosed(err)
+				}
+				if c.keepalive != nil && c.keepalive.dead.Swap(false) {
+					err = fmt.Errorf("%w: %w", ErrConnectionDead, err)
+				}
+				if errors.Is(err, websocket.ErrReadLimit) {
+					err = fmt.Errorf("%w: inbound message exceeds %d bytes", ErrMessageTooLarge, c.maxInbound)
+					c.setDisconnectErr(err)
+					c.handleError(err)
+					c.rejectFrame(messageType, 0, false)
+				} else if c.shouldReconnect(err) {
+					if c.reconnectSession(err) {
+						continue
+					}
+				} else if !c.isClosed() {
 					// Connection closed unexpectedly
-					c.handleError(fmt.Errorf("WebSocket read error: %w", err))
+					c.log().Error("read failed", "error", err)
+					err = fmt.Errorf("WebSocket read error: %w", err)
+					if !normal {
+						c.setDisconnectErr(err)
+					}
+					c.handleError(err)
 				}
 				return
 			}
+			if c.keepalive != nil {
+				c.keepalive.seen()
+			}
+			if !c.sensitiveFrame(messageType, data) {
+				c.dumpFrame(WireInbound, messageType, data)
+			}
 
-			if messageType == websocket.TextMessage {
-				c.handleMessage(data)
+			copies := 1
+			if c.faults != nil {
+				copies = c.faults.inbound(messageType, data)
+			}
+			for i := 0; i < copies; i++ {
+				switch messageType {
+				case websocket.TextMessage:
+					c.handleMessage(data)
+				case websocket.BinaryMessage:
+					c.handleAudio(data)
+				}
 			}
 		}
 	}
@@ -117,35 +460,165 @@ func (c *Connection) readLoop() {
 
 // handleMessage processes incoming WebSocket messages
 func (c *Connection) handleMessage(data []byte) {
+	sensitive := c.sensitiveFrame(websocket.TextMessage, data)
+	event, err := decodeEvent(data, c.maxEventSize)
+	if err != nil {
+		if c.quarantine != nil && !sensitive {
+			c.quarantine(data, err)
+		}
+		c.log().Warn("dropped malformed event", "error", err, "size", len(data))
+		c.handleError(fmt.Errorf("failed to parse event: %w", err))
+		return
+	}
+	if c.validation != ValidationOff {
+		if err := ValidateEvent(data); err != nil {
+			c.reportProtocolViolation(err)
+			if c.validation == ValidationStrict {
+				if c.quarantine != nil {
+					c.quarantine(data, err)
+				}
+				return
+			}
+		}
+	}
+	if !sensitive {
+		c.log().Debug("received event", "event", event.Event)
+	}
+	c.countEvent(event)
+	if event.Event == "hangup" && c.reconnect != nil {
+		c.endSetup()
+	}
+	if c.replay != nil {
+		c.replay.acknowledge()
+	}
+	if c.observeEvent(event) {
+		if c.queue != nil {
+			// Stop reading while the handlers are too far behind
+			c.queue.waitForSpace()
+		}
+		c.dispatch(event)
+	}
+}
+
+// decodeEvent parses an event frame. Invalid UTF-8 is replaced rather than rejected, and
+// fields of an unexpected type are left empty as long as the event type can be read.
+func decodeEvent(data []byte, maxSize int) (*Event, error) {
+	if maxSize > 0 && len(data) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrEventTooLarge, len(data), maxSize)
+	}
+	if !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte("\uFFFD"))
+	}
+
 	var event Event
 	if err := json.Unmarshal(data, &event); err != nil {
-		c.handleError(fmt.Errorf("failed to parse event: %w", err))
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) || event.Event == "" {
+			return nil, err
+		}
+	}
+	if event.Event == "" {
+		return nil, fmt.Errorf("missing event type")
+	}
+	return &event, nil
+}
+
+// dispatch delivers an event to the handlers, on the workers of the EventDispatcher if any
+func (c *Connection) dispatch(event *Event) {
+	event.Context = c.callContext
+	if c.queue != nil {
+		c.queue.push(event)
 		return
 	}
+	c.deliver(event)
+}
+
+// deliver passes an event through the event interceptors to the handlers
+func (c *Connection) deliver(event *Event) {
+	// The handlers recover on their own; this recovers the interceptors
+	c.callHandler(c.eventReceiver(), event)
+}
 
+// deliverToHandlers calls the OnEvent handler, the added handlers and the typed handler,
+// in that order
+func (c *Connection) deliverToHandlers(event *Event) {
 	c.mu.RLock()
 	handler := c.eventHandler
+	subscriptions := c.subscriptions
+	typedHandler := c.handlers[event.Event]
 	c.mu.RUnlock()
 
 	if handler != nil {
-		handler(&event)
+		c.callHandler(handler, event)
+	}
+	for _, sub := range subscriptions {
+		c.callHandler(sub.handler, event)
+	}
+	if typedHandler != nil {
+		c.callHandler(typedHandler, event)
 	}
 }
 
 // handleError handles connection errors
 func (c *Connection) handleError(err error) {
-	c.mu.RLock()
-	handler := c.eventHandler
-	c.mu.RUnlock()
+	c.dispatch(&Event{
+		Event:     "error",
+		Timestamp: time.Now().UnixMilli(),
+		Error:     err.Error(),
+	})
+}
 
-	if handler != nil {
-		errorEvent := &Event{
-			Event:     "error",
-			Timestamp: time.Now().UnixMilli(),
-			Error:     err.Error(),
+// observeEvent updates connection state from an incoming event before it is dispatched.
+// It returns false if the event should not be delivered to the handler.
+func (c *Connection) observeEvent(event *Event) bool {
+	if c.profiler != nil {
+		c.profiler.Observe(event)
+	}
+	c.observeMediaEvent(event)
+
+	switch event.Event {
+	case "incoming":
+		if !c.refuseUnencrypted(event) {
+			return false
+		}
+		if c.screening.slow() || c.enricher != nil || c.memory != nil {
+			// The lookups may take seconds, so they do not hold up the read loop
+			go func() {
+				if c.screenIncoming(event) && c.prepareIncoming(event) {
+					c.dispatch(event)
+				}
+			}()
+			return false
 		}
-		handler(errorEvent)
+		return c.screenIncoming(event) && c.prepareIncoming(event)
+	case "answer":
+		if !c.refuseUnencrypted(event) {
+			return false
+		}
+		if addresses := ParseMediaAddresses(event.SDP); len(addresses) > 0 {
+			c.log().Debug("media addresses", "addresses", addresses)
+		}
+		c.startRecordingBudget()
+		c.observeRecording(event)
+	case "hangup":
+		c.stopRecordingBudget()
+		c.observeRecording(event)
+		c.releaseAdmission()
+		c.rememberOnHangup()
+		c.mu.Lock()
+		c.hungUp = true
+		c.mu.Unlock()
+		c.cancelTasks()
+		c.observePlayback(event)
+	case "trackStart", "trackEnd", "interruption":
+		c.observePlayback(event)
+	case "dtmf", "asrDelta", "asrFinal":
+		return c.captureSensitive(event)
+	case "error":
+		c.observeProviderError(event)
+		c.observeRecording(event)
 	}
+	return true
 }
 
 // isClosed checks if the connection is closed
@@ -157,70 +630,193 @@ func (c *Connection) isClosed() bool {
 
 // sendCommand sends a command to the WebSocket
 func (c *Connection) sendCommand(command interface{}) error {
+	return c.sendCommandContext(context.Background(), command)
+}
+
+// sendCommandContext sends a command to the WebSocket, bounded by ctx
+func (c *Connection) sendCommandContext(ctx context.Context, command interface{}) error {
+	c.sends.begin()
+	defer c.sends.end()
 	if c.isClosed() {
-		return fmt.Errorf("connection is closed")
+		return ErrConnectionClosed
 	}
 
-	data, err := json.Marshal(command)
-	if err != nil {
-		return fmt.Errorf("failed to marshal command: %w", err)
-	}
+	return c.commandSender()(ctx, command)
+}
+
+// writeMessage writes a single WebSocket message
+func (c *Connection) writeMessage(messageType int, data []byte) error {
+	return c.writeMessageContext(context.Background(), messageType, data)
+}
 
+// writeMessageContext writes a single WebSocket message. The write is bounded by the deadline
+// of ctx, or 10s if it has none. Cancelling ctx during the write drops the connection, since
+// a partially written frame cannot be recovered.
+func (c *Connection) writeMessageContext(ctx context.Context, messageType int, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return classifyError(err)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.closed {
-		return fmt.Errorf("connection is closed")
+		return ErrConnectionClosed
+	}
+	if c.maxOutbound > 0 && len(data) > c.maxOutbound {
+		return fmt.Errorf("%w: outbound message of %d bytes exceeds %d", ErrMessageTooLarge, len(data), c.maxOutbound)
+	}
+	if c.faults != nil && c.faults.outbound(messageType) {
+		return nil
 	}
 
-	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	err = c.conn.WriteMessage(websocket.TextMessage, data)
-	if err != nil {
-		return fmt.Errorf("failed to send command: %w", err)
+	if err := ctx.Err(); err != nil {
+		// Cancelled while waiting for another write
+		return classifyError(err)
 	}
 
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	conn := c.conn
+	conn.SetWriteDeadline(deadline)
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(interrupted)
+		conn.UnderlyingConn().SetWriteDeadline(time.Now())
+	})
+	defer func() {
+		if !stop() {
+			// Let the interruption finish before the next write sets its own deadline
+			<-interrupted
+		}
+	}()
+
+	if err := conn.WriteMessage(messageType, data); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return classifyError(fmt.Errorf("%w: %v", ctxErr, err))
+		}
+		if hasDeadline && !time.Now().Before(deadline) {
+			// The write timed out just before ctx noticed its deadline
+			return classifyError(fmt.Errorf("%w: %v", context.DeadlineExceeded, err))
+		}
+		return classifyError(err)
+	}
+	c.dumpFrame(WireOutbound, messageType, data)
 	return nil
 }
 
 // Invite sends an invite command to initiate a call
 func (c *Connection) Invite(option *CallOption) error {
+	return c.InviteContext(context.Background(), option)
+}
+
+// InviteContext is like Invite but bounded by ctx
+func (c *Connection) InviteContext(ctx context.Context, option *CallOption) error {
+	if option != nil && option.Callee != "" {
+		callee, err := c.checkEmergencyTarget(option.Callee)
+		if err != nil {
+			return err
+		}
+		if callee != option.Callee {
+			routed := *option
+			routed.Callee = callee
+			option = &routed
+		}
+	}
+
+	option = c.applyConfig(option)
+	if err := c.validateCallOption(option, true); err != nil {
+		return err
+	}
+	if err := c.checkOfferEncryption(option); err != nil {
+		return err
+	}
+	option = c.checkAudio(option)
+	option = applyAddressFamily(option)
+	c.trackRecording(option)
+	c.requestRecording(option)
+	c.negotiateAudio(option)
 	cmd := InviteCommand{
 		Command: "invite",
 		Option:  option,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Accept sends an accept command to accept an incoming call
 func (c *Connection) Accept(option *CallOption) error {
+	return c.AcceptContext(context.Background(), option)
+}
+
+// AcceptContext is like Accept but bounded by ctx
+func (c *Connection) AcceptContext(ctx context.Context, option *CallOption) error {
+	option = c.applyConfig(option)
+	if err := c.validateCallOption(option, false); err != nil {
+		return err
+	}
+	if err := c.checkOfferEncryption(option); err != nil {
+		return err
+	}
+	option = c.checkAudio(option)
+	option = applyAddressFamily(option)
+	c.trackRecording(option)
+	c.requestRecording(option)
+	c.negotiateAudio(option)
 	cmd := AcceptCommand{
 		Command: "accept",
 		Option:  option,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Reject sends a reject command to reject an incoming call
 func (c *Connection) Reject(reason string, code int) error {
+	return c.RejectContext(context.Background(), reason, code)
+}
+
+// RejectContext is like Reject but bounded by ctx
+func (c *Connection) RejectContext(ctx context.Context, reason string, code int) error {
 	cmd := RejectCommand{
 		Command: "reject",
 		Reason:  reason,
 		Code:    code,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Candidate sends ICE candidates for WebRTC negotiation
 func (c *Connection) Candidate(candidates []string) error {
+	return c.CandidateContext(context.Background(), candidates)
+}
+
+// CandidateContext is like Candidate but bounded by ctx
+func (c *Connection) CandidateContext(ctx context.Context, candidates []string) error {
 	cmd := CandidateCommand{
 		Command:    "candidate",
 		Candidates: candidates,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // TTS sends a text-to-speech command
 func (c *Connection) TTS(text, speaker, playID string, options *TTSOptions) error {
+	return c.TTSContext(context.Background(), text, speaker, playID, options)
+}
+
+// TTSContext is like TTS but bounded by ctx
+func (c *Connection) TTSContext(ctx context.Context, text, speaker, playID string, options *TTSOptions) error {
+	if c.sanitizer != nil {
+		text = c.sanitizer.Sanitize(text)
+		if text == "" && (options == nil || !options.Streaming) {
+			// Nothing left to speak
+			return nil
+		}
+	}
+	if err := c.chargeTTS(text); err != nil {
+		return err
+	}
+
 	cmd := TTSCommand{
 		Command: "tts",
 		Text:    text,
@@ -234,7 +830,21 @@ func (c *Connection) TTS(text, speaker, playID string, options *TTSOptions) erro
 		cmd.EndOfStream = options.EndOfStream
 	}
 
-	return c.sendCommand(cmd)
+	if !cmd.Streaming {
+		segments := []string{text}
+		if c.sanitizer != nil {
+			segments = c.sanitizer.Split(text)
+		}
+		if len(segments) > 1 {
+			return c.sendTTSChunks(ctx, cmd, segments)
+		}
+		if c.maxOutbound > 0 {
+			if data, err := json.Marshal(cmd); err == nil && len(data) > c.maxOutbound {
+				return c.sendTTSChunks(ctx, cmd, segments)
+			}
+		}
+	}
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // TTSSimple sends a simple text-to-speech command with default options
@@ -244,40 +854,66 @@ func (c *Connection) TTSSimple(text string) error {
 
 // Play sends a play command to play audio from URL
 func (c *Connection) Play(url string, autoHangup bool) error {
+	return c.PlayContext(context.Background(), url, autoHangup)
+}
+
+// PlayContext is like Play but bounded by ctx
+func (c *Connection) PlayContext(ctx context.Context, url string, autoHangup bool) error {
 	cmd := PlayCommand{
 		Command:    "play",
 		URL:        url,
 		AutoHangup: autoHangup,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Interrupt sends an interrupt command to stop current audio playback
 func (c *Connection) Interrupt() error {
+	return c.InterruptContext(context.Background())
+}
+
+// InterruptContext is like Interrupt but bounded by ctx
+func (c *Connection) InterruptContext(ctx context.Context) error {
+	c.interruptPlaybacks()
 	cmd := Command{Command: "interrupt"}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Pause sends a pause command to pause audio playback
 func (c *Connection) Pause() error {
+	return c.PauseContext(context.Background())
+}
+
+// PauseContext is like Pause but bounded by ctx
+func (c *Connection) PauseContext(ctx context.Context) error {
 	cmd := Command{Command: "pause"}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Resume sends a resume command to resume audio playback
 func (c *Connection) Resume() error {
+	return c.ResumeContext(context.Background())
+}
+
+// ResumeContext is like Resume but bounded by ctx
+func (c *Connection) ResumeContext(ctx context.Context) error {
 	cmd := Command{Command: "resume"}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Hangup sends a hangup command to terminate the call
 func (c *Connection) Hangup(reason, initiator string) error {
+	return c.HangupContext(context.Background(), reason, initiator)
+}
+
+// HangupContext is like Hangup but bounded by ctx
+func (c *Connection) HangupContext(ctx context.Context, reason, initiator string) error {
 	cmd := HangupCommand{
 		Command:   "hangup",
 		Reason:    reason,
 		Initiator: initiator,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // HangupSimple sends a simple hangup command with default values
@@ -287,83 +923,109 @@ func (c *Connection) HangupSimple() error {
 
 // Refer sends a refer command to transfer the call
 func (c *Connection) Refer(target string, options *ReferOption) error {
+	return c.ReferContext(context.Background(), target, options)
+}
+
+// ReferContext is like Refer but bounded by ctx
+func (c *Connection) ReferContext(ctx context.Context, target string, options *ReferOption) error {
+	target, err := c.checkEmergencyTarget(target)
+	if err != nil {
+		return err
+	}
+
 	cmd := ReferCommand{
 		Command: "refer",
 		Target:  target,
 		Options: options,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Mute sends a mute command to mute an audio track
 func (c *Connection) Mute(trackID string) error {
+	return c.MuteContext(context.Background(), trackID)
+}
+
+// MuteContext is like Mute but bounded by ctx
+func (c *Connection) MuteContext(ctx context.Context, trackID string) error {
 	cmd := MuteCommand{
 		Command: "mute",
 		TrackID: trackID,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // Unmute sends an unmute command to unmute an audio track
 func (c *Connection) Unmute(trackID string) error {
+	return c.UnmuteContext(context.Background(), trackID)
+}
+
+// UnmuteContext is like Unmute but bounded by ctx
+func (c *Connection) UnmuteContext(ctx context.Context, trackID string) error {
 	cmd := UnmuteCommand{
 		Command: "unmute",
 		TrackID: trackID,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // History sends a history command to add conversation context
 func (c *Connection) History(speaker, text string) error {
+	return c.HistoryContext(context.Background(), speaker, text)
+}
+
+// HistoryContext is like History but bounded by ctx
+func (c *Connection) HistoryContext(ctx context.Context, speaker, text string) error {
 	cmd := HistoryCommand{
 		Command: "history",
 		Speaker: speaker,
 		Text:    text,
 	}
-	return c.sendCommand(cmd)
+	return c.sendCommandContext(ctx, cmd)
 }
 
 // SendRawCommand sends a raw command as a JSON object
 func (c *Connection) SendRawCommand(command map[string]interface{}) error {
-	return c.sendCommand(command)
+	return c.SendRawCommandContext(context.Background(), command)
 }
 
-// WaitForEvent waits for a specific event type with timeout
-func (c *Connection) WaitForEvent(eventType string, timeout time.Duration) (*Event, error) {
-	eventChan := make(chan *Event, 1)
-	var originalHandler EventHandler
+// SendRawCommandContext is like SendRawCommand but bounded by ctx
+func (c *Connection) SendRawCommandContext(ctx context.Context, command map[string]interface{}) error {
+	return c.sendCommandContext(ctx, command)
+}
 
-	// Set up temporary event handler
-	c.mu.Lock()
-	originalHandler = c.eventHandler
-	c.eventHandler = func(event *Event) {
-		if event.Event == eventType {
+// subscribe delivers the events matching filter to the returned channel until the
+// returned function is called
+func (c *Connection) subscribe(filter func(*Event) bool) (<-chan *Event, func()) {
+	events := make(chan *Event, 16)
+
+	sub := c.AddEventHandler(func(event *Event) {
+		if filter(event) {
 			select {
-			case eventChan <- event:
+			case events <- event:
 			default:
 			}
 		}
-		// Also call original handler if it exists
-		if originalHandler != nil {
-			originalHandler(event)
-		}
+	})
+	return events, func() {
+		c.RemoveEventHandler(sub)
 	}
-	c.mu.Unlock()
+}
 
-	// Restore original handler when done
-	defer func() {
-		c.mu.Lock()
-		c.eventHandler = originalHandler
-		c.mu.Unlock()
-	}()
+// WaitForEvent waits for a specific event type with timeout
+func (c *Connection) WaitForEvent(eventType string, timeout time.Duration) (*Event, error) {
+	eventChan, unsubscribe := c.subscribe(func(event *Event) bool {
+		return event.Event == eventType
+	})
+	defer unsubscribe()
 
 	// Wait for event or timeout
 	select {
 	case event := <-eventChan:
 		return event, nil
 	case <-time.After(timeout):
-		return nil, fmt.Errorf("timeout waiting for event: %s", eventType)
+		return nil, fmt.Errorf("%w waiting for event: %s", ErrTimeout, eventType)
 	case <-c.ctx.Done():
-		return nil, fmt.Errorf("connection closed while waiting for event: %s", eventType)
+		return nil, fmt.Errorf("%w while waiting for event: %s", ErrConnectionClosed, eventType)
 	}
 }
\ No newline at end of file
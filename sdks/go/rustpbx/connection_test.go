@@ -0,0 +1,167 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer starts a WebSocket server that forwards received commands to a channel
+func newTestServer(t *testing.T, onConnect func(conn *websocket.Conn)) (*httptest.Server, <-chan map[string]interface{}) {
+	t.Helper()
+	commands := make(chan map[string]interface{}, 16)
+	server := newCommandServer(t, func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{}) {
+		if onConnect != nil {
+			onConnect(conn)
+		}
+		return func(cmd map[string]interface{}) {
+			if cmd != nil {
+				commands <- cmd
+			}
+		}
+	})
+	return server, commands
+}
+
+// newCommandServer starts a WebSocket server that passes each command received on a
+// connection to the handler newHandler makes for the connection, and each binary frame
+// as a nil command
+func newCommandServer(t *testing.T, newHandler func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{})) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle := newHandler(r, conn)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var cmd map[string]interface{}
+			if messageType == websocket.TextMessage {
+				if err := json.Unmarshal(data, &cmd); err != nil {
+					return
+				}
+			}
+			handle(cmd)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDecodeEvent(t *testing.T) {
+	event, err := decodeEvent([]byte("{\"event\":\"asrFinal\",\"timestamp\":\"soon\",\"text\":\"caf\xe9\"}"), 0)
+	if err != nil {
+		t.Fatalf("Expected tolerant decoding, got %v", err)
+	}
+	if event.Event != "asrFinal" || event.Timestamp != 0 || event.Text != "caf�" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	if _, err := decodeEvent([]byte(`{"event":"answer","padding":"xxxxxxxx"}`), 16); !errors.Is(err, ErrEventTooLarge) {
+		t.Errorf("Expected ErrEventTooLarge, got %v", err)
+	}
+	for _, frame := range []string{`{"text":"no type"}`, `{"event":42}`, `{"event":"answer"`, `null`} {
+		if _, err := decodeEvent([]byte(frame), 0); err == nil {
+			t.Errorf("Expected error for %s", frame)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"event":`))
+		conn.WriteJSON(Event{Event: "answer"})
+	})
+
+	quarantined := make(chan string, 1)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Quarantine: func(frame []byte, err error) { quarantined <- string(frame) },
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 2)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	if frame := <-quarantined; frame != `{"event":` {
+		t.Errorf("Expected the corrupted frame to be quarantined, got %q", frame)
+	}
+	if event := <-events; event.Event != "error" {
+		t.Errorf("Expected error event, got '%s'", event.Event)
+	}
+	if event := <-events; event.Event != "answer" {
+		t.Errorf("Expected the next event to be delivered, got '%s'", event.Event)
+	}
+}
+
+func FuzzDecodeEvent(f *testing.F) {
+	f.Add([]byte(`{"event":"answer","timestamp":1}`))
+	f.Add([]byte(`{"event":"dtmf","digit":"1","data":{"a":[1,2]}}`))
+	f.Add([]byte("{\"event\":\"asrFinal\",\"text\":\"\xff\xfe\"}"))
+	f.Add([]byte(`{"event":"hangup","code":"486"}`))
+	f.Add([]byte(`[`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		event, err := decodeEvent(data, 4096)
+		if err == nil && (event == nil || event.Event == "") {
+			t.Errorf("Expected an event with a type for %q", data)
+		}
+		if err == nil && !utf8.ValidString(event.Text) {
+			t.Errorf("Expected valid UTF-8 text for %q", data)
+		}
+	})
+}
+
+func TestCommandContext(t *testing.T) {
+	stalled := make(chan struct{})
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		// Stop reading so the client's writes back up
+		<-stalled
+	})
+	defer close(stalled)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := conn.HangupContext(cancelled, "normal_clearing", "caller"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	text := strings.Repeat("x", 512<<10)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		if err = conn.TTSContext(ctx, text, "", "", nil); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded once the writes back up, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the deadline to bound the write, took %v", elapsed)
+	}
+}
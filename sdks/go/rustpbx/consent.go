@@ -0,0 +1,220 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConsentMode is how the recording consent of a participant is obtained
+type ConsentMode string
+
+const (
+	// ConsentBlind plays the disclaimer; staying on the call implies consent
+	ConsentBlind ConsentMode = "blind"
+	// ConsentSupervised asks every participant to consent by keypad or voice before recording
+	ConsentSupervised ConsentMode = "supervised"
+)
+
+// Consent methods of a ConsentRecord
+const (
+	ConsentImplicit = "implicit"
+	ConsentDTMF     = "dtmf"
+	ConsentSpeech   = "speech"
+	// ConsentNone means the participant gave no usable answer
+	ConsentNone = "none"
+)
+
+// ConsentRecord is the consent artifact of a participant, delivered with a
+// "recordingConsent" event on the participant's leg for the application to keep
+type ConsentRecord struct {
+	Participant string      `json:"participant"`
+	Mode        ConsentMode `json:"mode"`
+	Granted     bool        `json:"granted"`
+	// Method is how consent was given or refused: implicit, dtmf, speech or none
+	Method string `json:"method"`
+	// Input is the digit or transcript of the answer
+	Input      string `json:"input,omitempty"`
+	Disclaimer string `json:"disclaimer"`
+	Timestamp  int64  `json:"timestamp"`
+	// Error is why no answer was obtained, e.g. the participant hung up
+	Error string `json:"error,omitempty"`
+}
+
+// ConsentParticipant is a participant of a recorded conference, on its own leg
+type ConsentParticipant struct {
+	Name string
+	Conn *Connection
+}
+
+// ConsentOptions represents recording consent configuration
+type ConsentOptions struct {
+	// Mode defaults to ConsentBlind
+	Mode ConsentMode
+	// Locale provides the default prompts and the yes/no grammar; EnglishLocale when nil
+	Locale *LocaleBundle
+	// Disclaimer is spoken to every participant; the locale's "recording_disclaimer" prompt when empty
+	Disclaimer string
+	// Question asks for consent in supervised mode; the locale's "recording_consent" prompt when empty
+	Question string
+	// Confirm configures the answers in supervised mode, such as the consent digits and timeout
+	Confirm *ConfirmOptions
+	// HangupOnRefusal hangs up the participants who refuse, so the others can be recorded
+	HangupOnRefusal bool
+}
+
+// ConsentResult is the outcome of a consent flow
+type ConsentResult struct {
+	// Records are the consent records, in the order of the participants
+	Records []ConsentRecord
+	// Consented is set when every participant remaining in the call consented
+	Consented bool
+}
+
+// ErrConsentRefused is returned by RequestRecordingConsent when a participant refused
+// consent but stays on a leg that is being recorded
+var ErrConsentRefused = errors.New("recording consent refused on a recorded leg")
+
+// RequestRecordingConsent obtains the recording consent of the participants of a call or
+// conference: the disclaimer is played to all of them at once and, in supervised mode,
+// each is asked to consent. Every participant's consent is delivered as a
+// "recordingConsent" event on its leg.
+//
+// RustPBX records a leg from its answer when its call option has a recorder, and cannot
+// pause or start the recording later. Obtain consent before setting up the recorded
+// legs, or set HangupOnRefusal so that refusing participants leave the recording; a
+// participant refusing on a recorded leg otherwise fails with ErrConsentRefused.
+func RequestRecordingConsent(ctx context.Context, participants []ConsentParticipant, options *ConsentOptions) (*ConsentResult, error) {
+	opts := ConsentOptions{}
+	if options != nil {
+		opts = *options
+	}
+	if opts.Mode == "" {
+		opts.Mode = ConsentBlind
+	}
+	if opts.Mode != ConsentBlind && opts.Mode != ConsentSupervised {
+		return nil, fmt.Errorf("unknown consent mode %q", opts.Mode)
+	}
+	if opts.Locale == nil {
+		opts.Locale = EnglishLocale
+	}
+	if opts.Disclaimer == "" {
+		opts.Disclaimer = opts.Locale.Prompt("recording_disclaimer", nil)
+	}
+	if opts.Question == "" {
+		opts.Question = opts.Locale.Prompt("recording_consent", nil)
+	}
+
+	result := &ConsentResult{Records: make([]ConsentRecord, len(participants))}
+	var wg sync.WaitGroup
+	for i, p := range participants {
+		wg.Add(1)
+		go func(i int, p ConsentParticipant) {
+			defer wg.Done()
+			result.Records[i] = askConsent(ctx, p, &opts)
+		}(i, p)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	granted, refused := 0, false
+	for i, p := range participants {
+		record := &result.Records[i]
+		if record.Error != "" {
+			// The leg is gone or broken, e.g. the participant hung up during the disclaimer
+			continue
+		}
+		p.Conn.recordConsent(record)
+		switch {
+		case record.Granted:
+			granted++
+		case opts.HangupOnRefusal:
+			if err := p.Conn.HangupContext(ctx, "recording_consent_refused", "system"); err != nil {
+				errs = append(errs, fmt.Errorf("failed to hang up %s: %w", p.Name, err))
+			}
+		default:
+			refused = true
+			if p.Conn.recorded() {
+				errs = append(errs, fmt.Errorf("%s: %w", p.Name, ErrConsentRefused))
+			}
+		}
+	}
+	result.Consented = granted > 0 && !refused
+	return result, errors.Join(errs...)
+}
+
+// RequestRecordingConsent obtains the recording consent of the caller, like the
+// RequestRecordingConsent function for a single participant named "caller"
+func (c *Connection) RequestRecordingConsent(ctx context.Context, options *ConsentOptions) (*ConsentRecord, error) {
+	result, err := RequestRecordingConsent(ctx, []ConsentParticipant{{Name: "caller", Conn: c}}, options)
+	if result == nil {
+		return nil, err
+	}
+	return &result.Records[0], err
+}
+
+// askConsent plays the disclaimer to a participant and, in supervised mode, waits for the answer
+func askConsent(ctx context.Context, p ConsentParticipant, opts *ConsentOptions) ConsentRecord {
+	record := ConsentRecord{
+		Participant: p.Name,
+		Mode:        opts.Mode,
+		Disclaimer:  opts.Disclaimer,
+		Method:      ConsentNone,
+	}
+	if opts.Mode == ConsentBlind {
+		if err := p.Conn.TTS(opts.Disclaimer, "", "", nil); err != nil {
+			record.Error = err.Error()
+		} else {
+			record.Granted, record.Method = true, ConsentImplicit
+		}
+		record.Timestamp = time.Now().UnixMilli()
+		return record
+	}
+
+	confirm := &ConfirmOptions{}
+	if opts.Confirm != nil {
+		*confirm = *opts.Confirm
+	}
+	if confirm.Locale == nil {
+		confirm.Locale = opts.Locale
+	}
+	answer, err := p.Conn.Confirm(ctx, opts.Disclaimer+" "+opts.Question, confirm)
+	record.Timestamp = time.Now().UnixMilli()
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	record.Input = answer.Input
+	if answer.Answer == ConfirmationYes || answer.Answer == ConfirmationNo {
+		record.Granted = answer.Confirmed()
+		record.Method = ConsentSpeech
+		if answer.DTMF {
+			record.Method = ConsentDTMF
+		}
+	}
+	return record
+}
+
+// recordConsent delivers the consent record of the leg's participant with a
+// "recordingConsent" event
+func (c *Connection) recordConsent(record *ConsentRecord) {
+	data, _ := json.Marshal(record)
+	c.dispatch(&Event{
+		Event:     "recordingConsent",
+		Timestamp: record.Timestamp,
+		Data:      data,
+	})
+}
+
+// recorded reports whether the call option of the leg asked the server to record it
+func (c *Connection) recorded() bool {
+	c.recording.mu.Lock()
+	defer c.recording.mu.Unlock()
+	return c.recording.requested
+}
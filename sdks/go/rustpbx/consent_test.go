@@ -0,0 +1,144 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// consentLeg connects a leg answering the consent prompt with answer, or not at all when
+// nil, and returns the names of the commands it received
+func consentLeg(t *testing.T, answer map[string]interface{}) (*Connection, <-chan map[string]interface{}) {
+	t.Helper()
+	commands := make(chan map[string]interface{}, 16)
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			commands <- cmd
+			if cmd["command"] == "tts" && answer != nil {
+				conn.WriteJSON(answer)
+			}
+		}
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, commands
+}
+
+// expectCommands checks the commands received by a leg, in order
+func expectCommands(t *testing.T, name string, commands <-chan map[string]interface{}, expected ...string) []map[string]interface{} {
+	t.Helper()
+	var received []map[string]interface{}
+	for _, want := range expected {
+		select {
+		case cmd := <-commands:
+			if cmd["command"] != want {
+				t.Fatalf("%s: expected %s, got %v", name, want, cmd)
+			}
+			received = append(received, cmd)
+		case <-time.After(time.Second):
+			t.Fatalf("%s: expected %s", name, want)
+		}
+	}
+	return received
+}
+
+// consentEvents collects the records of the recordingConsent events of a leg
+func consentEvents(conn *Connection) <-chan ConsentRecord {
+	records := make(chan ConsentRecord, 4)
+	conn.AddEventHandler(func(event *Event) {
+		if event.Event != "recordingConsent" {
+			return
+		}
+		var record ConsentRecord
+		json.Unmarshal(event.Data, &record)
+		records <- record
+	})
+	return records
+}
+
+func TestRecordingConsentBlind(t *testing.T) {
+	conn, commands := consentLeg(t, nil)
+	records := consentEvents(conn)
+	record, err := conn.RequestRecordingConsent(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RequestRecordingConsent failed: %v", err)
+	}
+	if !record.Granted || record.Method != ConsentImplicit || record.Mode != ConsentBlind {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	received := expectCommands(t, "caller", commands, "tts")
+	if received[0]["text"] != EnglishLocale.Prompt("recording_disclaimer", nil) {
+		t.Errorf("Expected the disclaimer, got %v", received[0]["text"])
+	}
+	if event := <-records; event.Participant != "caller" || !event.Granted || event.Method != ConsentImplicit {
+		t.Errorf("Unexpected recordingConsent event %+v", event)
+	}
+}
+
+func TestRecordingConsentSupervised(t *testing.T) {
+	agent, agentCommands := consentLeg(t, map[string]interface{}{"event": "dtmf", "digit": "1"})
+	caller, callerCommands := consentLeg(t, map[string]interface{}{"event": "asrFinal", "text": "No"})
+
+	result, err := RequestRecordingConsent(context.Background(), []ConsentParticipant{
+		{Name: "agent", Conn: agent},
+		{Name: "caller", Conn: caller},
+	}, &ConsentOptions{Mode: ConsentSupervised, HangupOnRefusal: true})
+	if err != nil {
+		t.Fatalf("RequestRecordingConsent failed: %v", err)
+	}
+	if !result.Consented {
+		t.Error("Expected consent of the agent remaining in the call")
+	}
+	if r := result.Records[0]; !r.Granted || r.Method != ConsentDTMF || r.Input != "1" {
+		t.Errorf("Unexpected agent record %+v", r)
+	}
+	if r := result.Records[1]; r.Granted || r.Method != ConsentSpeech || r.Input != "No" {
+		t.Errorf("Unexpected caller record %+v", r)
+	}
+
+	expectCommands(t, "agent", agentCommands, "tts")
+	received := expectCommands(t, "caller", callerCommands, "tts", "hangup")
+	if !strings.Contains(received[0]["text"].(string), "Do you agree to be recorded?") {
+		t.Errorf("Expected the consent question, got %v", received[0]["text"])
+	}
+	if received[1]["reason"] != "recording_consent_refused" {
+		t.Errorf("Unexpected hangup %v", received[1])
+	}
+}
+
+func TestRecordingConsentRefusedOnRecordedLeg(t *testing.T) {
+	conn, commands := consentLeg(t, nil)
+	if err := conn.Invite(&CallOption{Recorder: &RecorderOption{}}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	expectCommands(t, "caller", commands, "invite")
+
+	record, err := conn.RequestRecordingConsent(context.Background(), &ConsentOptions{
+		Mode:    ConsentSupervised,
+		Confirm: &ConfirmOptions{Retries: -1, Timeout: 50 * time.Millisecond},
+	})
+	if !errors.Is(err, ErrConsentRefused) {
+		t.Fatalf("Expected ErrConsentRefused, got %v", err)
+	}
+	if record.Granted || record.Method != ConsentNone {
+		t.Errorf("Expected no consent, got %+v", record)
+	}
+	expectCommands(t, "caller", commands, "tts")
+	select {
+	case cmd := <-commands:
+		t.Errorf("Expected the leg to be left alone, got %v", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,88 @@
+package rustpbx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Headers used to propagate the call context to RustPBX and upstream services
+const (
+	HeaderCallID         = "X-Call-ID"
+	HeaderSessionID      = "X-Session-ID"
+	HeaderTenant         = "X-Tenant-ID"
+	HeaderMetadataPrefix = "X-Call-Meta-"
+)
+
+// CallContext carries the identifiers that correlate a call across systems
+type CallContext struct {
+	CallID    string
+	SessionID string
+	Tenant    string
+	Metadata  map[string]string
+	// Identity is set on incoming calls when an IdentityEnricher is configured
+	Identity *CallerIdentity
+	// Priority is assigned to incoming calls from screening and enrichment
+	Priority Priority
+	// Profile is set on incoming calls when a CallerMemory is configured
+	Profile *CallerProfile
+}
+
+// newCallContext builds the call context for a session
+func newCallContext(sessionID string, options *ConnectionOptions) *CallContext {
+	cc := &CallContext{
+		// RustPBX uses the session ID as the call ID
+		CallID:    sessionID,
+		SessionID: sessionID,
+		Tenant:    options.Tenant,
+	}
+	if len(options.Metadata) > 0 {
+		cc.Metadata = make(map[string]string, len(options.Metadata))
+		for k, v := range options.Metadata {
+			cc.Metadata[k] = v
+		}
+	}
+	return cc
+}
+
+// Headers returns the call context encoded as HTTP headers
+func (cc *CallContext) Headers() http.Header {
+	header := http.Header{}
+	cc.applyHeaders(header)
+	return header
+}
+
+// applyHeaders sets the call context headers on an existing header set
+func (cc *CallContext) applyHeaders(header http.Header) {
+	if cc == nil {
+		return
+	}
+	if cc.CallID != "" {
+		header.Set(HeaderCallID, cc.CallID)
+	}
+	if cc.SessionID != "" {
+		header.Set(HeaderSessionID, cc.SessionID)
+	}
+	if cc.Tenant != "" {
+		header.Set(HeaderTenant, cc.Tenant)
+	}
+	if cc.Priority != PriorityNormal {
+		header.Set(HeaderPriority, cc.Priority.String())
+	}
+	for k, v := range cc.Metadata {
+		header.Set(HeaderMetadataPrefix+k, v)
+	}
+}
+
+type callContextKey struct{}
+
+// WithCallContext returns a copy of ctx carrying the call context.
+// Client requests made with the returned context include the call context headers.
+func WithCallContext(ctx context.Context, cc *CallContext) context.Context {
+	return context.WithValue(ctx, callContextKey{}, cc)
+}
+
+// CallContextFromContext returns the call context stored in ctx, if any
+func CallContextFromContext(ctx context.Context) (*CallContext, bool) {
+	cc, ok := ctx.Value(callContextKey{}).(*CallContext)
+	return cc, ok && cc != nil
+}
@@ -0,0 +1,45 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCallContextHeaders(t *testing.T) {
+	cc := newCallContext("session-1", &ConnectionOptions{
+		Tenant:   "acme",
+		Metadata: map[string]string{"Campaign": "spring"},
+	})
+
+	header := cc.Headers()
+	if header.Get(HeaderCallID) != "session-1" {
+		t.Errorf("Expected call ID header to be 'session-1', got '%s'", header.Get(HeaderCallID))
+	}
+	if header.Get(HeaderTenant) != "acme" {
+		t.Errorf("Expected tenant header to be 'acme', got '%s'", header.Get(HeaderTenant))
+	}
+	if header.Get(HeaderMetadataPrefix+"Campaign") != "spring" {
+		t.Errorf("Expected metadata header to be 'spring', got '%s'", header.Get(HeaderMetadataPrefix+"Campaign"))
+	}
+}
+
+func TestRequestCarriesCallContext(t *testing.T) {
+	client := NewClient("http://localhost:8080")
+	cc := &CallContext{CallID: "call-1", SessionID: "call-1"}
+
+	req, err := client.newRequest(WithCallContext(context.Background(), cc), "GET", "http://localhost:8080/call/lists", nil)
+	if err != nil {
+		t.Fatalf("newRequest failed: %v", err)
+	}
+	if req.Header.Get(HeaderCallID) != "call-1" {
+		t.Errorf("Expected call ID header to be 'call-1', got '%s'", req.Header.Get(HeaderCallID))
+	}
+
+	req, err = client.newRequest(context.Background(), "GET", "http://localhost:8080/call/lists", nil)
+	if err != nil {
+		t.Fatalf("newRequest failed: %v", err)
+	}
+	if req.Header.Get(HeaderCallID) != "" {
+		t.Errorf("Expected no call ID header, got '%s'", req.Header.Get(HeaderCallID))
+	}
+}
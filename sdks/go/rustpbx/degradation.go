@@ -0,0 +1,215 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderKind identifies the speech provider an error came from
+type ProviderKind string
+
+const (
+	ProviderASR ProviderKind = "asr"
+	ProviderTTS ProviderKind = "tts"
+)
+
+// ErrDegradationUnavailable is reported when a degradation action cannot be carried out
+var ErrDegradationUnavailable = errors.New("degradation unavailable")
+
+// DegradationAction is a step taken when a speech provider keeps failing
+type DegradationAction string
+
+const (
+	// DegradeBackup would switch the call to a backup provider. RustPBX cannot change the
+	// providers of a live session, so it is reported with ErrDegradationUnavailable and
+	// the next action is taken instead.
+	DegradeBackup DegradationAction = "backup"
+	// DegradeDTMFOnly marks the call as keypad-only so flows stop relying on speech
+	DegradeDTMFOnly DegradationAction = "dtmf_only"
+	// DegradeHuman transfers the call to a human
+	DegradeHuman DegradationAction = "human"
+)
+
+// DegradationPolicy represents graceful degradation configuration
+type DegradationPolicy struct {
+	// Threshold is the number of provider errors within Window that triggers the next action; 2 when zero
+	Threshold int
+	// Window is the error counting window; 30s when zero
+	Window time.Duration
+	// ASRActions and TTSActions are taken in order each time the provider keeps failing.
+	// When empty they default to DTMF-only for ASR, then the human target, if any.
+	ASRActions []DegradationAction
+	TTSActions []DegradationAction
+	// HumanTarget is the transfer target of DegradeHuman
+	HumanTarget string
+	Refer       *ReferOption
+}
+
+// actions returns the degradation steps of a provider
+func (p *DegradationPolicy) actions(kind ProviderKind) []DegradationAction {
+	configured := p.ASRActions
+	if kind == ProviderTTS {
+		configured = p.TTSActions
+	}
+	if len(configured) > 0 {
+		return configured
+	}
+
+	var actions []DegradationAction
+	// Keypad menus need prompts, so they only help when recognition fails
+	if kind == ProviderASR {
+		actions = append(actions, DegradeDTMFOnly)
+	}
+	if p.HumanTarget != "" {
+		actions = append(actions, DegradeHuman)
+	}
+	return actions
+}
+
+// degradationTracker counts provider errors of a connection
+type degradationTracker struct {
+	policy DegradationPolicy
+	mu     sync.Mutex
+	errors map[ProviderKind][]time.Time
+	level  map[ProviderKind]int
+	active map[ProviderKind]DegradationAction
+}
+
+// newDegradationTracker creates a tracker with the policy defaults applied
+func newDegradationTracker(policy DegradationPolicy) *degradationTracker {
+	if policy.Threshold <= 0 {
+		policy.Threshold = 2
+	}
+	if policy.Window <= 0 {
+		policy.Window = 30 * time.Second
+	}
+	return &degradationTracker{
+		policy: policy,
+		errors: make(map[ProviderKind][]time.Time),
+		level:  make(map[ProviderKind]int),
+		active: make(map[ProviderKind]DegradationAction),
+	}
+}
+
+// providerKind classifies the sender of an error event, such as "tencent_cloud_asr" or "tts.tencent"
+func providerKind(sender string) (ProviderKind, bool) {
+	sender = strings.ToLower(sender)
+	switch {
+	case strings.Contains(sender, "asr"), strings.Contains(sender, "transcription"):
+		return ProviderASR, true
+	case strings.Contains(sender, "tts"), strings.Contains(sender, "synthesis"):
+		return ProviderTTS, true
+	}
+	return "", false
+}
+
+// record counts a provider error and returns the action it triggers, if any
+func (t *degradationTracker) record(kind ProviderKind, at time.Time) (DegradationAction, int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	recent := t.errors[kind][:0]
+	for _, e := range t.errors[kind] {
+		if at.Sub(e) < t.policy.Window {
+			recent = append(recent, e)
+		}
+	}
+	recent = append(recent, at)
+	t.errors[kind] = recent
+	if len(recent) < t.policy.Threshold {
+		return "", 0, false
+	}
+	// Give the new configuration a fresh window
+	t.errors[kind] = nil
+	return t.escalate(kind)
+}
+
+// escalate moves a provider to its next action and returns it, if any. The caller holds t.mu.
+func (t *degradationTracker) escalate(kind ProviderKind) (DegradationAction, int, bool) {
+	actions := t.policy.actions(kind)
+	level := t.level[kind]
+	if level >= len(actions) {
+		return "", 0, false
+	}
+	t.level[kind] = level + 1
+	t.active[kind] = actions[level]
+	return actions[level], level + 1, true
+}
+
+// skip abandons an action that could not be carried out and returns the next one, if any
+func (t *degradationTracker) skip(kind ProviderKind) (DegradationAction, int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, kind)
+	return t.escalate(kind)
+}
+
+// Degradation returns the degradation action in effect for a provider, or "" if it is healthy
+func (c *Connection) Degradation(kind ProviderKind) DegradationAction {
+	if c.degradation == nil {
+		return ""
+	}
+	c.degradation.mu.Lock()
+	defer c.degradation.mu.Unlock()
+	return c.degradation.active[kind]
+}
+
+// DTMFOnly reports whether speech recognition was degraded to keypad input
+func (c *Connection) DTMFOnly() bool {
+	return c.Degradation(ProviderASR) == DegradeDTMFOnly
+}
+
+// observeProviderError degrades the call when a speech provider keeps failing
+func (c *Connection) observeProviderError(event *Event) {
+	if c.degradation == nil {
+		return
+	}
+	kind, ok := providerKind(event.Sender)
+	if !ok {
+		return
+	}
+	action, level, ok := c.degradation.record(kind, time.Now())
+	for ok && action == DegradeBackup {
+		c.handleError(fmt.Errorf("failed to degrade %s to a backup provider: %w", kind, ErrDegradationUnavailable))
+		action, level, ok = c.degradation.skip(kind)
+	}
+	if !ok {
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"provider": kind,
+		"action":   action,
+		"level":    level,
+		"sender":   event.Sender,
+	})
+	c.dispatch(&Event{
+		Event:     "degradationActivated",
+		Timestamp: time.Now().UnixMilli(),
+		Sender:    event.Sender,
+		Error:     event.Error,
+		Data:      data,
+	})
+
+	if err := c.applyDegradation(kind, action); err != nil {
+		c.handleError(fmt.Errorf("failed to degrade %s: %w", kind, err))
+	}
+}
+
+// applyDegradation carries out a degradation action
+func (c *Connection) applyDegradation(kind ProviderKind, action DegradationAction) error {
+	policy := &c.degradation.policy
+	switch action {
+	case DegradeHuman:
+		if policy.HumanTarget == "" {
+			return fmt.Errorf("no human target configured")
+		}
+		return c.Refer(policy.HumanTarget, policy.Refer)
+	}
+	// DTMF-only is applied by the flows checking DTMFOnly
+	return nil
+}
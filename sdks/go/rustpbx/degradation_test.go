@@ -0,0 +1,97 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestProviderKind(t *testing.T) {
+	tests := map[string]ProviderKind{"tencent_cloud_asr": ProviderASR, "tts.tencent": ProviderTTS}
+	for sender, expected := range tests {
+		if kind, ok := providerKind(sender); !ok || kind != expected {
+			t.Errorf("providerKind(%s) = %s, expected %s", sender, kind, expected)
+		}
+	}
+	if _, ok := providerKind("file"); ok {
+		t.Error("Expected file track errors not to be provider errors")
+	}
+}
+
+func TestDegradationDefaults(t *testing.T) {
+	policy := &DegradationPolicy{HumanTarget: "sip:agent@pbx"}
+	asr := policy.actions(ProviderASR)
+	if len(asr) != 2 || asr[0] != DegradeDTMFOnly || asr[1] != DegradeHuman {
+		t.Errorf("Unexpected ASR actions: %v", asr)
+	}
+	if tts := policy.actions(ProviderTTS); len(tts) != 1 || tts[0] != DegradeHuman {
+		t.Errorf("Unexpected TTS actions: %v", tts)
+	}
+}
+
+func TestDegradationEscalates(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		for i := 0; i < 6; i++ {
+			conn.WriteJSON(Event{Event: "error", Sender: "tencent_cloud_asr", Error: "connection reset"})
+		}
+		// Unrelated errors are ignored
+		conn.WriteJSON(Event{Event: "error", Sender: "file", Error: "not found"})
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Degradation: &DegradationPolicy{
+			ASRActions:  []DegradationAction{DegradeBackup, DegradeDTMFOnly, DegradeHuman},
+			HumanTarget: "sip:agent@pbx",
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	activated := make(chan *Event, 4)
+	unavailable := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) {
+		switch {
+		case event.Event == "degradationActivated":
+			activated <- event
+		case event.Event == "error" && strings.Contains(event.Error, ErrDegradationUnavailable.Error()):
+			unavailable <- event
+		}
+	})
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	var actions []string
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-activated:
+			var data map[string]interface{}
+			json.Unmarshal(event.Data, &data)
+			actions = append(actions, data["action"].(string))
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 degradations, got %v", actions)
+		}
+	}
+	// The backup provider is unavailable, so the first errors already degrade to DTMF-only
+	if actions[0] != "dtmf_only" || actions[1] != "human" {
+		t.Errorf("Expected dtmf_only then human, got %v", actions)
+	}
+	select {
+	case <-unavailable:
+	case <-time.After(time.Second):
+		t.Error("Expected the backup provider to be reported unavailable")
+	}
+
+	if refer := <-commands; refer["command"] != "refer" || refer["target"] != "sip:agent@pbx" {
+		t.Errorf("Expected refer to the human target, got %v", refer)
+	}
+	if conn.Degradation(ProviderASR) != DegradeHuman || conn.Degradation(ProviderTTS) != "" {
+		t.Error("Unexpected degradation state")
+	}
+}
@@ -0,0 +1,387 @@
+package rustpbx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CandidateType is the type of an ICE candidate
+type CandidateType string
+
+const (
+	// CandidateHost is an address of a local interface
+	CandidateHost CandidateType = "host"
+	// CandidateServerReflexive is the public address a STUN server sees, behind NAT
+	CandidateServerReflexive CandidateType = "srflx"
+	// CandidateRelay is an address allocated on a TURN server
+	CandidateRelay CandidateType = "relay"
+)
+
+// diagnoseTimeout bounds the check of a single ICE server URL
+const diagnoseTimeout = 5 * time.Second
+
+// ICEServerCheck is the result of checking an ICE server URL
+type ICEServerCheck struct {
+	URL string
+	// Candidate is the type of candidate obtained: srflx from STUN, relay from TURN
+	Candidate CandidateType
+	// Address is the server-reflexive address for STUN and the relayed address for TURN
+	Address string
+	// MappedAddress is the server-reflexive address a TURN server reports with the allocation
+	MappedAddress string
+	// RTT is the round trip time of the last request
+	RTT time.Duration
+	// Err is why the server could not be used; nil when a candidate was obtained
+	Err error
+}
+
+// ConnectivityReport is the result of DiagnoseConnectivity
+type ConnectivityReport struct {
+	// HostAddresses are the addresses of the local interfaces, without loopback and link-local ones
+	HostAddresses []string
+	Servers       []ICEServerCheck
+}
+
+// Has reports whether a candidate of a type was obtained
+func (r *ConnectivityReport) Has(candidate CandidateType) bool {
+	for _, c := range r.CandidateTypes() {
+		if c == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// CandidateTypes returns the candidate types obtained, in the order host, srflx, relay.
+// Without srflx the media only works on the same network; without relay it fails behind
+// symmetric NATs and firewalls blocking UDP.
+func (r *ConnectivityReport) CandidateTypes() []CandidateType {
+	found := map[CandidateType]bool{CandidateHost: len(r.HostAddresses) > 0}
+	for _, s := range r.Servers {
+		if s.Err != nil {
+			continue
+		}
+		found[s.Candidate] = true
+		if s.MappedAddress != "" {
+			found[CandidateServerReflexive] = true
+		}
+	}
+	var types []CandidateType
+	for _, c := range []CandidateType{CandidateHost, CandidateServerReflexive, CandidateRelay} {
+		if found[c] {
+			types = append(types, c)
+		}
+	}
+	return types
+}
+
+// DiagnoseConnectivity checks the reachability of the ICE servers configured on the server
+// and reports the candidate types they provide, to triage WebRTC setup failures
+func (c *Client) DiagnoseConnectivity(ctx context.Context) (*ConnectivityReport, error) {
+	servers, err := c.GetICEServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ICE servers: %w", err)
+	}
+	return DiagnoseICEServers(ctx, servers), nil
+}
+
+// DiagnoseICEServers checks every URL of the ICE servers concurrently: STUN URLs with a
+// binding request, TURN URLs with an allocation that is released right away
+func DiagnoseICEServers(ctx context.Context, servers []ICEServer) *ConnectivityReport {
+	report := &ConnectivityReport{HostAddresses: hostAddresses()}
+	type check struct {
+		url    string
+		server ICEServer
+	}
+	var checks []check
+	for _, server := range servers {
+		for _, url := range server.URLs {
+			checks = append(checks, check{url: url, server: server})
+		}
+	}
+
+	report.Servers = make([]ICEServerCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+			defer cancel()
+			report.Servers[i] = checkICEServer(checkCtx, c.url, c.server)
+		}(i, c)
+	}
+	wg.Wait()
+	return report
+}
+
+// hostAddresses returns the addresses of the local interfaces usable as host candidates
+func hostAddresses() []string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var hosts []string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		hosts = append(hosts, ipNet.IP.String())
+	}
+	return hosts
+}
+
+// iceURL is a parsed STUN or TURN URL (RFC 7064, RFC 7065)
+type iceURL struct {
+	turn      bool
+	secure    bool
+	host      string
+	port      int
+	transport string
+}
+
+// parseICEURL parses scheme:host[:port][?transport=udp|tcp]
+func parseICEURL(raw string) (*iceURL, error) {
+	scheme, rest, ok := strings.Cut(raw, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid ICE server URL %q", raw)
+	}
+	u := &iceURL{transport: "udp", port: 3478}
+	switch strings.ToLower(scheme) {
+	case "stun":
+	case "stuns":
+		u.secure = true
+	case "turn":
+		u.turn = true
+	case "turns":
+		u.turn, u.secure = true, true
+	default:
+		return nil, fmt.Errorf("unsupported ICE server scheme %q", scheme)
+	}
+	if u.secure {
+		u.transport, u.port = "tcp", 5349
+	}
+
+	hostPort, query, _ := strings.Cut(rest, "?")
+	if query != "" {
+		transport, ok := strings.CutPrefix(query, "transport=")
+		if !ok || (transport != "udp" && transport != "tcp") {
+			return nil, fmt.Errorf("invalid ICE server URL query %q", query)
+		}
+		u.transport = transport
+	}
+	if u.secure && u.transport == "udp" {
+		return nil, fmt.Errorf("DTLS transport of %q is not supported", raw)
+	}
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		u.host = host
+		if u.port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid port in ICE server URL %q", raw)
+		}
+	} else {
+		u.host = strings.Trim(hostPort, "[]")
+	}
+	if u.host == "" {
+		return nil, fmt.Errorf("missing host in ICE server URL %q", raw)
+	}
+	return u, nil
+}
+
+// checkICEServer checks a single ICE server URL
+func checkICEServer(ctx context.Context, raw string, server ICEServer) ICEServerCheck {
+	result := ICEServerCheck{URL: raw, Candidate: CandidateServerReflexive}
+	u, err := parseICEURL(raw)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if u.turn {
+		result.Candidate = CandidateRelay
+		if server.Username == nil || server.Credential == nil {
+			result.Err = errors.New("TURN server has no username or credential")
+			return result
+		}
+	}
+
+	t, err := dialSTUN(ctx, u)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to reach %s: %w", raw, err)
+		return result
+	}
+	defer t.conn.Close()
+
+	if !u.turn {
+		start := time.Now()
+		response, err := t.roundTrip(ctx, newSTUNRequest(stunBindingRequest), nil)
+		result.RTT = time.Since(start)
+		if err != nil {
+			result.Err = fmt.Errorf("binding request failed: %w", err)
+			return result
+		}
+		mapped, ok := response.address(stunAttrXORMappedAddress)
+		if !ok {
+			mapped, ok = response.address(stunAttrMappedAddress)
+		}
+		if response.typ != stunBindingSuccess || !ok {
+			result.Err = fmt.Errorf("binding request failed: unexpected response %#04x", response.typ)
+			return result
+		}
+		result.Address = mapped.String()
+		return result
+	}
+
+	relayed, mapped, rtt, err := t.allocate(ctx, *server.Username, *server.Credential)
+	result.RTT = rtt
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Address = relayed.String()
+	if mapped != nil {
+		result.MappedAddress = mapped.String()
+	}
+	return result
+}
+
+// stunTransport exchanges STUN messages with a server
+type stunTransport struct {
+	conn net.Conn
+	// stream is set for TCP and TLS, where requests are not retransmitted
+	stream bool
+}
+
+func dialSTUN(ctx context.Context, u *iceURL) (*stunTransport, error) {
+	address := net.JoinHostPort(u.host, strconv.Itoa(u.port))
+	var conn net.Conn
+	var err error
+	if u.secure {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.host}}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, u.transport, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stunTransport{conn: conn, stream: u.transport == "tcp"}, nil
+}
+
+// roundTrip sends a request and waits for its response until ctx is done. Over UDP the
+// request is retransmitted with a doubling timeout from 500ms.
+func (t *stunTransport) roundTrip(ctx context.Context, request *stunMessage, key []byte) (*stunMessage, error) {
+	data := request.encode(key)
+	deadline, _ := ctx.Deadline()
+	timeout := 500 * time.Millisecond
+	buf := make([]byte, 1500)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return nil, context.DeadlineExceeded
+		}
+		if _, err := t.conn.Write(data); err != nil {
+			return nil, err
+		}
+		attempt := time.Now().Add(timeout)
+		if t.stream || (!deadline.IsZero() && deadline.Before(attempt)) {
+			attempt = deadline
+		}
+		t.conn.SetDeadline(attempt)
+		timeout *= 2
+
+		for {
+			var response *stunMessage
+			var err error
+			if t.stream {
+				response, err = readSTUN(t.conn)
+			} else {
+				var n int
+				if n, err = t.conn.Read(buf); err == nil {
+					response, err = decodeSTUN(buf[:n])
+				}
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && !t.stream {
+				break
+			}
+			if errors.Is(err, errNotSTUN) || (err == nil && response.transaction != request.transaction) {
+				continue
+			}
+			return response, err
+		}
+	}
+}
+
+// allocate requests a relayed address with the long-term credentials, then releases it.
+// It returns the relayed and the server-reflexive address.
+func (t *stunTransport) allocate(ctx context.Context, username, password string) (relayed, mapped *net.UDPAddr, rtt time.Duration, err error) {
+	var key []byte
+	var realm, nonce []byte
+	for attempt := 0; attempt < 3; attempt++ {
+		request := newSTUNRequest(stunAllocateRequest)
+		// Relay over UDP, as media does
+		request.add(stunAttrRequestedTransport, []byte{17, 0, 0, 0})
+		if key != nil {
+			request.add(stunAttrUsername, []byte(username))
+			request.add(stunAttrRealm, realm)
+			request.add(stunAttrNonce, nonce)
+		}
+		start := time.Now()
+		response, err := t.roundTrip(ctx, request, key)
+		rtt = time.Since(start)
+		if err != nil {
+			return nil, nil, rtt, fmt.Errorf("allocate request failed: %w", err)
+		}
+
+		switch response.typ {
+		case stunAllocateSuccess:
+			relayed, ok := response.address(stunAttrXORRelayedAddress)
+			if !ok {
+				return nil, nil, rtt, errors.New("allocate response has no relayed address")
+			}
+			mapped, _ := response.address(stunAttrXORMappedAddress)
+			t.release(ctx, username, realm, nonce, key)
+			return relayed, mapped, rtt, nil
+		case stunAllocateError:
+			code, reason := response.errorCode()
+			// 401 asks for the credentials, 438 for a fresh nonce
+			if (code == 401 && key == nil) || code == 438 {
+				realm, _ = response.get(stunAttrRealm)
+				nonce, _ = response.get(stunAttrNonce)
+				key = turnKey(username, string(realm), password)
+				continue
+			}
+			if code == 401 {
+				return nil, nil, rtt, fmt.Errorf("TURN credentials rejected: %d %s", code, reason)
+			}
+			return nil, nil, rtt, fmt.Errorf("allocate request failed: %d %s", code, reason)
+		default:
+			return nil, nil, rtt, fmt.Errorf("allocate request failed: unexpected response %#04x", response.typ)
+		}
+	}
+	return nil, nil, rtt, errors.New("allocate request failed: nonce kept going stale")
+}
+
+// release deletes an allocation with a zero lifetime refresh, without waiting long for the answer
+func (t *stunTransport) release(ctx context.Context, username string, realm, nonce, key []byte) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	request := newSTUNRequest(stunRefreshRequest)
+	request.add(stunAttrLifetime, []byte{0, 0, 0, 0})
+	if key != nil {
+		request.add(stunAttrUsername, []byte(username))
+		request.add(stunAttrRealm, realm)
+		request.add(stunAttrNonce, nonce)
+	}
+	t.roundTrip(ctx, request, key)
+}
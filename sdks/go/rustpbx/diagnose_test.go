@@ -0,0 +1,246 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xorAddress encodes an XOR address attribute value, the inverse of address
+func (m *stunMessage) xorAddress(addr *net.UDPAddr) []byte {
+	ip := addr.IP.To4()
+	family := byte(0x01)
+	if ip == nil {
+		ip, family = addr.IP.To16(), 0x02
+	}
+	value := make([]byte, 4, 4+len(ip))
+	value[1] = family
+	binary.BigEndian.PutUint16(value[2:], uint16(addr.Port)^stunMagicCookie>>16)
+	mask := make([]byte, 16)
+	binary.BigEndian.PutUint32(mask, stunMagicCookie)
+	copy(mask[4:], m.transaction[:])
+	for i, b := range ip {
+		value = append(value, b^mask[i])
+	}
+	return value
+}
+
+// fakeTURN answers binding requests and long-term authenticated allocations
+func fakeTURN(t *testing.T, request *stunMessage, from net.Addr) *stunMessage {
+	t.Helper()
+	response := &stunMessage{transaction: request.transaction}
+	mapped := &net.UDPAddr{IP: net.ParseIP("198.51.100.7"), Port: 40000}
+	switch request.typ {
+	case stunBindingRequest:
+		response.typ = stunBindingSuccess
+		response.add(stunAttrXORMappedAddress, response.xorAddress(mapped))
+	case stunAllocateRequest, stunRefreshRequest:
+		integrity, ok := request.get(stunAttrMessageIntegrity)
+		if !ok {
+			response.typ = stunAllocateError
+			response.add(stunAttrErrorCode, append([]byte{0, 0, 4, 1}, "Unauthorized"...))
+			response.add(stunAttrRealm, []byte("example.org"))
+			response.add(stunAttrNonce, []byte("abc"))
+			return response
+		}
+		unsigned := &stunMessage{typ: request.typ, transaction: request.transaction}
+		for _, a := range request.attributes {
+			if a.typ != stunAttrMessageIntegrity {
+				unsigned.add(a.typ, a.value)
+			}
+		}
+		signed := unsigned.encode(turnKey("alice", "example.org", "secret"))
+		if !bytes.Equal(signed[len(signed)-20:], integrity) {
+			response.typ = stunAllocateError
+			response.add(stunAttrErrorCode, append([]byte{0, 0, 4, 1}, "Unauthorized"...))
+			return response
+		}
+		if request.typ == stunRefreshRequest {
+			response.typ = 0x0104
+			return response
+		}
+		response.typ = stunAllocateSuccess
+		response.add(stunAttrXORRelayedAddress, response.xorAddress(&net.UDPAddr{IP: net.ParseIP("203.0.113.5"), Port: 50000}))
+		response.add(stunAttrXORMappedAddress, response.xorAddress(mapped))
+	}
+	return response
+}
+
+func udpSTUNServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			request, err := decodeSTUN(buf[:n])
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(fakeTURN(t, request, from).encode(nil), from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func tcpSTUNServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					request, err := readSTUN(conn)
+					if err != nil {
+						return
+					}
+					conn.Write(fakeTURN(t, request, conn.RemoteAddr()).encode(nil))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSTUNEncoding(t *testing.T) {
+	request := newSTUNRequest(stunAllocateRequest)
+	request.add(stunAttrUsername, []byte("alice"))
+	data := request.encode([]byte("key"))
+	if len(data)%4 != 0 || int(binary.BigEndian.Uint16(data[2:])) != len(data)-stunHeaderSize {
+		t.Fatalf("Unexpected message length in %x", data)
+	}
+	decoded, err := decodeSTUN(data)
+	if err != nil {
+		t.Fatalf("decodeSTUN failed: %v", err)
+	}
+	if username, _ := decoded.get(stunAttrUsername); string(username) != "alice" {
+		t.Errorf("Expected username alice, got %q", username)
+	}
+	if integrity, _ := decoded.get(stunAttrMessageIntegrity); len(integrity) != 20 {
+		t.Errorf("Expected a 20 byte message integrity, got %d", len(integrity))
+	}
+
+	addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 3478}
+	decoded.add(stunAttrXORMappedAddress, decoded.xorAddress(addr))
+	if got, ok := decoded.address(stunAttrXORMappedAddress); !ok || got.String() != addr.String() {
+		t.Errorf("Expected %s, got %v", addr, got)
+	}
+	if _, err := decodeSTUN([]byte("not stun")); err != errNotSTUN {
+		t.Errorf("Expected errNotSTUN, got %v", err)
+	}
+}
+
+func TestParseICEURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		host      string
+		port      int
+		transport string
+		turn      bool
+		secure    bool
+	}{
+		{"stun:stun.example.org", "stun.example.org", 3478, "udp", false, false},
+		{"stun:[2001:db8::1]:19302", "2001:db8::1", 19302, "udp", false, false},
+		{"turn:turn.example.org:3479?transport=tcp", "turn.example.org", 3479, "tcp", true, false},
+		{"turns:turn.example.org", "turn.example.org", 5349, "tcp", true, true},
+	}
+	for _, test := range tests {
+		u, err := parseICEURL(test.url)
+		if err != nil {
+			t.Errorf("parseICEURL(%q) failed: %v", test.url, err)
+			continue
+		}
+		if u.host != test.host || u.port != test.port || u.transport != test.transport || u.turn != test.turn || u.secure != test.secure {
+			t.Errorf("parseICEURL(%q) = %+v", test.url, u)
+		}
+	}
+	for _, url := range []string{"http://example.org", "stun:", "turn:host?transport=sctp", "turns:host?transport=udp"} {
+		if _, err := parseICEURL(url); err == nil {
+			t.Errorf("Expected parseICEURL(%q) to fail", url)
+		}
+	}
+}
+
+func TestDiagnoseICEServers(t *testing.T) {
+	udp := udpSTUNServer(t)
+	tcp := tcpSTUNServer(t)
+	username, credential, wrong := "alice", "secret", "wrong"
+
+	report := DiagnoseICEServers(context.Background(), []ICEServer{
+		{URLs: []string{"stun:" + udp}},
+		{URLs: []string{"turn:" + udp, "turn:" + tcp + "?transport=tcp"}, Username: &username, Credential: &credential},
+		{URLs: []string{"turn:" + udp}, Username: &username, Credential: &wrong},
+		{URLs: []string{"turn:" + udp}},
+	})
+	if len(report.Servers) != 5 {
+		t.Fatalf("Expected 5 checks, got %d", len(report.Servers))
+	}
+	stun := report.Servers[0]
+	if stun.Err != nil || stun.Candidate != CandidateServerReflexive || stun.Address != "198.51.100.7:40000" {
+		t.Errorf("Unexpected STUN check %+v", stun)
+	}
+	for _, turn := range report.Servers[1:3] {
+		if turn.Err != nil || turn.Candidate != CandidateRelay || turn.Address != "203.0.113.5:50000" || turn.MappedAddress != "198.51.100.7:40000" {
+			t.Errorf("Unexpected TURN check %+v", turn)
+		}
+	}
+	if err := report.Servers[3].Err; err == nil || !strings.Contains(err.Error(), "credentials rejected") {
+		t.Errorf("Expected the credentials to be rejected, got %v", err)
+	}
+	if err := report.Servers[4].Err; err == nil || !strings.Contains(err.Error(), "no username") {
+		t.Errorf("Expected missing credentials, got %v", err)
+	}
+	if !report.Has(CandidateServerReflexive) || !report.Has(CandidateRelay) {
+		t.Errorf("Expected srflx and relay candidates, got %v", report.CandidateTypes())
+	}
+}
+
+func TestDiagnoseConnectivity(t *testing.T) {
+	// Nothing listens on the port of a closed socket
+	closed, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	address := closed.LocalAddr().String()
+	closed.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/iceservers" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode([]ICEServer{{URLs: []string{"stun:" + address}}})
+	}))
+	defer api.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	report, err := NewClient(api.URL).DiagnoseConnectivity(ctx)
+	if err != nil {
+		t.Fatalf("DiagnoseConnectivity failed: %v", err)
+	}
+	if len(report.Servers) != 1 || report.Servers[0].Err == nil {
+		t.Fatalf("Expected the STUN server to be unreachable, got %+v", report.Servers)
+	}
+	if report.Has(CandidateServerReflexive) || report.Has(CandidateRelay) {
+		t.Errorf("Expected no server candidates, got %v", report.CandidateTypes())
+	}
+}
@@ -0,0 +1,53 @@
+package rustpbx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DialOptions represents the WebSocket handshake configuration of a connection, used
+// again when reconnecting
+type DialOptions struct {
+	// HandshakeTimeout bounds the handshake; 30s when zero
+	HandshakeTimeout time.Duration
+	// Proxy returns the proxy for a handshake request; http.ProxyFromEnvironment when nil
+	Proxy func(*http.Request) (*url.URL, error)
+	// NetDialContext dials the TCP connection, e.g. to pin a source address; net.Dialer when nil
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// ReadBufferSize and WriteBufferSize are the I/O buffer sizes; 4096 bytes when zero
+	ReadBufferSize  int
+	WriteBufferSize int
+	// Subprotocols are requested in the handshake, in order of preference
+	Subprotocols []string
+	// EnableCompression negotiates per-message compression, trading CPU for bandwidth on
+	// event heavy calls
+	EnableCompression bool
+}
+
+// dialer returns a new dialer for the options; the shared default dialer is never modified
+func (o *DialOptions) dialer() *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+	}
+	if o == nil {
+		return dialer
+	}
+	if o.HandshakeTimeout > 0 {
+		dialer.HandshakeTimeout = o.HandshakeTimeout
+	}
+	if o.Proxy != nil {
+		dialer.Proxy = o.Proxy
+	}
+	dialer.NetDialContext = o.NetDialContext
+	dialer.ReadBufferSize = o.ReadBufferSize
+	dialer.WriteBufferSize = o.WriteBufferSize
+	dialer.Subprotocols = append([]string(nil), o.Subprotocols...)
+	dialer.EnableCompression = o.EnableCompression
+	return dialer
+}
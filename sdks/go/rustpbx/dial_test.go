@@ -0,0 +1,87 @@
+package rustpbx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDialOptions(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"rustpbx.v1"}, EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	proxied := false
+	defaultDialer := *websocket.DefaultDialer
+	conn, err := NewConnectionWithOptions(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), &ConnectionOptions{
+		Dial: &DialOptions{
+			Proxy: func(*http.Request) (*url.URL, error) {
+				proxied = true
+				return nil, nil
+			},
+			Subprotocols:      []string{"rustpbx.v2", "rustpbx.v1"},
+			EnableCompression: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if protocol := conn.conn.Subprotocol(); protocol != "rustpbx.v1" {
+		t.Errorf("Expected subprotocol 'rustpbx.v1', got '%s'", protocol)
+	}
+	if !proxied {
+		t.Error("Expected the proxy function to be consulted")
+	}
+	if websocket.DefaultDialer.HandshakeTimeout != defaultDialer.HandshakeTimeout || websocket.DefaultDialer.Subprotocols != nil {
+		t.Error("Expected the default dialer to be left unchanged")
+	}
+}
+
+func TestDialHandshakeTimeout(t *testing.T) {
+	// Accept TCP connections but never answer the handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = NewConnectionWithOptions(context.Background(), "ws://"+listener.Addr().String()+"/call", &ConnectionOptions{
+		Dial: &DialOptions{HandshakeTimeout: 100 * time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("Expected the handshake to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the handshake timeout to apply, took %v", elapsed)
+	}
+}
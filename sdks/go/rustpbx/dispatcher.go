@@ -0,0 +1,189 @@
+package rustpbx
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DispatcherOptions represents event dispatcher configuration
+type DispatcherOptions struct {
+	// Workers bounds the handlers running at once across connections; 8 when zero
+	Workers int
+	// QueueSize bounds the events queued per connection; the connection stops reading
+	// until its handlers catch up when exceeded. 256 when zero, unlimited when negative.
+	QueueSize int
+	// Backlog is the queue depth at which OnBacklog is called; half QueueSize when zero
+	Backlog int
+	// OnBacklog is called when the events queued for a connection reach Backlog, e.g. to
+	// alert on slow handlers
+	OnBacklog func(conn *Connection, depth int)
+}
+
+// EventDispatcher runs event handlers on a pool of workers rather than on the goroutine
+// reading the connection, so that a slow handler, such as one waiting for an LLM, does
+// not hold back the reading of later events. The events of a connection are delivered
+// one at a time, in order. Share one dispatcher between the connections it serves.
+type EventDispatcher struct {
+	options DispatcherOptions
+	// slots holds a token per running worker
+	slots chan struct{}
+
+	queued    atomic.Int64
+	sessions  atomic.Int64
+	delivered atomic.Uint64
+	stalls    atomic.Uint64
+	maxDepth  atomic.Int64
+}
+
+// DispatchStats reports the dispatcher counters
+type DispatchStats struct {
+	// Queued counts the events waiting for a worker and Sessions the connections they belong to
+	Queued   int
+	Sessions int
+	// Delivered counts the events handed to the handlers
+	Delivered uint64
+	// Stalls counts the times a connection stopped reading because its queue was full
+	Stalls uint64
+	// MaxDepth is the deepest queue of a connection seen
+	MaxDepth int
+}
+
+// NewEventDispatcher creates an event dispatcher
+func NewEventDispatcher(options DispatcherOptions) *EventDispatcher {
+	if options.Workers <= 0 {
+		options.Workers = 8
+	}
+	if options.QueueSize == 0 {
+		options.QueueSize = 256
+	}
+	if options.Backlog == 0 && options.QueueSize > 0 {
+		options.Backlog = (options.QueueSize + 1) / 2
+	}
+	return &EventDispatcher{options: options, slots: make(chan struct{}, options.Workers)}
+}
+
+// Stats returns the dispatcher counters
+func (d *EventDispatcher) Stats() DispatchStats {
+	return DispatchStats{
+		Queued:    int(d.queued.Load()),
+		Sessions:  int(d.sessions.Load()),
+		Delivered: d.delivered.Load(),
+		Stalls:    d.stalls.Load(),
+		MaxDepth:  int(d.maxDepth.Load()),
+	}
+}
+
+// dispatchQueue holds the events of a connection waiting for a worker
+type dispatchQueue struct {
+	dispatcher *EventDispatcher
+	conn       *Connection
+
+	mu      sync.Mutex
+	events  []*Event
+	running bool
+	// backlogged is set when OnBacklog was called, until the queue drains below Backlog
+	backlogged bool
+	// space is signalled whenever an event is taken from the queue
+	space chan struct{}
+}
+
+// newQueue creates the queue of a connection
+func (d *EventDispatcher) newQueue(conn *Connection) *dispatchQueue {
+	return &dispatchQueue{dispatcher: d, conn: conn, space: make(chan struct{}, 1)}
+}
+
+// push queues an event and schedules the connection on a worker if none is delivering its events
+func (q *dispatchQueue) push(event *Event) {
+	d := q.dispatcher
+	q.mu.Lock()
+	q.events = append(q.events, event)
+	depth := len(q.events)
+	start := !q.running
+	q.running = true
+	backlog := d.options.Backlog > 0 && depth >= d.options.Backlog && !q.backlogged
+	if backlog {
+		q.backlogged = true
+	}
+	q.mu.Unlock()
+
+	d.queued.Add(1)
+	for {
+		max := d.maxDepth.Load()
+		if int64(depth) <= max || d.maxDepth.CompareAndSwap(max, int64(depth)) {
+			break
+		}
+	}
+	if backlog && d.options.OnBacklog != nil {
+		d.options.OnBacklog(q.conn, depth)
+	}
+	if start {
+		d.sessions.Add(1)
+		go q.run()
+	}
+}
+
+// run delivers the queued events once a worker is free, until the queue is empty
+func (q *dispatchQueue) run() {
+	d := q.dispatcher
+	d.slots <- struct{}{}
+	defer func() { <-d.slots }()
+
+	for {
+		q.mu.Lock()
+		if len(q.events) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			d.sessions.Add(-1)
+			return
+		}
+		event := q.events[0]
+		q.events[0] = nil
+		q.events = q.events[1:]
+		if len(q.events) < d.options.Backlog {
+			q.backlogged = false
+		}
+		q.mu.Unlock()
+
+		d.queued.Add(-1)
+		select {
+		case q.space <- struct{}{}:
+		default:
+		}
+		q.conn.deliver(event)
+		d.delivered.Add(1)
+	}
+}
+
+// depth returns the number of queued events
+func (q *dispatchQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events)
+}
+
+// waitForSpace blocks the read loop while the queue is full; it returns false when the
+// connection closes first
+func (q *dispatchQueue) waitForSpace() bool {
+	max := q.dispatcher.options.QueueSize
+	if max < 0 || q.depth() < max {
+		return true
+	}
+	q.dispatcher.stalls.Add(1)
+	for q.depth() >= max {
+		select {
+		case <-q.space:
+		case <-q.conn.ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// DispatchBacklog returns the number of events waiting for the handlers; always zero
+// without an EventDispatcher, as handlers then run on the reading goroutine
+func (c *Connection) DispatchBacklog() int {
+	if c.queue == nil {
+		return 0
+	}
+	return c.queue.depth()
+}
@@ -0,0 +1,109 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dtmfServer sends n DTMF events to each connection once it is ready
+func dtmfServer(t *testing.T, n int) string {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		for i := 0; i < n; i++ {
+			conn.WriteJSON(map[string]interface{}{"event": "dtmf", "digit": fmt.Sprint(i % 10)})
+		}
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestEventDispatcherBackpressure(t *testing.T) {
+	backlogs := make(chan int, 4)
+	dispatcher := NewEventDispatcher(DispatcherOptions{
+		QueueSize: 4,
+		OnBacklog: func(conn *Connection, depth int) { backlogs <- depth },
+	})
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var digits []string
+	conn, err := newConnection(context.Background(), dtmfServer(t, 10), nil, &ConnectionOptions{Dispatcher: dispatcher}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) {
+		if event.Event != "dtmf" {
+			return
+		}
+		<-release
+		mu.Lock()
+		digits = append(digits, event.Digit)
+		mu.Unlock()
+	})
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for dispatcher.Stats().Stalls == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stats := dispatcher.Stats()
+	if stats.Stalls == 0 || stats.MaxDepth != 4 || conn.DispatchBacklog() != 4 {
+		t.Errorf("Expected the read loop to stall on a full queue, got %+v with %d queued", stats, conn.DispatchBacklog())
+	}
+	if depth := <-backlogs; depth != 2 {
+		t.Errorf("Expected the backlog to be reported at depth 2, got %d", depth)
+	}
+
+	close(release)
+	for dispatcher.Stats().Delivered < 10 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(digits, ""); got != "0123456789" {
+		t.Errorf("Expected the events in order, got '%s'", got)
+	}
+	if stats := dispatcher.Stats(); stats.Queued != 0 || stats.Sessions != 0 {
+		t.Errorf("Expected the queues to drain, got %+v", stats)
+	}
+}
+
+func TestEventDispatcherIsolatesConnections(t *testing.T) {
+	dispatcher := NewEventDispatcher(DispatcherOptions{Workers: 2})
+	wsURL := dtmfServer(t, 1)
+
+	release := make(chan struct{})
+	defer close(release)
+	slow, err := newConnection(context.Background(), wsURL, nil, &ConnectionOptions{Dispatcher: dispatcher}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer slow.Close()
+	slow.OnEvent(func(event *Event) { <-release })
+	slow.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	received := make(chan *Event, 1)
+	fast, err := newConnection(context.Background(), wsURL, nil, &ConnectionOptions{Dispatcher: dispatcher}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer fast.Close()
+	fast.OnEvent(func(event *Event) { received <- event })
+	fast.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case event := <-received:
+		if event.Digit != "0" {
+			t.Errorf("Expected digit 0, got '%s'", event.Digit)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a slow handler not to hold back another connection")
+	}
+}
@@ -0,0 +1,93 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Common disposition codes
+const (
+	DispositionResolvedByBot     = "resolved_by_bot"
+	DispositionEscalated         = "escalated"
+	DispositionWrongNumber       = "wrong_number"
+	DispositionCallbackRequested = "callback_requested"
+	DispositionAbandoned         = "abandoned"
+)
+
+// DispositionTaxonomy maps the allowed disposition codes to their descriptions
+type DispositionTaxonomy map[string]string
+
+// DefaultDispositionTaxonomy is used when a connection has no taxonomy configured
+var DefaultDispositionTaxonomy = DispositionTaxonomy{
+	DispositionResolvedByBot:     "Resolved by the bot without a human",
+	DispositionEscalated:         "Escalated to a human agent",
+	DispositionWrongNumber:       "Caller reached the wrong number",
+	DispositionCallbackRequested: "Caller asked to be called back",
+	DispositionAbandoned:         "Caller hung up before resolution",
+}
+
+// Disposition represents the business outcome of a call
+type Disposition struct {
+	Code      string `json:"code"`
+	Notes     string `json:"notes,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// dispositionSlot collects the disposition set by a campaign call
+type dispositionSlot struct {
+	mu          sync.Mutex
+	disposition *Disposition
+}
+
+// dispositionSlotKey is the context key of a campaign attempt's disposition slot
+type dispositionSlotKey struct{}
+
+// SetDisposition sets the outcome of the call and delivers it to the handlers with a
+// "disposition" event, e.g. to store it with the call record, which RustPBX does not
+// keep dispositions in. The code must belong to the connection's taxonomy. Connections
+// dialed with a campaign attempt's context also report the disposition in the campaign
+// result.
+func (c *Connection) SetDisposition(code, notes string) error {
+	taxonomy := c.dispositions
+	if taxonomy == nil {
+		taxonomy = DefaultDispositionTaxonomy
+	}
+	if _, ok := taxonomy[code]; !ok {
+		return fmt.Errorf("unknown disposition code: %s", code)
+	}
+
+	disposition := &Disposition{Code: code, Notes: notes, Timestamp: time.Now().UnixMilli()}
+	c.mu.Lock()
+	c.disposition = disposition
+	c.mu.Unlock()
+
+	if slot, ok := c.ctx.Value(dispositionSlotKey{}).(*dispositionSlot); ok {
+		slot.mu.Lock()
+		slot.disposition = disposition
+		slot.mu.Unlock()
+	}
+
+	data, _ := json.Marshal(disposition)
+	c.dispatch(&Event{
+		Event:     "disposition",
+		Timestamp: disposition.Timestamp,
+		Data:      data,
+	})
+	return nil
+}
+
+// Disposition returns the disposition set on the call, or nil
+func (c *Connection) Disposition() *Disposition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.disposition
+}
+
+// withDispositionSlot returns a copy of ctx collecting the disposition of the call dialed with it
+func withDispositionSlot(ctx context.Context) (context.Context, *dispositionSlot) {
+	slot := &dispositionSlot{}
+	return context.WithValue(ctx, dispositionSlotKey{}, slot), slot
+}
@@ -0,0 +1,81 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSetDisposition(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Dispositions: DispositionTaxonomy{"sold": "Customer bought the upgrade"},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "disposition" {
+			events <- event
+		}
+	})
+
+	if err := conn.SetDisposition(DispositionEscalated, ""); err == nil {
+		t.Error("Expected error for a code outside the taxonomy")
+	}
+	if err := conn.SetDisposition("sold", "annual plan"); err != nil {
+		t.Fatalf("SetDisposition failed: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		var d Disposition
+		json.Unmarshal(event.Data, &d)
+		if d.Code != "sold" || d.Notes != "annual plan" {
+			t.Errorf("Unexpected disposition event: %s", event.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a disposition event")
+	}
+	select {
+	case cmd := <-commands:
+		t.Errorf("Unexpected command: %v", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if d := conn.Disposition(); d == nil || d.Code != "sold" {
+		t.Errorf("Expected disposition 'sold', got %+v", d)
+	}
+}
+
+func TestCampaignRecordsDisposition(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	client := NewClient(server.URL)
+
+	campaign := NewCampaign(func(ctx context.Context, target *CampaignTarget) (CampaignOutcome, error) {
+		conn, err := client.ConnectCall(ctx, nil)
+		if err != nil {
+			return CampaignFailed, err
+		}
+		defer conn.Close()
+		return CampaignAnswered, conn.SetDisposition(DispositionWrongNumber, "asked for Maria")
+	}, nil)
+	// Noon in London, inside the default window
+	campaign.now = func() time.Time { return time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC) }
+
+	if err := campaign.Add(CampaignTarget{ID: "t1", Number: "+442071838750"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := campaign.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Disposition == nil || results[0].Disposition.Code != DispositionWrongNumber {
+		t.Errorf("Expected wrong number disposition in campaign result, got %+v", results)
+	}
+}
@@ -0,0 +1,191 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EmergencyAction is taken when a dial target is an emergency number
+type EmergencyAction int
+
+const (
+	// EmergencyBlock refuses to dial the target
+	EmergencyBlock EmergencyAction = iota
+	// EmergencyWarn emits an emergencyDetected event and dials the target
+	EmergencyWarn
+	// EmergencyAllow dials the target, or RouteTo when set
+	EmergencyAllow
+)
+
+// DefaultEmergencyNumbers are the emergency numbers checked when a policy does not list its own.
+// Some of them are common internal extensions, so a PBX dialing such extensions should list
+// its own numbers.
+var DefaultEmergencyNumbers = []string{"911", "112", "999", "000", "110", "119", "120", "122", "108"}
+
+// EmergencyPolicy represents how dial targets matching emergency numbers are handled.
+// Connections without a policy dial every target unchecked.
+type EmergencyPolicy struct {
+	Action  EmergencyAction
+	Numbers []string
+	// RouteTo replaces the target when Action is EmergencyAllow
+	RouteTo string
+	// WebhookURL is notified of every detected emergency target
+	WebhookURL string
+	// HTTPClient posts to the webhook; a client with a 10s timeout when nil
+	HTTPClient *http.Client
+}
+
+// EmergencyCallError is returned when a dial target is blocked by the emergency policy
+type EmergencyCallError struct {
+	Target string
+}
+
+func (e *EmergencyCallError) Error() string {
+	return fmt.Sprintf("refusing to dial emergency number %s", e.Target)
+}
+
+// emergencyWebhookClient posts to webhooks of policies without their own client
+var emergencyWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// dialedUser extracts the user part of a SIP URI, tel URI or plain number
+func dialedUser(target string) string {
+	number := strings.TrimSpace(target)
+	for _, scheme := range []string{"sip:", "sips:", "tel:"} {
+		if strings.HasPrefix(strings.ToLower(number), scheme) {
+			number = number[len(scheme):]
+			break
+		}
+	}
+	if i := strings.IndexAny(number, "@;?"); i >= 0 {
+		number = number[:i]
+	}
+	return number
+}
+
+// dialedNumber extracts the dialed digits from a SIP URI, tel URI or plain number
+func dialedNumber(target string) string {
+	var digits strings.Builder
+	for _, r := range dialedUser(target) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == ' ' || r == '.' || r == '(' || r == ')' || r == '+':
+			// Visual separators
+		default:
+			// Not a phone number
+			return ""
+		}
+	}
+	return digits.String()
+}
+
+// nationalNumbers returns the national numbers an international number, written with a
+// + or the 00 international prefix, may stand for: the digits after a country code of one
+// to three digits, as +1 911 or +86 110 dial the emergency number of the country
+func nationalNumbers(target, dialed string) []string {
+	switch {
+	case strings.HasPrefix(dialedUser(target), "+"):
+	case strings.HasPrefix(dialed, "00"):
+		dialed = dialed[2:]
+	default:
+		return nil
+	}
+	var numbers []string
+	for length := 1; length <= 3 && length < len(dialed); length++ {
+		numbers = append(numbers, dialed[length:])
+	}
+	return numbers
+}
+
+// IsEmergencyNumber reports whether target dials one of the given emergency numbers,
+// or one of DefaultEmergencyNumbers when numbers is empty, directly or after a country code
+func IsEmergencyNumber(target string, numbers []string) bool {
+	if len(numbers) == 0 {
+		numbers = DefaultEmergencyNumbers
+	}
+	dialed := dialedNumber(target)
+	if dialed == "" {
+		return false
+	}
+	candidates := append([]string{dialed}, nationalNumbers(target, dialed)...)
+	for _, number := range numbers {
+		for _, candidate := range candidates {
+			if candidate == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkEmergencyTarget applies the emergency policy to a dial target and returns the target to dial
+func (c *Connection) checkEmergencyTarget(target string) (string, error) {
+	policy := c.emergency
+	if policy == nil {
+		return target, nil
+	}
+	if !IsEmergencyNumber(target, policy.Numbers) {
+		return target, nil
+	}
+
+	if policy.WebhookURL != "" {
+		go c.notifyEmergency(policy, target)
+	}
+
+	switch policy.Action {
+	case EmergencyWarn:
+		c.dispatch(&Event{
+			Event:     "emergencyDetected",
+			Timestamp: time.Now().UnixMilli(),
+			Callee:    target,
+		})
+		return target, nil
+	case EmergencyAllow:
+		if policy.RouteTo != "" {
+			return policy.RouteTo, nil
+		}
+		return target, nil
+	default:
+		return "", &EmergencyCallError{Target: target}
+	}
+}
+
+// notifyEmergency posts a detected emergency target to the policy webhook
+func (c *Connection) notifyEmergency(policy *EmergencyPolicy, target string) {
+	payload := map[string]interface{}{
+		"target":    target,
+		"action":    policy.Action,
+		"timestamp": time.Now().UnixMilli(),
+	}
+	if c.callContext != nil {
+		payload["callId"] = c.callContext.CallID
+	}
+	body, _ := json.Marshal(payload)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", policy.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		c.handleError(fmt.Errorf("failed to create emergency webhook request: %w", err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.callContext.applyHeaders(req.Header)
+
+	httpClient := policy.HTTPClient
+	if httpClient == nil {
+		httpClient = emergencyWebhookClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		c.handleError(fmt.Errorf("failed to notify emergency webhook: %w", err))
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,62 @@
+package rustpbx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsEmergencyNumber(t *testing.T) {
+	tests := []struct {
+		target    string
+		emergency bool
+	}{
+		{"911", true},
+		{"sip:112@pbx.example.com", true},
+		{"tel:+999", true},
+		{"9-1-1", true},
+		{"sip:9110@pbx.example.com", false},
+		{"sip:alice@example.com", false},
+		{"+15550100", false},
+		{"+1 911", true},
+		{"tel:+44-999", true},
+		{"sip:+86110@pbx.example.com", true},
+		{"0061 000", true},
+		{"+1 555 0100 911", false},
+		{"1911", false},
+	}
+
+	for _, test := range tests {
+		if IsEmergencyNumber(test.target, nil) != test.emergency {
+			t.Errorf("IsEmergencyNumber(%q) expected %t", test.target, test.emergency)
+		}
+	}
+
+	if !IsEmergencyNumber("sip:333@pbx", []string{"333"}) {
+		t.Error("Expected custom emergency number to match")
+	}
+}
+
+func TestEmergencyPolicy(t *testing.T) {
+	conn := &Connection{}
+	if target, err := conn.checkEmergencyTarget("sip:110@pbx"); err != nil || target != "sip:110@pbx" {
+		t.Errorf("Expected targets to pass unchecked without a policy, got '%s' (%v)", target, err)
+	}
+
+	conn.emergency = &EmergencyPolicy{}
+	_, err := conn.checkEmergencyTarget("sip:911@pbx")
+	var emergencyErr *EmergencyCallError
+	if !errors.As(err, &emergencyErr) {
+		t.Errorf("Expected EmergencyCallError with a blocking policy, got %v", err)
+	}
+
+	conn.emergency = &EmergencyPolicy{Action: EmergencyAllow, RouteTo: "sip:psap-gateway@carrier"}
+	target, err := conn.checkEmergencyTarget("911")
+	if err != nil || target != "sip:psap-gateway@carrier" {
+		t.Errorf("Expected emergency call to be routed, got '%s' (%v)", target, err)
+	}
+
+	target, err = conn.checkEmergencyTarget("sip:alice@example.com")
+	if err != nil || target != "sip:alice@example.com" {
+		t.Errorf("Expected regular target to pass unchanged, got '%s' (%v)", target, err)
+	}
+}
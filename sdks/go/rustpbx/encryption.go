@@ -0,0 +1,146 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnencryptedMedia is returned when RequireEncryption refuses a call whose media is not
+// SRTP protected
+var ErrUnencryptedMedia = errors.New("unencrypted media")
+
+// MediaEncryption is the protection of the media of a call
+type MediaEncryption string
+
+const (
+	// EncryptionNone is plain RTP
+	EncryptionNone MediaEncryption = "none"
+	// EncryptionSRTP is SRTP keyed in the SDP (SDES, a=crypto)
+	EncryptionSRTP MediaEncryption = "srtp"
+	// EncryptionDTLSSRTP is SRTP keyed by a DTLS handshake, as in WebRTC
+	EncryptionDTLSSRTP MediaEncryption = "dtls-srtp"
+)
+
+// Encrypted reports whether the media is SRTP protected; false for EncryptionNone and
+// an unknown encryption
+func (e MediaEncryption) Encrypted() bool {
+	return e == EncryptionSRTP || e == EncryptionDTLSSRTP
+}
+
+// ParseMediaEncryption returns the encryption of the media of an SDP from the transport
+// of its media sections and their keying attributes. The media is only as protected as
+// its weakest active section. It returns "" when the SDP has no active media section.
+func ParseMediaEncryption(sdp string) MediaEncryption {
+	var sections []MediaEncryption
+	sessionFingerprint := false
+	var proto string
+	active, fingerprint := false, false
+	flush := func() {
+		if !active {
+			return
+		}
+		switch {
+		case !strings.Contains(proto, "SAVP"):
+			sections = append(sections, EncryptionNone)
+		case strings.Contains(proto, "TLS") || fingerprint || sessionFingerprint:
+			sections = append(sections, EncryptionDTLSSRTP)
+		default:
+			// SDES keys in a=crypto, or keys exchanged out of band
+			sections = append(sections, EncryptionSRTP)
+		}
+	}
+	inMedia := false
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "m="):
+			flush()
+			inMedia = true
+			fields := strings.Fields(line[2:])
+			proto, fingerprint = "", false
+			// A zero port disables the section
+			active = len(fields) > 2 && fields[1] != "0" && strings.Contains(fields[2], "RTP/")
+			if active {
+				proto = fields[2]
+			}
+		case strings.HasPrefix(line, "a=fingerprint:"):
+			if inMedia {
+				fingerprint = true
+			} else {
+				sessionFingerprint = true
+			}
+		}
+	}
+	flush()
+
+	if len(sections) == 0 {
+		return ""
+	}
+	weakest := EncryptionDTLSSRTP
+	for _, section := range sections {
+		switch section {
+		case EncryptionNone:
+			return EncryptionNone
+		case EncryptionSRTP:
+			weakest = EncryptionSRTP
+		}
+	}
+	return weakest
+}
+
+// checkOfferEncryption refuses an offer of unprotected media under RequireEncryption
+func (c *Connection) checkOfferEncryption(option *CallOption) error {
+	if !c.requireEncryption || option == nil || option.Offer == "" {
+		return nil
+	}
+	if encryption := ParseMediaEncryption(option.Offer); !encryption.Encrypted() {
+		return fmt.Errorf("%w: the offer uses %s media", ErrUnencryptedMedia, describeEncryption(encryption))
+	}
+	return nil
+}
+
+// refuseUnencrypted ends a call whose incoming or answer SDP does not protect the media,
+// under RequireEncryption. It reports the refusal with an "unencryptedMedia" event and
+// returns false when the event must not be delivered.
+func (c *Connection) refuseUnencrypted(event *Event) bool {
+	if !c.requireEncryption {
+		return true
+	}
+	encryption := ParseMediaEncryption(event.SDP)
+	if encryption.Encrypted() {
+		return true
+	}
+	c.log().Warn("refusing unencrypted media", "event", event.Event, "encryption", describeEncryption(encryption))
+	data, _ := json.Marshal(map[string]interface{}{
+		"encryption": describeEncryption(encryption),
+	})
+	c.dispatch(&Event{
+		Event:     "unencryptedMedia",
+		TrackID:   event.TrackID,
+		Timestamp: time.Now().UnixMilli(),
+		Reason:    "unencrypted_media",
+		Data:      data,
+	})
+
+	var err error
+	if event.Event == "incoming" {
+		err = c.Reject("unencrypted_media", 488)
+	} else {
+		err = c.Hangup("unencrypted_media", "system")
+	}
+	if err != nil {
+		c.handleError(err)
+	}
+	return false
+}
+
+// describeEncryption names an encryption in messages, including the unknown one
+func describeEncryption(encryption MediaEncryption) string {
+	if encryption == "" {
+		return "unknown"
+	}
+	return string(encryption)
+}
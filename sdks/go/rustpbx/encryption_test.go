@@ -0,0 +1,166 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	plainSDP = "v=0\r\no=- 1 1 IN IP4 203.0.113.5\r\ns=-\r\nc=IN IP4 203.0.113.5\r\nt=0 0\r\n" +
+		"m=audio 4000 RTP/AVP 0\r\n"
+	sdesSDP = "v=0\r\no=- 1 1 IN IP4 203.0.113.5\r\ns=-\r\nc=IN IP4 203.0.113.5\r\nt=0 0\r\n" +
+		"m=audio 4000 RTP/SAVP 0\r\na=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz\r\n"
+	dtlsSDP = "v=0\r\no=- 1 1 IN IP4 203.0.113.5\r\ns=-\r\nt=0 0\r\na=fingerprint:sha-256 AB:CD\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\nc=IN IP4 203.0.113.5\r\na=rtpmap:111 opus/48000/2\r\n" +
+		"m=application 9 UDP/DTLS/SCTP webrtc-datachannel\r\n"
+)
+
+func TestParseMediaEncryption(t *testing.T) {
+	for _, test := range []struct {
+		sdp  string
+		want MediaEncryption
+	}{
+		{plainSDP, EncryptionNone},
+		{sdesSDP, EncryptionSRTP},
+		{dtlsSDP, EncryptionDTLSSRTP},
+		// A plain video section leaves the call unprotected
+		{sdesSDP + "m=video 4002 RTP/AVP 96\r\n", EncryptionNone},
+		// Disabled sections do not count
+		{sdesSDP + "m=video 0 RTP/AVP 96\r\n", EncryptionSRTP},
+		{"", ""},
+		{"v=0", ""},
+	} {
+		if got := ParseMediaEncryption(test.sdp); got != test.want {
+			t.Errorf("Expected %q for %q, got %q", test.want, test.sdp, got)
+		}
+	}
+	if EncryptionNone.Encrypted() || MediaEncryption("").Encrypted() || !EncryptionSRTP.Encrypted() {
+		t.Error("Expected only SRTP encryptions to be encrypted")
+	}
+}
+
+// encryptionServer answers invites with an SDP and forwards the other commands
+func encryptionServer(t *testing.T, answer string) (*Client, <-chan map[string]interface{}) {
+	commands := make(chan map[string]interface{}, 16)
+	server := newCommandServer(t, func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{}) {
+		return func(cmd map[string]interface{}) {
+			switch {
+			case cmd == nil:
+			case cmd["command"] == "invite":
+				conn.WriteJSON(Event{Event: "answer", SDP: answer})
+			default:
+				commands <- cmd
+			}
+		}
+	})
+	return NewClient(server.URL), commands
+}
+
+func TestAnswerEncryption(t *testing.T) {
+	client, _ := encryptionServer(t, dtlsSDP)
+	conn, err := client.ConnectCall(context.Background(), &ConnectionOptions{RequireEncryption: true})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	answers := make(chan *AnswerEvent, 1)
+	conn.OnAnswer(func(e *AnswerEvent) { answers <- e })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := conn.InviteAndWaitAnswer(ctx, &CallOption{Offer: dtlsSDP})
+	if err != nil {
+		t.Fatalf("InviteAndWaitAnswer failed: %v", err)
+	}
+	if result.Encryption != EncryptionDTLSSRTP {
+		t.Errorf("Expected DTLS-SRTP, got %q", result.Encryption)
+	}
+	if e := <-answers; e.Encryption != EncryptionDTLSSRTP {
+		t.Errorf("Expected the answer event to report DTLS-SRTP, got %q", e.Encryption)
+	}
+}
+
+func TestRequireEncryptionRefusesPlainAnswer(t *testing.T) {
+	client, commands := encryptionServer(t, plainSDP)
+	conn, err := client.ConnectCall(context.Background(), &ConnectionOptions{RequireEncryption: true})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	answered := make(chan struct{}, 1)
+	conn.OnAnswer(func(*AnswerEvent) { answered <- struct{}{} })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = conn.InviteAndWaitAnswer(ctx, nil)
+	if !errors.Is(err, ErrUnencryptedMedia) || !errors.Is(err, ErrCallNotAnswered) {
+		t.Fatalf("Expected ErrUnencryptedMedia, got %v", err)
+	}
+	select {
+	case cmd := <-commands:
+		if cmd["command"] != "hangup" || cmd["reason"] != "unencrypted_media" {
+			t.Errorf("Expected a hangup for unencrypted media, got %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the call to be hung up")
+	}
+	select {
+	case <-answered:
+		t.Error("Expected the answer not to be delivered")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := conn.Invite(&CallOption{Offer: plainSDP}); !errors.Is(err, ErrUnencryptedMedia) {
+		t.Errorf("Expected a plain offer to be refused, got %v", err)
+	}
+	if err := conn.Invite(&CallOption{Offer: sdesSDP}); err != nil {
+		t.Errorf("Expected an SRTP offer to be sent, got %v", err)
+	}
+}
+
+func TestRequireEncryptionRejectsPlainIncoming(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		// The call comes in once the client is ready, signaled by its first command
+		var cmd map[string]interface{}
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		conn.WriteJSON(Event{Event: "incoming", Caller: "sip:alice@example.com", SDP: plainSDP})
+	})
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{RequireEncryption: true})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+	refused := make(chan *Event, 1)
+	conn.AddEventHandler(func(e *Event) {
+		if e.Event == "unencryptedMedia" {
+			refused <- e
+		}
+	})
+	if err := conn.Interrupt(); err != nil {
+		t.Fatalf("Interrupt failed: %v", err)
+	}
+
+	select {
+	case cmd := <-commands:
+		if cmd["command"] != "reject" || cmd["code"] != float64(488) {
+			t.Errorf("Expected a 488 reject, got %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the incoming call to be rejected")
+	}
+	select {
+	case e := <-refused:
+		if string(e.Data) != `{"encryption":"none"}` {
+			t.Errorf("Unexpected unencryptedMedia data: %s", e.Data)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected an unencryptedMedia event")
+	}
+}
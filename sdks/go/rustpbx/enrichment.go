@@ -0,0 +1,126 @@
+package rustpbx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CallerIdentity represents what a CRM or CNAM source knows about a caller
+type CallerIdentity struct {
+	Name      string
+	Company   string
+	AccountID string
+	// Tier is a customer segment such as "vip"; it sets the call priority when Priority is normal
+	Tier       string
+	Priority   Priority
+	Attributes map[string]string
+}
+
+// IdentityLookup resolves a caller number to an identity; it returns nil when the caller is unknown
+type IdentityLookup func(ctx context.Context, caller string) (*CallerIdentity, error)
+
+// IdentityEnricher looks up incoming callers and attaches the result to the call context
+// before the incoming event is dispatched. The lookup does not hold up the events that
+// follow; they may be delivered before the incoming event. Share one enricher across
+// connections to share its cache.
+type IdentityEnricher struct {
+	lookup  IdentityLookup
+	ttl     time.Duration
+	timeout time.Duration
+	mu      sync.Mutex
+	entries map[string]identityEntry
+}
+
+type identityEntry struct {
+	identity *CallerIdentity
+	expires  time.Time
+}
+
+// NewIdentityEnricher creates an enricher caching lookups for ttl; a zero ttl disables caching.
+// Lookups taking longer than timeout are abandoned so the call is not held; 2s when zero.
+func NewIdentityEnricher(lookup IdentityLookup, ttl, timeout time.Duration) *IdentityEnricher {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &IdentityEnricher{
+		lookup:  lookup,
+		ttl:     ttl,
+		timeout: timeout,
+		entries: make(map[string]identityEntry),
+	}
+}
+
+// Lookup returns the identity of a caller, from the cache when possible.
+// Unknown callers are cached too; failed lookups are not.
+func (e *IdentityEnricher) Lookup(ctx context.Context, caller string) (*CallerIdentity, error) {
+	key := dialedNumber(caller)
+	if key == "" {
+		key = dialedUser(caller)
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	entry, ok := e.entries[key]
+	if ok && now.After(entry.expires) {
+		delete(e.entries, key)
+		ok = false
+	}
+	e.mu.Unlock()
+	if ok {
+		return entry.identity, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	identity, err := e.lookup(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.ttl > 0 {
+		e.mu.Lock()
+		e.entries[key] = identityEntry{identity: identity, expires: now.Add(e.ttl)}
+		e.mu.Unlock()
+	}
+	return identity, nil
+}
+
+// Forget removes a caller from the cache, e.g. after their CRM record changed
+func (e *IdentityEnricher) Forget(caller string) {
+	key := dialedNumber(caller)
+	if key == "" {
+		key = dialedUser(caller)
+	}
+	e.mu.Lock()
+	delete(e.entries, key)
+	e.mu.Unlock()
+}
+
+// prepareIncoming enriches and prioritizes an incoming call and admits it; it returns
+// false when the incoming event must not be delivered now
+func (c *Connection) prepareIncoming(event *Event) bool {
+	c.enrichIncoming(event)
+	c.loadIncomingProfile(event)
+	c.prioritizeIncoming(event)
+	return c.admitIncoming(event)
+}
+
+// enrichIncoming attaches the caller identity to the call context of an incoming call
+func (c *Connection) enrichIncoming(event *Event) {
+	if c.enricher == nil || c.callContext == nil || event.Caller == "" {
+		return
+	}
+	identity, err := c.enricher.Lookup(c.ctx, event.Caller)
+	if err != nil {
+		// Never block a call because the lookup failed
+		c.handleError(err)
+		return
+	}
+	c.mu.Lock()
+	c.callContext.Identity = identity
+	c.mu.Unlock()
+}
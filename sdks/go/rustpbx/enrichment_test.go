@@ -0,0 +1,121 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIdentityEnricherCache(t *testing.T) {
+	lookups := 0
+	enricher := NewIdentityEnricher(func(ctx context.Context, caller string) (*CallerIdentity, error) {
+		lookups++
+		if caller == "15550100" {
+			return &CallerIdentity{Name: "Ada"}, nil
+		}
+		if caller == "15550199" {
+			return nil, errors.New("crm unavailable")
+		}
+		return nil, nil
+	}, time.Minute, 0)
+
+	for _, caller := range []string{"sip:+1-555-0100@pbx", "+15550100"} {
+		identity, err := enricher.Lookup(context.Background(), caller)
+		if err != nil || identity == nil || identity.Name != "Ada" {
+			t.Errorf("Expected Ada for %s, got %+v (%v)", caller, identity, err)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected 1 lookup, got %d", lookups)
+	}
+
+	enricher.Lookup(context.Background(), "+15550111")
+	enricher.Lookup(context.Background(), "+15550111")
+	if lookups != 2 {
+		t.Errorf("Expected unknown callers to be cached, got %d lookups", lookups)
+	}
+
+	enricher.Lookup(context.Background(), "+15550199")
+	enricher.Lookup(context.Background(), "+15550199")
+	if lookups != 4 {
+		t.Errorf("Expected failed lookups not to be cached, got %d lookups", lookups)
+	}
+
+	enricher.Forget("+15550100")
+	enricher.Lookup(context.Background(), "+15550100")
+	if lookups != 5 {
+		t.Errorf("Expected forgotten caller to be looked up again, got %d lookups", lookups)
+	}
+}
+
+func TestIncomingEnrichment(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		// Wait for the client to install its handler
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100"})
+	})
+
+	enricher := NewIdentityEnricher(func(ctx context.Context, caller string) (*CallerIdentity, error) {
+		return &CallerIdentity{Name: "Ada", Tier: "vip"}, nil
+	}, 0, 0)
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{Enricher: enricher})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+
+	events := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case event := <-events:
+		if event.Context.Identity == nil || event.Context.Identity.Tier != "vip" {
+			t.Errorf("Expected incoming event to carry the caller identity, got %+v", event.Context.Identity)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected incoming event")
+	}
+}
+
+func TestIncomingEnrichmentDoesNotBlockEvents(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100"})
+		conn.WriteJSON(Event{Event: "dtmf", Digit: "1"})
+	})
+
+	release := make(chan struct{})
+	enricher := NewIdentityEnricher(func(ctx context.Context, caller string) (*CallerIdentity, error) {
+		<-release
+		return &CallerIdentity{Name: "Ada"}, nil
+	}, 0, 0)
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{Enricher: enricher})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+
+	events := make(chan *Event, 2)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	for _, expected := range []string{"dtmf", "incoming"} {
+		select {
+		case event := <-events:
+			if event.Event != expected {
+				t.Fatalf("Expected %s, got %s", expected, event.Event)
+			}
+			if expected == "dtmf" {
+				close(release)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s while the lookup is pending", expected)
+		}
+	}
+}
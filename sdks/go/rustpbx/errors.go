@@ -0,0 +1,106 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrConnectionClosed is wrapped by the errors of commands and waits that failed because
+// the connection is closed
+var ErrConnectionClosed = errors.New("connection closed")
+
+// ErrTimeout is wrapped by the errors of commands and waits that timed out, alongside
+// context.DeadlineExceeded or the network error when there is one
+var ErrTimeout = errors.New("timeout")
+
+// ErrCallNotFound is wrapped by the errors of API requests for a call the server does not know
+var ErrCallNotFound = errors.New("call not found")
+
+// APIError is returned for HTTP requests answered with an unexpected status, those of
+// the RustPBX API and those the SDK makes to token, flag, webhook and stream endpoints
+type APIError struct {
+	StatusCode int
+	// Body is the response body, usually the reason given by the server
+	Body string
+	// Err is the sentinel the status maps to, such as ErrCallNotFound, or nil
+	Err error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap makes the error match its sentinel
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError reads the body of a response with an unexpected status into an APIError
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// CommandError reports an error or reject event of the server, failing a command or the call
+type CommandError struct {
+	// Code is the SIP or server status code, zero when the server gave none
+	Code   int
+	Reason string
+	// Event is the error or reject event
+	Event *Event
+}
+
+func (e *CommandError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("server rejected command with code %d: %s", e.Code, e.Reason)
+	}
+	return fmt.Sprintf("server rejected command: %s", e.Reason)
+}
+
+// Err returns the error reported by an error or reject event as a *CommandError, or nil
+// for other events
+func (e *Event) Err() error {
+	switch e.Event {
+	case "error":
+		return &CommandError{Code: e.Code, Reason: e.Error, Event: e}
+	case "reject":
+		return &CommandError{Code: e.Code, Reason: e.Reason, Event: e}
+	}
+	return nil
+}
+
+// classifiedError adds a sentinel to an error without changing its message
+type classifiedError struct {
+	err  error
+	kind error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
+
+// classifyError makes a failed write match ErrTimeout or ErrConnectionClosed from its cause
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, ErrConnectionClosed) {
+		return err
+	}
+	var netErr net.Error
+	var closeErr *websocket.CloseError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &classifiedError{err: err, kind: ErrTimeout}
+	case errors.Is(err, net.ErrClosed), errors.Is(err, websocket.ErrCloseSent), errors.As(err, &closeErr):
+		return &classifiedError{err: err, kind: ErrConnectionClosed}
+	}
+	return err
+}
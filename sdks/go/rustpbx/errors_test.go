@@ -0,0 +1,98 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/call/kill/missing" {
+			http.Error(w, "no such call", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	err := client.KillCall(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Body != "no such call\n" {
+		t.Errorf("Expected an APIError with status 404, got %v", err)
+	}
+	if !errors.Is(err, ErrCallNotFound) {
+		t.Errorf("Expected ErrCallNotFound, got %v", err)
+	}
+
+	_, err = client.GetActiveCalls(context.Background())
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || errors.Is(err, ErrCallNotFound) {
+		t.Errorf("Expected an APIError with status 503, got %v", err)
+	}
+
+	// Requests of the helpers built on the HTTP API fail the same way
+	_, err = (&ProxyLLM{Client: client}).Complete(context.Background(), nil)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected an APIError from the LLM proxy, got %v", err)
+	}
+	_, err = (&ModerationGuardrail{Client: client}).Check(context.Background(), "hello")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected an APIError from moderation, got %v", err)
+	}
+}
+
+func TestConnectionErrors(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+
+	if _, err := conn.WaitForEvent("answer", 10*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = conn.HangupContext(ctx, "normal_clearing", "caller")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTimeout and context.DeadlineExceeded, got %v", err)
+	}
+
+	conn.Close()
+	if err := conn.Mute(""); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed, got %v", err)
+	}
+	if err := conn.WriteAudio([]byte{0, 0}); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed for audio, got %v", err)
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	wsURL := answerServer(t, map[string][]map[string]interface{}{
+		"sip:erin@example.com": {{"event": "reject", "reason": "Busy Here", "code": 486}},
+	})
+	conn, err := NewConnection(context.Background(), wsURL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = conn.InviteAndWaitAnswer(ctx, &CallOption{Callee: "sip:erin@example.com"})
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Code != 486 || cmdErr.Reason != "Busy Here" || !errors.Is(err, ErrCallNotAnswered) {
+		t.Errorf("Expected a rejected call with code 486, got %v", err)
+	}
+
+	if err := (&Event{Event: "answer"}).Err(); err != nil {
+		t.Errorf("Expected no error for answer events, got %v", err)
+	}
+	if err := (&Event{Event: "error", Error: "tts failed"}).Err(); err == nil || err.Error() != "server rejected command: tts failed" {
+		t.Errorf("Expected a command error, got %v", err)
+	}
+}
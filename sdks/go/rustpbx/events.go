@@ -0,0 +1,307 @@
+package rustpbx
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// IncomingEvent is delivered when a call arrives
+type IncomingEvent struct {
+	TrackID   string
+	Timestamp int64
+	Caller    string
+	Callee    string
+	SDP       string
+	// Attestation is the STIR/SHAKEN attestation level, if provided; RustPBX does not send it
+	Attestation string
+	// SpamScore is set when call screening is enabled
+	SpamScore float64
+	Raw       *Event
+}
+
+// AnswerEvent is delivered when the call is answered
+type AnswerEvent struct {
+	TrackID   string
+	Timestamp int64
+	SDP       string
+	// MediaAddresses are the media addresses of the SDP, e.g. to check the address family
+	MediaAddresses []MediaAddress
+	// Encryption is the protection of the media negotiated by the SDP
+	Encryption MediaEncryption
+	Raw        *Event
+}
+
+// RingingEvent is delivered while the callee is ringing
+type RingingEvent struct {
+	TrackID    string
+	Timestamp  int64
+	EarlyMedia bool
+	Raw        *Event
+}
+
+// HangupEvent is delivered when the call ends
+type HangupEvent struct {
+	Timestamp int64
+	Reason    string
+	Initiator string
+	Raw       *Event
+}
+
+// ASREvent is delivered for final and partial transcripts
+type ASREvent struct {
+	TrackID   string
+	Timestamp int64
+	Index     int
+	StartTime int64
+	EndTime   int64
+	Text      string
+	Raw       *Event
+}
+
+// DTMFEvent is delivered when the caller presses a key
+type DTMFEvent struct {
+	TrackID   string
+	Timestamp int64
+	Digit     string
+	Raw       *Event
+}
+
+// VoiceActivityEvent is delivered when the caller starts speaking or falls silent
+type VoiceActivityEvent struct {
+	TrackID   string
+	Timestamp int64
+	StartTime int64
+	// Duration is the length of the silence, for silence events
+	Duration int64
+	Raw      *Event
+}
+
+// TrackEvent is delivered when a media track starts or ends
+type TrackEvent struct {
+	TrackID   string
+	Timestamp int64
+	Duration  int64
+	Raw       *Event
+}
+
+// ErrorEvent is delivered for server and connection errors
+type ErrorEvent struct {
+	TrackID   string
+	Timestamp int64
+	Sender    string
+	Error     string
+	Code      int
+	Raw       *Event
+}
+
+// Subscription is the handle of an event handler added with AddEventHandler
+type Subscription struct {
+	handler EventHandler
+}
+
+// AddEventHandler adds an event handler alongside the OnEvent handler and the other added
+// handlers, so that independent modules can all receive events. Handlers run in the
+// order they were added, after the OnEvent handler.
+func (c *Connection) AddEventHandler(handler EventHandler) *Subscription {
+	sub := &Subscription{handler: handler}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Copy on write, so dispatch can range over the handlers without holding the lock
+	subscriptions := make([]*Subscription, 0, len(c.subscriptions)+1)
+	subscriptions = append(subscriptions, c.subscriptions...)
+	c.subscriptions = append(subscriptions, sub)
+	return sub
+}
+
+// RemoveEventHandler removes a handler added with AddEventHandler; it reports false if
+// the handler was already removed
+func (c *Connection) RemoveEventHandler(sub *Subscription) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, s := range c.subscriptions {
+		if s == sub {
+			subscriptions := make([]*Subscription, 0, len(c.subscriptions)-1)
+			subscriptions = append(subscriptions, c.subscriptions[:i]...)
+			c.subscriptions = append(subscriptions, c.subscriptions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// HandlerPanic reports a panic recovered from an event handler or event interceptor
+type HandlerPanic struct {
+	// Event is the event being handled
+	Event *Event
+	// Value is the value passed to panic and Stack the stack of the goroutine that panicked
+	Value interface{}
+	Stack []byte
+}
+
+func (p *HandlerPanic) Error() string {
+	return fmt.Sprintf("event handler panicked on %s event: %v", p.Event.Event, p.Value)
+}
+
+// OnHandlerPanic sets the hook called with the panics recovered from event handlers and
+// event interceptors, replacing the previous one; a nil hook removes it. It is called
+// before the panic is reported as an "error" event, e.g. to send the stack to a crash reporter.
+func (c *Connection) OnHandlerPanic(hook func(*HandlerPanic)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.panicHook = hook
+}
+
+// callHandler calls an event handler, recovering from a panic so that it does not take
+// down the connection or keep the other handlers from running. The panic is passed to the
+// OnHandlerPanic hook and reported with an "error" event from sender "handler".
+func (c *Connection) callHandler(handler EventHandler, event *Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			if event.Event == "error" && event.Sender == "handler" {
+				// Do not report a panic while handling the report of another
+				return
+			}
+			recovered := &HandlerPanic{Event: event, Value: r, Stack: debug.Stack()}
+			c.log().Error("event handler panicked", "event", event.Event, "panic", r)
+			c.reportHandlerPanic(recovered)
+			c.dispatch(&Event{
+				Event:     "error",
+				Timestamp: time.Now().UnixMilli(),
+				Sender:    "handler",
+				Error:     recovered.Error(),
+			})
+		}
+	}()
+	handler(event)
+}
+
+// reportHandlerPanic passes a recovered panic to the OnHandlerPanic hook, if any
+func (c *Connection) reportHandlerPanic(recovered *HandlerPanic) {
+	c.mu.RLock()
+	hook := c.panicHook
+	c.mu.RUnlock()
+	if hook == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.log().Error("handler panic hook panicked", "panic", r)
+		}
+	}()
+	hook(recovered)
+}
+
+// on sets the typed handler of an event type, replacing any previous one; a nil handler removes it
+func (c *Connection) on(eventType string, handler EventHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if handler == nil {
+		delete(c.handlers, eventType)
+		return
+	}
+	if c.handlers == nil {
+		c.handlers = make(map[string]EventHandler)
+	}
+	c.handlers[eventType] = handler
+}
+
+// OnIncoming sets the handler of incoming events. Typed handlers run after the OnEvent handler.
+func (c *Connection) OnIncoming(handler func(*IncomingEvent)) {
+	c.on("incoming", typed(handler, func(e *Event) *IncomingEvent {
+		return &IncomingEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, Caller: e.Caller, Callee: e.Callee,
+			SDP: e.SDP, Attestation: e.Attestation, SpamScore: e.SpamScore, Raw: e}
+	}))
+}
+
+// OnAnswer sets the handler of answer events
+func (c *Connection) OnAnswer(handler func(*AnswerEvent)) {
+	c.on("answer", typed(handler, func(e *Event) *AnswerEvent {
+		return &AnswerEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, SDP: e.SDP,
+			MediaAddresses: ParseMediaAddresses(e.SDP), Encryption: ParseMediaEncryption(e.SDP), Raw: e}
+	}))
+}
+
+// OnRinging sets the handler of ringing events
+func (c *Connection) OnRinging(handler func(*RingingEvent)) {
+	c.on("ringing", typed(handler, func(e *Event) *RingingEvent {
+		return &RingingEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, EarlyMedia: e.EarlyMedia, Raw: e}
+	}))
+}
+
+// OnHangup sets the handler of hangup events
+func (c *Connection) OnHangup(handler func(*HangupEvent)) {
+	c.on("hangup", typed(handler, func(e *Event) *HangupEvent {
+		return &HangupEvent{Timestamp: e.Timestamp, Reason: e.Reason, Initiator: e.Initiator, Raw: e}
+	}))
+}
+
+// OnASRFinal sets the handler of final transcripts
+func (c *Connection) OnASRFinal(handler func(*ASREvent)) {
+	c.on("asrFinal", typed(handler, newASREvent))
+}
+
+// OnASRDelta sets the handler of partial transcripts
+func (c *Connection) OnASRDelta(handler func(*ASREvent)) {
+	c.on("asrDelta", typed(handler, newASREvent))
+}
+
+// OnDTMF sets the handler of DTMF events
+func (c *Connection) OnDTMF(handler func(*DTMFEvent)) {
+	c.on("dtmf", typed(handler, func(e *Event) *DTMFEvent {
+		return &DTMFEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, Digit: e.Digit, Raw: e}
+	}))
+}
+
+// OnSpeaking sets the handler of speaking events
+func (c *Connection) OnSpeaking(handler func(*VoiceActivityEvent)) {
+	c.on("speaking", typed(handler, newVoiceActivityEvent))
+}
+
+// OnSilence sets the handler of silence events
+func (c *Connection) OnSilence(handler func(*VoiceActivityEvent)) {
+	c.on("silence", typed(handler, newVoiceActivityEvent))
+}
+
+// OnTrackStart sets the handler of trackStart events
+func (c *Connection) OnTrackStart(handler func(*TrackEvent)) {
+	c.on("trackStart", typed(handler, newTrackEvent))
+}
+
+// OnTrackEnd sets the handler of trackEnd events
+func (c *Connection) OnTrackEnd(handler func(*TrackEvent)) {
+	c.on("trackEnd", typed(handler, newTrackEvent))
+}
+
+// OnError sets the handler of error events
+func (c *Connection) OnError(handler func(*ErrorEvent)) {
+	c.on("error", typed(handler, func(e *Event) *ErrorEvent {
+		return &ErrorEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, Sender: e.Sender, Error: e.Error, Code: e.Code, Raw: e}
+	}))
+}
+
+// typed adapts a typed handler to an event handler; a nil handler stays nil
+func typed[T any](handler func(*T), convert func(*Event) *T) EventHandler {
+	if handler == nil {
+		return nil
+	}
+	return func(event *Event) {
+		handler(convert(event))
+	}
+}
+
+// newASREvent converts a transcript event
+func newASREvent(e *Event) *ASREvent {
+	return &ASREvent{TrackID: e.TrackID, Timestamp: e.Timestamp, Index: e.Index, StartTime: e.StartTime,
+		EndTime: e.EndTime, Text: e.Text, Raw: e}
+}
+
+// newVoiceActivityEvent converts a speaking or silence event
+func newVoiceActivityEvent(e *Event) *VoiceActivityEvent {
+	return &VoiceActivityEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, StartTime: e.StartTime, Duration: e.Duration, Raw: e}
+}
+
+// newTrackEvent converts a trackStart or trackEnd event
+func newTrackEvent(e *Event) *TrackEvent {
+	return &TrackEvent{TrackID: e.TrackID, Timestamp: e.Timestamp, Duration: e.Duration, Raw: e}
+}
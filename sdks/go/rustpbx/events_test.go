@@ -0,0 +1,189 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTypedEventHandlers(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "sip:alice@example.com", Callee: "sip:bot@example.com"})
+		conn.WriteJSON(Event{Event: "asrFinal", Index: 2, Text: "hello"})
+		conn.WriteJSON(Event{Event: "dtmf", Digit: "5"})
+		conn.WriteJSON(Event{Event: "hangup", Reason: "normal_clearing", Initiator: "caller"})
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	generic := make(chan string, 8)
+	typed := make(chan string, 8)
+	conn.OnEvent(func(event *Event) { generic <- event.Event })
+	conn.OnIncoming(func(e *IncomingEvent) { typed <- "incoming:" + e.Caller })
+	conn.OnASRFinal(func(e *ASREvent) {
+		if e.Index != 2 || e.Raw == nil {
+			t.Errorf("Unexpected transcript event: %+v", e)
+		}
+		typed <- "asrFinal:" + e.Text
+	})
+	conn.OnDTMF(func(e *DTMFEvent) { typed <- "dtmf:" + e.Digit })
+	conn.OnHangup(func(e *HangupEvent) { typed <- "hangup:" + e.Reason })
+	conn.OnDTMF(nil)
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	expected := []string{"incoming:sip:alice@example.com", "asrFinal:hello", "hangup:normal_clearing"}
+	for _, want := range expected {
+		select {
+		case got := <-typed:
+			if got != want {
+				t.Errorf("Expected '%s', got '%s'", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected typed event '%s'", want)
+		}
+	}
+	for _, want := range []string{"incoming", "asrFinal", "dtmf", "hangup"} {
+		if got := <-generic; got != want {
+			t.Errorf("Expected the OnEvent handler to get '%s', got '%s'", want, got)
+		}
+	}
+}
+
+func TestAddEventHandler(t *testing.T) {
+	conn := &Connection{}
+	var order []string
+	conn.OnEvent(func(event *Event) { order = append(order, "onEvent") })
+	metrics := conn.AddEventHandler(func(event *Event) { order = append(order, "metrics") })
+	broken := conn.AddEventHandler(func(event *Event) { panic("broken handler") })
+	conn.AddEventHandler(func(event *Event) { order = append(order, "logic:"+event.Event) })
+	conn.OnDTMF(func(e *DTMFEvent) { order = append(order, "dtmf") })
+
+	conn.dispatch(&Event{Event: "dtmf", Digit: "1"})
+	expected := []string{
+		"onEvent", "metrics",
+		// The panic is reported to all handlers, including the one that panicked
+		"onEvent", "metrics", "logic:error",
+		"logic:dtmf", "dtmf",
+	}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, order)
+			break
+		}
+	}
+
+	if !conn.RemoveEventHandler(metrics) || conn.RemoveEventHandler(metrics) {
+		t.Error("Expected the handler to be removed once")
+	}
+	conn.RemoveEventHandler(broken)
+	order = nil
+	conn.dispatch(&Event{Event: "hangup"})
+	if len(order) != 2 || order[0] != "onEvent" || order[1] != "logic:hangup" {
+		t.Errorf("Expected the removed handler to be skipped, got %v", order)
+	}
+}
+
+func TestOnHandlerPanic(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "dtmf", Digit: "1"})
+		conn.WriteJSON(Event{Event: "asrFinal", Text: "hello"})
+		conn.WriteJSON(Event{Event: "hangup", Reason: "normal_clearing"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	panics := make(chan *HandlerPanic, 4)
+	errs := make(chan string, 4)
+	hangups := make(chan struct{}, 1)
+	conn.OnHandlerPanic(func(p *HandlerPanic) { panics <- p })
+	conn.UseEventInterceptor(func(next EventHandler) EventHandler {
+		return func(event *Event) {
+			if event.Event == "asrFinal" {
+				panic("broken interceptor")
+			}
+			next(event)
+		}
+	})
+	conn.OnDTMF(func(*DTMFEvent) { panic("broken handler") })
+	conn.OnError(func(e *ErrorEvent) {
+		if e.Sender == "handler" {
+			errs <- e.Error
+		}
+	})
+	conn.OnHangup(func(*HangupEvent) { hangups <- struct{}{} })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case <-hangups:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the connection to survive the panics")
+	}
+	for _, want := range []string{"broken handler", "broken interceptor"} {
+		select {
+		case p := <-panics:
+			if p.Value != want || len(p.Stack) == 0 {
+				t.Errorf("Expected panic '%s' with its stack, got %v", want, p.Value)
+			}
+		default:
+			t.Fatalf("Expected panic '%s' to be passed to the hook", want)
+		}
+		select {
+		case e := <-errs:
+			if !strings.Contains(e, want) {
+				t.Errorf("Expected an error event for '%s', got '%s'", want, e)
+			}
+		default:
+			t.Fatalf("Expected an error event for '%s'", want)
+		}
+	}
+}
+
+func TestWaitForEventKeepsHandlers(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "answer"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	received := make(chan string, 4)
+	conn.AddEventHandler(func(event *Event) { received <- event.Event })
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		// Setting the handler while waiting must not be undone when the wait ends
+		conn.OnEvent(func(event *Event) {})
+		conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	}()
+	if _, err := conn.WaitForEvent("answer", time.Second); err != nil {
+		t.Fatalf("WaitForEvent failed: %v", err)
+	}
+	if event := <-received; event != "answer" {
+		t.Errorf("Expected the added handler to receive the event, got %s", event)
+	}
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if conn.eventHandler == nil || len(conn.subscriptions) != 1 {
+		t.Errorf("Expected the handlers to be kept, got %d subscriptions", len(conn.subscriptions))
+	}
+}
@@ -0,0 +1,168 @@
+package rustpbx
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Call option extra keys tagging a call record with its experiment variant
+const (
+	ExtraExperiment = "experiment"
+	ExtraVariant    = "variant"
+)
+
+// Variant represents one arm of an experiment
+type Variant struct {
+	Name string
+	// Weight is the relative share of calls assigned to the variant; 1 when zero
+	Weight int
+	// Persona names the assistant persona used by the variant, if any
+	Persona string
+	// Params holds variant settings such as prompts, voices or flow names
+	Params map[string]string
+}
+
+// MetricStats represents the aggregated values of a metric
+type MetricStats struct {
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+// Mean returns the average value of the metric
+func (m MetricStats) Mean() float64 {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.Sum / float64(m.Count)
+}
+
+// VariantStats represents the metrics collected for a variant
+type VariantStats struct {
+	Variant string
+	Calls   int
+	Metrics map[string]MetricStats
+}
+
+// Experiment assigns calls to variants deterministically by caller, so a caller
+// always gets the same experience, and collects per-variant metrics
+type Experiment struct {
+	Name     string
+	Variants []Variant
+
+	mu    sync.Mutex
+	stats map[string]*VariantStats
+}
+
+// Assign returns the variant of a caller. The same caller always gets the same variant
+// as long as the experiment name and variants do not change.
+func (e *Experiment) Assign(caller string) *Variant {
+	total := 0
+	for _, v := range e.Variants {
+		total += variantWeight(v)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	// The same number hashes alike whatever its formatting
+	key := dialedNumber(caller)
+	if key == "" {
+		key = dialedUser(caller)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(e.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	bucket := int(h.Sum64() % uint64(total))
+
+	for i := range e.Variants {
+		bucket -= variantWeight(e.Variants[i])
+		if bucket < 0 {
+			return &e.Variants[i]
+		}
+	}
+	return nil
+}
+
+// variantWeight returns the effective weight of a variant
+func variantWeight(v Variant) int {
+	if v.Weight <= 0 {
+		return 1
+	}
+	return v.Weight
+}
+
+// Enroll assigns a caller to a variant, counts the call and tags the call option so
+// the call record carries the variant
+func (e *Experiment) Enroll(caller string, option *CallOption) *Variant {
+	variant := e.Assign(caller)
+	if variant == nil {
+		return nil
+	}
+
+	if option != nil {
+		if option.Extra == nil {
+			option.Extra = make(map[string]interface{})
+		}
+		option.Extra[ExtraExperiment] = e.Name
+		option.Extra[ExtraVariant] = variant.Name
+	}
+
+	e.mu.Lock()
+	e.variantStats(variant.Name).Calls++
+	e.mu.Unlock()
+	return variant
+}
+
+// Record adds a metric value, e.g. call duration or resolution, to a variant
+func (e *Experiment) Record(variant, metric string, value float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stats := e.variantStats(variant)
+	m, ok := stats.Metrics[metric]
+	if !ok {
+		m.Min, m.Max = math.Inf(1), math.Inf(-1)
+	}
+	m.Count++
+	m.Sum += value
+	m.Min = math.Min(m.Min, value)
+	m.Max = math.Max(m.Max, value)
+	stats.Metrics[metric] = m
+}
+
+// variantStats returns the stats of a variant; the caller must hold e.mu
+func (e *Experiment) variantStats(variant string) *VariantStats {
+	if e.stats == nil {
+		e.stats = make(map[string]*VariantStats)
+	}
+	stats, ok := e.stats[variant]
+	if !ok {
+		stats = &VariantStats{Variant: variant, Metrics: make(map[string]MetricStats)}
+		e.stats[variant] = stats
+	}
+	return stats
+}
+
+// Results returns the metrics of every variant, ordered by variant name
+func (e *Experiment) Results() []VariantStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	results := make([]VariantStats, 0, len(e.stats))
+	for _, stats := range e.stats {
+		metrics := make(map[string]MetricStats, len(stats.Metrics))
+		for name, m := range stats.Metrics {
+			metrics[name] = m
+		}
+		results = append(results, VariantStats{Variant: stats.Variant, Calls: stats.Calls, Metrics: metrics})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Variant < results[j].Variant
+	})
+	return results
+}
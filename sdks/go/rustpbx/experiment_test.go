@@ -0,0 +1,49 @@
+package rustpbx
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExperimentAssignIsDeterministic(t *testing.T) {
+	experiment := &Experiment{
+		Name:     "greeting-v2",
+		Variants: []Variant{{Name: "control", Weight: 3}, {Name: "short", Weight: 1}},
+	}
+
+	first := experiment.Assign("sip:+1 (212) 555-0100@carrier")
+	second := experiment.Assign("tel:+12125550100")
+	if first == nil || first != second {
+		t.Errorf("Expected the same caller to get the same variant, got %v and %v", first, second)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[experiment.Assign(fmt.Sprintf("+1212555%04d", i)).Name]++
+	}
+	if counts["control"] < 2700 || counts["control"] > 3300 {
+		t.Errorf("Expected about 3000 control assignments, got %v", counts)
+	}
+}
+
+func TestExperimentEnrollAndRecord(t *testing.T) {
+	experiment := &Experiment{Name: "voice", Variants: []Variant{{Name: "a"}}}
+
+	option := &CallOption{}
+	variant := experiment.Enroll("alice", option)
+	if variant == nil || option.Extra[ExtraExperiment] != "voice" || option.Extra[ExtraVariant] != "a" {
+		t.Fatalf("Expected call option tagged with the variant, got %v", option.Extra)
+	}
+
+	experiment.Record("a", "duration", 30)
+	experiment.Record("a", "duration", 90)
+
+	results := experiment.Results()
+	if len(results) != 1 || results[0].Calls != 1 {
+		t.Fatalf("Expected one enrolled call, got %+v", results)
+	}
+	duration := results[0].Metrics["duration"]
+	if duration.Mean() != 60 || duration.Min != 30 || duration.Max != 90 {
+		t.Errorf("Unexpected duration stats: %+v", duration)
+	}
+}
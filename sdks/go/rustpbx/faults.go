@@ -0,0 +1,177 @@
+package rustpbx
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// FaultConfig represents the failures injected into the control channel. Probabilities
+// range from 0 (never) to 1 (always).
+type FaultConfig struct {
+	// DropEvents drops inbound events
+	DropEvents float64
+	// DuplicateEvents delivers inbound events twice
+	DuplicateEvents float64
+	// DelayEvents holds inbound events, and everything behind them, for EventDelay
+	DelayEvents float64
+	EventDelay  time.Duration
+	// DropAudio drops binary audio frames in both directions
+	DropAudio float64
+	// DisconnectAfter drops the connection without a close handshake after the given time
+	DisconnectAfter time.Duration
+	// Match limits event faults to some events; all events when nil
+	Match func(event *Event) bool
+	// Seed makes the injected faults reproducible; a random seed is used when zero
+	Seed int64
+}
+
+// FaultStats counts the faults injected so far
+type FaultStats struct {
+	DroppedEvents    int
+	DuplicatedEvents int
+	DelayedEvents    int
+	DroppedAudio     int
+	Disconnects      int
+}
+
+// FaultInjector injects control channel failures into the connections it is attached to
+// through ConnectionOptions.Faults, for resilience tests and staging environments
+type FaultInjector struct {
+	config FaultConfig
+	mu     sync.Mutex
+	rand   *rand.Rand
+	stats  FaultStats
+	conns  map[*Connection]struct{}
+}
+
+// NewFaultInjector creates a fault injector
+func NewFaultInjector(config FaultConfig) *FaultInjector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{
+		config: config,
+		rand:   rand.New(rand.NewSource(seed)),
+		conns:  make(map[*Connection]struct{}),
+	}
+}
+
+// Stats returns the faults injected so far
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// Disconnect drops every attached connection without a close handshake, as a network failure would
+func (f *FaultInjector) Disconnect() {
+	f.mu.Lock()
+	conns := make([]*Connection, 0, len(f.conns))
+	for c := range f.conns {
+		conns = append(conns, c)
+	}
+	f.mu.Unlock()
+
+	for _, c := range conns {
+		f.disconnect(c)
+	}
+}
+
+// attach starts injecting faults into a connection
+func (f *FaultInjector) attach(c *Connection) {
+	f.mu.Lock()
+	f.conns[c] = struct{}{}
+	f.mu.Unlock()
+
+	if f.config.DisconnectAfter > 0 {
+		timer := time.AfterFunc(f.config.DisconnectAfter, func() { f.disconnect(c) })
+		go func() {
+			<-c.done
+			timer.Stop()
+		}()
+	}
+}
+
+// detach stops tracking a connection whose read loop ended
+func (f *FaultInjector) detach(c *Connection) {
+	f.mu.Lock()
+	delete(f.conns, c)
+	f.mu.Unlock()
+}
+
+// disconnect closes the network connection under the WebSocket
+func (f *FaultInjector) disconnect(c *Connection) {
+	f.mu.Lock()
+	_, attached := f.conns[c]
+	if attached {
+		f.stats.Disconnects++
+	}
+	f.mu.Unlock()
+	if attached {
+		c.mu.RLock()
+		conn := c.conn
+		c.mu.RUnlock()
+		conn.UnderlyingConn().Close()
+	}
+}
+
+// roll reports whether a fault with the given probability happens
+func (f *FaultInjector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rand.Float64() < probability
+}
+
+// count updates the stats under the mutex
+func (f *FaultInjector) count(update func(stats *FaultStats)) {
+	f.mu.Lock()
+	update(&f.stats)
+	f.mu.Unlock()
+}
+
+// inbound returns the number of times a received frame is delivered, after any delay
+func (f *FaultInjector) inbound(messageType int, data []byte) int {
+	if messageType == websocket.BinaryMessage {
+		if f.roll(f.config.DropAudio) {
+			f.count(func(s *FaultStats) { s.DroppedAudio++ })
+			return 0
+		}
+		return 1
+	}
+
+	if f.config.Match != nil {
+		event, err := decodeEvent(data, 0)
+		if err != nil || !f.config.Match(event) {
+			return 1
+		}
+	}
+	if f.roll(f.config.DropEvents) {
+		f.count(func(s *FaultStats) { s.DroppedEvents++ })
+		return 0
+	}
+	if f.config.EventDelay > 0 && f.roll(f.config.DelayEvents) {
+		f.count(func(s *FaultStats) { s.DelayedEvents++ })
+		time.Sleep(f.config.EventDelay)
+	}
+	if f.roll(f.config.DuplicateEvents) {
+		f.count(func(s *FaultStats) { s.DuplicatedEvents++ })
+		return 2
+	}
+	return 1
+}
+
+// outbound reports whether a frame about to be sent should be dropped
+func (f *FaultInjector) outbound(messageType int) bool {
+	if messageType != websocket.BinaryMessage || !f.roll(f.config.DropAudio) {
+		return false
+	}
+	f.count(func(s *FaultStats) { s.DroppedAudio++ })
+	return true
+}
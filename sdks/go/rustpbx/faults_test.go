@@ -0,0 +1,81 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// faultyConnection connects to a server sending the given events once the client is ready
+func faultyConnection(t *testing.T, faults *FaultInjector, events ...Event) (*Connection, chan *Event) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		for _, event := range events {
+			conn.WriteJSON(event)
+		}
+		time.Sleep(time.Second)
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{Faults: faults})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	received := make(chan *Event, 16)
+	conn.OnEvent(func(event *Event) { received <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	return conn, received
+}
+
+func TestFaultsDropAndDuplicate(t *testing.T) {
+	faults := NewFaultInjector(FaultConfig{
+		DropEvents:      1,
+		DuplicateEvents: 1,
+		Match:           func(event *Event) bool { return event.Event == "asrFinal" },
+	})
+	conn, received := faultyConnection(t, faults,
+		Event{Event: "asrFinal", Text: "dropped"}, Event{Event: "dtmf", Digit: "1"})
+	defer conn.Close()
+
+	if event := <-received; event.Event != "dtmf" {
+		t.Errorf("Expected the asrFinal event to be dropped, got '%s'", event.Event)
+	}
+	if stats := faults.Stats(); stats.DroppedEvents != 1 || stats.DuplicatedEvents != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	faults = NewFaultInjector(FaultConfig{DuplicateEvents: 1, DelayEvents: 1, EventDelay: 30 * time.Millisecond})
+	conn, received = faultyConnection(t, faults, Event{Event: "answer"})
+	defer conn.Close()
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if event := <-received; event.Event != "answer" {
+			t.Errorf("Expected duplicated answer event, got '%s'", event.Event)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("Expected the event to be delayed, got it after %v", elapsed)
+	}
+}
+
+func TestFaultsDisconnect(t *testing.T) {
+	faults := NewFaultInjector(FaultConfig{DisconnectAfter: 30 * time.Millisecond})
+	conn, received := faultyConnection(t, faults)
+	defer conn.Close()
+
+	select {
+	case event := <-received:
+		if event.Event != "error" || !strings.Contains(event.Error, "read error") {
+			t.Errorf("Expected read error after forced disconnect, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected forced disconnect")
+	}
+	if faults.Stats().Disconnects != 1 {
+		t.Errorf("Expected 1 disconnect, got %d", faults.Stats().Disconnects)
+	}
+}
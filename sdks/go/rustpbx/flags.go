@@ -0,0 +1,270 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Feature flags consulted by the assistant. When defined, they override the assistant options
+// of the call: speculative partials are turned on or off, while the response cache and the
+// verbosity policy can only be turned off since they need configuration.
+const (
+	FlagSpeculativePartials = "assistant.speculative_partials"
+	FlagResponseCache       = "assistant.response_cache"
+	FlagVerbosity           = "assistant.verbosity"
+)
+
+// Flag represents a feature flag
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Percentage rolls the flag out to a share of calls, from 0 to 100; all calls when zero.
+	// Calls are bucketed by call ID, so a call stays in or out while the percentage holds.
+	Percentage float64 `json:"percentage,omitempty"`
+	// Tenants limits the flag to some tenants; all tenants when empty
+	Tenants []string `json:"tenants,omitempty"`
+}
+
+// on reports whether the flag is on for a call
+func (f *Flag) on(cc *CallContext) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Tenants) > 0 {
+		tenant := ""
+		if cc != nil {
+			tenant = cc.Tenant
+		}
+		found := false
+		for _, t := range f.Tenants {
+			found = found || t == tenant
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Percentage <= 0 || f.Percentage >= 100 {
+		return true
+	}
+
+	key := ""
+	if cc != nil {
+		key = cc.CallID
+	}
+	h := fnv.New64a()
+	h.Write([]byte(f.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(h.Sum64()%10000) < f.Percentage*100
+}
+
+// FlagProvider supplies feature flags, e.g. from configuration or a remote flag service
+type FlagProvider interface {
+	// Flag returns a flag and whether it is defined
+	Flag(ctx context.Context, name string) (*Flag, bool, error)
+}
+
+// StaticFlags provides flags from a map keyed by flag name
+type StaticFlags map[string]Flag
+
+// Flag implements FlagProvider
+func (s StaticFlags) Flag(ctx context.Context, name string) (*Flag, bool, error) {
+	flag, ok := s[name]
+	if !ok {
+		return nil, false, nil
+	}
+	flag.Name = name
+	return &flag, true, nil
+}
+
+// flagSet caches a list of flags loaded from a file or a remote provider
+type flagSet struct {
+	mu     sync.Mutex
+	flags  map[string]Flag
+	loaded time.Time
+}
+
+// decode replaces the flags with a JSON array of flags
+func (s *flagSet) decode(data []byte) error {
+	var list []Flag
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to decode flags: %w", err)
+	}
+	flags := make(map[string]Flag, len(list))
+	for _, flag := range list {
+		flags[flag.Name] = flag
+	}
+	s.flags = flags
+	return nil
+}
+
+// lookup returns a cached flag; the caller must hold s.mu
+func (s *flagSet) lookup(name string) (*Flag, bool) {
+	flag, ok := s.flags[name]
+	if !ok {
+		return nil, false
+	}
+	return &flag, true
+}
+
+// FileFlags provides flags from a JSON file holding an array of flags. The file is
+// re-read when it changes, so flags can be toggled without a redeploy.
+type FileFlags struct {
+	path string
+	set  flagSet
+	// modTime is the modification time of the loaded file
+	modTime time.Time
+}
+
+// NewFileFlags creates a provider reading flags from path
+func NewFileFlags(path string) *FileFlags {
+	return &FileFlags{path: path}
+}
+
+// Flag implements FlagProvider
+func (f *FileFlags) Flag(ctx context.Context, name string) (*Flag, bool, error) {
+	f.set.mu.Lock()
+	defer f.set.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to stat flag file: %w", err)
+	}
+	if f.set.flags == nil || !info.ModTime().Equal(f.modTime) {
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read flag file: %w", err)
+		}
+		if err := f.set.decode(data); err != nil {
+			return nil, false, err
+		}
+		f.modTime = info.ModTime()
+	}
+	flag, ok := f.set.lookup(name)
+	return flag, ok, nil
+}
+
+// RemoteFlags provides flags from an HTTP endpoint returning a JSON array of flags.
+// The flags are cached for the refresh interval; the last flags are kept while the
+// endpoint fails.
+type RemoteFlags struct {
+	URL string
+	// Refresh is how long fetched flags are cached; 30s when zero
+	Refresh time.Duration
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	set flagSet
+}
+
+// Flag implements FlagProvider
+func (r *RemoteFlags) Flag(ctx context.Context, name string) (*Flag, bool, error) {
+	r.set.mu.Lock()
+	defer r.set.mu.Unlock()
+
+	refresh := r.Refresh
+	if refresh <= 0 {
+		refresh = 30 * time.Second
+	}
+	if r.set.flags == nil || time.Since(r.set.loaded) >= refresh {
+		if err := r.fetch(ctx); err != nil {
+			if r.set.flags == nil {
+				return nil, false, err
+			}
+			// Serve the last known flags and retry after the next interval
+			r.set.loaded = time.Now()
+		}
+	}
+	flag, ok := r.set.lookup(name)
+	return flag, ok, nil
+}
+
+// fetch loads the flags from the endpoint; the caller must hold r.set.mu
+func (r *RemoteFlags) fetch(ctx context.Context) error {
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", r.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch flags: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch flags: %w", newAPIError(resp))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read flags: %w", err)
+	}
+	if err := r.set.decode(data); err != nil {
+		return err
+	}
+	r.set.loaded = time.Now()
+	return nil
+}
+
+// FeatureFlags evaluates feature flags for calls. Flags missing from the provider, or
+// that cannot be fetched, fall back to the defaults.
+type FeatureFlags struct {
+	Provider FlagProvider
+	// Defaults holds the value of flags the provider does not define
+	Defaults map[string]bool
+}
+
+// Enabled reports whether a flag is on for the call whose context ctx carries
+func (f *FeatureFlags) Enabled(ctx context.Context, name string) bool {
+	enabled, _ := f.lookup(ctx, name)
+	return enabled
+}
+
+// lookup evaluates a flag and reports whether it is defined by the provider
+func (f *FeatureFlags) lookup(ctx context.Context, name string) (bool, bool) {
+	if f.Provider != nil {
+		flag, ok, err := f.Provider.Flag(ctx, name)
+		if err == nil && ok {
+			cc, _ := CallContextFromContext(ctx)
+			return flag.on(cc), true
+		}
+	}
+	return f.Defaults[name], false
+}
+
+// FeatureEnabled reports whether a feature flag is on for the call; false when the
+// connection has no feature flags
+func (c *Connection) FeatureEnabled(name string) bool {
+	if c.flags == nil {
+		return false
+	}
+	return c.flags.Enabled(WithCallContext(c.ctx, c.callContext), name)
+}
+
+// applyFlags lets the feature flags of the call override the assistant options
+func (a *Assistant) applyFlags() {
+	flags := a.conn.flags
+	if flags == nil {
+		return
+	}
+	ctx := WithCallContext(a.conn.ctx, a.conn.callContext)
+	if on, ok := flags.lookup(ctx, FlagSpeculativePartials); ok {
+		a.options.SpeculativePartials = on
+	}
+	if on, ok := flags.lookup(ctx, FlagResponseCache); ok && !on {
+		a.options.Cache = nil
+	}
+	if on, ok := flags.lookup(ctx, FlagVerbosity); ok && !on {
+		a.options.Verbosity = nil
+	}
+}
@@ -0,0 +1,114 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFeatureFlagsRollout(t *testing.T) {
+	flags := &FeatureFlags{
+		Provider: StaticFlags{
+			"new_flow": {Enabled: true, Percentage: 30},
+			"off":      {Enabled: false},
+			"vip":      {Enabled: true, Tenants: []string{"acme"}},
+		},
+		Defaults: map[string]bool{"undefined": true},
+	}
+
+	on := 0
+	for i := 0; i < 2000; i++ {
+		ctx := WithCallContext(context.Background(), &CallContext{CallID: fmt.Sprintf("call-%d", i)})
+		if flags.Enabled(ctx, "new_flow") {
+			on++
+		}
+		if flags.Enabled(ctx, "new_flow") != flags.Enabled(ctx, "new_flow") {
+			t.Fatal("Expected a stable decision per call")
+		}
+	}
+	if on < 500 || on > 700 {
+		t.Errorf("Expected about 30%% of calls, got %d of 2000", on)
+	}
+
+	acme := WithCallContext(context.Background(), &CallContext{CallID: "a", Tenant: "acme"})
+	other := WithCallContext(context.Background(), &CallContext{CallID: "b", Tenant: "other"})
+	if !flags.Enabled(acme, "vip") || flags.Enabled(other, "vip") {
+		t.Error("Expected the flag to be limited to its tenants")
+	}
+	if flags.Enabled(acme, "off") || !flags.Enabled(acme, "undefined") {
+		t.Error("Expected disabled flags off and undefined flags to use the defaults")
+	}
+}
+
+func TestFileFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modTime, modTime)
+	}
+	write(`[{"name":"greeting_v2","enabled":true}]`, time.Now().Add(-time.Minute))
+
+	flags := &FeatureFlags{Provider: NewFileFlags(path)}
+	if !flags.Enabled(context.Background(), "greeting_v2") {
+		t.Error("Expected the flag from the file to be on")
+	}
+	write(`[{"name":"greeting_v2","enabled":false}]`, time.Now())
+	if flags.Enabled(context.Background(), "greeting_v2") {
+		t.Error("Expected the changed file to be reloaded")
+	}
+}
+
+func TestRemoteFlags(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "down", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[{"name":"barge_in","enabled":true}]`))
+	}))
+	defer server.Close()
+
+	provider := &RemoteFlags{URL: server.URL, Refresh: time.Millisecond}
+	flags := &FeatureFlags{Provider: provider}
+	if !flags.Enabled(context.Background(), "barge_in") {
+		t.Error("Expected the remote flag to be on")
+	}
+	fail.Store(true)
+	time.Sleep(5 * time.Millisecond)
+	if !flags.Enabled(context.Background(), "barge_in") {
+		t.Error("Expected the last known flags while the endpoint fails")
+	}
+}
+
+func TestAssistantFlags(t *testing.T) {
+	conn := &Connection{
+		ctx:         context.Background(),
+		callContext: &CallContext{CallID: "call-1"},
+		flags: &FeatureFlags{Provider: StaticFlags{
+			FlagSpeculativePartials: {Enabled: true},
+			FlagResponseCache:       {Enabled: false},
+		}},
+	}
+	assistant := NewAssistant(conn, &AssistantOptions{
+		Cache:     NewResponseCache(10, time.Minute),
+		Verbosity: &VerbosityPolicy{},
+	})
+	if !assistant.options.SpeculativePartials || assistant.options.Cache != nil {
+		t.Errorf("Expected the flags to override the options, got %+v", assistant.options)
+	}
+	if assistant.options.Verbosity == nil {
+		t.Error("Expected options without a defined flag to be kept")
+	}
+	if !conn.FeatureEnabled(FlagSpeculativePartials) || conn.FeatureEnabled("unknown") {
+		t.Error("Unexpected FeatureEnabled result")
+	}
+}
@@ -0,0 +1,215 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNoTargetAnswered is returned by FollowMe.Ring when none of the targets answered; it
+// is joined with the error of every attempt
+var ErrNoTargetAnswered = errors.New("no follow-me target answered")
+
+// RingStrategy is the order in which FollowMe rings its targets
+type RingStrategy string
+
+const (
+	// RingSequential rings one target after the other, in order
+	RingSequential RingStrategy = "sequential"
+	// RingSimultaneous rings all targets at once and keeps the first to answer
+	RingSimultaneous RingStrategy = "simultaneous"
+)
+
+// RingTarget is a destination of a follow-me, such as a desk phone or a mobile
+type RingTarget struct {
+	Callee string
+	// Timeout bounds the ringing of the target; FollowMeOptions.Timeout when zero
+	Timeout time.Duration
+}
+
+// RingAttempt is the result of ringing a target
+type RingAttempt struct {
+	Target RingTarget
+	// Err is nil for the target that answered; it wraps ErrCallNotAnswered for a target
+	// that did not answer in time or was cancelled
+	Err      error
+	Duration time.Duration
+}
+
+// FollowMeResult describes the answered leg of a follow-me
+type FollowMeResult struct {
+	// Conn is the answered leg, owned by the caller
+	Conn   *Connection
+	Target RingTarget
+	Answer *AnswerResult
+	// Attempts are the results of the targets rung, in the order of the targets
+	Attempts []RingAttempt
+}
+
+// FollowMeOptions represents follow-me configuration
+type FollowMeOptions struct {
+	// Strategy defaults to RingSequential
+	Strategy RingStrategy
+	// Timeout is the ringing timeout of the targets without their own; 20s when zero
+	Timeout time.Duration
+	// Option is the call option of every leg; the callee is set from the target
+	Option *CallOption
+	// Connection configures the connections of the legs
+	Connection *ConnectionOptions
+	// Connect opens the connection of a leg; the client's ConnectSIP when nil. Set it to
+	// a ClusterClient's ConnectSIP to spread the legs over the cluster.
+	Connect func(ctx context.Context, options *ConnectionOptions) (*Connection, error)
+}
+
+// FollowMe rings a list of destinations for find-me/follow-me features, answering the
+// first to pick up and cancelling the rest
+type FollowMe struct {
+	targets []RingTarget
+	options FollowMeOptions
+}
+
+// NewFollowMe creates a follow-me ringing targets with client
+func NewFollowMe(client *Client, targets []RingTarget, options *FollowMeOptions) *FollowMe {
+	if options == nil {
+		options = &FollowMeOptions{}
+	}
+	opts := *options
+	if opts.Strategy == "" {
+		opts.Strategy = RingSequential
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 20 * time.Second
+	}
+	if opts.Connect == nil {
+		opts.Connect = client.ConnectSIP
+	}
+	return &FollowMe{targets: append([]RingTarget(nil), targets...), options: opts}
+}
+
+// Ring rings the targets with the strategy until one answers, and returns its leg. It
+// fails with ErrNoTargetAnswered if none does, or with the error of ctx.
+func (f *FollowMe) Ring(ctx context.Context) (*FollowMeResult, error) {
+	if len(f.targets) == 0 {
+		return nil, errors.New("follow-me has no targets")
+	}
+	var result *FollowMeResult
+	var attempts []RingAttempt
+	switch f.options.Strategy {
+	case RingSequential:
+		result, attempts = f.ringSequential(ctx)
+	case RingSimultaneous:
+		result, attempts = f.ringSimultaneous(ctx)
+	default:
+		return nil, fmt.Errorf("unknown ring strategy %q", f.options.Strategy)
+	}
+	if result != nil {
+		result.Attempts = attempts
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	errs := []error{ErrNoTargetAnswered}
+	for _, attempt := range attempts {
+		errs = append(errs, fmt.Errorf("%s: %w", attempt.Target.Callee, attempt.Err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ringSequential rings the targets one after the other
+func (f *FollowMe) ringSequential(ctx context.Context) (*FollowMeResult, []RingAttempt) {
+	var attempts []RingAttempt
+	for _, target := range f.targets {
+		if ctx.Err() != nil {
+			break
+		}
+		leg, attempt := f.ring(ctx, target)
+		attempts = append(attempts, attempt)
+		if leg != nil {
+			return leg, attempts
+		}
+	}
+	return nil, attempts
+}
+
+// ringSimultaneous rings all targets at once. The first to answer wins; the others are
+// cancelled, or hung up if they answered too.
+func (f *FollowMe) ringSimultaneous(ctx context.Context) (*FollowMeResult, []RingAttempt) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	attempts := make([]RingAttempt, len(f.targets))
+	var mu sync.Mutex
+	var winner *FollowMeResult
+	var wg sync.WaitGroup
+	for i, target := range f.targets {
+		wg.Add(1)
+		go func(i int, target RingTarget) {
+			defer wg.Done()
+			leg, attempt := f.ring(ctx, target)
+			mu.Lock()
+			defer mu.Unlock()
+			if leg != nil && winner != nil {
+				endLeg(leg.Conn, "answered_elsewhere")
+				leg = nil
+				attempt.Err = fmt.Errorf("%w: answered elsewhere", ErrCallNotAnswered)
+			}
+			if leg != nil {
+				winner = leg
+				cancel()
+			}
+			attempts[i] = attempt
+		}(i, target)
+	}
+	wg.Wait()
+	return winner, attempts
+}
+
+// ring rings a single target for its timeout
+func (f *FollowMe) ring(ctx context.Context, target RingTarget) (*FollowMeResult, RingAttempt) {
+	start := time.Now()
+	attempt := RingAttempt{Target: target}
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = f.options.Timeout
+	}
+	ringCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := f.options.Connect(ringCtx, f.options.Connection)
+	if err != nil {
+		attempt.Err, attempt.Duration = fmt.Errorf("failed to connect: %w", err), time.Since(start)
+		return nil, attempt
+	}
+	option := &CallOption{}
+	if f.options.Option != nil {
+		*option = *f.options.Option
+	}
+	option.Callee = target.Callee
+	answer, err := conn.InviteAndWaitAnswer(ringCtx, option)
+	attempt.Duration = time.Since(start)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			err = fmt.Errorf("%w: cancelled", ErrCallNotAnswered)
+		case ringCtx.Err() != nil:
+			err = fmt.Errorf("%w: no answer within %s", ErrCallNotAnswered, timeout)
+		}
+		attempt.Err = err
+		endLeg(conn, "cancelled")
+		return nil, attempt
+	}
+	return &FollowMeResult{Conn: conn, Target: target, Answer: answer}, attempt
+}
+
+// endLeg hangs up a leg that rings or was answered too late, then closes it
+func endLeg(conn *Connection, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := conn.HangupContext(ctx, reason, "system"); err != nil {
+		conn.log().Debug("failed to hang up leg", "error", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,123 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// followMeServer answers invites by callee: "busy" rejects, "away" never answers and
+// the others answer after the delay they are mapped to. Hangups are reported by callee.
+func followMeServer(t *testing.T, delays map[string]time.Duration) (*httptest.Server, <-chan string) {
+	hangups := make(chan string, 16)
+	server := newCommandServer(t, func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{}) {
+		var callee string
+		return func(cmd map[string]interface{}) {
+			switch cmd["command"] {
+			case "invite":
+				option, _ := cmd["option"].(map[string]interface{})
+				callee, _ = option["callee"].(string)
+				conn.WriteJSON(Event{Event: "ringing"})
+				switch delay, ok := delays[callee]; {
+				case callee == "busy":
+					conn.WriteJSON(Event{Event: "reject", Code: 486, Reason: "busy"})
+				case ok:
+					time.AfterFunc(delay, func() { conn.WriteJSON(Event{Event: "answer", SDP: "v=0"}) })
+				}
+			case "hangup":
+				hangups <- callee + ":" + cmd["reason"].(string)
+			}
+		}
+	})
+	return server, hangups
+}
+
+func TestFollowMeSequential(t *testing.T) {
+	server, hangups := followMeServer(t, map[string]time.Duration{"desk": 0})
+	followMe := NewFollowMe(NewClient(server.URL), []RingTarget{
+		{Callee: "busy"},
+		{Callee: "away", Timeout: 100 * time.Millisecond},
+		{Callee: "desk"},
+	}, nil)
+
+	result, err := followMe.Ring(context.Background())
+	if err != nil {
+		t.Fatalf("Ring failed: %v", err)
+	}
+	defer result.Conn.Close()
+	if result.Target.Callee != "desk" || result.Answer == nil || len(result.Attempts) != 3 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	var failed *CallFailedError
+	if !errors.As(result.Attempts[0].Err, &failed) || failed.Event.Code != 486 {
+		t.Errorf("Expected the busy target to be rejected, got %v", result.Attempts[0].Err)
+	}
+	if err := result.Attempts[1].Err; !errors.Is(err, ErrCallNotAnswered) || result.Attempts[1].Duration < 100*time.Millisecond {
+		t.Errorf("Expected the away target to time out, got %v after %s", err, result.Attempts[1].Duration)
+	}
+	if result.Attempts[2].Err != nil {
+		t.Errorf("Expected the desk target to answer, got %v", result.Attempts[2].Err)
+	}
+	for _, want := range []string{"busy:cancelled", "away:cancelled"} {
+		select {
+		case got := <-hangups:
+			if got != want {
+				t.Errorf("Expected hangup %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected hangup %s", want)
+		}
+	}
+}
+
+func TestFollowMeSimultaneous(t *testing.T) {
+	server, hangups := followMeServer(t, map[string]time.Duration{"mobile": 20 * time.Millisecond, "desk": time.Second})
+	followMe := NewFollowMe(NewClient(server.URL), []RingTarget{{Callee: "desk"}, {Callee: "away"}, {Callee: "mobile"}},
+		&FollowMeOptions{Strategy: RingSimultaneous, Timeout: 5 * time.Second})
+
+	start := time.Now()
+	result, err := followMe.Ring(context.Background())
+	if err != nil {
+		t.Fatalf("Ring failed: %v", err)
+	}
+	defer result.Conn.Close()
+	if result.Target.Callee != "mobile" || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Expected the mobile to win quickly, got %s after %s", result.Target.Callee, time.Since(start))
+	}
+	for _, i := range []int{0, 1} {
+		if err := result.Attempts[i].Err; !errors.Is(err, ErrCallNotAnswered) {
+			t.Errorf("Expected %s to be cancelled, got %v", result.Attempts[i].Target.Callee, err)
+		}
+	}
+	cancelled := map[string]bool{}
+	for len(cancelled) < 2 {
+		select {
+		case got := <-hangups:
+			cancelled[got] = true
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the other legs to be cancelled, got %v", cancelled)
+		}
+	}
+	if !cancelled["desk:cancelled"] || !cancelled["away:cancelled"] {
+		t.Errorf("Expected desk and away to be cancelled, got %v", cancelled)
+	}
+}
+
+func TestFollowMeNoAnswer(t *testing.T) {
+	server, _ := followMeServer(t, nil)
+	followMe := NewFollowMe(NewClient(server.URL), []RingTarget{{Callee: "busy"}, {Callee: "away"}},
+		&FollowMeOptions{Timeout: 50 * time.Millisecond})
+
+	_, err := followMe.Ring(context.Background())
+	if !errors.Is(err, ErrNoTargetAnswered) || !errors.Is(err, ErrCallNotAnswered) {
+		t.Fatalf("Expected ErrNoTargetAnswered, got %v", err)
+	}
+	if _, err := NewFollowMe(NewClient(server.URL), nil, nil).Ring(context.Background()); err == nil {
+		t.Error("Expected a follow-me without targets to fail")
+	}
+}
@@ -0,0 +1,162 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// VoiceGateway adapts a non-SIP voice source, such as a WhatsApp or Telegram
+// voice call, to a RustPBX connection. Audio frames are exchanged in the codec
+// negotiated for the call.
+type VoiceGateway interface {
+	// ReadAudio returns the next frame of caller audio; io.EOF ends the bridge
+	ReadAudio(ctx context.Context) ([]byte, error)
+	// WriteAudio delivers a frame of call audio, such as TTS output, to the caller. Frames
+	// are queued for it, and dropped while a second of audio is waiting.
+	WriteAudio(ctx context.Context, frame []byte) error
+	// Close ends the gateway side of the call
+	Close() error
+}
+
+// gatewayQueueFrames bounds the call audio queued for a gateway, a second of 20ms frames
+const gatewayQueueFrames = 50
+
+// setAudioHandler sets the handler for binary audio frames received from the server
+func (c *Connection) setAudioHandler(handler func(frame []byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.audioHandler = handler
+}
+
+// handleAudio processes incoming binary audio frames
+func (c *Connection) handleAudio(frame []byte) {
+	c.mu.RLock()
+	handler := c.audioHandler
+	frameHandler := c.frameHandler
+	c.mu.RUnlock()
+	c.observeMediaFrame(MediaInbound, frame)
+
+	if handler != nil {
+		handler(frame)
+	}
+	if frameHandler != nil {
+		format := c.AudioFormat()
+		frameHandler(&AudioFrame{Data: frame, Format: format, Duration: format.Duration(len(frame))})
+	}
+}
+
+// BridgeGateway pumps audio between a voice gateway and a connection until the
+// gateway ends, the connection fails or ctx is cancelled. The gateway is closed on return.
+func BridgeGateway(ctx context.Context, conn *Connection, gateway VoiceGateway) error {
+	defer gateway.Close()
+	ctx, cancel := context.WithCancel(ctx)
+
+	// The read loop hands the frames over so a slow gateway does not hold up events
+	frames := make(chan []byte, gatewayQueueFrames)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for {
+			select {
+			case frame := <-frames:
+				if err := gateway.WriteAudio(ctx, frame); err != nil && ctx.Err() == nil {
+					conn.handleError(fmt.Errorf("gateway write error: %w", err))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		<-written
+	}()
+
+	conn.setAudioHandler(func(frame []byte) {
+		select {
+		case frames <- frame:
+		default:
+			conn.log().Warn("gateway queue full, dropping call audio", "bytes", len(frame))
+		}
+	})
+	defer conn.setAudioHandler(nil)
+
+	for {
+		frame, err := gateway.ReadAudio(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("gateway read error: %w", err)
+		}
+		if err := conn.WriteAudioContext(ctx, frame); err != nil {
+			return err
+		}
+	}
+}
+
+// MemoryGateway is an in-memory VoiceGateway, useful as a reference
+// implementation and for tests
+type MemoryGateway struct {
+	in   chan []byte
+	out  chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+// NewMemoryGateway creates an in-memory gateway buffering up to size frames in each direction
+func NewMemoryGateway(size int) *MemoryGateway {
+	return &MemoryGateway{
+		in:   make(chan []byte, size),
+		out:  make(chan []byte, size),
+		done: make(chan struct{}),
+	}
+}
+
+// PushAudio queues a frame of caller audio to be sent to the call
+func (g *MemoryGateway) PushAudio(frame []byte) error {
+	select {
+	case g.in <- frame:
+		return nil
+	case <-g.done:
+		return io.ErrClosedPipe
+	}
+}
+
+// Audio returns the frames of call audio delivered to the caller
+func (g *MemoryGateway) Audio() <-chan []byte {
+	return g.out
+}
+
+// ReadAudio implements VoiceGateway
+func (g *MemoryGateway) ReadAudio(ctx context.Context) ([]byte, error) {
+	select {
+	case frame := <-g.in:
+		return frame, nil
+	case <-g.done:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WriteAudio implements VoiceGateway
+func (g *MemoryGateway) WriteAudio(ctx context.Context, frame []byte) error {
+	select {
+	case g.out <- frame:
+		return nil
+	case <-g.done:
+		return io.ErrClosedPipe
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close implements VoiceGateway and ends the bridge
+func (g *MemoryGateway) Close() error {
+	g.once.Do(func() { close(g.done) })
+	return nil
+}
@@ -0,0 +1,104 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBridgeGatewayEcho(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, data)
+		}
+	}))
+	defer server.Close()
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	gateway := NewMemoryGateway(4)
+	bridged := make(chan error, 1)
+	go func() { bridged <- BridgeGateway(context.Background(), conn, gateway) }()
+
+	frame := []byte{0x01, 0x02, 0x03, 0x04}
+	if err := gateway.PushAudio(frame); err != nil {
+		t.Fatalf("PushAudio failed: %v", err)
+	}
+
+	select {
+	case received := <-gateway.Audio():
+		if !bytes.Equal(received, frame) {
+			t.Errorf("Expected echoed frame %v, got %v", frame, received)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected audio from the call")
+	}
+
+	gateway.Close()
+	if err := <-bridged; err != nil {
+		t.Errorf("Expected bridge to end cleanly, got %v", err)
+	}
+}
+
+// blockedGateway never delivers call audio to the caller
+type blockedGateway struct {
+	*MemoryGateway
+}
+
+func (g blockedGateway) WriteAudio(ctx context.Context, frame []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBridgeGatewaySlowWriter(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		for i := 0; i < 3; i++ {
+			conn.WriteMessage(websocket.BinaryMessage, make([]byte, 640))
+		}
+		conn.WriteJSON(Event{Event: "dtmf", Digit: "1"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	digits := make(chan *DTMFEvent, 1)
+	conn.OnDTMF(func(event *DTMFEvent) { digits <- event })
+
+	gateway := blockedGateway{NewMemoryGateway(1)}
+	bridged := make(chan error, 1)
+	go func() { bridged <- BridgeGateway(context.Background(), conn, gateway) }()
+	time.Sleep(20 * time.Millisecond)
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case <-digits:
+	case <-time.After(time.Second):
+		t.Fatal("Expected events while the gateway is not writing")
+	}
+	gateway.Close()
+	if err := <-bridged; err != nil {
+		t.Errorf("Expected bridge to end cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GuardrailVerdict is the decision of a guardrail about a response
+type GuardrailVerdict int
+
+const (
+	GuardrailAllow GuardrailVerdict = iota
+	GuardrailRewrite
+	GuardrailBlock
+)
+
+// GuardrailResult represents the outcome of checking a response
+type GuardrailResult struct {
+	Verdict GuardrailVerdict
+	// Text replaces the response when Verdict is GuardrailRewrite
+	Text   string
+	Reason string
+}
+
+// Guardrail checks an LLM response before it is spoken
+type Guardrail interface {
+	Check(ctx context.Context, text string) (GuardrailResult, error)
+}
+
+// GuardrailFunc adapts a function to the Guardrail interface
+type GuardrailFunc func(ctx context.Context, text string) (GuardrailResult, error)
+
+// Check calls f(ctx, text)
+func (f GuardrailFunc) Check(ctx context.Context, text string) (GuardrailResult, error) {
+	return f(ctx, text)
+}
+
+// ProfanityFilter removes, replaces or blocks listed words in responses. Masking them
+// with symbols would be read aloud by TTS, so by default the words are dropped. Words
+// must not change once the filter is in use.
+type ProfanityFilter struct {
+	Words []string
+	// Replacement is spoken instead of a listed word; the word is dropped when empty
+	Replacement string
+	// Block rejects the response instead of rewriting it
+	Block bool
+
+	once    sync.Once
+	pattern *regexp.Regexp
+}
+
+// compile builds a single pattern matching any listed word, longest first
+func (f *ProfanityFilter) compile() {
+	words := make([]string, 0, len(f.Words))
+	for _, word := range f.Words {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return
+	}
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	f.pattern = regexp.MustCompile(`(?i)(?:` + strings.Join(words, "|") + `)`)
+}
+
+// matches returns the spans of the listed words in text. Words of scripts written without
+// spaces, such as Chinese, match anywhere; the others only as whole words, bounded by
+// characters that are not letters or digits in any script, which \b cannot express
+// as it only knows ASCII.
+func (f *ProfanityFilter) matches(text string) [][]int {
+	var spans [][]int
+	for _, span := range f.pattern.FindAllStringIndex(text, -1) {
+		if unspacedScript(text[span[0]:span[1]]) {
+			spans = append(spans, span)
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(text[:span[0]])
+		after, _ := utf8.DecodeRuneInString(text[span[1]:])
+		if !wordRune(before) && !wordRune(after) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// unspacedScript reports whether a word contains characters of a script written without
+// spaces between words
+func unspacedScript(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+			return true
+		}
+	}
+	return false
+}
+
+// wordRune reports whether r is part of a word; utf8.RuneError at the ends of the text is not
+func wordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+// Check implements Guardrail
+func (f *ProfanityFilter) Check(ctx context.Context, text string) (GuardrailResult, error) {
+	f.once.Do(f.compile)
+	if f.pattern == nil {
+		return GuardrailResult{Verdict: GuardrailAllow}, nil
+	}
+	spans := f.matches(text)
+	if len(spans) == 0 {
+		return GuardrailResult{Verdict: GuardrailAllow}, nil
+	}
+	if f.Block {
+		return GuardrailResult{Verdict: GuardrailBlock, Reason: "profanity"}, nil
+	}
+
+	var rewritten strings.Builder
+	last := 0
+	for _, span := range spans {
+		if f.Replacement == "" {
+			// Dropping a word drops the space before it too
+			rewritten.WriteString(strings.TrimRightFunc(text[last:span[0]], unicode.IsSpace))
+		} else {
+			rewritten.WriteString(text[last:span[0]])
+			rewritten.WriteString(f.Replacement)
+		}
+		last = span[1]
+	}
+	rewritten.WriteString(text[last:])
+	return GuardrailResult{Verdict: GuardrailRewrite, Text: strings.TrimSpace(rewritten.String()), Reason: "profanity"}, nil
+}
+
+// RegexGuardrail blocks responses matching a policy pattern, or rewrites the matches
+// when Replacement is set
+type RegexGuardrail struct {
+	Pattern     *regexp.Regexp
+	Replacement *string
+	Reason      string
+}
+
+// Check implements Guardrail
+func (g *RegexGuardrail) Check(ctx context.Context, text string) (GuardrailResult, error) {
+	if !g.Pattern.MatchString(text) {
+		return GuardrailResult{Verdict: GuardrailAllow}, nil
+	}
+	if g.Replacement != nil {
+		return GuardrailResult{
+			Verdict: GuardrailRewrite,
+			Text:    g.Pattern.ReplaceAllString(text, *g.Replacement),
+			Reason:  g.Reason,
+		}, nil
+	}
+	return GuardrailResult{Verdict: GuardrailBlock, Reason: g.Reason}, nil
+}
+
+// ModerationGuardrail blocks responses flagged by an OpenAI-compatible moderation
+// endpoint reached through the RustPBX LLM proxy
+type ModerationGuardrail struct {
+	Client *Client
+	Model  string
+}
+
+// moderationResponse represents an OpenAI-compatible moderation response
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Check implements Guardrail
+func (g *ModerationGuardrail) Check(ctx context.Context, text string) (GuardrailResult, error) {
+	body, err := json.Marshal(map[string]string{"model": g.Model, "input": text})
+	if err != nil {
+		return GuardrailResult{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := g.Client.ProxyLLMRequest(ctx, "moderations", "POST", bytes.NewReader(body), nil)
+	if err != nil {
+		return GuardrailResult{}, fmt.Errorf("failed to call moderation API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return GuardrailResult{}, fmt.Errorf("moderation request failed: %w", newAPIError(resp))
+	}
+
+	var result moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return GuardrailResult{}, fmt.Errorf("failed to parse moderation response: %w", err)
+	}
+
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		var categories []string
+		for category, flagged := range r.Categories {
+			if flagged {
+				categories = append(categories, category)
+			}
+		}
+		return GuardrailResult{Verdict: GuardrailBlock, Reason: "moderation: " + strings.Join(categories, ",")}, nil
+	}
+	return GuardrailResult{Verdict: GuardrailAllow}, nil
+}
+
+// GuardrailChain runs guardrails in order. Rewrites feed into the next guardrail;
+// a block stops the chain and the Fallback text is spoken instead.
+type GuardrailChain struct {
+	Guardrails []Guardrail
+	Fallback   string
+}
+
+// Apply checks a response and returns the text to speak. A guardrail error blocks the response.
+func (c *GuardrailChain) Apply(ctx context.Context, text string) (string, GuardrailResult, error) {
+	fallback := c.Fallback
+	if fallback == "" {
+		fallback = "I'm sorry, I can't help with that."
+	}
+
+	result := GuardrailResult{Verdict: GuardrailAllow}
+	for _, guardrail := range c.Guardrails {
+		r, err := guardrail.Check(ctx, text)
+		if err != nil {
+			return fallback, GuardrailResult{Verdict: GuardrailBlock, Reason: "guardrail error"}, err
+		}
+		switch r.Verdict {
+		case GuardrailBlock:
+			return fallback, r, nil
+		case GuardrailRewrite:
+			text = r.Text
+			result = r
+		}
+	}
+	return text, result, nil
+}
+
+// applyGuardrails filters a response through the chain, emitting audit events for
+// blocked and rewritten content
+func (c *Connection) applyGuardrails(ctx context.Context, chain *GuardrailChain, text string) (string, GuardrailVerdict) {
+	filtered, result, err := chain.Apply(ctx, text)
+	if err != nil {
+		c.handleError(fmt.Errorf("guardrail error: %w", err))
+	}
+	if result.Verdict == GuardrailAllow {
+		return filtered, GuardrailAllow
+	}
+
+	name := "guardrailRewritten"
+	if result.Verdict == GuardrailBlock {
+		name = "guardrailBlocked"
+	}
+	data, _ := json.Marshal(map[string]string{
+		"original": text,
+		"spoken":   filtered,
+	})
+	c.dispatch(&Event{
+		Event:     name,
+		Timestamp: time.Now().UnixMilli(),
+		Reason:    result.Reason,
+		Text:      filtered,
+		Data:      data,
+	})
+	return filtered, result.Verdict
+}
@@ -0,0 +1,102 @@
+package rustpbx
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestGuardrailChain(t *testing.T) {
+	redacted := "[redacted]"
+	chain := &GuardrailChain{
+		Guardrails: []Guardrail{
+			&ProfanityFilter{Words: []string{"darn", "heck"}, Replacement: "gosh"},
+			&RegexGuardrail{Pattern: regexp.MustCompile(`\d{16}`), Replacement: &redacted, Reason: "card number"},
+			&RegexGuardrail{Pattern: regexp.MustCompile(`(?i)guaranteed returns`), Reason: "financial advice"},
+		},
+		Fallback: "Let me connect you with someone who can help.",
+	}
+
+	text, result, err := chain.Apply(context.Background(), "Darn, your card 4111111111111111 is on file")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if text != "gosh, your card [redacted] is on file" || result.Verdict != GuardrailRewrite {
+		t.Errorf("Expected rewritten response, got '%s' (%v)", text, result.Verdict)
+	}
+
+	text, result, _ = chain.Apply(context.Background(), "This fund has guaranteed returns")
+	if text != chain.Fallback || result.Verdict != GuardrailBlock || result.Reason != "financial advice" {
+		t.Errorf("Expected blocked response replaced by fallback, got '%s' (%+v)", text, result)
+	}
+}
+
+func TestProfanityFilter(t *testing.T) {
+	filter := &ProfanityFilter{Words: []string{"darn", "heck", "傻瓜", "café"}}
+	for text, expected := range map[string]string{
+		"That is darn good":        "That is good",
+		"Darn it, what the heck.":  "it, what the.",
+		"Nothing to see here":      "Nothing to see here",
+		"Darned heckler":           "Darned heckler",
+		"你是傻瓜":                     "你是",
+		"你是 傻瓜 吗":                  "你是 吗",
+		"un café noir":             "un noir",
+		"Un CAFÉ, s'il vous plaît": "Un, s'il vous plaît",
+		"les cafés":                "les cafés",
+		"décafé":                   "décafé",
+	} {
+		result, err := filter.Check(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		rewritten := text
+		if result.Verdict == GuardrailRewrite {
+			rewritten = result.Text
+		}
+		if rewritten != expected {
+			t.Errorf("Expected %q to become %q, got %q (%v)", text, expected, rewritten, result.Verdict)
+		}
+	}
+
+	result, _ := (&ProfanityFilter{Words: []string{"darn"}, Block: true}).Check(context.Background(), "darn")
+	if result.Verdict != GuardrailBlock {
+		t.Errorf("Expected the response to be blocked, got %+v", result)
+	}
+}
+
+func TestAssistantAppliesGuardrails(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	blocked := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "guardrailBlocked" {
+			blocked <- event
+		}
+	})
+
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return "forbidden content", nil
+		}),
+		Patience: &PatiencePolicy{Adaptive: true},
+		Guardrails: &GuardrailChain{
+			Guardrails: []Guardrail{GuardrailFunc(func(ctx context.Context, text string) (GuardrailResult, error) {
+				return GuardrailResult{Verdict: GuardrailBlock, Reason: "custom"}, nil
+			})},
+			Fallback: "Sorry.",
+		},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "say something bad"})
+	if text := nextTTS(t, commands); text != "Sorry." {
+		t.Errorf("Expected fallback to be spoken, got '%s'", text)
+	}
+	if event := <-blocked; event.Reason != "custom" {
+		t.Errorf("Expected audit event with reason 'custom', got '%s'", event.Reason)
+	}
+}
@@ -0,0 +1,130 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handoffSummaryPrompt asks the LLM to summarize a conversation for a human agent
+const handoffSummaryPrompt = "Summarize this call for the human agent taking it over in two or three sentences. " +
+	"State who the caller is, what they want and what has been tried. Do not address the caller."
+
+// HandoffContext represents what a human agent needs to take over a call
+// without the caller repeating themselves
+type HandoffContext struct {
+	CallID  string `json:"callId"`
+	Summary string `json:"summary"`
+	Intent  string `json:"intent,omitempty"`
+	// Slots holds structured details collected so far, e.g. an account number
+	Slots      map[string]string `json:"slots,omitempty"`
+	Transcript []ChatMessage     `json:"transcript,omitempty"`
+	Timestamp  int64             `json:"timestamp"`
+}
+
+// HandoffOptions represents warm handoff configuration
+type HandoffOptions struct {
+	Refer *ReferOption
+	// WebhookURL receives the handoff context as JSON before the transfer, e.g. for
+	// an agent desktop screen pop. A failed delivery aborts the handoff.
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+// WarmHandoff transfers the call to a human agent together with the conversation
+// context. The context is posted to the webhook before the transfer. RustPBX's REFER
+// carries no custom SIP headers, so without a webhook the agent gets no context.
+func (c *Connection) WarmHandoff(ctx context.Context, target string, handoff *HandoffContext, options *HandoffOptions) error {
+	if options == nil {
+		options = &HandoffOptions{}
+	}
+	if handoff == nil {
+		handoff = &HandoffContext{}
+	}
+	// The caller's context is left as it is
+	delivered := *handoff
+	if delivered.CallID == "" && c.callContext != nil {
+		delivered.CallID = c.callContext.CallID
+	}
+	if delivered.Timestamp == 0 {
+		delivered.Timestamp = time.Now().UnixMilli()
+	}
+
+	if options.WebhookURL != "" {
+		if err := c.postHandoff(ctx, options, &delivered); err != nil {
+			return err
+		}
+	}
+
+	return c.ReferContext(ctx, target, options.Refer)
+}
+
+// postHandoff delivers the handoff context to the webhook
+func (c *Connection) postHandoff(ctx context.Context, options *HandoffOptions, handoff *HandoffContext) error {
+	body, err := json.Marshal(handoff)
+	if err != nil {
+		return fmt.Errorf("failed to marshal handoff context: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", options.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create handoff webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.callContext.applyHeaders(req.Header)
+
+	httpClient := options.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver handoff context: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("handoff webhook failed: %w", newAPIError(resp))
+	}
+	return nil
+}
+
+// Handoff summarizes the conversation with the assistant's LLM and transfers the
+// call to a human agent with a warm handoff
+func (a *Assistant) Handoff(ctx context.Context, target, intent string, slots map[string]string, options *HandoffOptions) error {
+	a.mu.Lock()
+	if a.turn != nil {
+		a.turn.stop()
+		a.turn = nil
+	}
+	a.mu.Unlock()
+
+	transcript := a.History()
+	var conversation []ChatMessage
+	for _, message := range transcript {
+		if message.Role != "system" {
+			conversation = append(conversation, message)
+		}
+	}
+
+	summary, err := a.options.LLM.Complete(WithCallContext(ctx, a.conn.callContext), append([]ChatMessage{{
+		Role:    "system",
+		Content: handoffSummaryPrompt,
+	}}, conversation...))
+	if err != nil {
+		// The agent still gets the intent and transcript
+		a.conn.handleError(fmt.Errorf("failed to summarize conversation: %w", err))
+		summary = ""
+	}
+
+	return a.conn.WarmHandoff(ctx, target, &HandoffContext{
+		Summary:    strings.TrimSpace(summary),
+		Intent:     intent,
+		Slots:      slots,
+		Transcript: conversation,
+	}, options)
+}
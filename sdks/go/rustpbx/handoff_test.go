@@ -0,0 +1,106 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAssistantHandoff(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{SessionID: "call-42"})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	delivered := make(chan HandoffContext, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var handoff HandoffContext
+		json.NewDecoder(r.Body).Decode(&handoff)
+		delivered <- handoff
+	}))
+	defer webhook.Close()
+
+	assistant := NewAssistant(conn, &AssistantOptions{
+		SystemPrompt: "You are a bank assistant.",
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			if messages[0].Content == handoffSummaryPrompt {
+				return "Caller disputes a card charge.", nil
+			}
+			return "Let me get someone to help.", nil
+		}),
+		Patience: &PatiencePolicy{Adaptive: true},
+	})
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "I don't recognize a charge"})
+	nextTTS(t, commands)
+
+	err = assistant.Handoff(context.Background(), "sip:agents@example.com", "dispute",
+		map[string]string{"last4": "1234"}, &HandoffOptions{WebhookURL: webhook.URL})
+	if err != nil {
+		t.Fatalf("Handoff failed: %v", err)
+	}
+
+	handoff := <-delivered
+	if handoff.CallID != "call-42" || handoff.Summary != "Caller disputes a card charge." || handoff.Slots["last4"] != "1234" {
+		t.Errorf("Unexpected handoff context: %+v", handoff)
+	}
+	if len(handoff.Transcript) != 2 || handoff.Transcript[0].Role != "user" {
+		t.Errorf("Expected transcript without system prompt, got %+v", handoff.Transcript)
+	}
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case cmd := <-commands:
+			if cmd["command"] != "refer" {
+				continue
+			}
+			if cmd["target"] != "sip:agents@example.com" || cmd["options"] != nil {
+				t.Errorf("Expected a plain refer to the agents, got %v", cmd)
+			}
+			return
+		case <-timeout:
+			t.Fatal("Expected a refer command")
+		}
+	}
+}
+
+func TestWarmHandoffContext(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{SessionID: "call-7"})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	delivered := make(chan HandoffContext, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var handoff HandoffContext
+		json.NewDecoder(r.Body).Decode(&handoff)
+		delivered <- handoff
+	}))
+	defer webhook.Close()
+	options := &HandoffOptions{WebhookURL: webhook.URL}
+
+	if err := conn.WarmHandoff(context.Background(), "sip:agents@example.com", nil, options); err != nil {
+		t.Fatalf("WarmHandoff without context failed: %v", err)
+	}
+	if handoff := <-delivered; handoff.CallID != "call-7" || handoff.Timestamp == 0 {
+		t.Errorf("Expected the call ID and a timestamp, got %+v", handoff)
+	}
+
+	handoff := &HandoffContext{Summary: "Billing question"}
+	if err := conn.WarmHandoff(context.Background(), "sip:agents@example.com", handoff, options); err != nil {
+		t.Fatalf("WarmHandoff failed: %v", err)
+	}
+	if handoff.CallID != "" || handoff.Timestamp != 0 {
+		t.Errorf("Expected the caller's context to be left as it is, got %+v", handoff)
+	}
+	if delivered := <-delivered; delivered.CallID != "call-7" || delivered.Summary != "Billing question" {
+		t.Errorf("Unexpected handoff context: %+v", delivered)
+	}
+}
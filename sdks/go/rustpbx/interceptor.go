@@ -0,0 +1,107 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// CommandSender sends a command to the server. Commands are the command structs of this
+// package, such as TTSCommand, or the maps passed to SendRawCommand.
+type CommandSender func(ctx context.Context, command interface{}) error
+
+// CommandInterceptor wraps the sending of commands, e.g. to log, measure, redact or retry
+// them; it calls next to send the command on
+type CommandInterceptor func(next CommandSender) CommandSender
+
+// EventInterceptor wraps the delivery of events to the handlers; it calls next to deliver
+// the event, or drops it by not calling next
+type EventInterceptor func(next EventHandler) EventHandler
+
+// UseCommandInterceptor adds an interceptor around every command sent. Interceptors run
+// in the order they were added, the first one outermost.
+func (c *Connection) UseCommandInterceptor(interceptor CommandInterceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Copy on write, so the chain can be built without holding the lock
+	c.commandInterceptors = append(c.commandInterceptors[:len(c.commandInterceptors):len(c.commandInterceptors)], interceptor)
+}
+
+// UseEventInterceptor adds an interceptor around the delivery of every event, including
+// the events raised by the SDK. Interceptors run in the order they were added, the first
+// one outermost, on the goroutine running the handlers.
+func (c *Connection) UseEventInterceptor(interceptor EventInterceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.eventInterceptors = append(c.eventInterceptors[:len(c.eventInterceptors):len(c.eventInterceptors)], interceptor)
+}
+
+// commandSender returns the command interceptor chain ending in writeCommand
+func (c *Connection) commandSender() CommandSender {
+	c.mu.RLock()
+	interceptors := c.commandInterceptors
+	c.mu.RUnlock()
+
+	sender := CommandSender(c.writeCommand)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		sender = interceptors[i](sender)
+	}
+	return sender
+}
+
+// eventReceiver returns the event interceptor chain ending in deliverToHandlers
+func (c *Connection) eventReceiver() EventHandler {
+	c.mu.RLock()
+	interceptors := c.eventInterceptors
+	c.mu.RUnlock()
+
+	receiver := EventHandler(c.deliverToHandlers)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		receiver = interceptors[i](receiver)
+	}
+	return receiver
+}
+
+// writeCommand encodes and writes a command
+func (c *Connection) writeCommand(ctx context.Context, command interface{}) error {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command: %w", err)
+	}
+
+	if c.validation != ValidationOff {
+		if err := ValidateCommand(data); err != nil {
+			if c.validation == ValidationStrict {
+				return fmt.Errorf("failed to send command: %w", err)
+			}
+			c.reportProtocolViolation(err)
+		}
+	}
+
+	logger := c.log()
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		logger.DebugContext(ctx, "sending command", "command", redactJSON(data))
+	}
+	start := time.Now()
+	if c.writes != nil {
+		err = c.enqueueWrite(ctx, data, urgentCommand(command))
+	} else {
+		err = c.writeMessageContext(ctx, websocket.TextMessage, data)
+	}
+	c.countCommand(data, time.Since(start), err)
+	if err != nil {
+		logger.WarnContext(ctx, "command failed", "error", err)
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	if c.replay != nil {
+		c.replay.record(ctx, data)
+	}
+	if c.reconnect != nil {
+		c.trackSetup(data)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCommandInterceptors(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewConnection(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	var order []string
+	conn.UseCommandInterceptor(func(next CommandSender) CommandSender {
+		return func(ctx context.Context, command interface{}) error {
+			order = append(order, "log")
+			return next(ctx, command)
+		}
+	})
+	conn.UseCommandInterceptor(func(next CommandSender) CommandSender {
+		return func(ctx context.Context, command interface{}) error {
+			order = append(order, "redact")
+			if tts, ok := command.(TTSCommand); ok {
+				tts.Text = strings.ReplaceAll(tts.Text, "4111", "****")
+				command = tts
+			}
+			return next(ctx, command)
+		}
+	})
+
+	if err := conn.TTSSimple("card 4111"); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	select {
+	case cmd := <-commands:
+		if cmd["text"] != "card ****" {
+			t.Errorf("Expected the redacted text, got %v", cmd["text"])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the command to be sent")
+	}
+	if strings.Join(order, ",") != "log,redact" {
+		t.Errorf("Expected the first interceptor outermost, got %v", order)
+	}
+
+	blocked := errors.New("blocked")
+	conn.UseCommandInterceptor(func(next CommandSender) CommandSender {
+		return func(ctx context.Context, command interface{}) error { return blocked }
+	})
+	if err := conn.HangupSimple(); !errors.Is(err, blocked) {
+		t.Errorf("Expected the interceptor error, got %v", err)
+	}
+}
+
+func TestEventInterceptors(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(map[string]interface{}{"event": "dtmf", "digit": "1"})
+		conn.WriteJSON(map[string]interface{}{"event": "asrFinal", "text": "my pin is 1234"})
+	})
+	conn, err := NewConnection(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	var mu sync.Mutex
+	var seen []string
+	conn.UseEventInterceptor(func(next EventHandler) EventHandler {
+		return func(event *Event) {
+			if event.Event == "dtmf" {
+				// Drop the event
+				return
+			}
+			next(event)
+		}
+	})
+	conn.UseEventInterceptor(func(next EventHandler) EventHandler {
+		return func(event *Event) {
+			event.Text = strings.ReplaceAll(event.Text, "1234", "****")
+			next(event)
+		}
+	})
+	received := make(chan *Event, 2)
+	conn.OnEvent(func(event *Event) {
+		mu.Lock()
+		seen = append(seen, event.Event)
+		mu.Unlock()
+		received <- event
+	})
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case event := <-received:
+		if event.Event != "asrFinal" || event.Text != "my pin is ****" {
+			t.Errorf("Expected the redacted transcript, got %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the transcript to be delivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 1 {
+		t.Errorf("Expected the DTMF event to be dropped, got %v", seen)
+	}
+}
@@ -0,0 +1,243 @@
+package rustpbx
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JWTSource issues JWTs and reports when they expire
+type JWTSource interface {
+	FetchToken(ctx context.Context) (token string, expires time.Time, err error)
+}
+
+// JWTSigner mints JWTs locally with a signing key shared with the auth gateway
+type JWTSigner struct {
+	// Key signs the tokens: a []byte secret for HS256, an *rsa.PrivateKey for RS256 or an
+	// *ecdsa.PrivateKey on P-256 for ES256
+	Key interface{}
+	// KeyID is set as the "kid" header when not empty
+	KeyID    string
+	Issuer   string
+	Subject  string
+	Audience string
+	// TTL is the token lifetime; 5 minutes when zero
+	TTL time.Duration
+	// Claims are added to the registered claims
+	Claims map[string]interface{}
+}
+
+// FetchToken implements JWTSource
+func (s *JWTSigner) FetchToken(ctx context.Context) (string, time.Time, error) {
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+
+	claims := make(map[string]interface{}, len(s.Claims)+6)
+	for key, value := range s.Claims {
+		claims[key] = value
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = expires.Unix()
+	claims["jti"] = uuid.New().String()
+	for key, value := range map[string]string{"iss": s.Issuer, "sub": s.Subject, "aud": s.Audience} {
+		if value != "" {
+			claims[key] = value
+		}
+	}
+
+	token, err := s.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expires, nil
+}
+
+// sign encodes and signs the claims
+func (s *JWTSigner) sign(claims map[string]interface{}) (string, error) {
+	header := map[string]string{"typ": "JWT"}
+	switch key := s.Key.(type) {
+	case []byte:
+		header["alg"] = "HS256"
+	case *rsa.PrivateKey:
+		header["alg"] = "RS256"
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported ECDSA curve %s for ES256, expected P-256", key.Curve.Params().Name)
+		}
+		header["alg"] = "ES256"
+	default:
+		return "", fmt.Errorf("unsupported JWT signing key %T", s.Key)
+	}
+	if s.KeyID != "" {
+		header["kid"] = s.KeyID
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JWT claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := s.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, signErr := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err = signErr; err == nil {
+			// ES256 signatures are r and s as fixed-size big-endian integers
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// JWTEndpoint fetches JWTs from a token endpoint, such as an OAuth client credentials
+// endpoint. The response must be JSON with the token in "access_token" or "token" and
+// its lifetime in seconds in "expires_in"; tokens without a lifetime are used for 5 minutes.
+type JWTEndpoint struct {
+	URL string
+	// Form is posted as application/x-www-form-urlencoded, e.g. grant_type and client_id;
+	// the request is a GET when empty
+	Form url.Values
+	// Headers are set on the request, e.g. for basic authentication
+	Headers map[string]string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// FetchToken implements JWTSource
+func (e *JWTEndpoint) FetchToken(ctx context.Context) (string, time.Time, error) {
+	method := "GET"
+	var body io.Reader
+	if len(e.Form) > 0 {
+		method = "POST"
+		body = strings.NewReader(e.Form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.URL, body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to fetch token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("failed to fetch token: %w", newAPIError(resp))
+	}
+
+	var result struct {
+		AccessToken string  `json:"access_token"`
+		Token       string  `json:"token"`
+		ExpiresIn   float64 `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	token := result.AccessToken
+	if token == "" {
+		token = result.Token
+	}
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("token response has no token")
+	}
+	lifetime := 5 * time.Minute
+	if result.ExpiresIn > 0 {
+		lifetime = time.Duration(result.ExpiresIn * float64(time.Second))
+	}
+	return token, time.Now().Add(lifetime), nil
+}
+
+// JWTProvider is a TokenProvider serving JWTs from a source; set it as the TokenProvider
+// of ClientOptions. Tokens are cached and refreshed before they expire, so dials,
+// reconnects and requests of long-lived clients always present a valid token.
+type JWTProvider struct {
+	source JWTSource
+	// refreshBefore is how long before expiry tokens are refreshed
+	refreshBefore time.Duration
+
+	mu        sync.Mutex
+	token     string
+	expires   time.Time
+	refreshAt time.Time
+}
+
+// NewJWTProvider creates a provider refreshing tokens refreshBefore their expiry; 30s when
+// zero. Tokens living shorter than twice refreshBefore are refreshed halfway.
+func NewJWTProvider(source JWTSource, refreshBefore time.Duration) *JWTProvider {
+	if refreshBefore <= 0 {
+		refreshBefore = 30 * time.Second
+	}
+	return &JWTProvider{source: source, refreshBefore: refreshBefore}
+}
+
+// Token implements TokenProvider. A token that cannot be refreshed is served until it expires.
+func (p *JWTProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.token != "" && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+	token, expires, err := p.source.FetchToken(ctx)
+	if err != nil {
+		if p.token != "" && now.Before(p.expires) {
+			return p.token, nil
+		}
+		return "", err
+	}
+	p.token, p.expires = token, expires
+	p.refreshAt = expires.Add(-p.refreshBefore)
+	if lifetime := expires.Sub(now); lifetime < 2*p.refreshBefore {
+		// Refresh short-lived tokens halfway rather than on every use
+		p.refreshAt = now.Add(lifetime / 2)
+	}
+	return token, nil
+}
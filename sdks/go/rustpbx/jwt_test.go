@@ -0,0 +1,121 @@
+package rustpbx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func decodeJWT(t *testing.T, token string) (map[string]interface{}, map[string]interface{}, []byte) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected three parts, got '%s'", token)
+	}
+	var header, claims map[string]interface{}
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	json.Unmarshal(data, &header)
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	json.Unmarshal(data, &claims)
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	return header, claims, signature
+}
+
+func TestJWTSigner(t *testing.T) {
+	secret := []byte("gateway-secret")
+	signer := &JWTSigner{Key: secret, KeyID: "k1", Issuer: "bot-fleet", Audience: "rustpbx", TTL: time.Minute,
+		Claims: map[string]interface{}{"tenant": "acme"}}
+	token, expires, err := signer.FetchToken(context.Background())
+	if err != nil {
+		t.Fatalf("FetchToken failed: %v", err)
+	}
+	header, claims, signature := decodeJWT(t, token)
+	if header["alg"] != "HS256" || header["kid"] != "k1" {
+		t.Errorf("Unexpected header: %v", header)
+	}
+	if claims["iss"] != "bot-fleet" || claims["tenant"] != "acme" || int64(claims["exp"].(float64)) != expires.Unix() {
+		t.Errorf("Unexpected claims: %v", claims)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(token[:strings.LastIndex(token, ".")]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Error("Expected a valid HS256 signature")
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	token, _, err = (&JWTSigner{Key: key}).FetchToken(context.Background())
+	if err != nil {
+		t.Fatalf("FetchToken failed: %v", err)
+	}
+	_, _, signature = decodeJWT(t, token)
+	digest := sha256.Sum256([]byte(token[:strings.LastIndex(token, ".")]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if len(signature) != 64 || !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("Expected a valid ES256 signature")
+	}
+
+	if _, _, err := (&JWTSigner{Key: "not a key"}).FetchToken(context.Background()); err == nil {
+		t.Error("Expected an unsupported key to fail")
+	}
+	key384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, _, err := (&JWTSigner{Key: key384}).FetchToken(context.Background()); err == nil {
+		t.Error("Expected a P-384 key to fail for ES256")
+	}
+}
+
+func TestJWTProvider(t *testing.T) {
+	var fetches atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if fail.Load() || r.PostForm.Get("grant_type") != "client_credentials" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		n := fetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": fmt.Sprintf("token-%d", n), "expires_in": 0.1})
+	}))
+	defer server.Close()
+
+	provider := NewJWTProvider(&JWTEndpoint{URL: server.URL, Form: url.Values{"grant_type": {"client_credentials"}}}, time.Second)
+	first, err := provider.Token(context.Background())
+	if err != nil || first != "token-1" {
+		t.Fatalf("Expected the first token, got '%s' %v", first, err)
+	}
+	if token, _ := provider.Token(context.Background()); token != first {
+		t.Errorf("Expected the cached token, got '%s'", token)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if token, _ := provider.Token(context.Background()); token != "token-2" {
+		t.Errorf("Expected the token to be refreshed before it expires, got '%s'", token)
+	}
+
+	fail.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if token, err := provider.Token(context.Background()); err != nil || token != "token-2" {
+		t.Errorf("Expected the unexpired token while the endpoint fails, got '%s' %v", token, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := provider.Token(context.Background()); err == nil {
+		t.Error("Expected an error once the token expired")
+	}
+
+	failing := NewJWTProvider(&JWTSigner{}, 0)
+	if _, err := failing.Token(context.Background()); err == nil {
+		t.Error("Expected the signer error")
+	}
+}
@@ -0,0 +1,111 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrConnectionDead is wrapped by the error of a connection dropped because the server
+// was silent for KeepalivePolicy.DeadAfter
+var ErrConnectionDead = errors.New("connection dead")
+
+// KeepalivePolicy represents WebSocket keepalive configuration. Pings keep idle calls from
+// being cut by intermediate proxies, and pongs or any other frame show the server is alive.
+// When nothing was heard for StaleAfter, a "connectionStale" event is emitted; after
+// DeadAfter the connection is dropped, to be reconnected if a reconnect policy is set.
+type KeepalivePolicy struct {
+	// Interval is the time between pings; 15s when zero
+	Interval time.Duration
+	// StaleAfter is the silence after which the connection is reported stale; two intervals when zero
+	StaleAfter time.Duration
+	// DeadAfter is the silence after which the connection is dropped; four intervals when zero
+	DeadAfter time.Duration
+}
+
+// keepalive tracks the liveness of a connection
+type keepalive struct {
+	policy KeepalivePolicy
+	// lastSeen is the time in Unix nanoseconds a frame or pong was last received
+	lastSeen atomic.Int64
+	// dead is set when the connection is dropped for silence, until the read loop sees the drop
+	dead atomic.Bool
+}
+
+// newKeepalive creates a keepalive tracker with the policy defaults applied
+func newKeepalive(policy KeepalivePolicy) *keepalive {
+	if policy.Interval <= 0 {
+		policy.Interval = 15 * time.Second
+	}
+	if policy.StaleAfter <= 0 {
+		policy.StaleAfter = 2 * policy.Interval
+	}
+	if policy.DeadAfter <= 0 {
+		policy.DeadAfter = 4 * policy.Interval
+	}
+	if policy.DeadAfter < policy.StaleAfter {
+		policy.DeadAfter = policy.StaleAfter
+	}
+	k := &keepalive{policy: policy}
+	k.seen()
+	return k
+}
+
+// seen records that the server is alive
+func (k *keepalive) seen() {
+	k.lastSeen.Store(time.Now().UnixNano())
+}
+
+// idle returns how long the server has been silent
+func (k *keepalive) idle() time.Duration {
+	return time.Since(time.Unix(0, k.lastSeen.Load()))
+}
+
+// keepaliveLoop pings the server and watches for silence until the read loop ends
+func (c *Connection) keepaliveLoop() {
+	k := c.keepalive
+	ticker := time.NewTicker(k.policy.Interval)
+	defer ticker.Stop()
+
+	stale := false
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		idle := k.idle()
+		if idle < k.policy.StaleAfter {
+			stale = false
+		} else if !stale {
+			stale = true
+			data, _ := json.Marshal(map[string]interface{}{
+				"idleMs": idle.Milliseconds(),
+			})
+			c.dispatch(&Event{
+				Event:     "connectionStale",
+				Timestamp: time.Now().UnixMilli(),
+				Data:      data,
+			})
+		}
+
+		c.mu.RLock()
+		conn := c.conn
+		c.mu.RUnlock()
+		if idle >= k.policy.DeadAfter {
+			// The read loop sees the connection drop and reconnects or reports the error
+			c.log().Warn("connection dead", "idle", idle)
+			k.dead.Store(true)
+			conn.UnderlyingConn().Close()
+			// Give a new connection a full DeadAfter before judging it
+			k.seen()
+			continue
+		}
+		// A failed ping surfaces as a read error
+		conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(k.policy.Interval))
+	}
+}
@@ -0,0 +1,79 @@
+package rustpbx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestKeepalivePings(t *testing.T) {
+	var pings atomic.Int32
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		conn.SetPingHandler(func(data string) error {
+			pings.Add(1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Keepalive: &KeepalivePolicy{Interval: 20 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 16)
+	conn.OnEvent(func(event *Event) { events <- event })
+
+	time.Sleep(200 * time.Millisecond)
+	if n := pings.Load(); n < 3 {
+		t.Errorf("Expected regular pings, got %d", n)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Expected a healthy connection, got '%s' event", event.Event)
+	default:
+	}
+}
+
+func TestKeepaliveStale(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Never read, so pings go unanswered
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Keepalive: &KeepalivePolicy{Interval: 20 * time.Millisecond, StaleAfter: 50 * time.Millisecond, DeadAfter: 150 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 16)
+	conn.OnEvent(func(event *Event) { events <- event })
+
+	var names []string
+	for len(names) < 2 {
+		select {
+		case event := <-events:
+			names = append(names, event.Event)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected connectionStale then error, got %v", names)
+		}
+	}
+	if names[0] != "connectionStale" || names[1] != "error" {
+		t.Errorf("Expected connectionStale then error, got %v", names)
+	}
+}
@@ -0,0 +1,232 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// sendTTSChunks sends a text as a stream of TTS chunks sharing a play ID: one per segment,
+// with segments too long for one message split to fit the outbound message limit
+func (c *Connection) sendTTSChunks(ctx context.Context, cmd TTSCommand, segments []string) error {
+	// Count the whole stream as one send, so a graceful close does not cut it off
+	c.sends.begin()
+	defer c.sends.end()
+	if cmd.PlayID == "" {
+		cmd.PlayID = uuid.New().String()
+	}
+
+	chunks := segments
+	if c.maxOutbound > 0 {
+		empty := cmd
+		empty.Text = ""
+		empty.Streaming, empty.EndOfStream = true, true
+		overhead, err := json.Marshal(empty)
+		if err != nil {
+			return fmt.Errorf("failed to marshal command: %w", err)
+		}
+		budget := c.maxOutbound - len(overhead)
+		if budget < 16 {
+			return fmt.Errorf("%w: outbound limit of %d bytes cannot fit a TTS command", ErrMessageTooLarge, c.maxOutbound)
+		}
+		chunks = nil
+		for _, segment := range segments {
+			chunks = append(chunks, chunkText(segment, budget)...)
+		}
+	}
+
+	for i, chunk := range chunks {
+		part := cmd
+		part.Text = chunk
+		part.Streaming = true
+		part.EndOfStream = i == len(chunks)-1
+		// Hang up only after the last chunk was spoken
+		part.AutoHangup = cmd.AutoHangup && part.EndOfStream
+		if err := c.sendCommandContext(ctx, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkText splits a text into chunks whose JSON encoding fits the budget, preferring
+// to split after sentences, then between words
+func chunkText(text string, budget int) []string {
+	return splitText(text, budget, 2, jsonRuneSize)
+}
+
+// splitText splits a text into chunks of at most budget, where a chunk's size is base
+// plus the size of its runes. It prefers to split after sentences, then between words.
+func splitText(text string, budget, base int, runeSize func(r rune) int) []string {
+	var chunks []string
+	for text != "" {
+		// Longest prefix that fits, on a rune boundary
+		end := 0
+		size := base
+		fits := true
+		for i, r := range text {
+			size += runeSize(r)
+			if size > budget {
+				fits = false
+				break
+			}
+			end = i + utf8.RuneLen(r)
+		}
+		if fits {
+			chunks = append(chunks, text)
+			break
+		}
+		if end == 0 {
+			// Keep going even if a single rune does not fit
+			_, end = utf8.DecodeRuneInString(text)
+		}
+
+		cut := end
+		if i := lastSentenceEnd(text[:end]); i > 0 {
+			cut = i
+		} else if i := strings.LastIndexFunc(text[:end], unicode.IsSpace); i > 0 {
+			cut = i + 1
+		}
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	return chunks
+}
+
+// lastSentenceEnd returns the index just after the last sentence end in s, or 0
+func lastSentenceEnd(s string) int {
+	end := 0
+	for i, r := range s {
+		switch r {
+		case '.', '!', '?':
+			if strings.HasPrefix(s[i+1:], " ") {
+				end = i + 2
+			}
+		case '。', '！', '？':
+			end = i + utf8.RuneLen(r)
+		}
+	}
+	return end
+}
+
+// jsonStringSize returns the size of s encoded as a JSON string
+func jsonStringSize(s string) int {
+	size := 2
+	for _, r := range s {
+		size += jsonRuneSize(r)
+	}
+	return size
+}
+
+// jsonRuneSize returns the encoded size of a rune inside a JSON string
+func jsonRuneSize(r rune) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20, r == '<', r == '>', r == '&', r == '\u2028', r == '\u2029':
+		return 6
+	case r == utf8.RuneError:
+		return 6
+	}
+	return utf8.RuneLen(r)
+}
+
+// OversizedFrames is the handling of frames exceeding the inbound message limit
+type OversizedFrames int
+
+const (
+	// OversizedClose drops the connection with ErrMessageTooLarge, reconnecting if a
+	// reconnect policy is set
+	OversizedClose OversizedFrames = iota
+	// OversizedSkip discards the frame and keeps the connection, e.g. for an unexpectedly
+	// large SDP or dump the call can do without
+	OversizedSkip
+)
+
+// FrameRejectedEvent is delivered when a frame exceeding the inbound message limit is rejected
+type FrameRejectedEvent struct {
+	Timestamp int64
+	// Size is the size of the frame in bytes; zero when the connection was dropped before
+	// the frame was read to the end
+	Size  int64
+	Limit int64
+	// Binary is set for audio frames
+	Binary bool
+	// Skipped is set when the frame was discarded and the connection kept
+	Skipped bool
+	Raw     *Event
+}
+
+// OnFrameRejected sets the handler of frameRejected events, emitted when a frame exceeds
+// the inbound message limit
+func (c *Connection) OnFrameRejected(handler func(*FrameRejectedEvent)) {
+	c.on("frameRejected", typed(handler, newFrameRejectedEvent))
+}
+
+// newFrameRejectedEvent converts a frameRejected event
+func newFrameRejectedEvent(e *Event) *FrameRejectedEvent {
+	var data struct {
+		Size    int64 `json:"size"`
+		Limit   int64 `json:"limit"`
+		Binary  bool  `json:"binary"`
+		Skipped bool  `json:"skipped"`
+	}
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, &data)
+	}
+	return &FrameRejectedEvent{Timestamp: e.Timestamp, Size: data.Size, Limit: data.Limit, Binary: data.Binary,
+		Skipped: data.Skipped, Raw: e}
+}
+
+// rejectFrame reports a frame exceeding the inbound message limit
+func (c *Connection) rejectFrame(messageType int, size int64, skipped bool) {
+	c.log().Warn("inbound message too large", "size", size, "limit", c.maxInbound, "skipped", skipped)
+	data, _ := json.Marshal(map[string]interface{}{
+		"size":    size,
+		"limit":   c.maxInbound,
+		"binary":  messageType == websocket.BinaryMessage,
+		"skipped": skipped,
+	})
+	c.dispatch(&Event{
+		Event:     "frameRejected",
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	})
+}
+
+// readFrame reads the next frame within the inbound message limit. Frames exceeding it
+// are skipped with OversizedSkip; otherwise the limit is enforced by the WebSocket.
+func (c *Connection) readFrame() (int, []byte, error) {
+	if c.oversized != OversizedSkip || c.maxInbound <= 0 {
+		return c.conn.ReadMessage()
+	}
+	for {
+		messageType, r, err := c.conn.NextReader()
+		if err != nil {
+			return messageType, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(r, c.maxInbound+1))
+		if err != nil {
+			return messageType, nil, err
+		}
+		if int64(len(data)) <= c.maxInbound {
+			return messageType, data, nil
+		}
+		rest, err := io.Copy(io.Discard, r)
+		if err != nil {
+			return messageType, nil, err
+		}
+		if c.keepalive != nil {
+			c.keepalive.seen()
+		}
+		c.rejectFrame(messageType, int64(len(data))+rest, true)
+	}
+}
@@ -0,0 +1,141 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) + "你好。" + strings.Repeat("很长的句子", 30)
+	chunks := chunkText(text, 64)
+	if strings.Join(chunks, "") != text {
+		t.Fatal("Expected chunks to add up to the text")
+	}
+	for _, chunk := range chunks {
+		if jsonStringSize(chunk) > 64 {
+			t.Errorf("Chunk of %d bytes exceeds the budget: %q", jsonStringSize(chunk), chunk)
+		}
+	}
+	if !strings.HasSuffix(chunks[0], "dog. ") {
+		t.Errorf("Expected the first chunk to end on a sentence, got %q", chunks[0])
+	}
+}
+
+func TestOutboundLimits(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{MaxOutboundMessage: 256})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	text := strings.Repeat("This sentence is spoken in parts. ", 20)
+	if err := conn.TTS(text, "", "", &TTSOptions{AutoHangup: true}); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+
+	var spoken strings.Builder
+	var playID string
+	for {
+		cmd := <-commands
+		if cmd["streaming"] != true {
+			t.Fatalf("Expected streaming TTS chunks, got %v", cmd)
+		}
+		if playID == "" {
+			playID = cmd["playId"].(string)
+		} else if cmd["playId"] != playID {
+			t.Errorf("Expected chunks to share play ID %s, got %v", playID, cmd["playId"])
+		}
+		spoken.WriteString(cmd["text"].(string))
+		if cmd["end_of_stream"] == true {
+			if cmd["autoHangup"] != true {
+				t.Error("Expected the last chunk to carry autoHangup")
+			}
+			break
+		}
+		if cmd["autoHangup"] == true {
+			t.Error("Expected only the last chunk to carry autoHangup")
+		}
+	}
+	if spoken.String() != text {
+		t.Error("Expected the chunks to add up to the text")
+	}
+
+	offer := "v=0\r\no=- 1 1 IN IP4 192.0.2.1\r\ns=-\r\nc=IN IP4 192.0.2.1\r\nt=0 0\r\nm=audio 4000 RTP/AVP 0\r\n"
+	err = conn.Invite(&CallOption{Offer: offer + strings.Repeat("a=candidate\r\n", 100)})
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge for an oversized invite, got %v", err)
+	}
+}
+
+func TestInboundLimit(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "asrFinal", Text: strings.Repeat("x", 2048)})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{MaxInboundMessage: 1024})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	events := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case event := <-events:
+		if event.Event != "error" || !strings.Contains(event.Error, "inbound message exceeds 1024 bytes") {
+			t.Errorf("Expected inbound limit error, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected inbound limit error")
+	}
+}
+
+func TestInboundLimitSkip(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "answer", SDP: strings.Repeat("x", 2048)})
+		conn.WriteMessage(websocket.BinaryMessage, make([]byte, 4096))
+		conn.WriteJSON(Event{Event: "hangup"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		MaxInboundMessage: 1024,
+		OversizedFrames:   OversizedSkip,
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	rejected := make(chan *FrameRejectedEvent, 4)
+	hangups := make(chan struct{}, 1)
+	conn.OnFrameRejected(func(e *FrameRejectedEvent) { rejected <- e })
+	conn.OnHangup(func(*HangupEvent) { hangups <- struct{}{} })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case <-hangups:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the connection to survive oversized frames")
+	}
+	for _, binary := range []bool{false, true} {
+		select {
+		case e := <-rejected:
+			if e.Binary != binary || !e.Skipped || e.Limit != 1024 || e.Size <= 1024 {
+				t.Errorf("Unexpected rejected frame %+v", e)
+			}
+		default:
+			t.Fatalf("Expected a frameRejected event for the binary %v frame", binary)
+		}
+	}
+	if state := conn.State(); state != ConnectionConnected {
+		t.Errorf("Expected the connection to stay connected, got '%s'", state)
+	}
+}
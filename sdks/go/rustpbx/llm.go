@@ -0,0 +1,78 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ChatMessage represents a message of an LLM conversation
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// LLM generates assistant replies from a conversation
+type LLM interface {
+	Complete(ctx context.Context, messages []ChatMessage) (string, error)
+}
+
+// LLMFunc adapts a function to the LLM interface
+type LLMFunc func(ctx context.Context, messages []ChatMessage) (string, error)
+
+// Complete calls f(ctx, messages)
+func (f LLMFunc) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+	return f(ctx, messages)
+}
+
+// ProxyLLM completes conversations through the RustPBX OpenAI-compatible LLM proxy
+type ProxyLLM struct {
+	Client *Client
+	Model  string
+}
+
+// chatCompletionRequest represents an OpenAI-compatible chat completion request
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+}
+
+// chatCompletionResponse represents an OpenAI-compatible chat completion response
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Complete sends the conversation to the chat completions endpoint of the proxy
+func (p *ProxyLLM) Complete(ctx context.Context, messages []ChatMessage) (string, error) {
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:    p.Model,
+		Messages: messages,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.Client.ProxyLLMRequest(ctx, "chat/completions", "POST", bytes.NewReader(body), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to call LLM: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("LLM request failed: %w", newAPIError(resp))
+	}
+
+	var result chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+	if len(result.Choices) == 0 {
+		return "", fmt.Errorf("no response from LLM")
+	}
+
+	return result.Choices[0].Message.Content, nil
+}
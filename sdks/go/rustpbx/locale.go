@@ -0,0 +1,342 @@
+package rustpbx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LocaleBundle represents the prompts, speakable formats and grammars of a language
+type LocaleBundle struct {
+	// Tag is a BCP 47 language tag such as "en-US" or "es"
+	Tag string
+	// Prompts maps prompt keys to text; "{name}" placeholders are filled by Prompt
+	Prompts map[string]string
+	// Yes and No list the words and phrases accepted as an answer, in lower case
+	Yes []string
+	No  []string
+	// Months and Weekdays hold the spoken names, January and Sunday first
+	Months   [12]string
+	Weekdays [7]string
+	// DatePattern formats dates with {weekday}, {month}, {day} and {year}
+	DatePattern string
+	// TimeLayout is a Go time layout for speakable times
+	TimeLayout string
+	// NumberWords spells out integers; digits are used when nil
+	NumberWords func(n int64) string
+	// Ordinal formats a day of the month; the plain number when nil
+	Ordinal func(n int) string
+}
+
+// Prompt returns a prompt with its placeholders filled, or "" if the key is unknown
+func (b *LocaleBundle) Prompt(key string, args map[string]string) string {
+	text, ok := b.Prompts[key]
+	if !ok {
+		return ""
+	}
+	for name, value := range args {
+		text = strings.ReplaceAll(text, "{"+name+"}", value)
+	}
+	return text
+}
+
+// SpeakNumber formats an integer for TTS
+func (b *LocaleBundle) SpeakNumber(n int64) string {
+	if b.NumberWords == nil {
+		return fmt.Sprint(n)
+	}
+	return b.NumberWords(n)
+}
+
+// SpeakDigits formats a digit string such as an account number to be read digit by digit
+func (b *LocaleBundle) SpeakDigits(digits string) string {
+	var words []string
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			continue
+		}
+		words = append(words, b.SpeakNumber(int64(r-'0')))
+	}
+	return strings.Join(words, ", ")
+}
+
+// SpeakDate formats a date for TTS
+func (b *LocaleBundle) SpeakDate(t time.Time) string {
+	day := fmt.Sprint(t.Day())
+	if b.Ordinal != nil {
+		day = b.Ordinal(t.Day())
+	}
+	return strings.NewReplacer(
+		"{weekday}", b.Weekdays[t.Weekday()],
+		"{month}", b.Months[t.Month()-1],
+		"{day}", day,
+		"{year}", fmt.Sprint(t.Year()),
+	).Replace(b.DatePattern)
+}
+
+// SpeakTime formats a time of day for TTS
+func (b *LocaleBundle) SpeakTime(t time.Time) string {
+	return t.Format(b.TimeLayout)
+}
+
+// IsYes reports whether an utterance is an affirmative answer
+func (b *LocaleBundle) IsYes(text string) bool {
+	return matchesGrammar(text, b.Yes)
+}
+
+// IsNo reports whether an utterance is a negative answer
+func (b *LocaleBundle) IsNo(text string) bool {
+	return matchesGrammar(text, b.No)
+}
+
+// matchesGrammar reports whether the normalized utterance starts with or equals one
+// of the phrases, so "yes please" matches "yes" but "yesterday" does not
+func matchesGrammar(text string, phrases []string) bool {
+	utterance := normalizeUtterance(text)
+	for _, phrase := range phrases {
+		phrase = normalizeUtterance(phrase)
+		if utterance == phrase || strings.HasPrefix(utterance, phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Localizer selects a locale bundle by configured or detected language
+type Localizer struct {
+	bundles  map[string]*LocaleBundle
+	fallback string
+}
+
+// NewLocalizer creates a localizer that falls back to the bundle tagged fallback
+func NewLocalizer(fallback string, bundles ...*LocaleBundle) *Localizer {
+	l := &Localizer{
+		bundles:  make(map[string]*LocaleBundle),
+		fallback: strings.ToLower(fallback),
+	}
+	for _, bundle := range bundles {
+		l.bundles[strings.ToLower(bundle.Tag)] = bundle
+	}
+	return l
+}
+
+// Select returns the bundle of a language, trying the exact tag, then the base
+// language, then the fallback
+func (l *Localizer) Select(language string) *LocaleBundle {
+	tag := strings.ToLower(strings.ReplaceAll(language, "_", "-"))
+	if bundle, ok := l.bundles[tag]; ok {
+		return bundle
+	}
+	if i := strings.Index(tag, "-"); i > 0 {
+		if bundle, ok := l.bundles[tag[:i]]; ok {
+			return bundle
+		}
+	}
+	return l.bundles[l.fallback]
+}
+
+// Prompt returns a prompt in a language, falling back to the fallback bundle for
+// keys the language does not define
+func (l *Localizer) Prompt(language, key string, args map[string]string) string {
+	if bundle := l.Select(language); bundle != nil {
+		if text := bundle.Prompt(key, args); text != "" {
+			return text
+		}
+	}
+	if bundle := l.bundles[l.fallback]; bundle != nil {
+		return bundle.Prompt(key, args)
+	}
+	return ""
+}
+
+// EnglishLocale is the built-in English bundle
+var EnglishLocale = &LocaleBundle{
+	Tag: "en",
+	Prompts: map[string]string{
+		"confirm_reprompt":         "Sorry, I didn't get that. Please say yes or no, or press 1 for yes or 2 for no.",
+		"menu_option":              "Press {digit} for {label}.",
+		"key_star":                 "star",
+		"key_pound":                "pound",
+		"recording_disclaimer":     "This call may be recorded for quality and training purposes.",
+		"recording_consent":        "Do you agree to be recorded? Say yes or press 1, say no or press 2.",
+		"capture_retry":            "Sorry, I didn't catch that. {prompt}",
+		"capture_confirm":          "I have {value}. Is that correct?",
+		"capture_email_invalid":    "That doesn't sound like a valid email address. Please spell it out, for example j o h n at example dot com.",
+		"capture_email_domain":     "I couldn't find the email domain {domain}. {prompt}",
+		"capture_email_again":      "Let's try again. Please spell your email address.",
+		"capture_street":           "What is your street address, including the house number?",
+		"capture_city":             "Which city is that in?",
+		"capture_postal_code":      "And the postal code? You can say it digit by digit.",
+		"capture_address_unknown":  "I couldn't find that address. Let's try again.",
+		"capture_address_readback": "{street}, {city}, postal code {postalCode}",
+		"symbol_at":                "at",
+		"symbol_dot":               "dot",
+		"symbol_underscore":        "underscore",
+		"symbol_dash":              "dash",
+		"symbol_plus":              "plus",
+		"payment_card_number":      "Please enter your card number using your keypad, followed by the pound key.",
+		"payment_expiry":           "Please enter the expiry date as four digits, month then year, followed by the pound key.",
+		"payment_cvv":              "Please enter the {length} digit security code, followed by the pound key.",
+		"payment_retry":            "Sorry, that entry was not valid. {prompt}",
+	},
+	Yes:         []string{"yes", "yeah", "yep", "yup", "sure", "correct", "right", "that's right", "absolutely", "of course", "ok", "okay"},
+	No:          []string{"no", "nope", "nah", "not really", "incorrect", "wrong", "that's wrong", "negative"},
+	Months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	Weekdays:    [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	DatePattern: "{weekday}, {month} {day}",
+	TimeLayout:  "3:04 PM",
+	NumberWords: englishNumberWords,
+	Ordinal:     englishOrdinal,
+}
+
+// SpanishLocale is the built-in Spanish bundle
+var SpanishLocale = &LocaleBundle{
+	Tag: "es",
+	Prompts: map[string]string{
+		"confirm_reprompt":         "Perdone, no le he entendido. Diga sí o no, o pulse 1 para sí o 2 para no.",
+		"menu_option":              "Pulse {digit} para {label}.",
+		"key_star":                 "asterisco",
+		"key_pound":                "almohadilla",
+		"recording_disclaimer":     "Esta llamada puede ser grabada con fines de calidad y formación.",
+		"recording_consent":        "¿Acepta que se grabe la llamada? Diga sí o pulse 1, diga no o pulse 2.",
+		"capture_retry":            "Perdone, no le he entendido. {prompt}",
+		"capture_confirm":          "Tengo {value}. ¿Es correcto?",
+		"capture_email_invalid":    "Eso no parece una dirección de correo válida. Deletréela, por ejemplo j o s e arroba ejemplo punto com.",
+		"capture_email_domain":     "No encuentro el dominio de correo {domain}. {prompt}",
+		"capture_email_again":      "Volvamos a intentarlo. Deletree su dirección de correo.",
+		"capture_street":           "¿Cuál es su dirección, con el número?",
+		"capture_city":             "¿En qué ciudad?",
+		"capture_postal_code":      "¿Y el código postal? Puede decirlo dígito a dígito.",
+		"capture_address_unknown":  "No encuentro esa dirección. Volvamos a intentarlo.",
+		"capture_address_readback": "{street}, {city}, código postal {postalCode}",
+		"symbol_at":                "arroba",
+		"symbol_dot":               "punto",
+		"symbol_underscore":        "guion bajo",
+		"symbol_dash":              "guion",
+		"symbol_plus":              "más",
+		"payment_card_number":      "Introduzca el número de su tarjeta con el teclado, seguido de la tecla almohadilla.",
+		"payment_expiry":           "Introduzca la fecha de caducidad con cuatro dígitos, mes y año, seguida de la tecla almohadilla.",
+		"payment_cvv":              "Introduzca el código de seguridad de {length} dígitos, seguido de la tecla almohadilla.",
+		"payment_retry":            "Perdone, esa entrada no es válida. {prompt}",
+	},
+	Yes:         []string{"sí", "si", "claro", "correcto", "vale", "de acuerdo", "exacto", "por supuesto"},
+	No:          []string{"no", "incorrecto", "para nada", "negativo"},
+	Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	Weekdays:    [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	DatePattern: "{weekday} {day} de {month}",
+	TimeLayout:  "15:04",
+	NumberWords: spanishNumberWords,
+}
+
+var englishOnes = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+
+var englishTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+// englishNumberWords spells out an integer in English
+func englishNumberWords(n int64) string {
+	if n < 0 {
+		return "minus " + englishNumberWords(-n)
+	}
+	if n < 20 {
+		return englishOnes[n]
+	}
+	if n < 100 {
+		if n%10 == 0 {
+			return englishTens[n/10]
+		}
+		return englishTens[n/10] + "-" + englishOnes[n%10]
+	}
+
+	scales := []struct {
+		value int64
+		name  string
+	}{{1e9, "billion"}, {1e6, "million"}, {1e3, "thousand"}, {100, "hundred"}}
+	for _, scale := range scales {
+		if n < scale.value {
+			continue
+		}
+		words := englishNumberWords(n/scale.value) + " " + scale.name
+		if rest := n % scale.value; rest > 0 {
+			words += " " + englishNumberWords(rest)
+		}
+		return words
+	}
+	return ""
+}
+
+// englishOrdinal formats a day of the month as "1st", "2nd", "3rd" and so on
+func englishOrdinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+var spanishUnits = []string{"cero", "uno", "dos", "tres", "cuatro", "cinco", "seis", "siete", "ocho", "nueve",
+	"diez", "once", "doce", "trece", "catorce", "quince", "dieciséis", "diecisiete", "dieciocho", "diecinueve",
+	"veinte", "veintiuno", "veintidós", "veintitrés", "veinticuatro", "veinticinco", "veintiséis", "veintisiete", "veintiocho", "veintinueve"}
+
+var spanishTens = []string{"", "", "", "treinta", "cuarenta", "cincuenta", "sesenta", "setenta", "ochenta", "noventa"}
+
+var spanishHundreds = []string{"", "ciento", "doscientos", "trescientos", "cuatrocientos", "quinientos",
+	"seiscientos", "setecientos", "ochocientos", "novecientos"}
+
+// spanishNumberWords spells out an integer in Spanish
+func spanishNumberWords(n int64) string {
+	switch {
+	case n < 0:
+		return "menos " + spanishNumberWords(-n)
+	case n < 30:
+		return spanishUnits[n]
+	case n < 100:
+		if n%10 == 0 {
+			return spanishTens[n/10]
+		}
+		return spanishTens[n/10] + " y " + spanishUnits[n%10]
+	case n == 100:
+		return "cien"
+	case n < 1000:
+		words := spanishHundreds[n/100]
+		if rest := n % 100; rest > 0 {
+			words += " " + spanishNumberWords(rest)
+		}
+		return words
+	case n < 1e6:
+		words := "mil"
+		if n/1000 > 1 {
+			words = spanishApocope(spanishNumberWords(n/1000)) + " mil"
+		}
+		if rest := n % 1000; rest > 0 {
+			words += " " + spanishNumberWords(rest)
+		}
+		return words
+	default:
+		words := "un millón"
+		if n/1e6 > 1 {
+			words = spanishApocope(spanishNumberWords(n/1e6)) + " millones"
+		}
+		if rest := n % 1e6; rest > 0 {
+			words += " " + spanishNumberWords(rest)
+		}
+		return words
+	}
+}
+
+// spanishApocope shortens a trailing "uno" before a noun: veintiún mil, treinta y un mil
+func spanishApocope(words string) string {
+	switch {
+	case strings.HasSuffix(words, "veintiuno"):
+		return strings.TrimSuffix(words, "uno") + "ún"
+	case words == "uno" || strings.HasSuffix(words, " uno"):
+		return strings.TrimSuffix(words, "o")
+	}
+	return words
+}
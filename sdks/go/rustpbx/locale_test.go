@@ -0,0 +1,82 @@
+package rustpbx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNumberWords(t *testing.T) {
+	tests := []struct {
+		n       int64
+		english string
+		spanish string
+	}{
+		{0, "zero", "cero"},
+		{21, "twenty-one", "veintiuno"},
+		{100, "one hundred", "cien"},
+		{115, "one hundred fifteen", "ciento quince"},
+		{21000, "twenty-one thousand", "veintiún mil"},
+		{31500, "thirty-one thousand five hundred", "treinta y un mil quinientos"},
+		{2000000, "two million", "dos millones"},
+		{4000, "four thousand", "cuatro mil"},
+		{105000, "one hundred five thousand", "ciento cinco mil"},
+	}
+
+	for _, test := range tests {
+		if words := EnglishLocale.SpeakNumber(test.n); words != test.english {
+			t.Errorf("English %d: expected '%s', got '%s'", test.n, test.english, words)
+		}
+		if words := SpanishLocale.SpeakNumber(test.n); words != test.spanish {
+			t.Errorf("Spanish %d: expected '%s', got '%s'", test.n, test.spanish, words)
+		}
+	}
+}
+
+func TestLocaleSpeakableFormats(t *testing.T) {
+	date := time.Date(2026, 3, 3, 15, 5, 0, 0, time.UTC)
+	if s := EnglishLocale.SpeakDate(date); s != "Tuesday, March 3rd" {
+		t.Errorf("Unexpected English date: '%s'", s)
+	}
+	if s := SpanishLocale.SpeakDate(date); s != "martes 3 de marzo" {
+		t.Errorf("Unexpected Spanish date: '%s'", s)
+	}
+	if s := EnglishLocale.SpeakTime(date); s != "3:05 PM" {
+		t.Errorf("Unexpected English time: '%s'", s)
+	}
+	if s := EnglishLocale.SpeakDigits("40-12"); s != "four, zero, one, two" {
+		t.Errorf("Unexpected digits: '%s'", s)
+	}
+}
+
+func TestLocaleYesNoGrammar(t *testing.T) {
+	if !EnglishLocale.IsYes("Yes, please.") || !EnglishLocale.IsYes("that's right") {
+		t.Error("Expected affirmative answers to match")
+	}
+	if EnglishLocale.IsYes("yesterday I called") || EnglishLocale.IsNo("nobody answered") {
+		t.Error("Expected words merely starting like an answer not to match")
+	}
+	if !SpanishLocale.IsYes("Sí, claro") || !SpanishLocale.IsNo("no gracias") {
+		t.Error("Expected Spanish answers to match")
+	}
+}
+
+func TestLocalizerSelect(t *testing.T) {
+	english := &LocaleBundle{Tag: "en", Prompts: map[string]string{
+		"greeting": "Hello {name}!",
+		"goodbye":  "Goodbye!",
+	}}
+	spanish := &LocaleBundle{Tag: "es", Prompts: map[string]string{
+		"greeting": "¡Hola {name}!",
+	}}
+	localizer := NewLocalizer("en", english, spanish)
+
+	if localizer.Select("es_MX") != spanish || localizer.Select("fr-FR") != english {
+		t.Error("Expected base language and fallback selection")
+	}
+	if text := localizer.Prompt("es-ES", "greeting", map[string]string{"name": "Ana"}); text != "¡Hola Ana!" {
+		t.Errorf("Unexpected prompt: '%s'", text)
+	}
+	if text := localizer.Prompt("es", "goodbye", nil); text != "Goodbye!" {
+		t.Errorf("Expected fallback for missing prompt, got '%s'", text)
+	}
+}
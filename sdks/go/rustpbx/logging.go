@@ -0,0 +1,98 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// redactedFields are the JSON fields whose values are never logged, in lower case
+var redactedFields = map[string]bool{
+	"secretkey":     true,
+	"password":      true,
+	"token":         true,
+	"accesstoken":   true,
+	"apikey":        true,
+	"clientsecret":  true,
+	"authorization": true,
+}
+
+// discardHandler drops all log records
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// discardLogger is used when no logger is configured
+var discardLogger = slog.New(discardHandler{})
+
+// log returns the logger of the connection
+func (c *Connection) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// log returns the logger of the client
+func (c *Client) log() *slog.Logger {
+	if c.options.Logger == nil {
+		return discardLogger
+	}
+	return c.options.Logger
+}
+
+// connectionLogger returns the logger of a new connection, tagged with its session
+func connectionLogger(wsURL string, callContext *CallContext, options *ConnectionOptions, client *Client) *slog.Logger {
+	var logger *slog.Logger
+	if options != nil && options.Logger != nil {
+		logger = options.Logger
+	} else if client != nil && client.options.Logger != nil {
+		logger = client.options.Logger
+	} else {
+		return nil
+	}
+	session := sessionIDFromURL(wsURL)
+	if session == "" && callContext != nil {
+		session = callContext.SessionID
+	}
+	if session != "" {
+		logger = logger.With("session", session)
+	}
+	return logger
+}
+
+// redactJSON returns a JSON document with the values of secret fields replaced, for logging
+func redactJSON(data []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "<unparseable>"
+	}
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return "<unparseable>"
+	}
+	return string(redacted)
+}
+
+// redactValue replaces the values of secret fields in a decoded JSON value
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if redactedFields[strings.ToLower(key)] {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,77 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// syncBuffer is a buffer safe for concurrent logging
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestConnectionLogging(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(map[string]interface{}{"event": "answer"})
+	})
+	var logs syncBuffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := NewClientWithOptions(server.URL, ClientOptions{Logger: logger})
+
+	conn, err := client.ConnectCall(context.Background(), &ConnectionOptions{SessionID: "call-7"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.Invite(&CallOption{
+		Callee: "sip:bob@example.com",
+		ASR:    &TranscriptionOption{Provider: "tencent", SecretID: "id", SecretKey: "s3cret"},
+	})
+	if err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	<-commands
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(logs.String(), "received event") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	output := logs.String()
+	for _, expected := range []string{"msg=connected", "session=call-7", "msg=\"sending command\"", "[REDACTED]", "msg=\"received event\" session=call-7 event=answer"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected the logs to contain %s, got:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "s3cret") {
+		t.Errorf("Expected the secret key to be redacted, got:\n%s", output)
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	redacted := redactJSON([]byte(`{"command":"invite","option":{"sip":{"username":"bob","password":"pw"},"tts":[{"secretKey":"k"}]}}`))
+	expected := `{"command":"invite","option":{"sip":{"password":"[REDACTED]","username":"bob"},"tts":[{"secretKey":"[REDACTED]"}]}}`
+	if redacted != expected {
+		t.Errorf("Expected %s, got %s", expected, redacted)
+	}
+}
@@ -0,0 +1,306 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+// Media directions of the call audio
+const (
+	// MediaInbound is the remote party's audio, received as binary messages
+	MediaInbound = "inbound"
+	// MediaOutbound is the audio played into the call, written with WriteAudio or played
+	// by the server's TTS and Play tracks
+	MediaOutbound = "outbound"
+)
+
+// Media anomaly reasons
+const (
+	// MediaNoPackets reports a direction whose audio frames stopped
+	MediaNoPackets = "noPackets"
+	// MediaNoEnergy reports a direction whose frames keep coming but carry silence
+	MediaNoEnergy = "noEnergy"
+)
+
+// MediaWatchPolicy represents one-way audio detection. RustPBX reports no media quality
+// events, so the SDK watches the audio frames of the call itself: when a direction stays
+// silent for Silence while the other is active, a "mediaAnomaly" event is dispatched to
+// the handlers, and a "mediaRecovered" event once the direction is heard again. These
+// events never come from the server. A direction is only watched once its frames were
+// seen during the call, so calls without audio streaming are not reported; outbound
+// audio played by the server counts as activity but is not watched.
+type MediaWatchPolicy struct {
+	// Silence is how long a direction may stay silent while the other is active; 5s when zero
+	Silence time.Duration
+	// MinLevel is the RMS level, as a fraction of full scale, under which a frame is
+	// silent; 0.001 when zero. G.722 frames cannot be measured and count as active.
+	MinLevel float64
+	// Recover runs in a goroutine started with Go when an anomaly is detected, e.g. to
+	// refer the call to a fresh leg or hang up; the protocol has no ICE restart or
+	// re-INVITE command
+	Recover func(ctx context.Context, anomaly *MediaAnomaly) error
+}
+
+// MediaAnomaly is delivered when a direction of the call audio goes silent or recovers
+type MediaAnomaly struct {
+	Timestamp int64
+	// Direction is MediaInbound or MediaOutbound
+	Direction string
+	// Reason is MediaNoPackets or MediaNoEnergy
+	Reason string
+	// Silent is how long the direction had been silent
+	Silent time.Duration
+	Raw    *Event
+}
+
+// OnMediaAnomaly sets the handler of mediaAnomaly events, emitted when one direction of
+// the call audio is silent while the other is active
+func (c *Connection) OnMediaAnomaly(handler func(*MediaAnomaly)) {
+	c.on("mediaAnomaly", typed(handler, newMediaAnomaly))
+}
+
+// OnMediaRecovered sets the handler of mediaRecovered events, emitted when a direction
+// reported by a mediaAnomaly event is heard again
+func (c *Connection) OnMediaRecovered(handler func(*MediaAnomaly)) {
+	c.on("mediaRecovered", typed(handler, newMediaAnomaly))
+}
+
+// newMediaAnomaly converts a mediaAnomaly or mediaRecovered event
+func newMediaAnomaly(e *Event) *MediaAnomaly {
+	var data struct {
+		Direction string `json:"direction"`
+		Reason    string `json:"reason"`
+		SilentMs  int64  `json:"silentMs"`
+	}
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, &data)
+	}
+	return &MediaAnomaly{Timestamp: e.Timestamp, Direction: data.Direction, Reason: data.Reason,
+		Silent: time.Duration(data.SilentMs) * time.Millisecond, Raw: e}
+}
+
+// mediaDirection tracks the audio of a direction
+type mediaDirection struct {
+	// lastPacket is the time a frame was last seen, zero before the first frame of the call
+	lastPacket time.Time
+	// lastActive is the time a frame above the level or a sign of speech was last seen
+	lastActive time.Time
+	// reported is set while an anomaly of the direction is reported
+	reported bool
+	reason   string
+}
+
+// mediaWatch detects one-way audio
+type mediaWatch struct {
+	policy MediaWatchPolicy
+
+	mu sync.Mutex
+	// answered is the time the call was answered, zero before the answer and after the hangup
+	answered time.Time
+	inbound  mediaDirection
+	outbound mediaDirection
+	// playing counts the server tracks playing into the call
+	playing int
+}
+
+// newMediaWatch creates a one-way audio detector with the policy defaults applied
+func newMediaWatch(policy MediaWatchPolicy) *mediaWatch {
+	if policy.Silence <= 0 {
+		policy.Silence = 5 * time.Second
+	}
+	if policy.MinLevel <= 0 {
+		policy.MinLevel = 0.001
+	}
+	return &mediaWatch{policy: policy}
+}
+
+// direction returns the tracker of a direction; the caller must hold w.mu
+func (w *mediaWatch) direction(name string) *mediaDirection {
+	if name == MediaInbound {
+		return &w.inbound
+	}
+	return &w.outbound
+}
+
+// observeMediaEvent follows the call and the server's playback from events
+func (c *Connection) observeMediaEvent(event *Event) {
+	w := c.media
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	switch event.Event {
+	case "answer":
+		w.answered = now
+		w.inbound, w.outbound = mediaDirection{}, mediaDirection{}
+	case "hangup":
+		w.answered = time.Time{}
+	case "trackStart":
+		w.playing++
+	case "trackEnd":
+		// Interrupted tracks end with a trackEnd too
+		w.playing = max(w.playing-1, 0)
+	case "speaking", "asrDelta", "asrFinal":
+		// The server heard the remote party
+		w.inbound.lastActive = now
+	}
+}
+
+// observeMediaFrame records a frame of a direction
+func (c *Connection) observeMediaFrame(direction string, frame []byte) {
+	w := c.media
+	if w == nil {
+		return
+	}
+	active := true
+	if level, ok := audioLevel(c.AudioFormat(), frame); ok {
+		active = level >= w.policy.MinLevel
+	}
+
+	w.mu.Lock()
+	now := time.Now()
+	d := w.direction(direction)
+	d.lastPacket = now
+	if active {
+		d.lastActive = now
+	}
+	w.mu.Unlock()
+}
+
+// mediaWatchLoop checks the directions for silence until the read loop ends
+func (c *Connection) mediaWatchLoop() {
+	w := c.media
+	ticker := time.NewTicker(w.policy.Silence / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		c.checkMedia(MediaInbound, MediaOutbound)
+		c.checkMedia(MediaOutbound, MediaInbound)
+	}
+}
+
+// checkMedia reports a direction silent while the other is active, or its recovery
+func (c *Connection) checkMedia(direction, other string) {
+	w := c.media
+	w.mu.Lock()
+	if w.answered.IsZero() {
+		w.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	d, o := w.direction(direction), w.direction(other)
+	since := d.lastActive
+	if since.Before(w.answered) {
+		since = w.answered
+	}
+	silent := now.Sub(since)
+	otherActive := now.Sub(o.lastActive) < w.policy.Silence || (other == MediaOutbound && w.playing > 0)
+
+	var event string
+	switch {
+	case d.reported && silent < w.policy.Silence:
+		d.reported = false
+		event = "mediaRecovered"
+	case !d.reported && !d.lastPacket.IsZero() && silent >= w.policy.Silence && otherActive:
+		d.reported = true
+		d.reason = MediaNoEnergy
+		if now.Sub(d.lastPacket) >= w.policy.Silence {
+			d.reason = MediaNoPackets
+		}
+		event = "mediaAnomaly"
+	default:
+		w.mu.Unlock()
+		return
+	}
+	reason := d.reason
+	w.mu.Unlock()
+
+	if event == "mediaAnomaly" {
+		c.log().Warn("one-way audio", "direction", direction, "reason", reason, "silent", silent)
+	} else {
+		c.log().Info("audio recovered", "direction", direction)
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"direction": direction,
+		"reason":    reason,
+		"silentMs":  silent.Milliseconds(),
+	})
+	anomaly := &Event{
+		Event:     event,
+		Timestamp: now.UnixMilli(),
+		Data:      data,
+	}
+	c.dispatch(anomaly)
+	if event == "mediaAnomaly" && w.policy.Recover != nil {
+		c.Go(func(ctx context.Context) error {
+			return w.policy.Recover(ctx, newMediaAnomaly(anomaly))
+		})
+	}
+}
+
+// audioLevel returns the RMS level of a frame as a fraction of full scale, or false if
+// the codec cannot be measured
+func audioLevel(format AudioFormat, frame []byte) (float64, bool) {
+	var sum float64
+	var n int
+	switch format.Codec {
+	case CodecPCM, "":
+		for i := 0; i+1 < len(frame); i += 2 {
+			s := float64(int16(binary.LittleEndian.Uint16(frame[i:])))
+			sum += s * s
+			n++
+		}
+	case CodecPCMU:
+		for _, b := range frame {
+			s := float64(ulawToLinear(b))
+			sum += s * s
+			n++
+		}
+	case CodecPCMA:
+		for _, b := range frame {
+			s := float64(alawToLinear(b))
+			sum += s * s
+			n++
+		}
+	default:
+		return 0, false
+	}
+	if n == 0 {
+		return 0, true
+	}
+	return math.Sqrt(sum/float64(n)) / 32768, true
+}
+
+// ulawToLinear decodes a G.711 μ-law sample
+func ulawToLinear(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0f) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(0x84 - t)
+	}
+	return int16(t - 0x84)
+}
+
+// alawToLinear decodes a G.711 A-law sample
+func alawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0f)<<4 + 8
+	if segment := (a & 0x70) >> 4; segment > 0 {
+		t = (t + 0x100) << (segment - 1)
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
@@ -0,0 +1,153 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAudioLevel(t *testing.T) {
+	tests := []struct {
+		codec  Codec
+		frame  []byte
+		silent bool
+	}{
+		{CodecPCMU, bytes.Repeat([]byte{0xff}, 160), true},
+		{CodecPCMU, bytes.Repeat([]byte{0x80, 0x00}, 80), false},
+		{CodecPCMA, bytes.Repeat([]byte{0xd5}, 160), true},
+		{CodecPCMA, bytes.Repeat([]byte{0xaa, 0x2a}, 80), false},
+		{CodecPCM, make([]byte, 320), true},
+		{CodecPCM, bytes.Repeat([]byte{0x00, 0x40, 0x00, 0xc0}, 80), false},
+	}
+	for _, test := range tests {
+		level, ok := audioLevel(AudioFormatFor(test.codec), test.frame)
+		if !ok {
+			t.Errorf("%s: expected a level", test.codec)
+		}
+		if silent := level < 0.001; silent != test.silent {
+			t.Errorf("%s: expected silent %v, got level %f", test.codec, test.silent, level)
+		}
+	}
+	if _, ok := audioLevel(AudioFormatFor(CodecG722), make([]byte, 160)); ok {
+		t.Errorf("Expected G.722 frames not to be measured")
+	}
+}
+
+func TestMediaAnomaly(t *testing.T) {
+	loud := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		conn.WriteJSON(Event{Event: "answer"})
+		// Stream silence until told to stream speech
+		frame := bytes.Repeat([]byte{0xff}, 160)
+		for {
+			select {
+			case <-loud:
+				frame = bytes.Repeat([]byte{0x80, 0x00}, 80)
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	recovered := make(chan *MediaAnomaly, 1)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		MediaWatch: &MediaWatchPolicy{
+			Silence: 100 * time.Millisecond,
+			Recover: func(ctx context.Context, anomaly *MediaAnomaly) error {
+				recovered <- anomaly
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	anomalies := make(chan *MediaAnomaly, 4)
+	conn.OnMediaAnomaly(func(anomaly *MediaAnomaly) { anomalies <- anomaly })
+	conn.OnMediaRecovered(func(anomaly *MediaAnomaly) { anomalies <- anomaly })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			conn.WriteAudio(bytes.Repeat([]byte{0x80, 0x00}, 80))
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case anomaly := <-anomalies:
+		if anomaly.Raw.Event != "mediaAnomaly" || anomaly.Direction != MediaInbound || anomaly.Reason != MediaNoEnergy {
+			t.Errorf("Expected silent inbound audio, got %+v", anomaly)
+		}
+		if anomaly.Silent < 100*time.Millisecond {
+			t.Errorf("Expected at least 100ms of silence, got %s", anomaly.Silent)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a mediaAnomaly event")
+	}
+	select {
+	case anomaly := <-recovered:
+		if anomaly.Direction != MediaInbound {
+			t.Errorf("Expected Recover for inbound audio, got %+v", anomaly)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Recover to run")
+	}
+
+	close(loud)
+	select {
+	case anomaly := <-anomalies:
+		if anomaly.Raw.Event != "mediaRecovered" || anomaly.Direction != MediaInbound {
+			t.Errorf("Expected inbound audio to recover, got %+v", anomaly)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a mediaRecovered event")
+	}
+}
+
+func TestMediaWatchWithoutAudio(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		conn.WriteJSON(Event{Event: "answer"})
+		conn.WriteJSON(Event{Event: "trackStart", TrackID: "tts-1"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		MediaWatch: &MediaWatchPolicy{Silence: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	anomalies := make(chan *MediaAnomaly, 4)
+	conn.OnMediaAnomaly(func(anomaly *MediaAnomaly) { anomalies <- anomaly })
+
+	select {
+	case anomaly := <-anomalies:
+		t.Errorf("Expected no anomaly without audio streaming, got %+v", anomaly)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
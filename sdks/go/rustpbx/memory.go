@@ -0,0 +1,246 @@
+package rustpbx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CallerProfile is what is remembered about a caller across calls
+type CallerProfile struct {
+	// Caller is the normalized caller number, see NormalizeCaller
+	Caller      string            `json:"caller"`
+	Preferences map[string]string `json:"preferences,omitempty"`
+	// Interactions summarizes the past calls, oldest first
+	Interactions []InteractionSummary `json:"interactions,omitempty"`
+	// Calls counts the past calls; zero for a first-time caller
+	Calls     int       `json:"calls"`
+	FirstSeen time.Time `json:"firstSeen,omitempty"`
+	LastSeen  time.Time `json:"lastSeen,omitempty"`
+}
+
+// Returning reports whether the caller has called before
+func (p *CallerProfile) Returning() bool {
+	return p != nil && p.Calls > 0
+}
+
+// LastInteraction returns the summary of the previous call, or nil when there is none
+func (p *CallerProfile) LastInteraction() *InteractionSummary {
+	if p == nil || len(p.Interactions) == 0 {
+		return nil
+	}
+	return &p.Interactions[len(p.Interactions)-1]
+}
+
+// InteractionSummary summarizes a past call
+type InteractionSummary struct {
+	CallID  string    `json:"callId,omitempty"`
+	Time    time.Time `json:"time"`
+	Summary string    `json:"summary"`
+	Outcome string    `json:"outcome,omitempty"`
+}
+
+// ProfileStore persists caller profiles
+type ProfileStore interface {
+	// LoadProfile returns the profile of a normalized caller number, or nil when unknown
+	LoadProfile(ctx context.Context, caller string) (*CallerProfile, error)
+	SaveProfile(ctx context.Context, profile *CallerProfile) error
+}
+
+// MemoryProfileStore keeps caller profiles in memory, for tests and single-process deployments
+type MemoryProfileStore struct {
+	mu       sync.Mutex
+	profiles map[string]CallerProfile
+}
+
+// NewMemoryProfileStore creates an empty in-memory profile store
+func NewMemoryProfileStore() *MemoryProfileStore {
+	return &MemoryProfileStore{profiles: make(map[string]CallerProfile)}
+}
+
+// LoadProfile implements ProfileStore
+func (s *MemoryProfileStore) LoadProfile(ctx context.Context, caller string) (*CallerProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[caller]
+	if !ok {
+		return nil, nil
+	}
+	return profile.clone(), nil
+}
+
+// SaveProfile implements ProfileStore
+func (s *MemoryProfileStore) SaveProfile(ctx context.Context, profile *CallerProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles[profile.Caller] = *profile.clone()
+	return nil
+}
+
+// clone returns a deep copy of the profile
+func (p *CallerProfile) clone() *CallerProfile {
+	clone := *p
+	if p.Preferences != nil {
+		clone.Preferences = make(map[string]string, len(p.Preferences))
+		for key, value := range p.Preferences {
+			clone.Preferences[key] = value
+		}
+	}
+	clone.Interactions = append([]InteractionSummary(nil), p.Interactions...)
+	return &clone
+}
+
+// NormalizeCaller returns the key callers are remembered by: the digits of a phone
+// number, or the user part of a SIP URI that is not a number
+func NormalizeCaller(caller string) string {
+	if number := dialedNumber(caller); number != "" {
+		return number
+	}
+	return dialedUser(caller)
+}
+
+// CallerMemory remembers callers across calls. The profile of an incoming caller is loaded
+// into the call context before the incoming event is dispatched, so the application can
+// welcome them back; the call is recorded in the profile when it ends.
+type CallerMemory struct {
+	Store ProfileStore
+	// MaxInteractions bounds the summaries kept per caller; 10 when zero, unlimited when negative
+	MaxInteractions int
+	// Timeout bounds profile loads, so the call is not held, and saves; 2s when zero
+	Timeout time.Duration
+}
+
+// maxInteractions returns the summary limit; negative when unlimited
+func (m *CallerMemory) maxInteractions() int {
+	if m.MaxInteractions == 0 {
+		return 10
+	}
+	return m.MaxInteractions
+}
+
+// timeout returns the load timeout
+func (m *CallerMemory) timeout() time.Duration {
+	if m.Timeout <= 0 {
+		return 2 * time.Second
+	}
+	return m.Timeout
+}
+
+// loadIncomingProfile attaches the caller profile to the call context of an incoming call
+func (c *Connection) loadIncomingProfile(event *Event) {
+	if c.memory == nil || c.callContext == nil {
+		return
+	}
+	caller := NormalizeCaller(event.Caller)
+	if caller == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.memory.timeout())
+	defer cancel()
+	profile, err := c.memory.Store.LoadProfile(ctx, caller)
+	if err != nil {
+		// Never block a call because the profile could not be loaded
+		c.handleError(err)
+		return
+	}
+	if profile == nil {
+		profile = &CallerProfile{Caller: caller}
+	}
+	c.mu.Lock()
+	c.callContext.Profile = profile
+	c.mu.Unlock()
+}
+
+// CallerProfile returns the profile of the caller, or nil when the connection has no
+// caller memory or the call is not an incoming call
+func (c *Connection) CallerProfile() *CallerProfile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.callContext == nil {
+		return nil
+	}
+	return c.callContext.Profile
+}
+
+// RememberCaller records a summary of the call and the caller's preferences in their
+// profile and saves it. Preferences are merged into the known ones. It can be called
+// again to update the summary; without it, the call is recorded without a summary when
+// it ends.
+func (c *Connection) RememberCaller(summary InteractionSummary, preferences map[string]string) error {
+	c.mu.Lock()
+	profile := c.rememberLocked(&summary, preferences)
+	c.mu.Unlock()
+	if profile == nil {
+		return nil
+	}
+	return c.memory.Store.SaveProfile(c.ctx, profile)
+}
+
+// rememberOnHangup records a call that RememberCaller did not
+func (c *Connection) rememberOnHangup() {
+	c.mu.Lock()
+	var profile *CallerProfile
+	if !c.remembered {
+		profile = c.rememberLocked(nil, nil)
+	}
+	c.mu.Unlock()
+	if profile == nil {
+		return
+	}
+	// The connection context may end with the call
+	ctx, cancel := context.WithTimeout(context.Background(), c.memory.timeout())
+	defer cancel()
+	if err := c.memory.Store.SaveProfile(ctx, profile); err != nil {
+		c.handleError(err)
+	}
+}
+
+// rememberLocked records the call in the caller profile and returns a copy to save, or nil
+// when there is no profile; the caller must hold c.mu
+func (c *Connection) rememberLocked(summary *InteractionSummary, preferences map[string]string) *CallerProfile {
+	if c.memory == nil || c.callContext == nil || c.callContext.Profile == nil {
+		return nil
+	}
+	// The loaded profile stays as it was at the start of the call
+	profile := c.callContext.Profile.clone()
+	if c.remembered {
+		profile = c.rememberedProfile.clone()
+	} else {
+		now := time.Now()
+		profile.Calls++
+		profile.LastSeen = now
+		if profile.FirstSeen.IsZero() {
+			profile.FirstSeen = now
+		}
+	}
+
+	if summary != nil {
+		if summary.Time.IsZero() {
+			summary.Time = time.Now()
+		}
+		if summary.CallID == "" {
+			summary.CallID = c.callContext.CallID
+		}
+		n := len(profile.Interactions)
+		if c.remembered && n > 0 && profile.Interactions[n-1].CallID == summary.CallID {
+			// Update the summary of this call
+			profile.Interactions[n-1] = *summary
+		} else {
+			profile.Interactions = append(profile.Interactions, *summary)
+		}
+		if max := c.memory.maxInteractions(); max > 0 && len(profile.Interactions) > max {
+			profile.Interactions = profile.Interactions[len(profile.Interactions)-max:]
+		}
+	}
+	for key, value := range preferences {
+		if profile.Preferences == nil {
+			profile.Preferences = make(map[string]string)
+		}
+		profile.Preferences[key] = value
+	}
+
+	c.remembered = true
+	c.rememberedProfile = profile
+	return profile.clone()
+}
@@ -0,0 +1,61 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCallerMemory(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "sip:+1 (555) 010-0100@example.com"})
+		// Wait for the test to remember the call before hanging up
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "hangup"})
+	})
+	store := NewMemoryProfileStore()
+	memory := &CallerMemory{Store: store, MaxInteractions: 2}
+
+	call := func(remember func(conn *Connection)) *CallerProfile {
+		conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{Memory: memory})
+		if err != nil {
+			t.Fatalf("ConnectCall failed: %v", err)
+		}
+		defer conn.Close()
+		profiles := make(chan *CallerProfile, 1)
+		conn.OnIncoming(func(e *IncomingEvent) { profiles <- conn.CallerProfile() })
+		conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+		profile := <-profiles
+		remember(conn)
+		conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+		if _, err := conn.WaitForEvent("hangup", time.Second); err != nil {
+			t.Fatalf("Expected the hangup: %v", err)
+		}
+		return profile
+	}
+
+	first := call(func(conn *Connection) {
+		conn.RememberCaller(InteractionSummary{Summary: "Asked about roaming"}, map[string]string{"language": "fr"})
+		conn.RememberCaller(InteractionSummary{Summary: "Asked about roaming fees", Outcome: "resolved"}, nil)
+	})
+	if first.Returning() || first.Caller != "15550100100" {
+		t.Errorf("Expected a first-time caller, got %+v", first)
+	}
+
+	second := call(func(conn *Connection) {})
+	if !second.Returning() || second.Preferences["language"] != "fr" {
+		t.Errorf("Expected a returning caller with preferences, got %+v", second)
+	}
+	if last := second.LastInteraction(); last == nil || last.Summary != "Asked about roaming fees" || len(second.Interactions) != 1 {
+		t.Errorf("Expected one updated summary, got %+v", second.Interactions)
+	}
+
+	profile, _ := store.LoadProfile(context.Background(), "15550100100")
+	if profile.Calls != 2 {
+		t.Errorf("Expected calls without a summary to be counted, got %d", profile.Calls)
+	}
+}
@@ -0,0 +1,159 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// menuDigits are the keys assigned in order to menu options without a digit
+const menuDigits = "1234567890"
+
+// MenuFlow is a flow definition of a keypad menu. The spoken prompt and the DTMF routing
+// are both generated from it, so "press 1 for sales" always leads to sales.
+type MenuFlow struct {
+	// Intro is spoken before the options, e.g. "Thanks for calling Acme."
+	Intro   string       `json:"intro,omitempty"`
+	Options []MenuOption `json:"options"`
+	// Outro is spoken after the options, e.g. "To hear these options again, press star."
+	Outro string `json:"outro,omitempty"`
+}
+
+// MenuOption is an entry of a keypad menu, routed like a RouteRule
+type MenuOption struct {
+	// Digit is the key of the option, 0-9, * or #; the next free digit from 1 when empty
+	Digit string `json:"digit,omitempty"`
+	// Label completes the locale's "menu_option" prompt, e.g. "sales" in "Press 1 for sales."
+	Label  string `json:"label"`
+	Target string `json:"target,omitempty"`
+	Queue  string `json:"queue,omitempty"`
+	Flow   string `json:"flow,omitempty"`
+	// Hidden options are routed but not announced, e.g. an operator on 0
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// Menu is a keypad menu generated from a MenuFlow
+type Menu struct {
+	// Prompt is the text to speak, with the keys written as words
+	Prompt string
+	// Options are the options with their digits assigned, in the order of the flow
+	Options []MenuOption
+}
+
+// ParseMenuFlow decodes a menu flow definition, e.g. one of CallConfig.Flows
+func ParseMenuFlow(definition json.RawMessage) (*MenuFlow, error) {
+	var flow MenuFlow
+	if err := json.Unmarshal(definition, &flow); err != nil {
+		return nil, fmt.Errorf("failed to parse menu flow: %w", err)
+	}
+	return &flow, nil
+}
+
+// Menu generates the keypad menu of a flow of the config
+func (c *CallConfig) Menu(name string, locale *LocaleBundle) (*Menu, error) {
+	definition, ok := c.Flows[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown flow %q", name)
+	}
+	flow, err := ParseMenuFlow(definition)
+	if err != nil {
+		return nil, fmt.Errorf("flow %q: %w", name, err)
+	}
+	return flow.Generate(locale)
+}
+
+// Generate assigns the digits of the options and writes the prompt with the locale's
+// "menu_option" wording; EnglishLocale when nil. It fails on duplicate or invalid keys
+// and on options without a label or destination.
+func (f *MenuFlow) Generate(locale *LocaleBundle) (*Menu, error) {
+	if locale == nil {
+		locale = EnglishLocale
+	}
+	if len(f.Options) == 0 {
+		return nil, fmt.Errorf("menu has no options")
+	}
+
+	menu := &Menu{Options: make([]MenuOption, len(f.Options))}
+	used := make(map[string]bool)
+	for i, option := range f.Options {
+		if option.Digit != "" {
+			if len(option.Digit) != 1 || !strings.Contains(menuDigits+"*#", option.Digit) {
+				return nil, fmt.Errorf("menu option %d has invalid digit %q", i, option.Digit)
+			}
+			if used[option.Digit] {
+				return nil, fmt.Errorf("menu option %d reuses digit %s", i, option.Digit)
+			}
+			used[option.Digit] = true
+		}
+		if option.Label == "" && !option.Hidden {
+			return nil, fmt.Errorf("menu option %d has no label", i)
+		}
+		if option.Target == "" && option.Queue == "" && option.Flow == "" {
+			return nil, fmt.Errorf("menu option %d has no target, queue or flow", i)
+		}
+		menu.Options[i] = option
+	}
+	for i := range menu.Options {
+		if menu.Options[i].Digit != "" {
+			continue
+		}
+		for _, digit := range menuDigits {
+			if !used[string(digit)] {
+				menu.Options[i].Digit = string(digit)
+				used[string(digit)] = true
+				break
+			}
+		}
+		if menu.Options[i].Digit == "" {
+			return nil, fmt.Errorf("menu has more options than digits")
+		}
+	}
+
+	var prompt []string
+	if f.Intro != "" {
+		prompt = append(prompt, f.Intro)
+	}
+	for _, option := range menu.Options {
+		if option.Hidden {
+			continue
+		}
+		prompt = append(prompt, menuPrompt(locale, "menu_option", map[string]string{
+			"digit": speakKey(locale, option.Digit),
+			"label": option.Label,
+		}))
+	}
+	if f.Outro != "" {
+		prompt = append(prompt, f.Outro)
+	}
+	menu.Prompt = strings.Join(prompt, " ")
+	return menu, nil
+}
+
+// Route returns the option of a pressed key
+func (m *Menu) Route(digit string) (*MenuOption, bool) {
+	for i := range m.Options {
+		if m.Options[i].Digit == digit {
+			return &m.Options[i], true
+		}
+	}
+	return nil, false
+}
+
+// menuPrompt returns a prompt of the locale, falling back to English for missing keys
+func menuPrompt(locale *LocaleBundle, key string, args map[string]string) string {
+	if text := locale.Prompt(key, args); text != "" {
+		return text
+	}
+	return EnglishLocale.Prompt(key, args)
+}
+
+// speakKey writes a keypad key as a word for TTS
+func speakKey(locale *LocaleBundle, digit string) string {
+	switch digit {
+	case "*":
+		return menuPrompt(locale, "key_star", nil)
+	case "#":
+		return menuPrompt(locale, "key_pound", nil)
+	}
+	return locale.SpeakDigits(digit)
+}
@@ -0,0 +1,70 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMenuGenerate(t *testing.T) {
+	config := &CallConfig{Flows: map[string]json.RawMessage{
+		"main": json.RawMessage(`{
+			"intro": "Thanks for calling.",
+			"options": [
+				{"label": "sales", "queue": "sales"},
+				{"label": "support", "queue": "support"},
+				{"digit": "1", "label": "billing", "target": "sip:billing@pbx"},
+				{"digit": "0", "hidden": true, "target": "sip:operator@pbx"},
+				{"digit": "*", "label": "the main menu", "flow": "main"}
+			]
+		}`),
+	}}
+
+	menu, err := config.Menu("main", nil)
+	if err != nil {
+		t.Fatalf("Failed to generate menu: %v", err)
+	}
+	expected := "Thanks for calling. Press two for sales. Press three for support. Press one for billing. Press star for the main menu."
+	if menu.Prompt != expected {
+		t.Errorf("Expected prompt '%s', got '%s'", expected, menu.Prompt)
+	}
+	if option, ok := menu.Route("2"); !ok || option.Queue != "sales" {
+		t.Errorf("Expected 2 to route to sales, got %+v", option)
+	}
+	if option, ok := menu.Route("0"); !ok || option.Target != "sip:operator@pbx" {
+		t.Errorf("Expected 0 to route to the operator, got %+v", option)
+	}
+	if _, ok := menu.Route("9"); ok {
+		t.Errorf("Expected 9 to be unrouted")
+	}
+
+	spanish, err := config.Menu("main", SpanishLocale)
+	if err != nil {
+		t.Fatalf("Failed to generate Spanish menu: %v", err)
+	}
+	if spanish.Options[0].Digit != "2" || spanish.Prompt == expected {
+		t.Errorf("Unexpected Spanish menu: %+v", spanish)
+	}
+}
+
+func TestMenuGenerateErrors(t *testing.T) {
+	tests := map[string]MenuFlow{
+		"empty":     {},
+		"duplicate": {Options: []MenuOption{{Digit: "1", Label: "a", Queue: "a"}, {Digit: "1", Label: "b", Queue: "b"}}},
+		"invalid":   {Options: []MenuOption{{Digit: "12", Label: "a", Queue: "a"}}},
+		"label":     {Options: []MenuOption{{Queue: "a"}}},
+		"route":     {Options: []MenuOption{{Label: "a"}}},
+	}
+	for name, flow := range tests {
+		if _, err := flow.Generate(EnglishLocale); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	var flow MenuFlow
+	for i := 0; i < 11; i++ {
+		flow.Options = append(flow.Options, MenuOption{Label: "a", Queue: "a"})
+	}
+	if _, err := flow.Generate(EnglishLocale); err == nil {
+		t.Errorf("Expected an error for more options than digits")
+	}
+}
@@ -0,0 +1,155 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MetricsRecorder receives the measurements of connections as they happen, to feed them
+// into an application's metric system, e.g. Prometheus counters and histograms. Its
+// methods are called on the goroutines sending commands and reading events, and must not block.
+type MetricsRecorder interface {
+	// CommandSent is called for every command written or failed, with the time the write took
+	CommandSent(sessionID, command string, latency time.Duration, err error)
+	// EventReceived is called for every event decoded from the server
+	EventReceived(sessionID, event string)
+	// Reconnected is called when the connection was re-dialed after a drop
+	Reconnected(sessionID string, attempts int)
+}
+
+// LatencySummary summarizes the measured latencies of an operation
+type LatencySummary struct {
+	Count uint64
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average latency, or zero without measurements
+func (s LatencySummary) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// add records a latency
+func (s *LatencySummary) add(latency time.Duration) {
+	s.Count++
+	s.Total += latency
+	s.Max = max(s.Max, latency)
+}
+
+// ConnectionMetrics reports the counters of a connection
+type ConnectionMetrics struct {
+	// CommandsSent counts the commands written, by command type; failed commands are only
+	// counted in CommandsFailed
+	CommandsSent   map[string]uint64
+	CommandsFailed map[string]uint64
+	// EventsReceived counts the events from the server, by event type; events emitted by
+	// the SDK itself are not counted
+	EventsReceived map[string]uint64
+	// SendLatency measures the commands written, from sending to the end of the write,
+	// including the time queued in the write queue
+	SendLatency LatencySummary
+	Reconnects  int
+	// Priorities counts the incoming calls by the priority assigned to them
+	Priorities map[Priority]uint64
+}
+
+// connectionMetrics collects the counters of a connection
+type connectionMetrics struct {
+	mu      sync.Mutex
+	metrics ConnectionMetrics
+}
+
+// Metrics returns a snapshot of the counters of the connection
+func (c *Connection) Metrics() ConnectionMetrics {
+	m := &c.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := m.metrics
+	snapshot.CommandsSent = cloneCounts(m.metrics.CommandsSent)
+	snapshot.CommandsFailed = cloneCounts(m.metrics.CommandsFailed)
+	snapshot.EventsReceived = cloneCounts(m.metrics.EventsReceived)
+	snapshot.Priorities = make(map[Priority]uint64, len(m.metrics.Priorities))
+	for priority, n := range m.metrics.Priorities {
+		snapshot.Priorities[priority] = n
+	}
+	return snapshot
+}
+
+// cloneCounts copies a counter map, never returning nil
+func cloneCounts(counts map[string]uint64) map[string]uint64 {
+	clone := make(map[string]uint64, len(counts))
+	for name, n := range counts {
+		clone[name] = n
+	}
+	return clone
+}
+
+// countCommand records a command written or failed
+func (c *Connection) countCommand(data []byte, latency time.Duration, err error) {
+	var command struct {
+		Command string `json:"command"`
+	}
+	json.Unmarshal(data, &command)
+
+	m := &c.metrics
+	m.mu.Lock()
+	if err != nil {
+		m.metrics.CommandsFailed = incrementCount(m.metrics.CommandsFailed, command.Command)
+	} else {
+		m.metrics.CommandsSent = incrementCount(m.metrics.CommandsSent, command.Command)
+		m.metrics.SendLatency.add(latency)
+	}
+	m.mu.Unlock()
+
+	if c.metricsRecorder != nil {
+		c.metricsRecorder.CommandSent(c.sessionID(), command.Command, latency, err)
+	}
+}
+
+// countEvent records an event received from the server
+func (c *Connection) countEvent(event *Event) {
+	m := &c.metrics
+	m.mu.Lock()
+	m.metrics.EventsReceived = incrementCount(m.metrics.EventsReceived, event.Event)
+	m.mu.Unlock()
+
+	if c.metricsRecorder != nil {
+		c.metricsRecorder.EventReceived(c.sessionID(), event.Event)
+	}
+}
+
+// countReconnect records a successful reconnect
+func (c *Connection) countReconnect(attempts int) {
+	m := &c.metrics
+	m.mu.Lock()
+	m.metrics.Reconnects++
+	m.mu.Unlock()
+
+	if c.metricsRecorder != nil {
+		c.metricsRecorder.Reconnected(c.sessionID(), attempts)
+	}
+}
+
+// countPriority records the priority assigned to an incoming call
+func (c *Connection) countPriority(priority Priority) {
+	m := &c.metrics
+	m.mu.Lock()
+	if m.metrics.Priorities == nil {
+		m.metrics.Priorities = make(map[Priority]uint64)
+	}
+	m.metrics.Priorities[priority]++
+	m.mu.Unlock()
+}
+
+// incrementCount adds one to a counter, creating the map on first use
+func incrementCount(counts map[string]uint64, name string) map[string]uint64 {
+	if counts == nil {
+		counts = make(map[string]uint64)
+	}
+	counts[name]++
+	return counts
+}
@@ -0,0 +1,100 @@
+package rustpbx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRecorder collects the measurements passed to a MetricsRecorder
+type testRecorder struct {
+	mu         sync.Mutex
+	commands   []string
+	events     []string
+	reconnects []int
+}
+
+func (r *testRecorder) CommandSent(sessionID, command string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, command)
+}
+
+func (r *testRecorder) EventReceived(sessionID, event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *testRecorder) Reconnected(sessionID string, attempts int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reconnects = append(r.reconnects, attempts)
+}
+
+func TestConnectionMetrics(t *testing.T) {
+	server, _, _ := reconnectServer(t, 1)
+	recorder := &testRecorder{}
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond},
+		Metrics:   recorder,
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	answered := make(chan struct{}, 1)
+	conn.OnAnswer(func(*AnswerEvent) { answered <- struct{}{} })
+
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	select {
+	case <-answered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an answer after reconnecting")
+	}
+
+	metrics := conn.Metrics()
+	if metrics.CommandsSent["ready"] != 1 || len(metrics.CommandsFailed) != 0 {
+		t.Errorf("Expected one ready command sent, got %v and %v failed", metrics.CommandsSent, metrics.CommandsFailed)
+	}
+	if metrics.EventsReceived["answer"] != 1 {
+		t.Errorf("Expected one answer event, got %v", metrics.EventsReceived)
+	}
+	if metrics.EventsReceived["reconnected"] != 0 {
+		t.Errorf("Expected SDK events not to be counted, got %v", metrics.EventsReceived)
+	}
+	if metrics.Reconnects != 1 {
+		t.Errorf("Expected 1 reconnect, got %d", metrics.Reconnects)
+	}
+	if metrics.SendLatency.Count != 1 || metrics.SendLatency.Mean() != metrics.SendLatency.Max {
+		t.Errorf("Unexpected send latency %+v", metrics.SendLatency)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.commands) != 1 || recorder.commands[0] != "ready" {
+		t.Errorf("Expected the ready command recorded, got %v", recorder.commands)
+	}
+	if len(recorder.events) != 1 || recorder.events[0] != "answer" {
+		t.Errorf("Expected the answer event recorded, got %v", recorder.events)
+	}
+	if len(recorder.reconnects) != 1 || recorder.reconnects[0] != 1 {
+		t.Errorf("Expected one reconnect recorded, got %v", recorder.reconnects)
+	}
+}
+
+func TestConnectionMetricsFailed(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	conn.Close()
+	conn.writeCommand(context.Background(), map[string]interface{}{"command": "hangup"})
+
+	metrics := conn.Metrics()
+	if metrics.CommandsFailed["hangup"] != 1 || len(metrics.CommandsSent) != 0 {
+		t.Errorf("Expected one failed hangup, got %v sent and %v failed", metrics.CommandsSent, metrics.CommandsFailed)
+	}
+}
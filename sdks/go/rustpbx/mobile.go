@@ -0,0 +1,126 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MobileHandoffOptions represents the configuration of HandoffToMobile
+type MobileHandoffOptions struct {
+	// Caller is the caller ID presented on the mobile
+	Caller string
+	// Announcement is spoken on the current leg before dialing, e.g. "Calling your phone now."
+	Announcement string
+	// Option is the call option of the mobile leg; its callee and caller are set by the handoff
+	Option *CallOption
+	// Connection configures the connection of the mobile leg
+	Connection *ConnectionOptions
+}
+
+// HandoffToMobile continues the call on the user's mobile phone, e.g. to leave a browser
+// call: it dials the number on a new SIP call and, once answered, merges the current call
+// into it and hangs up the current leg. Merging moves the event handlers, interceptors and
+// stored values to the mobile leg, which is returned to continue the conversation on.
+// The current leg is left as is when the mobile does not answer; ctx should bound the wait.
+func (c *Connection) HandoffToMobile(ctx context.Context, number string, options *MobileHandoffOptions) (*Connection, error) {
+	if number == "" {
+		return nil, errors.New("mobile handoff needs a number")
+	}
+	if c.client == nil {
+		return nil, errors.New("mobile handoff needs a connection made with a Client")
+	}
+	if options == nil {
+		options = &MobileHandoffOptions{}
+	}
+	if options.Announcement != "" {
+		if err := c.TTS(options.Announcement, "", "", nil); err != nil {
+			c.log().Warn("handoff announcement failed", "error", err)
+		}
+	}
+
+	mobile, err := c.client.ConnectSIP(ctx, options.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect mobile leg: %w", err)
+	}
+	option := &CallOption{}
+	if options.Option != nil {
+		*option = *options.Option
+	}
+	option.Callee = number
+	if options.Caller != "" {
+		option.Caller = options.Caller
+	}
+	c.log().Info("handing off to mobile", "number", number, "mobileSession", mobile.sessionID())
+	if _, err := mobile.InviteAndWaitAnswer(ctx, option); err != nil {
+		mobile.Close()
+		return nil, fmt.Errorf("mobile %s did not answer: %w", number, err)
+	}
+
+	c.mergeInto(mobile)
+	if err := c.HangupContext(ctx, "mobile_handoff", "system"); err != nil {
+		c.log().Warn("failed to hang up the handed off leg", "error", err)
+	}
+	c.log().Info("handed off to mobile", "number", number, "mobileSession", mobile.sessionID())
+	return mobile, nil
+}
+
+// mergeInto moves the handlers, interceptors and stored values of the connection to
+// another one carrying on the call. The connection keeps none, so its own hangup does
+// not reach the handlers.
+func (c *Connection) mergeInto(target *Connection) {
+	c.mu.Lock()
+	eventHandler, handlers, subscriptions := c.eventHandler, c.handlers, c.subscriptions
+	audioHandler, frameHandler := c.audioHandler, c.frameHandler
+	commandInterceptors, eventInterceptors := c.commandInterceptors, c.eventInterceptors
+	store := c.store
+	c.eventHandler, c.handlers, c.subscriptions = nil, map[string]EventHandler{}, nil
+	c.audioHandler, c.frameHandler = nil, nil
+	c.commandInterceptors, c.eventInterceptors = nil, nil
+	c.mu.Unlock()
+
+	target.mu.Lock()
+	if eventHandler != nil {
+		target.eventHandler = eventHandler
+	}
+	if target.handlers == nil {
+		target.handlers = make(map[string]EventHandler, len(handlers))
+	}
+	for event, handler := range handlers {
+		target.handlers[event] = handler
+	}
+	target.subscriptions = append(target.subscriptions[:len(target.subscriptions):len(target.subscriptions)], subscriptions...)
+	if audioHandler != nil {
+		target.audioHandler = audioHandler
+	}
+	if frameHandler != nil {
+		target.frameHandler = frameHandler
+	}
+	target.commandInterceptors = append(target.commandInterceptors[:len(target.commandInterceptors):len(target.commandInterceptors)], commandInterceptors...)
+	target.eventInterceptors = append(target.eventInterceptors[:len(target.eventInterceptors):len(target.eventInterceptors)], eventInterceptors...)
+	target.mu.Unlock()
+
+	if store == nil {
+		return
+	}
+	store.mu.Lock()
+	entries := make(map[string]storeEntry, len(store.entries))
+	for key, entry := range store.entries {
+		entries[key] = entry
+	}
+	store.mu.Unlock()
+	now := time.Now()
+	for key, entry := range entries {
+		if entry.expired(now) {
+			continue
+		}
+		var ttl time.Duration
+		if !entry.expires.IsZero() {
+			ttl = entry.expires.Sub(now)
+		}
+		if err := target.Store().Set(key, entry.value, ttl); err != nil {
+			target.log().Warn("failed to carry over session value", "key", key, "error", err)
+		}
+	}
+}
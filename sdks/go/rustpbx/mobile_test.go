@@ -0,0 +1,108 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mobileServer serves a WebRTC leg recording its commands and a SIP leg that answers
+// invites, or rejects them when reject is set
+func mobileServer(t *testing.T, reject bool) (*httptest.Server, <-chan map[string]interface{}, <-chan *websocket.Conn) {
+	webrtc := make(chan map[string]interface{}, 16)
+	sip := make(chan *websocket.Conn, 1)
+	server := newCommandServer(t, func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{}) {
+		return func(cmd map[string]interface{}) {
+			if cmd == nil {
+				return
+			}
+			if !strings.HasSuffix(r.URL.Path, "/call/sip") {
+				webrtc <- cmd
+				return
+			}
+			if cmd["command"] != "invite" {
+				return
+			}
+			option, _ := cmd["option"].(map[string]interface{})
+			if option["callee"] != "+15551234567" {
+				t.Errorf("Expected the mobile number as callee, got %v", option["callee"])
+			}
+			if reject {
+				conn.WriteJSON(Event{Event: "reject", Code: 486, Reason: "busy"})
+				return
+			}
+			conn.WriteJSON(Event{Event: "answer", SDP: "v=0"})
+			sip <- conn
+		}
+	})
+	return server, webrtc, sip
+}
+
+func TestHandoffToMobile(t *testing.T) {
+	server, webrtc, sip := mobileServer(t, false)
+	conn, err := NewClient(server.URL).ConnectWebRTC(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectWebRTC failed: %v", err)
+	}
+	defer conn.Close()
+	digits := make(chan string, 1)
+	conn.OnDTMF(func(event *DTMFEvent) { digits <- event.Digit })
+	conn.Store().Set("verified", true, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	mobile, err := conn.HandoffToMobile(ctx, "+15551234567", &MobileHandoffOptions{Announcement: "Calling your phone now."})
+	if err != nil {
+		t.Fatalf("HandoffToMobile failed: %v", err)
+	}
+	defer mobile.Close()
+
+	for _, expected := range []string{"tts", "hangup"} {
+		cmd := <-webrtc
+		if cmd["command"] != expected {
+			t.Fatalf("Expected %s on the WebRTC leg, got %v", expected, cmd)
+		}
+		if expected == "hangup" && cmd["reason"] != "mobile_handoff" {
+			t.Errorf("Expected the mobile_handoff reason, got %v", cmd["reason"])
+		}
+	}
+	if verified, _ := mobile.Store().Value("verified"); verified != true {
+		t.Error("Expected the session values to move to the mobile leg")
+	}
+
+	(<-sip).WriteJSON(Event{Event: "dtmf", Digit: "5"})
+	select {
+	case digit := <-digits:
+		if digit != "5" {
+			t.Errorf("Expected digit 5, got %s", digit)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handlers to receive the mobile leg's events")
+	}
+}
+
+func TestHandoffToMobileNotAnswered(t *testing.T) {
+	server, webrtc, _ := mobileServer(t, true)
+	conn, err := NewClient(server.URL).ConnectWebRTC(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectWebRTC failed: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := conn.HandoffToMobile(ctx, "+15551234567", nil); !errors.Is(err, ErrCallNotAnswered) {
+		t.Fatalf("Expected ErrCallNotAnswered, got %v", err)
+	}
+	select {
+	case cmd := <-webrtc:
+		t.Errorf("Expected the WebRTC leg to be left as is, got %v", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,227 @@
+package rustpbx
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// mohFrame is the duration of the frames of streamed music on hold
+const mohFrame = 20 * time.Millisecond
+
+// MOHSource represents a music on hold source: a live HTTP stream, such as an Icecast
+// mount, with a static file to fall back to when the stream keeps failing
+type MOHSource struct {
+	// StreamURL serves audio in the audio format of the call, see Connection.AudioFormat,
+	// e.g. an Icecast mount transcoding to G.711; a leading WAV header is skipped. The
+	// server cannot play live streams itself, so the SDK relays them as audio frames.
+	StreamURL string
+	// FallbackURL is a file played by the server, looped, once the stream failed
+	// MaxRetries times in a row, or right away without a stream
+	FallbackURL string
+	// HTTPClient fetches the stream; http.DefaultClient when nil
+	HTTPClient *http.Client
+	// MaxRetries is the number of reconnects to the stream before falling back; 3 when
+	// zero, unlimited when negative
+	MaxRetries int
+	// RetryDelay is the delay of the first reconnect, doubling for the next ones; 500ms when zero
+	RetryDelay time.Duration
+}
+
+// MOH is music on hold started with StartMOH
+type MOH struct {
+	conn   *Connection
+	source MOHSource
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	fallback bool
+}
+
+// StartMOH plays music on hold into the call until Stop is called or the call ends, e.g.
+// while the caller is on hold or waiting in a queue. A dropped stream is reconnected,
+// and a "mohFallback" event is emitted when the fallback file takes over.
+func (c *Connection) StartMOH(source MOHSource) (*MOH, error) {
+	if source.StreamURL == "" && source.FallbackURL == "" {
+		return nil, fmt.Errorf("music on hold needs a stream or a fallback URL")
+	}
+	if source.HTTPClient == nil {
+		source.HTTPClient = http.DefaultClient
+	}
+	if source.MaxRetries == 0 {
+		source.MaxRetries = 3
+	}
+	if source.RetryDelay <= 0 {
+		source.RetryDelay = 500 * time.Millisecond
+	}
+
+	m := &MOH{conn: c, source: source, done: make(chan struct{})}
+	m.ctx, m.cancel = context.WithCancel(c.taskContext())
+	c.Go(func(context.Context) error {
+		defer close(m.done)
+		return m.run(m.ctx)
+	})
+	return m, nil
+}
+
+// Stop stops the music on hold and waits until it stopped
+func (m *MOH) Stop() {
+	m.cancel()
+	<-m.done
+}
+
+// Fallback reports whether the fallback file is playing instead of the stream
+func (m *MOH) Fallback() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fallback
+}
+
+// run streams the music on hold, reconnecting on failures, then falls back to the file
+func (m *MOH) run(ctx context.Context) error {
+	c := m.conn
+	var lastErr error
+	failures := 0
+	for m.source.StreamURL != "" {
+		streamed, err := m.stream(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if streamed {
+			// The stream played for a while before dropping
+			failures = 0
+		}
+		lastErr = err
+		failures++
+		if m.source.MaxRetries > 0 && failures > m.source.MaxRetries {
+			break
+		}
+
+		delay := m.source.RetryDelay << min(failures-1, 6)
+		c.log().Warn("music on hold stream failed", "attempt", failures, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	if m.source.FallbackURL == "" {
+		return fmt.Errorf("music on hold stream failed: %w", lastErr)
+	}
+	m.mu.Lock()
+	m.fallback = true
+	m.mu.Unlock()
+	if lastErr != nil {
+		c.dispatch(&Event{
+			Event:     "mohFallback",
+			Timestamp: time.Now().UnixMilli(),
+			Error:     lastErr.Error(),
+		})
+	}
+	return m.playFallback(ctx)
+}
+
+// stream relays the stream as audio frames until it ends or fails; streamed reports
+// whether any audio was relayed
+func (m *MOH) stream(ctx context.Context) (streamed bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.source.StreamURL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := m.source.HTTPClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("stream request failed: %w", newAPIError(resp))
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if err := skipWAVHeader(body); err != nil {
+		return false, err
+	}
+	format := m.conn.AudioFormat()
+	size := int(format.BytesPerSecond * int(mohFrame) / int(time.Second))
+	size -= size % format.FrameAlign
+	frame := make([]byte, size)
+
+	ticker := time.NewTicker(mohFrame)
+	defer ticker.Stop()
+	for {
+		if _, err := io.ReadFull(body, frame); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = io.EOF
+			}
+			return streamed, fmt.Errorf("stream ended: %w", err)
+		}
+		if err := m.conn.WriteAudioContext(ctx, frame); err != nil {
+			return streamed, err
+		}
+		streamed = true
+		select {
+		case <-ctx.Done():
+			return streamed, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// skipWAVHeader skips the chunks of a WAV header up to the audio data, if the stream has one
+func skipWAVHeader(r *bufio.Reader) error {
+	magic, err := r.Peek(4)
+	if err != nil || string(magic) != "RIFF" {
+		// Raw audio, or an empty stream reported by the first read
+		return nil
+	}
+	if _, err := r.Discard(12); err != nil {
+		return fmt.Errorf("invalid WAV header: %w", err)
+	}
+	var chunk [8]byte
+	for {
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return fmt.Errorf("invalid WAV header: %w", err)
+		}
+		if string(chunk[:4]) == "data" {
+			return nil
+		}
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		if _, err := r.Discard(int(size + size%2)); err != nil {
+			return fmt.Errorf("invalid WAV header: %w", err)
+		}
+	}
+}
+
+// playFallback plays the fallback file in a loop until stopped
+func (m *MOH) playFallback(ctx context.Context) error {
+	for {
+		playback, err := m.conn.StartPlay(ctx, m.source.FallbackURL, false)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to play music on hold: %w", err)
+		}
+		if err := playback.Wait(ctx); err != nil {
+			if ctx.Err() != nil && m.conn.taskContext().Err() == nil {
+				// Stopped while the call goes on: cut the file short
+				interruptCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				m.conn.InterruptContext(interruptCtx)
+			}
+			// Interrupted by the application or the call ended
+			return nil
+		}
+	}
+}
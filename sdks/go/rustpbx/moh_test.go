@@ -0,0 +1,133 @@
+package rustpbx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mohServer counts the audio frames it receives, forwards the commands and plays files
+// for 20ms
+func mohServer(t *testing.T) (string, *atomic.Int64, <-chan map[string]interface{}) {
+	var frames atomic.Int64
+	commands := make(chan map[string]interface{}, 64)
+	server := newCommandServer(t, func(r *http.Request, conn *websocket.Conn) func(cmd map[string]interface{}) {
+		return func(cmd map[string]interface{}) {
+			if cmd == nil {
+				frames.Add(1)
+				return
+			}
+			commands <- cmd
+			if cmd["command"] == "play" {
+				conn.WriteJSON(Event{Event: "trackStart", TrackID: "server-side-track"})
+				time.Sleep(20 * time.Millisecond)
+				conn.WriteJSON(Event{Event: "trackEnd", TrackID: "server-side-track"})
+			}
+		}
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http"), &frames, commands
+}
+
+func TestMOHStream(t *testing.T) {
+	// Three frames of linear PCM silence per request, with a WAV header on the first
+	var requests atomic.Int64
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Write([]byte("RIFF\x00\x00\x00\x00WAVEfmt \x04\x00\x00\x00\x07\x00\x01\x00data\x00\x00\x00\x00"))
+		}
+		w.Write(make([]byte, 640*3))
+	}))
+	defer stream.Close()
+	wsURL, frames, _ := mohServer(t)
+	conn, err := NewConnection(context.Background(), wsURL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	moh, err := conn.StartMOH(MOHSource{StreamURL: stream.URL, RetryDelay: 10 * time.Millisecond, MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("StartMOH failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for requests.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	moh.Stop()
+	if requests.Load() < 3 {
+		t.Errorf("Expected the ended stream to be reconnected, got %d requests", requests.Load())
+	}
+	if n := frames.Load(); n < 6 {
+		t.Errorf("Expected the stream to be relayed in 20ms frames, got %d frames", n)
+	}
+	if moh.Fallback() {
+		t.Error("Expected no fallback while the stream plays")
+	}
+}
+
+func TestMOHFallback(t *testing.T) {
+	stream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "mount not found", http.StatusNotFound)
+	}))
+	defer stream.Close()
+	wsURL, _, commands := mohServer(t)
+	conn, err := NewConnection(context.Background(), wsURL)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	fallbacks := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "mohFallback" {
+			fallbacks <- event
+		}
+	})
+
+	moh, err := conn.StartMOH(MOHSource{
+		StreamURL:   stream.URL,
+		FallbackURL: "http://example.com/hold.wav",
+		MaxRetries:  2,
+		RetryDelay:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("StartMOH failed: %v", err)
+	}
+	select {
+	case event := <-fallbacks:
+		if !strings.Contains(event.Error, "status 404") {
+			t.Errorf("Expected the stream failure, got %s", event.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a mohFallback event")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case cmd := <-commands:
+			if cmd["command"] != "play" || cmd["url"] != "http://example.com/hold.wav" {
+				t.Errorf("Expected the fallback to be played, got %v", cmd)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the fallback to be looped")
+		}
+	}
+	if !moh.Fallback() {
+		t.Error("Expected Fallback to report the fallback")
+	}
+	moh.Stop()
+	for {
+		select {
+		case cmd := <-commands:
+			if cmd["command"] == "interrupt" {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the fallback to be interrupted on Stop")
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package rustpbx
+
+import "context"
+
+// Notification describes a campaign outcome that needs a fallback message
+type Notification struct {
+	Target   CampaignTarget
+	Outcome  CampaignOutcome
+	Attempts int
+}
+
+// Notifier delivers fallback notifications through a user-provided gateway such as SMS or email
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+// Notify calls f(ctx, n)
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
+
+// MultiNotifier notifies through every notifier in order and returns the first error
+type MultiNotifier []Notifier
+
+// Notify delivers n through every notifier, continuing past failures
+func (m MultiNotifier) Notify(ctx context.Context, n *Notification) error {
+	var firstErr error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,30 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMultiNotifier(t *testing.T) {
+	failure := errors.New("sms gateway down")
+	var delivered []string
+	notifier := MultiNotifier{
+		NotifierFunc(func(ctx context.Context, n *Notification) error {
+			delivered = append(delivered, "sms")
+			return failure
+		}),
+		NotifierFunc(func(ctx context.Context, n *Notification) error {
+			delivered = append(delivered, "email")
+			return errors.New("mailbox full")
+		}),
+	}
+
+	err := notifier.Notify(context.Background(), &Notification{Target: CampaignTarget{ID: "t1"}, Outcome: CampaignNoAnswer})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected the first error, got %v", err)
+	}
+	if len(delivered) != 2 {
+		t.Errorf("Expected every notifier to be tried, got %v", delivered)
+	}
+}
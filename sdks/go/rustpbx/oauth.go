@@ -0,0 +1,59 @@
+package rustpbx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuth2ClientCredentials obtains access tokens from an identity provider with the OAuth2
+// client credentials grant, for deployments fronted by a standard identity provider:
+//
+//	client := NewClientWithOptions(baseURL, ClientOptions{
+//		TokenProvider: (&OAuth2ClientCredentials{TokenURL: url, ClientID: id, ClientSecret: secret}).TokenProvider(),
+//	})
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Params are added to the token request, e.g. "audience" for some providers
+	Params url.Values
+	// SecretInBody sends the client credentials as form parameters rather than with
+	// basic authentication, for providers that require it
+	SecretInBody bool
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// RefreshBefore is how long before expiry tokens are refreshed; 30s when zero
+	RefreshBefore time.Duration
+}
+
+// FetchToken implements JWTSource
+func (o *OAuth2ClientCredentials) FetchToken(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	for key, values := range o.Params {
+		form[key] = values
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	endpoint := &JWTEndpoint{URL: o.TokenURL, Form: form, HTTPClient: o.HTTPClient}
+	if o.SecretInBody {
+		form.Set("client_id", o.ClientID)
+		form.Set("client_secret", o.ClientSecret)
+	} else {
+		req := &http.Request{Header: http.Header{}}
+		// Client credentials are form encoded before basic authentication, as RFC 6749 requires
+		req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+		endpoint.Headers = map[string]string{"Authorization": req.Header.Get("Authorization")}
+	}
+	return endpoint.FetchToken(ctx)
+}
+
+// TokenProvider returns a provider caching the access tokens and refreshing them before
+// they expire, to set as the TokenProvider of ClientOptions
+func (o *OAuth2ClientCredentials) TokenProvider() *JWTProvider {
+	return NewJWTProvider(o, o.RefreshBefore)
+}
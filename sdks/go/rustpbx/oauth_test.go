@@ -0,0 +1,68 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var issued atomic.Int32
+	identity := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		id, secret, ok := r.BasicAuth()
+		if ok {
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+		} else {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if id != "bot fleet" || secret != "s3cret" || r.PostForm.Get("grant_type") != "client_credentials" ||
+			r.PostForm.Get("scope") != "calls:read calls:write" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer identity.Close()
+
+	authorized := make(chan string, 2)
+	pbx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized <- r.Header.Get("Authorization")
+		w.Write([]byte(`{"calls":[]}`))
+	}))
+	defer pbx.Close()
+
+	credentials := &OAuth2ClientCredentials{
+		TokenURL:     identity.URL,
+		ClientID:     "bot fleet",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"calls:read", "calls:write"},
+	}
+	client := NewClientWithOptions(pbx.URL, ClientOptions{TokenProvider: credentials.TokenProvider()})
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetActiveCalls(context.Background()); err != nil {
+			t.Fatalf("GetActiveCalls failed: %v", err)
+		}
+		if header := <-authorized; header != "Bearer access" {
+			t.Errorf("Expected the access token, got '%s'", header)
+		}
+	}
+	if n := issued.Load(); n != 1 {
+		t.Errorf("Expected the token to be cached, got %d token requests", n)
+	}
+
+	credentials.SecretInBody = true
+	if _, _, err := credentials.FetchToken(context.Background()); err != nil {
+		t.Errorf("Expected credentials in the body to be accepted, got %v", err)
+	}
+	credentials.ClientSecret = "wrong"
+	if _, _, err := credentials.FetchToken(context.Background()); err == nil {
+		t.Error("Expected invalid credentials to fail")
+	}
+}
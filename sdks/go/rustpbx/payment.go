@@ -0,0 +1,363 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrPaymentCaptureFailed is returned when the caller did not enter valid card details within the retries
+var ErrPaymentCaptureFailed = errors.New("payment capture failed")
+
+// errNoPaymentToken is returned when a PaymentProcessor returns neither a token nor an error
+var errNoPaymentToken = errors.New("payment processor returned no token")
+
+// CardDetails represents the card data collected from the caller. It is only handed
+// to the PaymentProcessor and never logged, dispatched or recorded.
+type CardDetails struct {
+	Number string
+	// ExpiryMonth and ExpiryYear are the card expiry, e.g. 9 and 2027
+	ExpiryMonth int
+	ExpiryYear  int
+	CVV         string
+}
+
+// PaymentToken represents a tokenized card returned by a payment service provider
+type PaymentToken struct {
+	Token string
+	Brand string
+	Last4 string
+}
+
+// PaymentProcessor tokenizes card details with a payment service provider
+type PaymentProcessor interface {
+	Tokenize(ctx context.Context, card *CardDetails) (*PaymentToken, error)
+}
+
+// PaymentOptions represents payment capture configuration
+type PaymentOptions struct {
+	Processor PaymentProcessor
+	Speaker   string
+	// Locale provides the prompts (payment_card_number, payment_expiry, payment_cvv and
+	// payment_retry); EnglishLocale when nil, falling back to English for missing keys
+	Locale *LocaleBundle
+	// FirstDigitTimeout is the wait for the first key press, counted again from the end
+	// of the prompt so a long prompt does not use it up; 15s when zero
+	FirstDigitTimeout time.Duration
+	// DigitTimeout is the longest pause between key presses; 8s when zero
+	DigitTimeout time.Duration
+	// Retries is the number of times each item is asked again; 2 when zero, none when negative
+	Retries int
+	// SkipCVV does not ask for the security code
+	SkipCVV bool
+	// KeepRecording captures on a recorded call, e.g. when the recordings are redacted
+	// downstream; the card tones are then in the recording
+	KeepRecording bool
+}
+
+// CapturePayment collects card details over DTMF and tokenizes them. While it runs, DTMF
+// and speech recognition events and the caller's audio are withheld from the event
+// handler, the debug log and the wire dump, so card data never reaches logs, dumps or
+// transcripts.
+// Audit events (paymentCaptureStarted, paymentCaptureCompleted, paymentCaptureFailed)
+// carry only the card brand and last four digits.
+//
+// RustPBX cannot pause a recording, so the capture fails closed with
+// ErrPaymentCaptureFailed on a call recorded through its call option, unless
+// KeepRecording is set.
+func (c *Connection) CapturePayment(ctx context.Context, options *PaymentOptions) (*PaymentToken, error) {
+	if options == nil || options.Processor == nil {
+		return nil, fmt.Errorf("payment capture requires a processor")
+	}
+	opts := *options
+	if opts.Locale == nil {
+		opts.Locale = EnglishLocale
+	}
+	if opts.FirstDigitTimeout == 0 {
+		opts.FirstDigitTimeout = 15 * time.Second
+	}
+	if opts.DigitTimeout == 0 {
+		opts.DigitTimeout = 8 * time.Second
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 2
+	} else if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+
+	if c.recorded() && !opts.KeepRecording {
+		c.auditPayment("paymentCaptureFailed", nil, "call is recorded")
+		return nil, fmt.Errorf("%w: the call is recorded and the server cannot pause the recording", ErrPaymentCaptureFailed)
+	}
+
+	c.mu.Lock()
+	if c.sensitive != nil {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("payment capture already in progress")
+	}
+	digits := make(chan string, 64)
+	c.sensitive = digits
+	c.mu.Unlock()
+
+	c.auditPayment("paymentCaptureStarted", nil, "")
+	token, err := c.collectPayment(ctx, &opts, digits)
+	c.endSensitiveCapture()
+	if err != nil {
+		c.auditPayment("paymentCaptureFailed", nil, err.Error())
+		return nil, err
+	}
+	c.auditPayment("paymentCaptureCompleted", token, "")
+	return token, nil
+}
+
+// collectPayment asks for each card item and tokenizes the card
+func (c *Connection) collectPayment(ctx context.Context, opts *PaymentOptions, digits <-chan string) (*PaymentToken, error) {
+	card := &CardDetails{}
+	defer func() {
+		// Drop the card data as soon as possible
+		*card = CardDetails{}
+	}()
+
+	number, err := c.collectDigits(ctx, opts, digits,
+		capturePrompt(opts.Locale, "payment_card_number", nil),
+		func(s string) bool { return len(s) >= 12 && len(s) <= 19 && luhnValid(s) })
+	if err != nil {
+		return nil, err
+	}
+	card.Number = number
+
+	now := time.Now()
+	expiry, err := c.collectDigits(ctx, opts, digits,
+		capturePrompt(opts.Locale, "payment_expiry", nil),
+		func(s string) bool {
+			month, year, ok := parseExpiry(s)
+			return ok && !time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, now.Location()).Before(now)
+		})
+	if err != nil {
+		return nil, err
+	}
+	card.ExpiryMonth, card.ExpiryYear, _ = parseExpiry(expiry)
+
+	if !opts.SkipCVV {
+		length := 3
+		if cardBrand(card.Number) == "amex" {
+			length = 4
+		}
+		cvv, err := c.collectDigits(ctx, opts, digits,
+			capturePrompt(opts.Locale, "payment_cvv", map[string]string{"length": strconv.Itoa(length)}),
+			func(s string) bool { return len(s) == length })
+		if err != nil {
+			return nil, err
+		}
+		card.CVV = cvv
+	}
+
+	token, err := opts.Processor.Tokenize(ctx, card)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tokenize card: %w", err)
+	}
+	if token == nil {
+		return nil, fmt.Errorf("failed to tokenize card: %w", errNoPaymentToken)
+	}
+	if token.Brand == "" {
+		token.Brand = cardBrand(card.Number)
+	}
+	if token.Last4 == "" {
+		token.Last4 = card.Number[len(card.Number)-4:]
+	}
+	return token, nil
+}
+
+// collectDigits prompts for a DTMF entry terminated by # or a pause, retrying invalid entries
+func (c *Connection) collectDigits(ctx context.Context, opts *PaymentOptions, digits <-chan string, prompt string, valid func(string) bool) (string, error) {
+	question := prompt
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		drainDigits(digits)
+		playback, err := c.StartTTS(ctx, question, opts.Speaker, "", nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to prompt for payment: %w", err)
+		}
+		prompted := playback.Done()
+
+		var entry strings.Builder
+		pressed := false
+		timer := time.NewTimer(opts.FirstDigitTimeout)
+	read:
+		for {
+			select {
+			case <-prompted:
+				prompted = nil
+				if !pressed {
+					timer.Reset(opts.FirstDigitTimeout)
+				}
+			case digit := <-digits:
+				pressed = true
+				if digit == "#" {
+					break read
+				}
+				if digit == "*" {
+					// Start over
+					entry.Reset()
+				} else {
+					entry.WriteString(digit)
+				}
+				timer.Reset(opts.DigitTimeout)
+			case <-timer.C:
+				break read
+			case <-ctx.Done():
+				timer.Stop()
+				return "", ctx.Err()
+			case <-c.ctx.Done():
+				timer.Stop()
+				return "", fmt.Errorf("%w during payment capture", ErrConnectionClosed)
+			}
+		}
+		timer.Stop()
+
+		if value := entry.String(); valid(value) {
+			return value, nil
+		}
+		question = capturePrompt(opts.Locale, "payment_retry", map[string]string{"prompt": prompt})
+	}
+	return "", ErrPaymentCaptureFailed
+}
+
+// drainDigits discards key presses made before a prompt
+func drainDigits(digits <-chan string) {
+	for {
+		select {
+		case <-digits:
+		default:
+			return
+		}
+	}
+}
+
+// endSensitiveCapture restores event delivery
+func (c *Connection) endSensitiveCapture() {
+	c.mu.Lock()
+	c.sensitive = nil
+	c.mu.Unlock()
+}
+
+// captureSensitive withholds DTMF and speech events during payment capture, delivering
+// key presses to the capture instead
+func (c *Connection) captureSensitive(event *Event) bool {
+	c.mu.RLock()
+	sensitive := c.sensitive
+	c.mu.RUnlock()
+	if sensitive == nil {
+		return true
+	}
+	if event.Event == "dtmf" {
+		select {
+		case sensitive <- event.Digit:
+		default:
+		}
+	}
+	return false
+}
+
+// sensitiveFrame reports whether a received frame may carry card data during payment
+// capture: key presses, transcripts and the caller's audio. Frames that cannot be read
+// are assumed to carry some.
+func (c *Connection) sensitiveFrame(messageType int, data []byte) bool {
+	c.mu.RLock()
+	capturing := c.sensitive != nil
+	c.mu.RUnlock()
+	if !capturing {
+		return false
+	}
+	if messageType == websocket.BinaryMessage {
+		return true
+	}
+	var frame struct {
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return true
+	}
+	switch frame.Event {
+	case "dtmf", "asrDelta", "asrFinal":
+		return true
+	}
+	return false
+}
+
+// auditPayment emits a payment audit event without card data
+func (c *Connection) auditPayment(name string, token *PaymentToken, reason string) {
+	audit := map[string]string{}
+	if token != nil {
+		audit["brand"] = token.Brand
+		audit["last4"] = token.Last4
+	}
+	data, _ := json.Marshal(audit)
+	c.dispatch(&Event{
+		Event:     name,
+		Timestamp: time.Now().UnixMilli(),
+		Reason:    reason,
+		Data:      data,
+	})
+}
+
+// luhnValid checks the Luhn checksum of a card number
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// parseExpiry reads an MMYY expiry date
+func parseExpiry(s string) (month, year int, ok bool) {
+	if len(s) != 4 || strings.Trim(s, "0123456789") != "" {
+		return 0, 0, false
+	}
+	month = int(s[0]-'0')*10 + int(s[1]-'0')
+	year = 2000 + int(s[2]-'0')*10 + int(s[3]-'0')
+	return month, year, month >= 1 && month <= 12
+}
+
+// cardBrand identifies the card network from the number prefix
+func cardBrand(number string) string {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		v := 0
+		for _, r := range number[:n] {
+			v = v*10 + int(r-'0')
+		}
+		return v
+	}
+	switch {
+	case prefix(1) == 4:
+		return "visa"
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return "mastercard"
+	case prefix(2) == 34 || prefix(2) == 37:
+		return "amex"
+	case prefix(4) == 6011 || prefix(2) == 65:
+		return "discover"
+	}
+	return "unknown"
+}
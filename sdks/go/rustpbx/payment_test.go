@@ -0,0 +1,228 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeProcessor tokenizes cards in memory
+type fakeProcessor struct {
+	card CardDetails
+}
+
+func (p *fakeProcessor) Tokenize(ctx context.Context, card *CardDetails) (*PaymentToken, error) {
+	p.card = *card
+	return &PaymentToken{Token: "tok_123"}, nil
+}
+
+func TestLuhnAndBrand(t *testing.T) {
+	if !luhnValid("4111111111111111") || luhnValid("4111111111111112") {
+		t.Error("Unexpected Luhn result")
+	}
+	brands := map[string]string{"4111111111111111": "visa", "5500000000000004": "mastercard", "378282246310005": "amex"}
+	for number, brand := range brands {
+		if b := cardBrand(number); b != brand {
+			t.Errorf("cardBrand(%s) = %s, expected %s", number, b, brand)
+		}
+	}
+}
+
+// paymentServer answers each prompt with the key presses of the next entry, after a
+// transcript of them, and returns a connection dumping its frames to dump
+func paymentServer(t *testing.T, dump *WireDump, entries ...string) (*Connection, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []string
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			mu.Lock()
+			received = append(received, cmd["command"].(string))
+			mu.Unlock()
+			if cmd["command"] != "tts" || len(entries) == 0 {
+				continue
+			}
+			conn.WriteJSON(map[string]interface{}{"event": "asrFinal", "text": "four one one one"})
+			for _, digit := range entries[0] {
+				conn.WriteJSON(map[string]interface{}{"event": "dtmf", "digit": string(digit)})
+			}
+			entries = entries[1:]
+		}
+	})
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{WireDump: dump})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+func TestCapturePayment(t *testing.T) {
+	year := time.Now().Year() + 2 - 2000
+	var dump bytes.Buffer
+	wireDump := NewWireDump(&dump)
+	wireDump.Audio = true
+	conn, received := paymentServer(t, wireDump, "4111111111111112#", "4111111111111111#", fmt.Sprintf("12%02d#", year), "123#")
+
+	var leaked []string
+	completed := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		switch event.Event {
+		case "dtmf", "asrFinal":
+			leaked = append(leaked, event.Event)
+		case "paymentCaptureCompleted":
+			completed <- event
+		}
+	})
+
+	processor := &fakeProcessor{}
+	token, err := conn.CapturePayment(context.Background(), &PaymentOptions{Processor: processor, DigitTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("CapturePayment failed: %v", err)
+	}
+
+	if token.Token != "tok_123" || token.Last4 != "1111" || token.Brand != "visa" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	if processor.card.Number != "4111111111111111" || processor.card.ExpiryMonth != 12 || processor.card.CVV != "123" {
+		t.Errorf("Unexpected card handed to the processor: %+v", processor.card)
+	}
+	if len(leaked) > 0 {
+		t.Errorf("Expected sensitive events to be withheld, got %v", leaked)
+	}
+
+	event := <-completed
+	var audit map[string]string
+	json.Unmarshal(event.Data, &audit)
+	if audit["last4"] != "1111" || len(audit) != 2 {
+		t.Errorf("Expected audit with brand and last 4 only, got %v", audit)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	for _, command := range received() {
+		if command != "tts" {
+			t.Errorf("Expected only prompts to be sent, got %v", received())
+		}
+	}
+
+	wireDump.Close()
+	records, err := ReadWireDump(&dump)
+	if err != nil {
+		t.Fatalf("ReadWireDump failed: %v", err)
+	}
+	prompts := 0
+	for _, record := range records {
+		if strings.Contains(record.Text, "dtmf") || strings.Contains(record.Text, "asrFinal") {
+			t.Errorf("Expected card data to be kept out of the wire dump, got %s", record.Text)
+		}
+		if strings.Contains(record.Text, `"tts"`) {
+			prompts++
+		}
+	}
+	if prompts == 0 {
+		t.Error("Expected the prompts in the wire dump")
+	}
+}
+
+// nilProcessor returns neither a token nor an error
+type nilProcessor struct{}
+
+func (nilProcessor) Tokenize(ctx context.Context, card *CardDetails) (*PaymentToken, error) {
+	return nil, nil
+}
+
+func TestCapturePaymentWithoutToken(t *testing.T) {
+	year := time.Now().Year() + 2 - 2000
+	conn, _ := paymentServer(t, nil, "4111111111111111#", fmt.Sprintf("12%02d#", year), "123#")
+	_, err := conn.CapturePayment(context.Background(), &PaymentOptions{Processor: nilProcessor{}, DigitTimeout: time.Second})
+	if !errors.Is(err, errNoPaymentToken) {
+		t.Fatalf("Expected an error for a missing token, got %v", err)
+	}
+}
+
+func TestCapturePaymentPrompts(t *testing.T) {
+	year := time.Now().Year() + 2 - 2000
+	entries := []string{"4111111111111111#", fmt.Sprintf("12%02d#", year)}
+	prompts := make(chan string, 4)
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for len(entries) > 0 {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if cmd["command"] != "tts" {
+				continue
+			}
+			prompts <- cmd["text"].(string)
+			// The prompt plays longer than the first digit timeout, and the caller
+			// starts typing once it ended
+			conn.WriteJSON(Event{Event: "trackStart", TrackID: "prompt"})
+			time.Sleep(300 * time.Millisecond)
+			conn.WriteJSON(Event{Event: "trackEnd", TrackID: "prompt"})
+			time.Sleep(200 * time.Millisecond)
+			for _, digit := range entries[0] {
+				conn.WriteJSON(Event{Event: "dtmf", Digit: string(digit)})
+			}
+			entries = entries[1:]
+		}
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.CapturePayment(context.Background(), &PaymentOptions{
+		Processor:         &fakeProcessor{},
+		Locale:            SpanishLocale,
+		FirstDigitTimeout: 400 * time.Millisecond,
+		DigitTimeout:      time.Second,
+		SkipCVV:           true,
+	})
+	if err != nil {
+		t.Fatalf("Expected the first digit timeout to count from the end of the prompt, got %v", err)
+	}
+	if prompt := <-prompts; prompt != SpanishLocale.Prompts["payment_card_number"] {
+		t.Errorf("Expected the Spanish card number prompt, got %q", prompt)
+	}
+}
+
+func TestCapturePaymentFailsClosedOnRecordedCall(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Invite(&CallOption{Recorder: &RecorderOption{}}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	<-commands
+
+	_, err = conn.CapturePayment(context.Background(), &PaymentOptions{Processor: &fakeProcessor{}})
+	if !errors.Is(err, ErrPaymentCaptureFailed) {
+		t.Fatalf("Expected ErrPaymentCaptureFailed on a recorded call, got %v", err)
+	}
+	select {
+	case cmd := <-commands:
+		t.Errorf("Expected no prompt, got %v", cmd)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
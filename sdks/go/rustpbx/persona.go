@@ -0,0 +1,101 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+)
+
+// Persona represents a named assistant role with its own prompt and voice
+type Persona struct {
+	Name         string
+	SystemPrompt string
+	// Speaker is the TTS voice of the persona; the assistant's Speaker when empty. RustPBX
+	// keeps the ASR and TTS languages of the call, so personas share them.
+	Speaker string
+	// Tools names the tools the persona may use. LLM implementations read the active
+	// persona with PersonaFromContext.
+	Tools []string
+	// Greeting is spoken when the assistant switches to the persona
+	Greeting string
+}
+
+// personaKey is the context key of the active persona
+type personaKey struct{}
+
+// withPersona returns a copy of ctx carrying the active persona
+func withPersona(ctx context.Context, persona *Persona) context.Context {
+	return context.WithValue(ctx, personaKey{}, persona)
+}
+
+// PersonaFromContext returns the persona an LLM request is made for
+func PersonaFromContext(ctx context.Context) (*Persona, bool) {
+	persona, ok := ctx.Value(personaKey{}).(*Persona)
+	return persona, ok
+}
+
+// Persona returns the active persona, or nil when none is configured
+func (a *Assistant) Persona() *Persona {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.persona
+}
+
+// SwitchPersona makes the named persona active. The system prompt of the conversation
+// and the TTS voice change together; a reply that has not been spoken yet is prepared
+// again by the new persona.
+func (a *Assistant) SwitchPersona(name string) error {
+	persona, ok := a.personas[name]
+	if !ok {
+		return fmt.Errorf("unknown persona: %s", name)
+	}
+
+	a.mu.Lock()
+	if a.persona == persona {
+		a.mu.Unlock()
+		return nil
+	}
+	a.persona = persona
+	a.setSystemPrompt(persona.SystemPrompt)
+
+	if turn := a.turn; turn != nil && !turn.spoken && !turn.speculative {
+		turn.cancel()
+		a.prepareTurn(turn)
+	} else if turn != nil && turn.speculative {
+		turn.stop()
+		a.turn = nil
+	}
+	if persona.Greeting != "" {
+		a.history = append(a.history, ChatMessage{Role: "assistant", Content: persona.Greeting})
+	}
+	speaker := a.speaker()
+	a.mu.Unlock()
+
+	if persona.Greeting != "" {
+		a.speakAs(persona.Greeting, speaker)
+		a.conn.History("assistant", persona.Greeting)
+	}
+	return nil
+}
+
+// setSystemPrompt replaces the system message of the conversation; the caller must hold a.mu
+func (a *Assistant) setSystemPrompt(prompt string) {
+	if len(a.history) > 0 && a.history[0].Role == "system" {
+		if prompt == "" {
+			a.history = a.history[1:]
+		} else {
+			a.history[0].Content = prompt
+		}
+		return
+	}
+	if prompt != "" {
+		a.history = append([]ChatMessage{{Role: "system", Content: prompt}}, a.history...)
+	}
+}
+
+// speaker returns the TTS voice of the active persona; the caller must hold a.mu
+func (a *Assistant) speaker() string {
+	if a.persona != nil && a.persona.Speaker != "" {
+		return a.persona.Speaker
+	}
+	return a.options.Speaker
+}
@@ -0,0 +1,81 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAssistantSwitchPersona(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			persona, _ := PersonaFromContext(ctx)
+			return persona.Name + ": " + messages[0].Content, nil
+		}),
+		Patience: &PatiencePolicy{Adaptive: true, Min: 10 * time.Millisecond},
+		Personas: []Persona{
+			{Name: "main", SystemPrompt: "You are a helpful assistant.", Speaker: "101002"},
+			{Name: "billing", SystemPrompt: "You handle billing.", Speaker: "101005", Greeting: "Billing here."},
+		},
+	})
+
+	if persona := assistant.Persona(); persona == nil || persona.Name != "main" {
+		t.Fatalf("Expected the first persona to be active, got %+v", persona)
+	}
+	if err := assistant.SwitchPersona("sales"); err == nil {
+		t.Error("Expected error for unknown persona")
+	}
+
+	if err := assistant.SwitchPersona("billing"); err != nil {
+		t.Fatalf("SwitchPersona failed: %v", err)
+	}
+	cmd := nextTTSCommand(t, commands)
+	if cmd["text"] != "Billing here." || cmd["speaker"] != "101005" {
+		t.Errorf("Expected greeting in the billing voice, got %v", cmd)
+	}
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "my invoice"})
+	cmd = nextTTSCommand(t, commands)
+	if cmd["text"] != "billing: You handle billing." || cmd["speaker"] != "101005" {
+		t.Errorf("Expected billing reply in the billing voice, got %v", cmd)
+	}
+
+	history := assistant.History()
+	if history[0].Role != "system" || history[0].Content != "You handle billing." {
+		t.Errorf("Expected system prompt to be replaced, got %+v", history[0])
+	}
+}
+
+func TestAssistantSwitchPersonaPreparesPendingReply(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			persona, _ := PersonaFromContext(ctx)
+			return "Reply from " + persona.Name, nil
+		}),
+		// The reply is not spoken before the persona changes
+		Patience: &PatiencePolicy{Base: 100 * time.Millisecond},
+		Personas: []Persona{{Name: "main"}, {Name: "support"}},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "my router is broken"})
+	if err := assistant.SwitchPersona("support"); err != nil {
+		t.Fatalf("SwitchPersona failed: %v", err)
+	}
+	if text := nextTTS(t, commands); text != "Reply from support" {
+		t.Errorf("Expected the pending reply to come from the new persona, got '%s'", text)
+	}
+}
@@ -0,0 +1,179 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPlaybackInterrupted is returned by Playback.Wait when the playback was interrupted,
+// by Interrupt or by the caller barging in
+var ErrPlaybackInterrupted = errors.New("playback interrupted")
+
+// ErrPlaybackAborted is returned by Playback.Wait when the call ended before the playback
+var ErrPlaybackAborted = errors.New("call ended before playback finished")
+
+// Playback is a TTS or Play started with StartTTS or StartPlay, resolving when its track ends
+type Playback struct {
+	PlayID string
+
+	// trackID is learned from the trackStart event of the playback
+	trackID     string
+	interrupted bool
+
+	once  sync.Once
+	done  chan struct{}
+	err   error
+	event *Event
+}
+
+// newPlayback creates a pending playback
+func newPlayback(playID string) *Playback {
+	return &Playback{PlayID: playID, done: make(chan struct{})}
+}
+
+// Done is closed when the playback finished, was interrupted or the call ended
+func (p *Playback) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits until the playback finishes. It returns ErrPlaybackInterrupted or
+// ErrPlaybackAborted if it did not play to the end, or the error of ctx.
+func (p *Playback) Wait(ctx context.Context) error {
+	select {
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Event returns the trackEnd or interruption event that ended the playback, or nil
+func (p *Playback) Event() *Event {
+	select {
+	case <-p.done:
+		return p.event
+	default:
+		return nil
+	}
+}
+
+// finish resolves the playback
+func (p *Playback) finish(event *Event, err error) {
+	p.once.Do(func() {
+		p.event, p.err = event, err
+		close(p.done)
+	})
+}
+
+// StartTTS is like TTSContext but returns a playback resolving when the speech ends, so
+// prompts can be sequenced without sleeps:
+//
+//	greeting, _ := conn.StartTTS(ctx, "Welcome", "", "", nil)
+//	greeting.Wait(ctx)
+//
+// The server plays one track at a time, so playbacks resolve in the order they started.
+func (c *Connection) StartTTS(ctx context.Context, text, speaker, playID string, options *TTSOptions) (*Playback, error) {
+	playback := c.trackPlayback(playID)
+	if err := c.TTSContext(ctx, text, speaker, playID, options); err != nil {
+		c.untrackPlayback(playback)
+		return nil, err
+	}
+	return playback, nil
+}
+
+// StartPlay is like PlayContext but returns a playback resolving when the audio ends
+func (c *Connection) StartPlay(ctx context.Context, url string, autoHangup bool) (*Playback, error) {
+	playback := c.trackPlayback("")
+	if err := c.PlayContext(ctx, url, autoHangup); err != nil {
+		c.untrackPlayback(playback)
+		return nil, err
+	}
+	return playback, nil
+}
+
+// trackPlayback adds a pending playback before its command is sent, so its events are not missed
+func (c *Connection) trackPlayback(playID string) *Playback {
+	playback := newPlayback(playID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hungUp || c.closed {
+		playback.finish(nil, ErrPlaybackAborted)
+		return playback
+	}
+	c.playbacks = append(c.playbacks, playback)
+	return playback
+}
+
+// untrackPlayback removes a playback whose command could not be sent
+func (c *Connection) untrackPlayback(playback *Playback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.playbacks {
+		if p == playback {
+			c.playbacks = append(c.playbacks[:i:i], c.playbacks[i+1:]...)
+			break
+		}
+	}
+	playback.finish(nil, fmt.Errorf("%w: command not sent", ErrPlaybackAborted))
+}
+
+// observePlayback resolves the pending playbacks from track and call events
+func (c *Connection) observePlayback(event *Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch event.Event {
+	case "trackStart":
+		if event.TrackID == c.endedTrack {
+			c.endedTrack = ""
+		}
+		for _, p := range c.playbacks {
+			if p.trackID == "" {
+				p.trackID = event.TrackID
+				return
+			}
+		}
+	case "trackEnd", "interruption":
+		if event.Event == "trackEnd" && event.TrackID != "" && event.TrackID == c.endedTrack {
+			// The track end following an interruption
+			c.endedTrack = ""
+			return
+		}
+		for i, p := range c.playbacks {
+			if p.trackID != "" && p.trackID != event.TrackID {
+				continue
+			}
+			c.playbacks = append(c.playbacks[:i:i], c.playbacks[i+1:]...)
+			var err error
+			if event.Event == "interruption" {
+				c.endedTrack = event.TrackID
+				err = ErrPlaybackInterrupted
+			} else if p.interrupted {
+				err = ErrPlaybackInterrupted
+			}
+			p.finish(event, err)
+			return
+		}
+	case "hangup":
+		c.abortPlaybacksLocked()
+	}
+}
+
+// interruptPlaybacks marks the pending playbacks as interrupted, when Interrupt is sent
+func (c *Connection) interruptPlaybacks() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.playbacks {
+		p.interrupted = true
+	}
+}
+
+// abortPlaybacksLocked fails the pending playbacks; the caller must hold c.mu
+func (c *Connection) abortPlaybacksLocked() {
+	for _, p := range c.playbacks {
+		p.finish(nil, ErrPlaybackAborted)
+	}
+	c.playbacks = nil
+}
@@ -0,0 +1,105 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// playbackServer plays TTS to the end, plays files until interrupted and hangs up on request
+func playbackServer(t *testing.T) string {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			switch cmd["command"] {
+			case "tts":
+				conn.WriteJSON(map[string]interface{}{"event": "trackStart", "trackId": "server-side"})
+				conn.WriteJSON(map[string]interface{}{"event": "trackEnd", "trackId": "server-side"})
+			case "play":
+				conn.WriteJSON(map[string]interface{}{"event": "trackStart", "trackId": "server-side"})
+			case "interrupt":
+				conn.WriteJSON(map[string]interface{}{"event": "trackEnd", "trackId": "caller-leg"})
+				conn.WriteJSON(map[string]interface{}{"event": "trackEnd", "trackId": "server-side"})
+			case "hangup":
+				conn.WriteJSON(map[string]interface{}{"event": "hangup"})
+			}
+		}
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestPlaybackWait(t *testing.T) {
+	conn, err := NewConnection(context.Background(), playbackServer(t))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	greeting, err := conn.StartTTS(ctx, "Welcome", "", "greeting", nil)
+	if err != nil {
+		t.Fatalf("StartTTS failed: %v", err)
+	}
+	if err := greeting.Wait(ctx); err != nil {
+		t.Errorf("Expected the greeting to finish, got %v", err)
+	}
+	if event := greeting.Event(); event == nil || event.Event != "trackEnd" {
+		t.Errorf("Expected the track end event, got %+v", event)
+	}
+
+	music, err := conn.StartPlay(ctx, "https://example.com/hold.wav", false)
+	if err != nil {
+		t.Fatalf("StartPlay failed: %v", err)
+	}
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if err := music.Wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the music to still play, got %v", err)
+	}
+	conn.Interrupt()
+	if err := music.Wait(ctx); !errors.Is(err, ErrPlaybackInterrupted) {
+		t.Errorf("Expected the music to be interrupted, got %v", err)
+	}
+
+	pending, err := conn.StartPlay(ctx, "https://example.com/hold.wav", false)
+	if err != nil {
+		t.Fatalf("StartPlay failed: %v", err)
+	}
+	conn.HangupSimple()
+	if err := pending.Wait(ctx); !errors.Is(err, ErrPlaybackAborted) {
+		t.Errorf("Expected the playback to be aborted by the hangup, got %v", err)
+	}
+	late, err := conn.StartTTS(ctx, "Anyone there?", "", "", nil)
+	if err != nil {
+		t.Fatalf("StartTTS failed: %v", err)
+	}
+	if err := late.Wait(ctx); !errors.Is(err, ErrPlaybackAborted) {
+		t.Errorf("Expected a playback after the hangup to be aborted, got %v", err)
+	}
+}
+
+func TestPlaybackInterruptionEvent(t *testing.T) {
+	conn := &Connection{}
+	playback := conn.trackPlayback("")
+	conn.observePlayback(&Event{Event: "trackStart", TrackID: "server-side"})
+	next := conn.trackPlayback("")
+	conn.observePlayback(&Event{Event: "interruption", TrackID: "server-side"})
+	conn.observePlayback(&Event{Event: "trackEnd", TrackID: "server-side"})
+
+	if err := playback.Wait(context.Background()); !errors.Is(err, ErrPlaybackInterrupted) {
+		t.Errorf("Expected the playback to be interrupted, got %v", err)
+	}
+	select {
+	case <-next.Done():
+		t.Error("Expected the track end following the interruption not to resolve the next playback")
+	default:
+	}
+}
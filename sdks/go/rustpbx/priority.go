@@ -0,0 +1,77 @@
+package rustpbx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeaderPriority carries the call priority on the client requests made with the call
+// context, see WithCallContext. RustPBX does not read it; the priorities of incoming
+// calls are counted in ConnectionMetrics.
+const HeaderPriority = "X-Call-Priority"
+
+// Priority is the service class of a call; higher priorities are admitted and routed first
+type Priority int
+
+const (
+	// PriorityLow is assigned to calls flagged by screening
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+	PriorityVIP
+)
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityVIP:
+		return "vip"
+	default:
+		return fmt.Sprint(int(p))
+	}
+}
+
+// ParsePriority returns the priority named by s, such as a CRM tier; unknown names are normal
+func ParsePriority(s string) Priority {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow
+	case "high", "gold", "premium":
+		return PriorityHigh
+	case "vip", "platinum":
+		return PriorityVIP
+	default:
+		return PriorityNormal
+	}
+}
+
+// prioritizeIncoming assigns the priority of an incoming call from its screening score
+// and caller identity. A flagged call is never raised above low.
+func (c *Connection) prioritizeIncoming(event *Event) {
+	if c.callContext == nil {
+		return
+	}
+	c.mu.RLock()
+	identity := c.callContext.Identity
+	c.mu.RUnlock()
+	priority := PriorityNormal
+	if identity != nil {
+		priority = identity.Priority
+		if priority == PriorityNormal && identity.Tier != "" {
+			priority = ParsePriority(identity.Tier)
+		}
+	}
+	if c.screening != nil && event.SpamScore >= c.screening.threshold() {
+		priority = PriorityLow
+	}
+	c.mu.Lock()
+	c.callContext.Priority = priority
+	c.mu.Unlock()
+	c.countPriority(priority)
+}
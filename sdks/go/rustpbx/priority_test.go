@@ -0,0 +1,100 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := map[string]Priority{"VIP": PriorityVIP, "gold": PriorityHigh, "low": PriorityLow, "bronze": PriorityNormal}
+	for name, expected := range tests {
+		if p := ParsePriority(name); p != expected {
+			t.Errorf("ParsePriority(%s) = %s, expected %s", name, p, expected)
+		}
+	}
+}
+
+func TestAdmissionPriorityOrder(t *testing.T) {
+	admission := NewAdmissionController(AdmissionOptions{MaxCalls: 1})
+	release, _ := admission.TryAcquire("", "", PriorityNormal)
+
+	admitted := make(chan Priority, 2)
+	wait := func(priority Priority) {
+		release, err := admission.Acquire(context.Background(), "", "", priority)
+		if err == nil {
+			admitted <- priority
+			time.Sleep(20 * time.Millisecond)
+			release()
+		}
+	}
+	go wait(PriorityNormal)
+	time.Sleep(20 * time.Millisecond)
+	go wait(PriorityVIP)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := admission.TryAcquire("", "", PriorityHigh); ok {
+		t.Error("Expected new call not to jump ahead of a queued VIP call")
+	}
+	release()
+
+	if first := <-admitted; first != PriorityVIP {
+		t.Errorf("Expected VIP call to be admitted first, got %s", first)
+	}
+	if second := <-admitted; second != PriorityNormal {
+		t.Errorf("Expected normal call to be admitted second, got %s", second)
+	}
+	if stats := admission.Stats(); stats.Admitted[PriorityVIP] != 1 || stats.Admitted[PriorityNormal] != 2 {
+		t.Errorf("Unexpected admission stats: %+v", stats)
+	}
+}
+
+func TestIncomingPriority(t *testing.T) {
+	tests := []struct {
+		name        string
+		attestation string
+		tier        string
+		expected    Priority
+	}{
+		{"vip caller", "A", "vip", PriorityVIP},
+		{"flagged vip caller", "C", "vip", PriorityLow},
+		{"unknown caller", "A", "", PriorityNormal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := newTestServer(t, func(conn *websocket.Conn) {
+				var ready map[string]interface{}
+				conn.ReadJSON(&ready)
+				conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100", Attestation: test.attestation})
+			})
+
+			conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{
+				Screening: &ScreeningPolicy{Threshold: 0.6},
+				Enricher: NewIdentityEnricher(func(ctx context.Context, caller string) (*CallerIdentity, error) {
+					return &CallerIdentity{Tier: test.tier}, nil
+				}, 0, 0),
+			})
+			if err != nil {
+				t.Fatalf("ConnectSIP failed: %v", err)
+			}
+			defer conn.Close()
+			events := make(chan *Event, 1)
+			conn.OnEvent(func(event *Event) { events <- event })
+			conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+			event := <-events
+			if event.Context.Priority != test.expected {
+				t.Errorf("Expected priority %s, got %s", test.expected, event.Context.Priority)
+			}
+			if header := event.Context.Headers().Get(HeaderPriority); test.expected != PriorityNormal && header != test.expected.String() {
+				t.Errorf("Expected priority header %s, got '%s'", test.expected, header)
+			}
+			if n := conn.Metrics().Priorities[test.expected]; n != 1 {
+				t.Errorf("Expected one %s call in the metrics, got %d", test.expected, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package rustpbx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyStage is a step of the speech-to-speech pipeline
+type LatencyStage string
+
+const (
+	StageVADEndpoint   LatencyStage = "vad_endpoint"
+	StageASRFinal      LatencyStage = "asr_final"
+	StageLLMFirstToken LatencyStage = "llm_first_token"
+	StageTTSFirstAudio LatencyStage = "tts_first_audio"
+)
+
+// latencyStages lists the stages in pipeline order
+var latencyStages = []LatencyStage{StageVADEndpoint, StageASRFinal, StageLLMFirstToken, StageTTSFirstAudio}
+
+// LatencyBudget represents the maximum time each stage may take, measured from the
+// previous stage. A zero budget is not enforced.
+type LatencyBudget struct {
+	ASRFinal      time.Duration
+	LLMFirstToken time.Duration
+	TTSFirstAudio time.Duration
+	Total         time.Duration
+}
+
+func (b LatencyBudget) limit(stage LatencyStage) time.Duration {
+	switch stage {
+	case StageASRFinal:
+		return b.ASRFinal
+	case StageLLMFirstToken:
+		return b.LLMFirstToken
+	case StageTTSFirstAudio:
+		return b.TTSFirstAudio
+	}
+	return 0
+}
+
+// TurnProfile reports the latency of one conversational turn
+type TurnProfile struct {
+	Turn int
+	// Marks holds the time each stage was reached
+	Marks map[LatencyStage]time.Time
+	// Stages holds the time each stage took since the previous one
+	Stages map[LatencyStage]time.Duration
+	Total  time.Duration
+	// Exceeded lists the stages that blew their budget
+	Exceeded []LatencyStage
+	// TotalExceeded is set when the turn as a whole blew the total budget
+	TotalExceeded bool
+}
+
+// String formats the profile as a single log line
+func (t *TurnProfile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "turn %d:", t.Turn)
+	for _, stage := range latencyStages[1:] {
+		if d, ok := t.Stages[stage]; ok {
+			fmt.Fprintf(&b, " %s=%s", stage, d.Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintf(&b, " total=%s", t.Total.Round(time.Millisecond))
+	if len(t.Exceeded) > 0 || t.TotalExceeded {
+		fmt.Fprintf(&b, " over budget: %v", t.Exceeded)
+	}
+	return b.String()
+}
+
+// LatencyProfiler measures each stage of the speech-to-speech pipeline per turn.
+// VAD endpoint, ASR final and TTS first audio are observed from connection events;
+// the LLM first token is reported by the application with Mark.
+type LatencyProfiler struct {
+	budget  LatencyBudget
+	onTurn  func(*TurnProfile)
+	mu      sync.Mutex
+	turn    int
+	current *TurnProfile
+	turns   []TurnProfile
+}
+
+// NewLatencyProfiler creates a profiler that calls onTurn with every completed turn
+func NewLatencyProfiler(budget LatencyBudget, onTurn func(*TurnProfile)) *LatencyProfiler {
+	return &LatencyProfiler{
+		budget: budget,
+		onTurn: onTurn,
+	}
+}
+
+// Observe records the pipeline stage signalled by a connection event
+func (p *LatencyProfiler) Observe(event *Event) {
+	switch event.Event {
+	case "silence", "eou":
+		p.Mark(StageVADEndpoint)
+	case "asrFinal":
+		p.Mark(StageASRFinal)
+	case "trackStart":
+		p.Mark(StageTTSFirstAudio)
+	}
+}
+
+// Mark records that a stage was reached now
+func (p *LatencyProfiler) Mark(stage LatencyStage) {
+	p.markAt(stage, time.Now())
+}
+
+func (p *LatencyProfiler) markAt(stage LatencyStage, at time.Time) {
+	p.mu.Lock()
+
+	switch {
+	case stage == StageVADEndpoint:
+		// The latest endpoint before the transcript wins; a new endpoint after it starts a new turn
+		if p.current == nil {
+			p.startTurn()
+		} else if _, ok := p.current.Marks[StageASRFinal]; ok {
+			p.startTurn()
+		}
+		p.current.Marks[stage] = at
+		p.mu.Unlock()
+		return
+	case p.current == nil && stage == StageASRFinal:
+		p.startTurn()
+	case p.current == nil:
+		p.mu.Unlock()
+		return
+	}
+
+	if _, ok := p.current.Marks[stage]; ok {
+		p.mu.Unlock()
+		return
+	}
+	// Audio that starts before the user's words are transcribed is not a response
+	if _, ok := p.current.Marks[StageASRFinal]; !ok && stage == StageTTSFirstAudio {
+		p.mu.Unlock()
+		return
+	}
+	p.current.Marks[stage] = at
+
+	if stage != StageTTSFirstAudio {
+		p.mu.Unlock()
+		return
+	}
+
+	profile := p.completeTurn()
+	p.mu.Unlock()
+
+	if p.onTurn != nil {
+		p.onTurn(profile)
+	}
+}
+
+// startTurn begins a new turn; the caller must hold p.mu
+func (p *LatencyProfiler) startTurn() {
+	p.turn++
+	p.current = &TurnProfile{
+		Turn:   p.turn,
+		Marks:  make(map[LatencyStage]time.Time),
+		Stages: make(map[LatencyStage]time.Duration),
+	}
+}
+
+// completeTurn computes the stage durations of the current turn; the caller must hold p.mu
+func (p *LatencyProfiler) completeTurn() *TurnProfile {
+	profile := p.current
+	p.current = nil
+
+	var first, previous time.Time
+	for _, stage := range latencyStages {
+		at, ok := profile.Marks[stage]
+		if !ok {
+			continue
+		}
+		if first.IsZero() {
+			first, previous = at, at
+			continue
+		}
+		d := at.Sub(previous)
+		profile.Stages[stage] = d
+		if limit := p.budget.limit(stage); limit > 0 && d > limit {
+			profile.Exceeded = append(profile.Exceeded, stage)
+		}
+		previous = at
+	}
+	profile.Total = previous.Sub(first)
+	profile.TotalExceeded = p.budget.Total > 0 && profile.Total > p.budget.Total
+
+	p.turns = append(p.turns, *profile)
+	return profile
+}
+
+// Turns returns the completed turns
+func (p *LatencyProfiler) Turns() []TurnProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]TurnProfile(nil), p.turns...)
+}
@@ -0,0 +1,50 @@
+package rustpbx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyProfilerTurn(t *testing.T) {
+	var reported *TurnProfile
+	profiler := NewLatencyProfiler(LatencyBudget{LLMFirstToken: 500 * time.Millisecond}, func(profile *TurnProfile) {
+		reported = profile
+	})
+
+	start := time.Now()
+	profiler.markAt(StageVADEndpoint, start)
+	profiler.markAt(StageASRFinal, start.Add(200*time.Millisecond))
+	profiler.markAt(StageLLMFirstToken, start.Add(900*time.Millisecond))
+	profiler.markAt(StageTTSFirstAudio, start.Add(1100*time.Millisecond))
+
+	if reported == nil {
+		t.Fatal("Expected turn to be reported")
+	}
+	if reported.Stages[StageLLMFirstToken] != 700*time.Millisecond {
+		t.Errorf("Expected LLM stage of 700ms, got %s", reported.Stages[StageLLMFirstToken])
+	}
+	if reported.Total != 1100*time.Millisecond {
+		t.Errorf("Expected total of 1.1s, got %s", reported.Total)
+	}
+	if len(reported.Exceeded) != 1 || reported.Exceeded[0] != StageLLMFirstToken {
+		t.Errorf("Expected LLM stage to exceed its budget, got %v", reported.Exceeded)
+	}
+}
+
+func TestLatencyProfilerObserve(t *testing.T) {
+	profiler := NewLatencyProfiler(LatencyBudget{}, nil)
+
+	// Audio before any user speech does not form a turn
+	profiler.Observe(&Event{Event: "trackStart"})
+	profiler.Observe(&Event{Event: "silence"})
+	profiler.Observe(&Event{Event: "asrFinal", Text: "hello"})
+	profiler.Observe(&Event{Event: "trackStart"})
+
+	turns := profiler.Turns()
+	if len(turns) != 1 || turns[0].Turn != 1 {
+		t.Fatalf("Expected one turn, got %+v", turns)
+	}
+	if _, ok := turns[0].Stages[StageASRFinal]; !ok {
+		t.Error("Expected ASR final stage to be measured")
+	}
+}
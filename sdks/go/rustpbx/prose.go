@@ -0,0 +1,233 @@
+package rustpbx
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ListStrategy controls how markdown lists are spoken
+type ListStrategy int
+
+const (
+	// ListSentences speaks every item as a sentence of its own
+	ListSentences ListStrategy = iota
+	// ListInline joins the items into one sentence: "apples, pears and plums."
+	ListInline
+	// ListOrdinal announces the items in turn: "First, apples. Second, pears."
+	ListOrdinal
+)
+
+// LinkStrategy controls how links are spoken
+type LinkStrategy int
+
+const (
+	// LinkText speaks the text of links; bare URLs are reduced to their host
+	LinkText LinkStrategy = iota
+	// LinkTextAndHost speaks the text of links followed by "at" and their host
+	LinkTextAndHost
+)
+
+// CodeStrategy controls how code is spoken
+type CodeStrategy int
+
+const (
+	// CodeRead speaks code blocks and inline code as text
+	CodeRead CodeStrategy = iota
+	// CodeSkip leaves out code blocks, speaking the code placeholder instead if set
+	CodeSkip
+)
+
+// DefaultOrdinals announce the items of ListOrdinal lists
+var DefaultOrdinals = []string{"First", "Second", "Third", "Fourth", "Fifth", "Sixth", "Seventh", "Eighth", "Ninth", "Tenth"}
+
+// MarkdownProse converts LLM markdown into speakable prose, so that lists, links and
+// code are not read out symbol by symbol. The zero value speaks list items as sentences,
+// links by their text and code as is.
+type MarkdownProse struct {
+	Lists ListStrategy
+	Links LinkStrategy
+	Code  CodeStrategy
+	// CodePlaceholder is spoken in place of skipped code blocks, e.g. "I've sent you the code."
+	CodePlaceholder string
+	// Ordinals announce ListOrdinal items, items past the last are spoken plainly; DefaultOrdinals when nil
+	Ordinals []string
+	// Conjunction joins the last ListInline item; "and" when empty
+	Conjunction string
+	// At introduces the host of LinkTextAndHost links; "at" when empty
+	At string
+}
+
+var (
+	markdownFence        = regexp.MustCompile("^(```|~~~)")
+	markdownRule         = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+	markdownTableDivider = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+	markdownBlock        = regexp.MustCompile(`^(#{1,6}|>)\s+`)
+	markdownItem         = regexp.MustCompile(`^([-*+]|\d{1,3}[.)])\s+`)
+	markdownImage        = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink         = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]*)[^)]*\)|\bhttps?://[^\s)>\]]+`)
+	markdownCode         = regexp.MustCompile("`([^`]*)`")
+	markdownBold         = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownItalic       = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	markdownUnderscore   = regexp.MustCompile(`(^|[^\w])_([^_]+)_([^\w]|$)`)
+	markdownStrike       = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// Convert returns the prose of a markdown text. Headings, list items and table rows become
+// sentences of their own so they are not run together once line breaks are collapsed.
+func (m *MarkdownProse) Convert(text string) string {
+	var out, items []string
+	flush := func() {
+		if len(items) > 0 {
+			out = append(out, m.list(items))
+			items = nil
+		}
+	}
+
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if markdownFence.MatchString(line) {
+			flush()
+			inCode = !inCode
+			if inCode && m.Code == CodeSkip && m.CodePlaceholder != "" {
+				out = append(out, m.CodePlaceholder)
+			}
+			continue
+		}
+		if inCode {
+			if m.Code == CodeRead {
+				out = append(out, line)
+			}
+			continue
+		}
+		if markdownRule.MatchString(line) || (strings.Contains(line, "|") && markdownTableDivider.MatchString(line)) {
+			continue
+		}
+
+		block := false
+		for {
+			loc := markdownBlock.FindStringIndex(line)
+			if loc == nil {
+				break
+			}
+			// Nested quotes and headings inside quotes
+			line, block = line[loc[1]:], true
+		}
+		if loc := markdownItem.FindStringIndex(line); loc != nil {
+			items = append(items, m.inline(line[loc[1]:]))
+			continue
+		}
+		if line == "" && len(items) > 0 {
+			// Loose lists separate their items with blank lines
+			continue
+		}
+		flush()
+
+		if strings.HasPrefix(line, "|") {
+			cells := strings.Split(strings.Trim(line, "|"), "|")
+			for i := range cells {
+				cells[i] = strings.TrimSpace(cells[i])
+			}
+			line, block = strings.Join(cells, ", "), true
+		}
+		line = m.inline(line)
+		if block {
+			line = sentence(line)
+		}
+		out = append(out, line)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// list speaks the items of a list with the list strategy
+func (m *MarkdownProse) list(items []string) string {
+	switch m.Lists {
+	case ListInline:
+		conjunction := m.Conjunction
+		if conjunction == "" {
+			conjunction = "and"
+		}
+		for i := range items {
+			items[i] = strings.TrimRightFunc(items[i], unicode.IsPunct)
+		}
+		last := len(items) - 1
+		if last == 0 {
+			return sentence(items[0])
+		}
+		return sentence(strings.Join(items[:last], ", ") + " " + conjunction + " " + items[last])
+	case ListOrdinal:
+		ordinals := m.Ordinals
+		if ordinals == nil {
+			ordinals = DefaultOrdinals
+		}
+		for i := range items {
+			items[i] = sentence(items[i])
+			if i < len(ordinals) {
+				items[i] = ordinals[i] + ", " + items[i]
+			}
+		}
+	default:
+		for i := range items {
+			items[i] = sentence(items[i])
+		}
+	}
+	return strings.Join(items, "\n")
+}
+
+// inline removes the emphasis, code and link formatting of a line
+func (m *MarkdownProse) inline(text string) string {
+	text = markdownImage.ReplaceAllString(text, "$1")
+	text = markdownLink.ReplaceAllStringFunc(text, func(link string) string {
+		match := markdownLink.FindStringSubmatch(link)
+		if match[1] == "" {
+			// A bare URL, possibly followed by punctuation
+			raw := strings.TrimRightFunc(link, unicode.IsPunct)
+			if host := urlHost(raw); host != "" {
+				return host + link[len(raw):]
+			}
+			return link
+		}
+		if host := urlHost(match[2]); m.Links == LinkTextAndHost && host != "" {
+			return match[1] + " " + m.at() + " " + host
+		}
+		return match[1]
+	})
+	text = markdownCode.ReplaceAllString(text, "$1")
+	text = markdownBold.ReplaceAllString(text, "$1$2")
+	text = markdownItalic.ReplaceAllString(text, "$1")
+	text = markdownUnderscore.ReplaceAllString(text, "$1$2$3")
+	text = markdownStrike.ReplaceAllString(text, "$1")
+	return text
+}
+
+// at returns the word introducing the host of a link
+func (m *MarkdownProse) at() string {
+	if m.At == "" {
+		return "at"
+	}
+	return m.At
+}
+
+// urlHost returns the host of a URL without "www.", or "" if it has none
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// sentence ends a line with a period unless it already ends with punctuation
+func sentence(line string) string {
+	if line == "" {
+		return line
+	}
+	if last, _ := utf8.DecodeLastRuneInString(line); unicode.IsPunct(last) {
+		return line
+	}
+	return line + "."
+}
@@ -0,0 +1,65 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownProse(t *testing.T) {
+	text := "You can pay:\n\n* by **card**\n* by [bank transfer](https://www.example.com/pay)\n* in person\n\nSee https://example.org/help."
+	tests := []struct {
+		prose    MarkdownProse
+		expected string
+	}{
+		{MarkdownProse{}, "You can pay: by card. by bank transfer. in person. See example.org."},
+		{MarkdownProse{Lists: ListInline}, "You can pay: by card, by bank transfer and in person. See example.org."},
+		{MarkdownProse{Lists: ListOrdinal, Ordinals: []string{"First", "Second"}, Links: LinkTextAndHost},
+			"You can pay: First, by card. Second, by bank transfer at example.com. in person. See example.org."},
+	}
+	for _, test := range tests {
+		got := strings.Join(strings.Fields(test.prose.Convert(text)), " ")
+		if got != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, got)
+		}
+	}
+}
+
+func TestMarkdownProseCode(t *testing.T) {
+	text := "Run this:\n```bash\nrm -rf /tmp/cache\n```\nThen call `restart`."
+	read := (&MarkdownProse{}).Convert(text)
+	if read != "Run this:\nrm -rf /tmp/cache\nThen call restart." {
+		t.Errorf("Expected code to be read, got %q", read)
+	}
+	skipped := (&MarkdownProse{Code: CodeSkip, CodePlaceholder: "I've sent you the command."}).Convert(text)
+	if skipped != "Run this:\nI've sent you the command.\nThen call restart." {
+		t.Errorf("Expected the code block to be skipped, got %q", skipped)
+	}
+}
+
+func TestAssistantProse(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	reply := "Options:\n- **red**\n- blue"
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return reply, nil
+		}),
+		Patience: &PatiencePolicy{Min: 10 * time.Millisecond},
+		Prose:    &MarkdownProse{Lists: ListInline, Conjunction: "or"},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "which colors?"})
+	if text := nextTTS(t, commands); text != "Options:\nred or blue." {
+		t.Errorf("Expected the reply as prose, got %q", text)
+	}
+	if history := assistant.History(); history[len(history)-1].Content != reply {
+		t.Errorf("Expected the history to keep the markdown reply, got %q", history[len(history)-1].Content)
+	}
+}
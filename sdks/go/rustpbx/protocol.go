@@ -0,0 +1,246 @@
+package rustpbx
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProtocolViolation is returned for commands and events that do not conform to the
+// protocol schema
+var ErrProtocolViolation = errors.New("protocol violation")
+
+// protocolSchemaJSON describes the commands sent by the SDK and the events it receives
+//
+//go:embed schema/protocol.json
+var protocolSchemaJSON []byte
+
+// ProtocolValidation controls the validation of commands and events against the protocol schema
+type ProtocolValidation int
+
+const (
+	// ValidationOff does not validate
+	ValidationOff ProtocolValidation = iota
+	// ValidationReport reports violations as "error" events with Sender "protocol", and
+	// sends the commands and delivers the events regardless
+	ValidationReport
+	// ValidationStrict fails the commands with ErrProtocolViolation and drops the events
+	// after reporting them, e.g. in integration tests against a new server version
+	ValidationStrict
+)
+
+// ProtocolSchema returns the embedded JSON schema of the protocol. It maps each command
+// and event type to the schema of its fields, sharing the option schemas in "definitions".
+func ProtocolSchema() []byte {
+	return bytes.Clone(protocolSchemaJSON)
+}
+
+// schemaNode is the subset of JSON Schema used by the protocol schema
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+}
+
+// schemaTypes is a type name or a list of type names
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// protocolSchema is the parsed protocol schema
+type protocolSchema struct {
+	Definitions map[string]*schemaNode `json:"definitions"`
+	Commands    map[string]*schemaNode `json:"commands"`
+	Events      map[string]*schemaNode `json:"events"`
+}
+
+// loadProtocolSchema parses the embedded schema once
+var loadProtocolSchema = sync.OnceValues(func() (*protocolSchema, error) {
+	var schema protocolSchema
+	if err := json.Unmarshal(protocolSchemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse protocol schema: %w", err)
+	}
+	return &schema, nil
+})
+
+// ValidateCommand checks an encoded command against the protocol schema
+func ValidateCommand(data []byte) error {
+	schema, err := loadProtocolSchema()
+	if err != nil {
+		return err
+	}
+	return schema.validateMessage(data, "command", schema.Commands)
+}
+
+// ValidateEvent checks an encoded event against the protocol schema
+func ValidateEvent(data []byte) error {
+	schema, err := loadProtocolSchema()
+	if err != nil {
+		return err
+	}
+	return schema.validateMessage(data, "event", schema.Events)
+}
+
+// validateMessage validates a command or event, identified by its tag field. Messages may
+// only carry the fields of their schema.
+func (s *protocolSchema) validateMessage(data []byte, tag string, messages map[string]*schemaNode) error {
+	var message map[string]interface{}
+	if err := json.Unmarshal(data, &message); err != nil {
+		return fmt.Errorf("%w: not a JSON object: %v", ErrProtocolViolation, err)
+	}
+	name, _ := message[tag].(string)
+	node, ok := messages[name]
+	if !ok {
+		return fmt.Errorf("%w: unknown %s %q", ErrProtocolViolation, tag, name)
+	}
+	delete(message, tag)
+
+	closed := *node
+	closed.Type = schemaTypes{"object"}
+	if closed.AdditionalProperties == nil {
+		closed.AdditionalProperties = json.RawMessage("false")
+	}
+	return s.validate(&closed, message, name)
+}
+
+// validate checks a decoded JSON value against a schema node; path names the value in errors
+func (s *protocolSchema) validate(node *schemaNode, value interface{}, path string) error {
+	if node.Ref != "" {
+		target, ok := s.Definitions[strings.TrimPrefix(node.Ref, "#/definitions/")]
+		if !ok {
+			return fmt.Errorf("unresolved schema reference %s", node.Ref)
+		}
+		node = target
+	}
+
+	if len(node.Type) > 0 && !matchesType(node.Type, value) {
+		return fmt.Errorf("%w: %s must be %s, got %s", ErrProtocolViolation, path, strings.Join(node.Type, " or "), jsonType(value))
+	}
+	if len(node.Enum) > 0 {
+		found := false
+		for _, allowed := range node.Enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: %s has unexpected value %v", ErrProtocolViolation, path, value)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range node.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%w: %s is missing %s", ErrProtocolViolation, path, name)
+			}
+		}
+		var additional *schemaNode
+		allowAdditional := true
+		if len(node.AdditionalProperties) > 0 {
+			if err := json.Unmarshal(node.AdditionalProperties, &allowAdditional); err != nil {
+				allowAdditional = true
+				if err := json.Unmarshal(node.AdditionalProperties, &additional); err != nil {
+					return fmt.Errorf("invalid additionalProperties at %s: %w", path, err)
+				}
+			}
+		}
+		// Validate in a stable order, so the same violation is reported each time
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child, ok := node.Properties[name]
+			switch {
+			case ok:
+			case additional != nil:
+				child = additional
+			case allowAdditional:
+				continue
+			default:
+				return fmt.Errorf("%w: %s has unexpected field %s", ErrProtocolViolation, path, name)
+			}
+			if err := s.validate(child, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if node.Items != nil {
+			for i, item := range v {
+				if err := s.validate(node.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value is of one of the schema types
+func matchesType(types schemaTypes, value interface{}) bool {
+	actual := jsonType(value)
+	for _, name := range types {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// reportProtocolViolation reports a command or event that does not conform to the schema
+func (c *Connection) reportProtocolViolation(err error) {
+	c.log().Warn("protocol violation", "error", err)
+	c.dispatch(&Event{
+		Event:     "error",
+		Timestamp: time.Now().UnixMilli(),
+		Sender:    "protocol",
+		Error:     err.Error(),
+	})
+}
@@ -0,0 +1,279 @@
+package rustpbx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares a value, encoded as indented JSON, with a golden file
+func checkGolden(t *testing.T, path string, value interface{}) {
+	t.Helper()
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+	if *updateGolden {
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file, run go test -update to create it: %v", err)
+	}
+	if !bytes.Equal(golden, data) {
+		t.Errorf("Command drifted from %s, run go test -update if intended:\n%s", path, data)
+	}
+}
+
+// TestCommandGolden sends every command with validation enabled and compares them with
+// the golden files, so protocol changes are deliberate
+func TestCommandGolden(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := newConnection(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil,
+		&ConnectionOptions{Validation: ValidationStrict}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	option := &CallOption{
+		Callee:   "sip:bob@example.com",
+		Caller:   "sip:alice@example.com",
+		Codec:    CodecPCMA,
+		Denoise:  true,
+		Recorder: &RecorderOption{RecorderFile: "call.wav", SampleRate: 16000, PTime: "20ms"},
+		VAD:      &VADOption{Type: VADTypeSilero},
+		ASR:      &TranscriptionOption{Provider: ProviderTencent, Language: "en-US", SecretID: "id", SecretKey: "key"},
+		TTS:      &SynthesisOption{Provider: ProviderTencent, Speaker: "1", Speed: 1.25, Emotion: EmotionNeutral},
+		SIP:      &SipOption{Username: "alice", Password: "secret", Realm: "example.com", Headers: map[string]string{"X-Tenant": "acme"}},
+		EOU:      &EouOption{Type: EOUTypeTencent, Timeout: 800},
+		Media:    &MediaOption{JitterMinMs: 20, JitterMaxMs: 200, PLC: PLCOn},
+	}
+	sends := []struct {
+		name string
+		send func() error
+	}{
+		{"invite", func() error { return conn.Invite(option) }},
+		{"accept", func() error { return conn.Accept(&CallOption{Codec: CodecPCMU}) }},
+		{"reject", func() error { return conn.Reject("busy", 486) }},
+		{"candidate", func() error { return conn.Candidate([]string{"candidate:1 1 UDP 2122260223 10.0.0.1 54321 typ host"}) }},
+		{"tts", func() error {
+			return conn.TTS("Hello", "1", "greeting", &TTSOptions{AutoHangup: true})
+		}},
+		{"tts_streaming", func() error {
+			return conn.TTS("Hel", "", "stream", &TTSOptions{Streaming: true, EndOfStream: true})
+		}},
+		{"play", func() error { return conn.Play("https://example.com/hold.wav", true) }},
+		{"interrupt", conn.Interrupt},
+		{"pause", conn.Pause},
+		{"resume", conn.Resume},
+		{"refer", func() error {
+			return conn.Refer("sip:agent@example.com", &ReferOption{Timeout: 30, AutoHangup: true})
+		}},
+		{"mute", func() error { return conn.Mute("track-1") }},
+		{"unmute", func() error { return conn.Unmute("track-1") }},
+		{"history", func() error { return conn.History("user", "I need help") }},
+		{"hangup", func() error { return conn.Hangup("normal_clearing", "caller") }},
+	}
+	for _, s := range sends {
+		if err := s.send(); err != nil {
+			t.Errorf("Failed to send %s: %v", s.name, err)
+			continue
+		}
+		select {
+		case cmd := <-commands:
+			checkGolden(t, filepath.Join("testdata", "golden", "commands", s.name+".json"), cmd)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the %s command", s.name)
+		}
+	}
+}
+
+// TestEventGolden validates and decodes the sample events of the server protocol
+func TestEventGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "golden", "events", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("Expected golden events, got %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateEvent(data); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+		event, err := decodeEvent(data, 0)
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", path, err)
+			continue
+		}
+		if name := strings.TrimSuffix(filepath.Base(path), ".json"); event.Event != name {
+			t.Errorf("%s: expected event %s, got %s", path, name, event.Event)
+		}
+	}
+}
+
+// TestProtocolSchemaMatchesServer compares the commands and events of the schema, and their
+// fields, with the Command and SessionEvent types of the server in this repository
+func TestProtocolSchemaMatchesServer(t *testing.T) {
+	commands, err := os.ReadFile(filepath.Join("..", "..", "..", "src", "handler", "mod.rs"))
+	if err != nil {
+		t.Skipf("Server sources not available: %v", err)
+	}
+	events, err := os.ReadFile(filepath.Join("..", "..", "..", "src", "event.rs"))
+	if err != nil {
+		t.Skipf("Server sources not available: %v", err)
+	}
+	schema, err := loadProtocolSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverEvents := rustEnumFields(t, string(events), "SessionEvent")
+	// Binary events are sent as binary frames
+	delete(serverEvents, "binary")
+	compareSchemaMessages(t, "command", schema.Commands, rustEnumFields(t, string(commands), "Command"))
+	compareSchemaMessages(t, "event", schema.Events, serverEvents)
+}
+
+func compareSchemaMessages(t *testing.T, kind string, schema map[string]*schemaNode, server map[string][]string) {
+	t.Helper()
+	for name, fields := range server {
+		node, ok := schema[name]
+		if !ok {
+			t.Errorf("The schema misses the %s %s", kind, name)
+			continue
+		}
+		var properties []string
+		for property := range node.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		sort.Strings(fields)
+		if strings.Join(properties, ",") != strings.Join(fields, ",") {
+			t.Errorf("The schema %s %s has fields %v, the server %v", kind, name, properties, fields)
+		}
+	}
+	for name := range schema {
+		if _, ok := server[name]; !ok {
+			t.Errorf("The schema %s %s is not sent by the server", kind, name)
+		}
+	}
+}
+
+var (
+	rustVariantPattern = regexp.MustCompile(`^\s*(\w+)\s*\{`)
+	rustFieldPattern   = regexp.MustCompile(`^\s*(?:pub\s+)?(\w+)\s*:`)
+	rustRenamePattern  = regexp.MustCompile(`rename\s*=\s*"(\w+)"`)
+)
+
+// rustEnumFields returns the wire names of the variants of a serde enum, tagged and
+// renamed to camelCase, with the wire names of their fields
+func rustEnumFields(t *testing.T, source, enum string) map[string][]string {
+	t.Helper()
+	start := strings.Index(source, "pub enum "+enum+" {")
+	if start < 0 {
+		t.Fatalf("enum %s not found", enum)
+	}
+	variants := map[string][]string{}
+	var variant, rename string
+	skip := false
+	depth := 0
+	for _, line := range strings.Split(source[start:], "\n")[1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case depth == 0 && line == "}":
+			return variants
+		case depth == 0:
+			if m := rustVariantPattern.FindStringSubmatch(line); m != nil {
+				variant = strings.ToLower(m[1][:1]) + m[1][1:]
+				variants[variant] = []string{}
+			}
+		case strings.HasPrefix(line, "#[serde("):
+			if m := rustRenamePattern.FindStringSubmatch(line); m != nil {
+				rename = m[1]
+			}
+			skip = skip || line == "#[serde(skip)]"
+		default:
+			if m := rustFieldPattern.FindStringSubmatch(line); m != nil {
+				if !skip {
+					if rename == "" {
+						rename = m[1]
+					}
+					variants[variant] = append(variants[variant], rename)
+				}
+				rename, skip = "", false
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	t.Fatalf("enum %s is not closed", enum)
+	return nil
+}
+
+func TestProtocolViolations(t *testing.T) {
+	for _, frame := range []string{
+		`{"command":"tts"}`,
+		`{"command":"tts","text":"hi","endOfStrem":true}`,
+		`{"command":"invite","option":{"codec":"opus"}}`,
+		`{"command":"candidate","candidates":[42]}`,
+		`{"command":"dial"}`,
+		`[]`,
+	} {
+		if err := ValidateCommand([]byte(frame)); !errors.Is(err, ErrProtocolViolation) {
+			t.Errorf("Expected %s to be a violation, got %v", frame, err)
+		}
+	}
+	if err := ValidateEvent([]byte(`{"event":"dtmf","trackId":"t","timestamp":1.5,"digit":"1"}`)); !errors.Is(err, ErrProtocolViolation) {
+		t.Errorf("Expected a fractional timestamp to be a violation, got %v", err)
+	}
+	if err := ValidateEvent([]byte(`{"event":"hangup","timestamp":1,"cause":"new field"}`)); err == nil ||
+		!strings.Contains(err.Error(), "unexpected field cause") {
+		t.Errorf("Expected a new field to be reported, got %v", err)
+	}
+}
+
+func TestProtocolValidationReport(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := newConnection(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil,
+		&ConnectionOptions{Validation: ValidationReport}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reported := make(chan *Event, 1)
+	conn.OnEvent(func(event *Event) {
+		if event.Sender == "protocol" {
+			reported <- event
+		}
+	})
+
+	if err := conn.SendRawCommand(map[string]interface{}{"command": "sip_info"}); err != nil {
+		t.Fatalf("Expected the command to be sent in report mode, got %v", err)
+	}
+	<-commands
+	select {
+	case event := <-reported:
+		if !strings.Contains(event.Error, `unknown command "sip_info"`) {
+			t.Errorf("Unexpected report: %s", event.Error)
+		}
+	default:
+		t.Error("Expected the violation to be reported")
+	}
+}
@@ -0,0 +1,174 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// qaEvaluationPrompt asks the LLM to grade a transcript against rubric criteria
+const qaEvaluationPrompt = `You are a call center quality analyst. Grade the call transcript against each criterion.
+Reply with JSON only, in the form {"criteria":[{"name":"...","passed":true,"score":1.0,"evidence":"..."}]}.
+Score is between 0 and 1; evidence quotes the transcript or explains why the criterion was not met.
+
+Criteria:
+%s`
+
+// QACriterion represents one check of a QA rubric
+type QACriterion struct {
+	Name        string
+	Description string
+	// Weight of the criterion in the overall score; 1 when zero
+	Weight float64
+	// Phrases are checked locally instead of by the LLM: the criterion passes when the
+	// assistant said any of them, case-insensitively
+	Phrases []string
+}
+
+// QARubric represents the criteria calls are scored against
+type QARubric struct {
+	Name     string
+	Criteria []QACriterion
+	// PassScore is the overall score a call needs to pass; 0.8 when zero
+	PassScore float64
+}
+
+// QACriterionResult represents the grade of one criterion
+type QACriterionResult struct {
+	Name     string  `json:"name"`
+	Passed   bool    `json:"passed"`
+	Score    float64 `json:"score"`
+	Evidence string  `json:"evidence,omitempty"`
+}
+
+// QAReport represents the structured QA evaluation of a call
+type QAReport struct {
+	CallID    string              `json:"callId"`
+	Rubric    string              `json:"rubric"`
+	Score     float64             `json:"score"`
+	Passed    bool                `json:"passed"`
+	Criteria  []QACriterionResult `json:"criteria"`
+	Timestamp int64               `json:"timestamp"`
+}
+
+// QAEvaluator scores call transcripts against a rubric, using the LLM for the
+// criteria that need judgement
+type QAEvaluator struct {
+	LLM    LLM
+	Rubric QARubric
+}
+
+// Evaluate scores a call transcript
+func (e *QAEvaluator) Evaluate(ctx context.Context, callID string, transcript []ChatMessage) (*QAReport, error) {
+	results := make(map[string]QACriterionResult, len(e.Rubric.Criteria))
+	var judged []QACriterion
+	for _, criterion := range e.Rubric.Criteria {
+		if len(criterion.Phrases) > 0 {
+			results[criterion.Name] = checkPhrases(criterion, transcript)
+		} else {
+			judged = append(judged, criterion)
+		}
+	}
+
+	if len(judged) > 0 {
+		graded, err := e.judge(ctx, judged, transcript)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range graded {
+			results[result.Name] = result
+		}
+	}
+
+	report := &QAReport{
+		CallID:    callID,
+		Rubric:    e.Rubric.Name,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	var total, weights float64
+	for _, criterion := range e.Rubric.Criteria {
+		result, ok := results[criterion.Name]
+		if !ok {
+			result = QACriterionResult{Name: criterion.Name, Evidence: "not graded"}
+		}
+		weight := criterion.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		total += weight * result.Score
+		weights += weight
+		report.Criteria = append(report.Criteria, result)
+	}
+	if weights > 0 {
+		report.Score = total / weights
+	}
+
+	pass := e.Rubric.PassScore
+	if pass == 0 {
+		pass = 0.8
+	}
+	report.Passed = report.Score >= pass
+	return report, nil
+}
+
+// checkPhrases grades a criterion by looking for its phrases in what the assistant said
+func checkPhrases(criterion QACriterion, transcript []ChatMessage) QACriterionResult {
+	for _, message := range transcript {
+		if message.Role != "assistant" {
+			continue
+		}
+		said := strings.ToLower(message.Content)
+		for _, phrase := range criterion.Phrases {
+			if strings.Contains(said, strings.ToLower(phrase)) {
+				return QACriterionResult{Name: criterion.Name, Passed: true, Score: 1, Evidence: message.Content}
+			}
+		}
+	}
+	return QACriterionResult{Name: criterion.Name, Evidence: "required phrase not said"}
+}
+
+// judge asks the LLM to grade criteria
+func (e *QAEvaluator) judge(ctx context.Context, criteria []QACriterion, transcript []ChatMessage) ([]QACriterionResult, error) {
+	var list strings.Builder
+	for _, criterion := range criteria {
+		fmt.Fprintf(&list, "- %s: %s\n", criterion.Name, criterion.Description)
+	}
+	var conversation strings.Builder
+	for _, message := range transcript {
+		if message.Role != "system" {
+			fmt.Fprintf(&conversation, "%s: %s\n", message.Role, message.Content)
+		}
+	}
+
+	reply, err := e.LLM.Complete(ctx, []ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(qaEvaluationPrompt, list.String())},
+		{Role: "user", Content: conversation.String()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate call: %w", err)
+	}
+
+	// Models often wrap JSON in a code fence
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("failed to parse evaluation: no JSON in reply")
+	}
+	var evaluation struct {
+		Criteria []QACriterionResult `json:"criteria"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &evaluation); err != nil {
+		return nil, fmt.Errorf("failed to parse evaluation: %w", err)
+	}
+
+	for i := range evaluation.Criteria {
+		score := &evaluation.Criteria[i].Score
+		if *score < 0 {
+			*score = 0
+		} else if *score > 1 {
+			*score = 1
+		}
+	}
+	return evaluation.Criteria, nil
+}
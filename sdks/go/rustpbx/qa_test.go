@@ -0,0 +1,57 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQAEvaluatorScoresRubric(t *testing.T) {
+	var prompt string
+	evaluator := &QAEvaluator{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			prompt = messages[0].Content
+			return "```json\n{\"criteria\":[{\"name\":\"resolution\",\"passed\":false,\"score\":0.5,\"evidence\":\"partially\"}]}\n```", nil
+		}),
+		Rubric: QARubric{
+			Name: "support",
+			Criteria: []QACriterion{
+				{Name: "greeting", Phrases: []string{"thank you for calling"}},
+				{Name: "disclosure", Phrases: []string{"this call may be recorded"}},
+				{Name: "resolution", Description: "The caller's issue was resolved", Weight: 2},
+			},
+		},
+	}
+
+	report, err := evaluator.Evaluate(context.Background(), "call-1", []ChatMessage{
+		{Role: "system", Content: "You are a support bot."},
+		{Role: "assistant", Content: "Thank you for calling Acme, how can I help?"},
+		{Role: "user", Content: "My order is late."},
+	})
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	if strings.Contains(prompt, "greeting") || !strings.Contains(prompt, "resolution") {
+		t.Errorf("Expected only judged criteria in the prompt, got %q", prompt)
+	}
+	if len(report.Criteria) != 3 || !report.Criteria[0].Passed || report.Criteria[1].Passed {
+		t.Errorf("Unexpected criteria results: %+v", report.Criteria)
+	}
+	// (1 + 0 + 2*0.5) / 4
+	if report.Score != 0.5 || report.Passed {
+		t.Errorf("Expected failing score 0.5, got %v (passed %v)", report.Score, report.Passed)
+	}
+}
+
+func TestQAEvaluatorRejectsMalformedReply(t *testing.T) {
+	evaluator := &QAEvaluator{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return "The call went well.", nil
+		}),
+		Rubric: QARubric{Criteria: []QACriterion{{Name: "empathy", Description: "Agent showed empathy"}}},
+	}
+	if _, err := evaluator.Evaluate(context.Background(), "call-1", nil); err == nil {
+		t.Error("Expected error for a reply without JSON")
+	}
+}
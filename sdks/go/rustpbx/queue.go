@@ -0,0 +1,339 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrQueueTimeout is returned when no agent took a queued call within the queue's maximum wait
+var ErrQueueTimeout = errors.New("queue wait timed out")
+
+// RoutingStrategy selects the agent that takes a call among the free agents with the required skills
+type RoutingStrategy int
+
+const (
+	// RouteMostIdle picks the agent that has been idle the longest
+	RouteMostIdle RoutingStrategy = iota
+	// RouteBestMatch picks the agent with the highest proficiency in the required skills
+	RouteBestMatch
+	// RouteRoundRobin cycles through the agents in the order they were added
+	RouteRoundRobin
+)
+
+// Agent represents a contact-center agent
+type Agent struct {
+	ID   string
+	Name string
+	// Target is where calls are transferred to reach the agent, such as a SIP URI
+	Target string
+	// Skills maps skill names, such as "lang:es" or "billing", to a proficiency from 1 to 10
+	Skills map[string]int
+}
+
+// hasSkills reports whether the agent has all the required skills
+func (a *Agent) hasSkills(skills []string) bool {
+	for _, skill := range skills {
+		if a.Skills[skill] <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// proficiency sums the agent's proficiency in the required skills
+func (a *Agent) proficiency(skills []string) int {
+	total := 0
+	for _, skill := range skills {
+		total += a.Skills[skill]
+	}
+	return total
+}
+
+// QueuedCall represents a call waiting for an agent
+type QueuedCall struct {
+	ID string
+	// Skills lists the skills an agent needs to take the call, in addition to the queue's
+	Skills []string
+	// Priority orders the calls waiting in the pool; use the call context's priority
+	Priority Priority
+
+	queue    *CallQueue
+	skills   []string
+	enqueued time.Time
+	answered time.Time
+	ended    time.Time
+	seq      uint64
+	assigned chan *Agent
+}
+
+// QueueOptions represents call queue configuration
+type QueueOptions struct {
+	Name     string
+	Strategy RoutingStrategy
+	// Skills are required of the agents taking any call of the queue
+	Skills []string
+	// MaxWait bounds how long a call waits for an agent; unlimited when zero
+	MaxWait time.Duration
+	// ServiceLevel is the answer time target reported on the wallboard; 20s when zero
+	ServiceLevel time.Duration
+}
+
+// poolAgent tracks the routing state of an agent
+type poolAgent struct {
+	agent     *Agent
+	order     int
+	state     AgentState
+	since     time.Time
+	callID    string
+	idleSince time.Time
+	wrapUp    *time.Timer
+	// call is the call the agent is busy with or wrapping up
+	call *QueuedCall
+}
+
+// AgentPool routes queued calls to agents. Agents are shared by all the queues of a pool;
+// when an agent frees up it takes the highest priority call, then the longest waiting,
+// among the queues it has the skills for.
+type AgentPool struct {
+	options AgentPoolOptions
+	mu      sync.Mutex
+	agents  map[string]*poolAgent
+	added   int
+	waiting []*QueuedCall
+	queues  []*CallQueue
+	seq     uint64
+	// changes and records are notified once the mutex is released
+	changes []AgentStateChange
+	records []CallRecord
+}
+
+// CallQueue is a queue of calls routed to the agents of a pool with its own strategy
+type CallQueue struct {
+	pool    *AgentPool
+	options QueueOptions
+	// last is the order of the last agent picked by round robin
+	last int
+	// Counters reported on the wallboard
+	answered  int
+	abandoned int
+	onTarget  int
+	totalWait time.Duration
+}
+
+// AgentPoolOptions represents agent pool configuration
+type AgentPoolOptions struct {
+	// WrapUp is how long agents stay in wrap-up after a call before taking the next one
+	WrapUp time.Duration
+	// OnStateChange is called on every agent state change
+	OnStateChange func(change AgentStateChange)
+	// OnCallRecord is called when a queued call is abandoned or its agent finished wrap-up
+	OnCallRecord func(record CallRecord)
+}
+
+// NewAgentPool creates an empty agent pool
+func NewAgentPool(options *AgentPoolOptions) *AgentPool {
+	if options == nil {
+		options = &AgentPoolOptions{}
+	}
+	return &AgentPool{options: *options, agents: make(map[string]*poolAgent)}
+}
+
+// NewQueue creates a call queue routed to the pool's agents
+func (p *AgentPool) NewQueue(options QueueOptions) *CallQueue {
+	if options.ServiceLevel == 0 {
+		options.ServiceLevel = 20 * time.Second
+	}
+	q := &CallQueue{pool: p, options: options, last: -1}
+	p.mu.Lock()
+	p.queues = append(p.queues, q)
+	p.mu.Unlock()
+	return q
+}
+
+// AddAgent adds an agent to the pool in the given state, replacing an agent with the same ID
+func (p *AgentPool) AddAgent(agent *Agent, state AgentState) error {
+	if agent == nil || agent.ID == "" {
+		return fmt.Errorf("agent ID is required")
+	}
+	if !state.valid() {
+		return fmt.Errorf("invalid agent state: %s", state)
+	}
+	p.mu.Lock()
+	defer p.unlock()
+	if existing, ok := p.agents[agent.ID]; ok {
+		existing.stopWrapUp()
+	}
+	p.added++
+	now := time.Now()
+	p.agents[agent.ID] = &poolAgent{agent: agent, order: p.added, since: now, idleSince: now}
+	p.setStateLocked(p.agents[agent.ID], state, "")
+	p.dispatchLocked()
+	return nil
+}
+
+// RemoveAgent removes an agent from the pool
+func (p *AgentPool) RemoveAgent(id string) {
+	p.mu.Lock()
+	defer p.unlock()
+	if agent, ok := p.agents[id]; ok {
+		p.setStateLocked(agent, AgentOffline, "")
+		delete(p.agents, id)
+	}
+}
+
+// Release ends an agent's call. The agent goes into wrap-up, if configured, then takes the next call.
+func (p *AgentPool) Release(id string) {
+	p.mu.Lock()
+	defer p.unlock()
+	agent, ok := p.agents[id]
+	if !ok || agent.state != AgentBusy {
+		return
+	}
+	if p.options.WrapUp > 0 {
+		p.setStateLocked(agent, AgentWrapUp, agent.callID)
+		return
+	}
+	p.setStateLocked(agent, AgentAvailable, "")
+	p.dispatchLocked()
+}
+
+// Enqueue waits until an agent with the required skills takes the call and returns the agent.
+// Call Release on the pool with the agent's ID once the call has ended.
+func (q *CallQueue) Enqueue(ctx context.Context, call *QueuedCall) (*Agent, error) {
+	p := q.pool
+	call.queue = q
+	call.skills = append(append([]string(nil), q.options.Skills...), call.Skills...)
+	call.enqueued = time.Now()
+	call.assigned = make(chan *Agent, 1)
+
+	p.mu.Lock()
+	p.seq++
+	call.seq = p.seq
+	p.waiting = append(p.waiting, call)
+	p.dispatchLocked()
+	p.unlock()
+
+	var timeout <-chan time.Time
+	if q.options.MaxWait > 0 {
+		timer := time.NewTimer(q.options.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case agent := <-call.assigned:
+		return agent, nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+	if !p.removeWaitingLocked(call) {
+		// An agent took the call as the wait ended
+		return <-call.assigned, nil
+	}
+	q.abandoned++
+	p.records = append(p.records, call.record("", true))
+	return nil, err
+}
+
+// removeWaitingLocked removes a call from the waiting list, reporting whether it was waiting
+func (p *AgentPool) removeWaitingLocked(call *QueuedCall) bool {
+	for i, waiting := range p.waiting {
+		if waiting == call {
+			p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// dispatchLocked assigns waiting calls to free agents, highest priority and longest
+// waiting calls first. It must be called with p.mu held.
+func (p *AgentPool) dispatchLocked() {
+	sort.SliceStable(p.waiting, func(i, j int) bool {
+		if p.waiting[i].Priority != p.waiting[j].Priority {
+			return p.waiting[i].Priority > p.waiting[j].Priority
+		}
+		return p.waiting[i].seq < p.waiting[j].seq
+	})
+
+	now := time.Now()
+	remaining := p.waiting[:0]
+	for _, call := range p.waiting {
+		agent := call.queue.pickLocked(call)
+		if agent == nil {
+			remaining = append(remaining, call)
+			continue
+		}
+		call.answered = now
+		call.queue.recordAnswerLocked(now.Sub(call.enqueued))
+		p.setStateLocked(agent, AgentBusy, call.ID)
+		agent.call = call
+		call.assigned <- agent.agent
+	}
+	p.waiting = remaining
+}
+
+// pickLocked chooses a free agent for a call with the queue's strategy. It must be called
+// with the pool's mutex held.
+func (q *CallQueue) pickLocked(call *QueuedCall) *poolAgent {
+	var candidates []*poolAgent
+	for _, agent := range q.pool.agents {
+		if agent.state == AgentAvailable && agent.agent.hasSkills(call.skills) {
+			candidates = append(candidates, agent)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Most idle first, so it also breaks best-match ties
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].idleSince.Equal(candidates[j].idleSince) {
+			return candidates[i].idleSince.Before(candidates[j].idleSince)
+		}
+		return candidates[i].order < candidates[j].order
+	})
+
+	switch q.options.Strategy {
+	case RouteBestMatch:
+		best := candidates[0]
+		for _, agent := range candidates[1:] {
+			if agent.agent.proficiency(call.skills) > best.agent.proficiency(call.skills) {
+				best = agent
+			}
+		}
+		return best
+	case RouteRoundRobin:
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].order < candidates[j].order })
+		for _, agent := range candidates {
+			if agent.order > q.last {
+				q.last = agent.order
+				return agent
+			}
+		}
+		q.last = candidates[0].order
+		return candidates[0]
+	default:
+		return candidates[0]
+	}
+}
+
+// recordAnswerLocked counts a call answered after waiting. It must be called with the
+// pool's mutex held.
+func (q *CallQueue) recordAnswerLocked(wait time.Duration) {
+	q.answered++
+	q.totalWait += wait
+	if wait <= q.options.ServiceLevel {
+		q.onTarget++
+	}
+}
@@ -0,0 +1,98 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestQueueSkillsRouting(t *testing.T) {
+	pool := NewAgentPool(nil)
+	pool.AddAgent(&Agent{ID: "ann", Skills: map[string]int{"lang:en": 9, "billing": 3}}, AgentAvailable)
+	pool.AddAgent(&Agent{ID: "bea", Skills: map[string]int{"lang:es": 8, "billing": 9}}, AgentAvailable)
+	pool.AddAgent(&Agent{ID: "cid", Skills: map[string]int{"lang:en": 5, "billing": 8}}, AgentAvailable)
+
+	billing := pool.NewQueue(QueueOptions{Name: "billing", Strategy: RouteBestMatch, Skills: []string{"billing"}})
+
+	agent, err := billing.Enqueue(context.Background(), &QueuedCall{ID: "c1", Skills: []string{"lang:en"}})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if agent.ID != "cid" {
+		t.Errorf("Expected best English billing match 'cid', got '%s'", agent.ID)
+	}
+
+	agent, _ = billing.Enqueue(context.Background(), &QueuedCall{ID: "c2", Skills: []string{"lang:es"}})
+	if agent.ID != "bea" {
+		t.Errorf("Expected the Spanish speaker 'bea', got '%s'", agent.ID)
+	}
+
+	// Only ann is free but she lacks Spanish
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := billing.Enqueue(ctx, &QueuedCall{ID: "c3", Skills: []string{"lang:es"}}); err != context.DeadlineExceeded {
+		t.Errorf("Expected the call to wait for a Spanish speaker, got %v", err)
+	}
+}
+
+func TestQueueStrategies(t *testing.T) {
+	newPool := func() *AgentPool {
+		pool := NewAgentPool(nil)
+		for _, id := range []string{"a", "b", "c"} {
+			pool.AddAgent(&Agent{ID: id}, AgentAvailable)
+			time.Sleep(time.Millisecond)
+		}
+		return pool
+	}
+
+	pool := newPool()
+	queue := pool.NewQueue(QueueOptions{Strategy: RouteMostIdle})
+	agent, _ := queue.Enqueue(context.Background(), &QueuedCall{ID: "1"})
+	pool.Release(agent.ID)
+	agent, _ = queue.Enqueue(context.Background(), &QueuedCall{ID: "2"})
+	if agent.ID != "b" {
+		t.Errorf("Expected most idle agent 'b' after 'a' took a call, got '%s'", agent.ID)
+	}
+
+	pool = newPool()
+	queue = pool.NewQueue(QueueOptions{Strategy: RouteRoundRobin})
+	var order []string
+	for i := 0; i < 4; i++ {
+		agent, _ := queue.Enqueue(context.Background(), &QueuedCall{})
+		order = append(order, agent.ID)
+		pool.Release(agent.ID)
+	}
+	if order[0] != "a" || order[1] != "b" || order[2] != "c" || order[3] != "a" {
+		t.Errorf("Expected round robin order a b c a, got %v", order)
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	pool := NewAgentPool(nil)
+	queue := pool.NewQueue(QueueOptions{MaxWait: time.Second})
+
+	assigned := make(chan string, 2)
+	for _, call := range []*QueuedCall{{ID: "normal"}, {ID: "vip", Priority: PriorityVIP}} {
+		go func(call *QueuedCall) {
+			if agent, err := queue.Enqueue(context.Background(), call); err == nil {
+				assigned <- call.ID
+				time.Sleep(10 * time.Millisecond)
+				pool.Release(agent.ID)
+			}
+		}(call)
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	pool.AddAgent(&Agent{ID: "a"}, AgentAvailable)
+	if first := <-assigned; first != "vip" {
+		t.Errorf("Expected the VIP call to be answered first, got '%s'", first)
+	}
+	if second := <-assigned; second != "normal" {
+		t.Errorf("Expected the normal call to be answered second, got '%s'", second)
+	}
+
+	queue = pool.NewQueue(QueueOptions{MaxWait: 20 * time.Millisecond, Skills: []string{"none"}})
+	if _, err := queue.Enqueue(context.Background(), &QueuedCall{}); err != ErrQueueTimeout {
+		t.Errorf("Expected ErrQueueTimeout, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package rustpbx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ReconnectPolicy represents automatic reconnection configuration. RustPBX ends the call
+// of a session when its connection drops, so a reconnection starts a new session with
+// the same session ID rather than resuming the call: the call in progress is reported
+// with a "hangup" event of reason "connection_lost", an outbound call is dialed again by
+// re-issuing its invite, and the event loop resumes. An accepted incoming call cannot be
+// accepted again and stays ended. "reconnecting" is emitted before every attempt and
+// "reconnected" once it succeeds.
+type ReconnectPolicy struct {
+	// MaxRetries bounds the attempts per drop; 5 when zero, unlimited when negative
+	MaxRetries int
+	// InitialBackoff is the delay before the first attempt; 500ms when zero
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts; 30s when zero
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every failed attempt; 2 when zero
+	Multiplier float64
+	// Jitter randomizes every delay by up to this fraction of it; 0.2 when zero, none when negative
+	Jitter float64
+	// Reinvite dials the outbound call ended by a drop again on the new session. It is off
+	// by default: a callee who already answered gets a second call.
+	Reinvite bool
+	// Replay writes the commands the server has not acknowledged again after the invite
+	// is re-issued with Reinvite; commands in flight when the connection drops are lost
+	// when nil
+	Replay *ReplayPolicy
+}
+
+// withDefaults returns the policy with the zero fields defaulted
+func (p ReconnectPolicy) withDefaults() ReconnectPolicy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 5
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 500 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = 2
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	} else if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	return p
+}
+
+// backoff returns the delay before an attempt, counted from 1, given a random number in [0, 1)
+func (p *ReconnectPolicy) backoff(attempt int, random float64) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < attempt && delay < float64(p.MaxBackoff); i++ {
+		delay *= p.Multiplier
+	}
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*random - 1)
+	}
+	return time.Duration(delay)
+}
+
+// shouldReconnect reports whether a read error is a drop the session should be re-dialed after
+func (c *Connection) shouldReconnect(err error) bool {
+	if c.reconnect == nil || c.isClosed() {
+		return false
+	}
+	// The server ended the session on purpose
+	var closeErr *CloseError
+	return !errors.As(err, &closeErr) || !closeErr.Normal()
+}
+
+// trackSetup keeps the last invite or accept written, whose call ends when the connection
+// drops
+func (c *Connection) trackSetup(data []byte) {
+	var command struct {
+		Command string `json:"command"`
+	}
+	json.Unmarshal(data, &command)
+	if command.Command != "invite" && command.Command != "accept" {
+		return
+	}
+	c.mu.Lock()
+	c.setup = &replayEntry{command: command.Command, data: data}
+	c.mu.Unlock()
+}
+
+// endSetup forgets the call set up on the session, once it is hung up, and returns it
+func (c *Connection) endSetup() *replayEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	setup := c.setup
+	c.setup = nil
+	return setup
+}
+
+// reconnectSession re-dials the session after a drop. It returns false, after reporting
+// the failure, if the retries ran out or the connection was closed meanwhile.
+func (c *Connection) reconnectSession(cause error) bool {
+	policy := c.reconnect
+	c.setState(ConnectionReconnecting)
+	// The server hung up the call with the connection
+	setup := c.endSetup()
+	if setup != nil {
+		c.log().Warn("call ended by the connection drop", "command", setup.command)
+		c.dispatch(&Event{
+			Event:     "hangup",
+			Timestamp: time.Now().UnixMilli(),
+			Reason:    "connection_lost",
+			Initiator: "system",
+		})
+	}
+	attempt := 0
+	for policy.MaxRetries < 0 || attempt < policy.MaxRetries {
+		attempt++
+		delay := policy.backoff(attempt, rand.Float64())
+		data, _ := json.Marshal(map[string]interface{}{
+			"attempt": attempt,
+			"delayMs": delay.Milliseconds(),
+		})
+		c.log().Warn("reconnecting", "attempt", attempt, "delay", delay, "error", cause)
+		c.dispatch(&Event{
+			Event:     "reconnecting",
+			Timestamp: time.Now().UnixMilli(),
+			Error:     cause.Error(),
+			Data:      data,
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		conn, err := dialWebSocket(c.ctx, c.wsURL, c.callContext, c.client, c.dial)
+		if err != nil {
+			// A session still held by the server is retried like any other failure
+			cause = err
+			continue
+		}
+		c.prepareConn(conn)
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return false
+		}
+		previous := c.conn
+		c.conn = conn
+		c.mu.Unlock()
+		previous.Close()
+		c.setState(ConnectionConnected)
+
+		c.log().Info("reconnected", "attempts", attempt)
+		c.countReconnect(attempt)
+		reinvited := c.reinvite(setup)
+		replayed := 0
+		if reinvited {
+			replayed = c.replayCommands()
+		} else if c.replay != nil {
+			// The new session only takes an invite or accept first
+			c.replay.acknowledge()
+		}
+		data, _ = json.Marshal(map[string]interface{}{
+			"attempts":  attempt,
+			"replayed":  replayed,
+			"reinvited": reinvited,
+		})
+		c.dispatch(&Event{
+			Event:     "reconnected",
+			Timestamp: time.Now().UnixMilli(),
+			Data:      data,
+		})
+		return true
+	}
+
+	c.log().Error("reconnect failed", "attempts", attempt, "error", cause)
+	err := fmt.Errorf("failed to reconnect after %d attempts: %w", attempt, cause)
+	c.setDisconnectErr(err)
+	c.handleError(err)
+	return false
+}
+
+// reinvite dials the outbound call ended by the drop again as the first message of the
+// new session when the policy asks for it, and reports whether it did
+func (c *Connection) reinvite(setup *replayEntry) bool {
+	if !c.reconnect.Reinvite || setup == nil || setup.command != "invite" {
+		return false
+	}
+	if err := c.writeMessage(websocket.TextMessage, setup.data); err != nil {
+		c.log().Warn("re-issuing the invite failed", "error", err)
+		return false
+	}
+	c.mu.Lock()
+	c.setup = setup
+	c.mu.Unlock()
+	return true
+}
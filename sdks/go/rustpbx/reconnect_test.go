@@ -0,0 +1,342 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	policy := ReconnectPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: -1}.withDefaults()
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, want := range expected {
+		if got := policy.backoff(i+1, 0.5); got != want*time.Millisecond {
+			t.Errorf("Expected backoff %v for attempt %d, got %v", want*time.Millisecond, i+1, got)
+		}
+	}
+
+	policy = ReconnectPolicy{InitialBackoff: 100 * time.Millisecond}.withDefaults()
+	if low, high := policy.backoff(1, 0), policy.backoff(1, 0.999); low != 80*time.Millisecond || high < 119*time.Millisecond {
+		t.Errorf("Expected 20%% jitter, got %v to %v", low, high)
+	}
+}
+
+// reconnectServer drops the first connections abruptly and sends an answer event on the next one.
+// With negative drops it drops the first connection and refuses the others. It also returns the
+// first message of every connection after the first.
+func reconnectServer(t *testing.T, drops int) (*httptest.Server, func() []string, <-chan string) {
+	var mu sync.Mutex
+	var sessions []string
+	first := make(chan string, 16)
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sessions = append(sessions, r.URL.Query().Get("id"))
+		attempt := len(sessions)
+		mu.Unlock()
+		if drops < 0 && attempt > 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if attempt == 1 {
+			// Wait for the client to be ready
+			conn.ReadMessage()
+		}
+		if drops < 0 || attempt <= drops {
+			conn.UnderlyingConn().Close()
+			return
+		}
+		conn.WriteJSON(Event{Event: "answer"})
+		for read := 0; ; read++ {
+			var cmd map[string]interface{}
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			if read == 0 {
+				first <- fmt.Sprint(cmd["command"])
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sessions...)
+	}, first
+}
+
+func TestReconnect(t *testing.T) {
+	server, sessions, first := reconnectServer(t, 2)
+	received := make(chan *Event, 16)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, Reinvite: true},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) { received <- event })
+	if err := conn.Invite(&CallOption{Callee: "sip:bob@example.com"}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+
+	var names []string
+	var reconnected *Event
+	for len(names) == 0 || names[len(names)-1] != "answer" {
+		select {
+		case event := <-received:
+			names = append(names, event.Event)
+			if event.Event == "hangup" && event.Reason != "connection_lost" {
+				t.Errorf("Expected the drop to hang up with connection_lost, got '%s'", event.Reason)
+			}
+			if event.Event == "reconnected" {
+				reconnected = event
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected an answer event after reconnecting, got %v", names)
+		}
+	}
+	if names[0] != "hangup" || names[1] != "reconnecting" || names[len(names)-2] != "reconnected" {
+		t.Errorf("Expected a hangup, reconnecting events then reconnected, got %v", names)
+	}
+	if !strings.Contains(string(reconnected.Data), `"reinvited":true`) {
+		t.Errorf("Expected the invite to be re-issued, got %s", reconnected.Data)
+	}
+	select {
+	case command := <-first:
+		if command != "invite" {
+			t.Errorf("Expected the new session to start with the invite, got %s", command)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the invite to be re-issued")
+	}
+
+	ids := sessions()
+	if len(ids) != 3 {
+		t.Fatalf("Expected 3 connections, got %d", len(ids))
+	}
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			t.Errorf("Expected session ID '%s' on reconnect, got '%s'", ids[0], id)
+		}
+	}
+}
+
+func TestReconnectWithoutReinvite(t *testing.T) {
+	server, _, first := reconnectServer(t, 1)
+	reconnected := make(chan *Event, 1)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, Replay: &ReplayPolicy{}},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "reconnected" {
+			reconnected <- event
+		}
+	})
+	if err := conn.Invite(&CallOption{Callee: "sip:bob@example.com"}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+
+	select {
+	case event := <-reconnected:
+		if !strings.Contains(string(event.Data), `"reinvited":false`) || !strings.Contains(string(event.Data), `"replayed":0`) {
+			t.Errorf("Expected the call not to be dialed again by default, got %s", event.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reconnection")
+	}
+	select {
+	case command := <-first:
+		t.Errorf("Expected nothing sent on the new session, got %s", command)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReconnectAfterAccept(t *testing.T) {
+	server, _, _ := reconnectServer(t, 1)
+	received := make(chan *Event, 16)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, Replay: &ReplayPolicy{}},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) { received <- event })
+	if err := conn.Accept(&CallOption{}); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+
+	var names []string
+	for {
+		select {
+		case event := <-received:
+			names = append(names, event.Event)
+			if event.Event != "reconnected" {
+				continue
+			}
+			if !strings.Contains(string(event.Data), `"reinvited":false`) || !strings.Contains(string(event.Data), `"replayed":0`) {
+				t.Errorf("Expected nothing re-issued after an accept, got %s", event.Data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a reconnection, got %v", names)
+		}
+		break
+	}
+	if names[0] != "hangup" {
+		t.Errorf("Expected the accepted call to hang up, got %v", names)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	server, _, _ := reconnectServer(t, -1)
+	received := make(chan *Event, 16)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{MaxRetries: 2, InitialBackoff: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) { received <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	attempts := 0
+	for {
+		select {
+		case event := <-received:
+			switch event.Event {
+			case "reconnecting":
+				attempts++
+				continue
+			case "error":
+				if !strings.Contains(event.Error, "failed to reconnect after 2 attempts") {
+					t.Errorf("Unexpected error: %s", event.Error)
+				}
+			default:
+				t.Errorf("Unexpected event '%s'", event.Event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the reconnection to fail")
+		}
+		break
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	select {
+	case <-conn.done:
+	case <-time.After(time.Second):
+		t.Error("Expected the read loop to end")
+	}
+}
+
+func TestReconnectReplay(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	replayed := make(chan string, 16)
+	var attempts int32
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		var cmd map[string]interface{}
+		if attempt == 1 {
+			// The invite is acknowledged by the answer, the next four commands are lost
+			conn.ReadJSON(&cmd)
+			conn.WriteJSON(Event{Event: "answer"})
+			for i := 0; i < 4; i++ {
+				conn.ReadJSON(&cmd)
+			}
+			conn.UnderlyingConn().Close()
+			return
+		}
+		for {
+			if err := conn.ReadJSON(&cmd); err != nil {
+				return
+			}
+			replayed <- cmd["command"].(string)
+		}
+	}))
+	defer server.Close()
+
+	answered := make(chan struct{}, 1)
+	reconnected := make(chan *Event, 1)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond, Reinvite: true, Replay: &ReplayPolicy{}},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnAnswer(func(*AnswerEvent) { answered <- struct{}{} })
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "reconnected" {
+			reconnected <- event
+		}
+	})
+
+	conn.SendRawCommand(map[string]interface{}{"command": "invite"})
+	select {
+	case <-answered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an answer")
+	}
+	conn.SendRawCommand(map[string]interface{}{"command": "ringing"})
+	conn.SendRawCommand(map[string]interface{}{"command": "tts", "text": "hello"})
+	conn.SendRawCommandContext(WithoutReplay(context.Background()), map[string]interface{}{"command": "history"})
+	conn.SendRawCommand(map[string]interface{}{"command": "resume"})
+
+	select {
+	case event := <-reconnected:
+		if !strings.Contains(string(event.Data), `"replayed":2`) {
+			t.Errorf("Expected 2 replayed commands, got %s", event.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reconnection")
+	}
+	for _, expected := range []string{"invite", "tts", "resume"} {
+		select {
+		case command := <-replayed:
+			if command != expected {
+				t.Errorf("Expected %s to be replayed, got %s", expected, command)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s to be replayed", expected)
+		}
+	}
+	select {
+	case command := <-replayed:
+		t.Errorf("Unexpected replay of %s", command)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
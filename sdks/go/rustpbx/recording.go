@@ -0,0 +1,243 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RecordingOptions represents how the recordings of calls are located once the server
+// has written them, to emit "recordingFinalized" events when they are safe to fetch
+type RecordingOptions struct {
+	// Dir is the recorder path of the server as seen by the application, e.g. a shared
+	// volume; the server writes the recording of a session to <Dir>/<session ID>.wav
+	Dir string
+	// Locate returns the path and size of the recording of a session, or an error while
+	// it is not available, e.g. by querying object storage; it looks in Dir when nil
+	Locate func(ctx context.Context, sessionID string) (path string, size int64, err error)
+	// Timeout bounds the wait for the recording after the call ends; 30s when zero
+	Timeout time.Duration
+	// PollInterval is the time between looks; 500ms when zero
+	PollInterval time.Duration
+	// Cipher encrypts a finalized recording to <path>.enc and removes the plain file, for
+	// recordings on a local path; the event reports the encrypted file
+	Cipher *ArtifactCipher
+}
+
+// RecordingEvent is delivered as the recording of a call starts, stops, fails or is
+// finalized. RustPBX sends no recording events, so the SDK derives them from the answer
+// and hangup of a call whose option has a recorder, and from the recording file.
+type RecordingEvent struct {
+	Timestamp int64
+	// Path and Size are set on recordingFinalized events
+	Path string
+	Size int64
+	// Duration is the recorded time in milliseconds, from the answer to the hangup
+	Duration int64
+	// Error is the reason of recordingFailed events
+	Error string
+	Raw   *Event
+}
+
+// recordingTracker follows the lifecycle of the call recording
+type recordingTracker struct {
+	mu        sync.Mutex
+	requested bool
+	started   time.Time
+	stopped   bool
+}
+
+// OnRecordingStarted sets the handler of recordingStarted events, emitted when a call
+// with a recorder option is answered
+func (c *Connection) OnRecordingStarted(handler func(*RecordingEvent)) {
+	c.on("recordingStarted", typed(handler, newRecordingEvent))
+}
+
+// OnRecordingStopped sets the handler of recordingStopped events, emitted when the
+// recorded call hangs up. The file may still be written; wait for recordingFinalized.
+func (c *Connection) OnRecordingStopped(handler func(*RecordingEvent)) {
+	c.on("recordingStopped", typed(handler, newRecordingEvent))
+}
+
+// OnRecordingFailed sets the handler of recordingFailed events, emitted when the recording
+// could not be located after the call. RustPBX only logs its recorder errors, so they
+// surface here once the call ends.
+func (c *Connection) OnRecordingFailed(handler func(*RecordingEvent)) {
+	c.on("recordingFailed", typed(handler, newRecordingEvent))
+}
+
+// OnRecordingFinalized sets the handler of recordingFinalized events, emitted when the
+// recording is complete, if RecordingOptions are set
+func (c *Connection) OnRecordingFinalized(handler func(*RecordingEvent)) {
+	c.on("recordingFinalized", typed(handler, newRecordingEvent))
+}
+
+// newRecordingEvent converts a recording lifecycle event
+func newRecordingEvent(e *Event) *RecordingEvent {
+	var data struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, &data)
+	}
+	return &RecordingEvent{Timestamp: e.Timestamp, Path: data.Path, Size: data.Size, Duration: e.Duration,
+		Error: e.Error, Raw: e}
+}
+
+// requestRecording notes that the call option asks the server to record the call
+func (c *Connection) requestRecording(option *CallOption) {
+	if option == nil || option.Recorder == nil {
+		return
+	}
+	c.recording.mu.Lock()
+	defer c.recording.mu.Unlock()
+	c.recording.requested = true
+}
+
+// observeRecording emits the recording lifecycle events from call events
+func (c *Connection) observeRecording(event *Event) {
+	r := &c.recording
+	r.mu.Lock()
+	if !r.requested {
+		r.mu.Unlock()
+		return
+	}
+	switch event.Event {
+	case "answer":
+		if !r.started.IsZero() {
+			r.mu.Unlock()
+			return
+		}
+		r.started = time.Now()
+		r.mu.Unlock()
+		c.dispatch(&Event{Event: "recordingStarted", Timestamp: time.Now().UnixMilli()})
+	case "hangup":
+		if r.started.IsZero() || r.stopped {
+			r.mu.Unlock()
+			return
+		}
+		r.stopped = true
+		duration := time.Since(r.started).Milliseconds()
+		r.mu.Unlock()
+		c.dispatch(&Event{Event: "recordingStopped", Timestamp: time.Now().UnixMilli(), Duration: duration})
+		if c.recordingOptions != nil {
+			go c.finalizeRecording(duration)
+		}
+	default:
+		r.mu.Unlock()
+	}
+}
+
+// recordingFailed emits a recordingFailed event
+func (c *Connection) recordingFailed(reason string) {
+	c.log().Warn("recording failed", "error", reason)
+	c.dispatch(&Event{Event: "recordingFailed", Timestamp: time.Now().UnixMilli(), Error: reason})
+}
+
+// finalizeRecording waits for the recording to be complete, once its size stops changing,
+// and emits a recordingFinalized or recordingFailed event
+func (c *Connection) finalizeRecording(duration int64) {
+	options := c.recordingOptions
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	interval := options.PollInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	locate := options.Locate
+	if locate == nil {
+		locate = func(ctx context.Context, sessionID string) (string, int64, error) {
+			path := filepath.Join(options.Dir, sessionID+".wav")
+			info, err := os.Stat(path)
+			if err != nil {
+				return "", 0, err
+			}
+			return path, info.Size(), nil
+		}
+	}
+	sessionID := sessionIDFromURL(c.wsURL)
+	if sessionID == "" && c.callContext != nil {
+		sessionID = c.callContext.SessionID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastSize := int64(-1)
+	var lastErr error
+	for {
+		path, size, err := locate(ctx, sessionID)
+		if err == nil && size > 0 && size == lastSize {
+			if options.Cipher != nil {
+				if path, size, err = encryptRecording(options.Cipher, path); err != nil {
+					c.recordingFailed(fmt.Sprintf("failed to encrypt recording: %v", err))
+					return
+				}
+			}
+			data, _ := json.Marshal(map[string]interface{}{"path": path, "size": size})
+			c.dispatch(&Event{
+				Event:     "recordingFinalized",
+				Timestamp: time.Now().UnixMilli(),
+				Duration:  duration,
+				Data:      data,
+			})
+			return
+		}
+		lastSize, lastErr = size, err
+		if err != nil {
+			lastSize = -1
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = fmt.Errorf("recording still growing")
+			}
+			c.recordingFailed(fmt.Sprintf("recording not finalized within %v: %v", timeout, lastErr))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// encryptRecording encrypts a recording to <path>.enc and removes the plain file
+func encryptRecording(cipher *ArtifactCipher, path string) (string, int64, error) {
+	plain, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer plain.Close()
+
+	encrypted := path + ".enc"
+	w, err := cipher.CreateFile(encrypted, 0o600)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := io.Copy(w, plain); err != nil {
+		w.Close()
+		os.Remove(encrypted)
+		return "", 0, err
+	}
+	if err := w.Close(); err != nil {
+		os.Remove(encrypted)
+		return "", 0, err
+	}
+	info, err := os.Stat(encrypted)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Remove(path); err != nil {
+		return "", 0, err
+	}
+	return encrypted, info.Size(), nil
+}
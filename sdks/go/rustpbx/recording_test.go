@@ -0,0 +1,146 @@
+package rustpbx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// recordedCallServer answers the invite and hangs up, then writes the recording to dir
+func recordedCallServer(t *testing.T, dir string, cipher *ArtifactCipher) *Connection {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var invite map[string]interface{}
+		conn.ReadJSON(&invite)
+		conn.WriteJSON(Event{Event: "answer"})
+		time.Sleep(20 * time.Millisecond)
+		conn.WriteJSON(Event{Event: "hangup", Reason: "normal_clearing"})
+	})
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		SessionID: "rec-1",
+		Recording: &RecordingOptions{Dir: dir, Timeout: time.Second, PollInterval: 10 * time.Millisecond, Cipher: cipher},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRecordingLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	conn := recordedCallServer(t, dir, nil)
+	received := make(chan *RecordingEvent, 4)
+	conn.OnRecordingStarted(func(e *RecordingEvent) { received <- e })
+	conn.OnRecordingStopped(func(e *RecordingEvent) {
+		received <- e
+		os.WriteFile(filepath.Join(dir, "rec-1.wav"), make([]byte, 1024), 0o600)
+	})
+	conn.OnRecordingFinalized(func(e *RecordingEvent) { received <- e })
+	conn.OnRecordingFailed(func(e *RecordingEvent) { received <- e })
+
+	if err := conn.Invite(&CallOption{Recorder: &RecorderOption{SampleRate: 16000}}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	var names []string
+	for len(names) < 3 {
+		select {
+		case e := <-received:
+			names = append(names, e.Raw.Event)
+			switch e.Raw.Event {
+			case "recordingStopped":
+				if e.Duration < 20 {
+					t.Errorf("Expected the recorded duration, got %dms", e.Duration)
+				}
+			case "recordingFinalized":
+				if e.Path != filepath.Join(dir, "rec-1.wav") || e.Size != 1024 || e.Duration < 20 {
+					t.Errorf("Unexpected finalized recording %+v", e)
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected the recording lifecycle, got %v", names)
+		}
+	}
+	if names[0] != "recordingStarted" || names[1] != "recordingStopped" || names[2] != "recordingFinalized" {
+		t.Errorf("Expected started, stopped and finalized, got %v", names)
+	}
+}
+
+func TestRecordingEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	cipher, err := NewArtifactCipher(context.Background(), func(ctx context.Context) ([]byte, error) {
+		return make([]byte, 32), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := recordedCallServer(t, dir, cipher)
+	finalized := make(chan *RecordingEvent, 1)
+	conn.OnRecordingStopped(func(e *RecordingEvent) {
+		os.WriteFile(filepath.Join(dir, "rec-1.wav"), []byte("RIFF audio"), 0o600)
+	})
+	conn.OnRecordingFinalized(func(e *RecordingEvent) { finalized <- e })
+
+	if err := conn.Invite(&CallOption{Recorder: &RecorderOption{}}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	select {
+	case e := <-finalized:
+		if e.Path != filepath.Join(dir, "rec-1.wav.enc") {
+			t.Errorf("Expected the encrypted recording, got %s", e.Path)
+		}
+		if data, err := cipher.ReadFile(e.Path); err != nil || string(data) != "RIFF audio" {
+			t.Errorf("Expected the recording back, got '%s', %v", data, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "rec-1.wav")); !os.IsNotExist(err) {
+			t.Errorf("Expected the plain recording to be removed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the recording to be finalized")
+	}
+}
+
+func TestRecordingFailed(t *testing.T) {
+	conn := recordedCallServer(t, t.TempDir(), nil)
+	failures := make(chan *RecordingEvent, 4)
+	conn.OnRecordingFailed(func(e *RecordingEvent) { failures <- e })
+
+	if err := conn.Invite(&CallOption{Recorder: &RecorderOption{}}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	select {
+	case e := <-failures:
+		if !strings.HasPrefix(e.Error, "recording not finalized within 1s") {
+			t.Errorf("Expected the recording not to be found, got %q", e.Error)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the recording to fail")
+	}
+}
+
+func TestRecordingNotRequested(t *testing.T) {
+	conn := recordedCallServer(t, t.TempDir(), nil)
+	received := make(chan *Event, 8)
+	conn.OnEvent(func(e *Event) { received <- e })
+
+	if err := conn.Invite(&CallOption{}); err != nil {
+		t.Fatalf("Invite failed: %v", err)
+	}
+	for {
+		select {
+		case e := <-received:
+			if e.Event == "hangup" {
+				return
+			}
+			if e.Event != "answer" {
+				t.Errorf("Unexpected event %s for an unrecorded call", e.Event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the hangup")
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDraining is returned when connecting a call while the session registry drains
+var ErrDraining = errors.New("draining: no new calls are accepted")
+
+// ErrSessionNotFound is returned for sessions the registry does not track
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionInfo reports the state of an active session
+type SessionInfo struct {
+	SessionID string            `json:"sessionId"`
+	Tenant    string            `json:"tenant,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Caller    string            `json:"caller,omitempty"`
+	Callee    string            `json:"callee,omitempty"`
+	// State is "connected", "ringing", "answered" or "hungUp"
+	State       string    `json:"state"`
+	StartedAt   time.Time `json:"startedAt"`
+	LastEvent   string    `json:"lastEvent,omitempty"`
+	LastEventAt time.Time `json:"lastEventAt,omitempty"`
+	Events      int       `json:"events"`
+	// ConfigVersion is the version of the config the call started with
+	ConfigVersion int `json:"configVersion,omitempty"`
+}
+
+// registeredSession is a session tracked by a registry
+type registeredSession struct {
+	conn *Connection
+	info SessionInfo
+}
+
+// SessionRegistry tracks the active sessions of an application, so operators can list,
+// inspect and end them and drain the application before a deploy. Share one registry
+// between the connections of the application.
+type SessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*registeredSession
+	draining bool
+	// drained is closed when the registry drains and the last session ends
+	drained chan struct{}
+}
+
+// NewSessionRegistry creates an empty session registry
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*registeredSession)}
+}
+
+// admit reports ErrDraining while the registry drains
+func (r *SessionRegistry) admit() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return ErrDraining
+	}
+	return nil
+}
+
+// register tracks a connection until it ends
+func (r *SessionRegistry) register(conn *Connection) {
+	id := conn.callContext.SessionID
+	session := &registeredSession{
+		conn: conn,
+		info: SessionInfo{
+			SessionID: id,
+			Tenant:    conn.callContext.Tenant,
+			Metadata:  conn.callContext.Metadata,
+			State:     "connected",
+			StartedAt: time.Now(),
+		},
+	}
+	if conn.config != nil {
+		session.info.ConfigVersion = conn.config.Version
+	}
+
+	r.mu.Lock()
+	r.sessions[id] = session
+	r.mu.Unlock()
+
+	conn.AddEventHandler(func(event *Event) {
+		r.track(session, event)
+	})
+	go func() {
+		<-conn.done
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.sessions[id] == session {
+			delete(r.sessions, id)
+		}
+		if r.draining && len(r.sessions) == 0 && r.drained != nil {
+			close(r.drained)
+			r.drained = nil
+		}
+	}()
+}
+
+// track updates the state of a session with an event
+func (r *SessionRegistry) track(session *registeredSession, event *Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := &session.info
+	info.Events++
+	info.LastEvent = event.Event
+	info.LastEventAt = time.Now()
+	switch event.Event {
+	case "incoming":
+		info.Caller, info.Callee = event.Caller, event.Callee
+		info.State = "ringing"
+	case "ringing":
+		info.State = "ringing"
+	case "answer":
+		info.State = "answered"
+	case "hangup":
+		info.State = "hungUp"
+	}
+}
+
+// Sessions returns the active sessions, oldest first
+func (r *SessionRegistry) Sessions() []SessionInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := make([]SessionInfo, 0, len(r.sessions))
+	for _, session := range r.sessions {
+		sessions = append(sessions, session.info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+	return sessions
+}
+
+// Session returns the state of an active session
+func (r *SessionRegistry) Session(sessionID string) (SessionInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return SessionInfo{}, false
+	}
+	return session.info, true
+}
+
+// Connection returns the connection of an active session
+func (r *SessionRegistry) Connection(sessionID string) (*Connection, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return nil, false
+	}
+	return session.conn, true
+}
+
+// Active returns the number of active sessions
+func (r *SessionRegistry) Active() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
+
+// Kill hangs up a session and closes its connection
+func (r *SessionRegistry) Kill(sessionID, reason string) error {
+	conn, ok := r.Connection(sessionID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if reason == "" {
+		reason = "killed"
+	}
+	// Close the connection even if the hangup cannot be sent
+	hangupErr := conn.Hangup(reason, "admin")
+	if err := conn.Close(); err != nil {
+		return fmt.Errorf("failed to close session: %w", err)
+	}
+	if hangupErr != nil {
+		return fmt.Errorf("failed to hang up session: %w", hangupErr)
+	}
+	return nil
+}
+
+// Drain stops new calls from connecting, with ErrDraining, while active calls go on.
+// The returned channel is closed once the last active session ends.
+func (r *SessionRegistry) Drain() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.draining = true
+	if r.drained == nil {
+		r.drained = make(chan struct{})
+	}
+	drained := r.drained
+	if len(r.sessions) == 0 {
+		close(r.drained)
+		r.drained = nil
+	}
+	return drained
+}
+
+// Resume accepts new calls again after Drain
+func (r *SessionRegistry) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.draining = false
+}
+
+// Draining reports whether the registry drains
+func (r *SessionRegistry) Draining() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.draining
+}
+
+// WaitDrained drains the registry and waits until the last active session ends or ctx is done
+func (r *SessionRegistry) WaitDrained(ctx context.Context) error {
+	select {
+	case <-r.Drain():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "1001", Callee: "2000"})
+	})
+	registry := NewSessionRegistry()
+	client := NewClient(server.URL)
+
+	conn, err := client.ConnectCall(context.Background(), &ConnectionOptions{SessionID: "call-1", Tenant: "acme", Registry: registry})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	if _, err := conn.WaitForEvent("incoming", time.Second); err != nil {
+		t.Fatalf("Expected the incoming event: %v", err)
+	}
+
+	info, ok := registry.Session("call-1")
+	if !ok || info.Tenant != "acme" || info.Caller != "1001" || info.State != "ringing" || info.Events != 1 {
+		t.Errorf("Unexpected session info: %+v", info)
+	}
+
+	drained := registry.Drain()
+	if _, err := client.ConnectCall(context.Background(), &ConnectionOptions{Registry: registry}); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining, got %v", err)
+	}
+	if err := registry.Kill("call-1", ""); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	if cmd := <-commands; cmd["command"] != "hangup" || cmd["reason"] != "killed" {
+		t.Errorf("Expected a hangup command, got %v", cmd)
+	}
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the registry to drain once the session ended")
+	}
+	if registry.Active() != 0 || registry.Kill("call-1", "") != ErrSessionNotFound {
+		t.Error("Expected the ended session to be removed")
+	}
+
+	registry.Resume()
+	conn, err = client.ConnectCall(context.Background(), &ConnectionOptions{Registry: registry})
+	if err != nil {
+		t.Fatalf("Expected calls to connect after Resume, got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,131 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// ReplayPolicy configures the replay of commands after a reconnection. A command written
+// just before the connection drops may never reach the server, so the commands the server
+// has not acknowledged yet are kept and written again once the session is re-dialed and
+// the invite of the call re-issued, which ReconnectPolicy.Reinvite must allow; they are
+// dropped when the call is not dialed again.
+// The protocol has no acknowledgements: a command counts as acknowledged once the server
+// sends an event after it.
+type ReplayPolicy struct {
+	// Size bounds the commands kept; 32 when zero. The oldest are dropped when it is full.
+	Size int
+	// Exclude names further commands that are never replayed, on top of the ones that are
+	// not idempotent: invite, accept, reject, ringing and refer
+	Exclude []string
+}
+
+// nonIdempotentCommands are never replayed, since repeating them changes the call
+var nonIdempotentCommands = map[string]bool{
+	"invite":  true,
+	"accept":  true,
+	"reject":  true,
+	"ringing": true,
+	"refer":   true,
+}
+
+// noReplayKey is the context key opting a command out of the replay
+type noReplayKey struct{}
+
+// WithoutReplay returns a copy of ctx whose commands are never replayed after a
+// reconnection, e.g. for a command that must not run twice
+func WithoutReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noReplayKey{}, true)
+}
+
+// replayEntry is an unacknowledged command
+type replayEntry struct {
+	command string
+	data    []byte
+}
+
+// replayBuffer keeps the unacknowledged commands of a connection
+type replayBuffer struct {
+	mu      sync.Mutex
+	size    int
+	exclude map[string]bool
+	pending []replayEntry
+	// dropped counts the commands dropped from the full buffer since the last acknowledgement
+	dropped int
+}
+
+func newReplayBuffer(policy ReplayPolicy) *replayBuffer {
+	b := &replayBuffer{size: policy.Size, exclude: map[string]bool{}}
+	if b.size <= 0 {
+		b.size = 32
+	}
+	for _, command := range policy.Exclude {
+		b.exclude[command] = true
+	}
+	return b
+}
+
+// record keeps a command written to the server unless it is opted out of the replay
+func (b *replayBuffer) record(ctx context.Context, data []byte) {
+	if skip, _ := ctx.Value(noReplayKey{}).(bool); skip {
+		return
+	}
+	var command struct {
+		Command string `json:"command"`
+	}
+	json.Unmarshal(data, &command)
+	if nonIdempotentCommands[command.Command] || b.exclude[command.Command] {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) == b.size {
+		b.pending = append(b.pending[:0], b.pending[1:]...)
+		b.dropped++
+	}
+	b.pending = append(b.pending, replayEntry{command: command.Command, data: data})
+}
+
+// acknowledge forgets the commands written before an event of the server
+func (b *replayBuffer) acknowledge() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = nil
+	b.dropped = 0
+}
+
+// unacknowledged returns the commands to replay, oldest first, and how many were dropped
+func (b *replayBuffer) unacknowledged() ([]replayEntry, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]replayEntry(nil), b.pending...), b.dropped
+}
+
+// replayCommands writes the unacknowledged commands again after a reconnection and the
+// re-issued invite, and returns how many were written. They stay buffered until acknowledged, so a further
+// drop replays them again.
+func (c *Connection) replayCommands() int {
+	if c.replay == nil {
+		return 0
+	}
+	entries, dropped := c.replay.unacknowledged()
+	if dropped > 0 {
+		c.log().Warn("replay buffer overflowed", "dropped", dropped)
+	}
+	replayed := 0
+	for _, entry := range entries {
+		if err := c.writeMessage(websocket.TextMessage, entry.data); err != nil {
+			c.log().Warn("replay failed", "command", entry.command, "error", err)
+			break
+		}
+		replayed++
+	}
+	if replayed > 0 {
+		c.log().Info("replayed commands", "count", replayed)
+	}
+	return replayed
+}
@@ -0,0 +1,306 @@
+package rustpbx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CallRecord is the queue detail record of a call, emitted by an agent pool
+type CallRecord struct {
+	CallID   string
+	Queue    string
+	Priority Priority
+	AgentID  string
+	Enqueued time.Time
+	// Answered and Ended are zero for abandoned calls
+	Answered  time.Time
+	Ended     time.Time
+	WrapUp    time.Duration
+	Abandoned bool
+}
+
+// HandleTime returns the talk time plus the after-call work of an answered call
+func (r *CallRecord) HandleTime() time.Duration {
+	if r.Abandoned || r.Answered.IsZero() {
+		return 0
+	}
+	return r.Ended.Sub(r.Answered) + r.WrapUp
+}
+
+// record builds the detail record of a queued call
+func (c *QueuedCall) record(agentID string, abandoned bool) CallRecord {
+	record := CallRecord{
+		CallID:    c.ID,
+		Queue:     c.queue.options.Name,
+		Priority:  c.Priority,
+		AgentID:   agentID,
+		Enqueued:  c.enqueued,
+		Abandoned: abandoned,
+	}
+	if !abandoned {
+		record.Answered, record.Ended = c.answered, c.ended
+		if record.Ended.IsZero() {
+			// The agent went offline without releasing the call
+			record.Ended = time.Now()
+		}
+		record.WrapUp = time.Since(record.Ended)
+	}
+	return record
+}
+
+// IntervalReport aggregates the calls of a queue offered during a reporting interval
+type IntervalReport struct {
+	Queue      string        `json:"queue"`
+	Start      time.Time     `json:"start"`
+	Offered    int           `json:"offered"`
+	Answered   int           `json:"answered"`
+	Abandoned  int           `json:"abandoned"`
+	HandleTime time.Duration `json:"handleTime"`
+}
+
+// AHT returns the average handle time of the answered calls
+func (r *IntervalReport) AHT() time.Duration {
+	if r.Answered == 0 {
+		return 0
+	}
+	return r.HandleTime / time.Duration(r.Answered)
+}
+
+// ReportStore persists interval reports. SaveIntervals replaces reports with the same
+// queue and start, since late call records update intervals that were already saved.
+type ReportStore interface {
+	SaveIntervals(ctx context.Context, reports []IntervalReport) error
+	// Intervals returns the reports of a queue, or of every queue when queue is empty,
+	// starting in [from, to), ordered by start
+	Intervals(ctx context.Context, queue string, from, to time.Time) ([]IntervalReport, error)
+}
+
+type intervalKey struct {
+	queue string
+	start int64
+}
+
+// MemoryReportStore keeps interval reports in memory, for tests and single-process deployments
+type MemoryReportStore struct {
+	mu      sync.Mutex
+	reports map[intervalKey]IntervalReport
+}
+
+// NewMemoryReportStore creates an empty in-memory report store
+func NewMemoryReportStore() *MemoryReportStore {
+	return &MemoryReportStore{reports: make(map[intervalKey]IntervalReport)}
+}
+
+// SaveIntervals stores the reports, replacing earlier versions
+func (s *MemoryReportStore) SaveIntervals(ctx context.Context, reports []IntervalReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, report := range reports {
+		s.reports[intervalKey{report.Queue, report.Start.UnixNano()}] = report
+	}
+	return nil
+}
+
+// Intervals returns the stored reports in a time range
+func (s *MemoryReportStore) Intervals(ctx context.Context, queue string, from, to time.Time) ([]IntervalReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reports []IntervalReport
+	for _, report := range s.reports {
+		if (queue == "" || report.Queue == queue) && !report.Start.Before(from) && report.Start.Before(to) {
+			reports = append(reports, report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].Start.Equal(reports[j].Start) {
+			return reports[i].Start.Before(reports[j].Start)
+		}
+		return reports[i].Queue < reports[j].Queue
+	})
+	return reports, nil
+}
+
+// ReporterOptions represents historical reporting configuration
+type ReporterOptions struct {
+	// Interval is the reporting interval; 15 minutes when zero
+	Interval time.Duration
+	// Retention is how long intervals are kept in memory to absorb late call records; 24h when zero
+	Retention time.Duration
+}
+
+// Reporter aggregates call records into per-queue interval reports and persists them.
+// Calls are counted in the interval they were offered in. Intervals already in the store,
+// e.g. from before a restart, are added to rather than replaced.
+type Reporter struct {
+	store     ReportStore
+	interval  time.Duration
+	retention time.Duration
+	mu        sync.Mutex
+	intervals map[intervalKey]*IntervalReport
+	dirty     map[intervalKey]bool
+	// loaded marks the intervals merged with their stored version
+	loaded map[intervalKey]bool
+	// flushing serializes flushes so stored intervals are merged once
+	flushing sync.Mutex
+}
+
+// NewReporter creates a reporter persisting to store
+func NewReporter(store ReportStore, options *ReporterOptions) *Reporter {
+	if options == nil {
+		options = &ReporterOptions{}
+	}
+	r := &Reporter{
+		store:     store,
+		interval:  options.Interval,
+		retention: options.Retention,
+		intervals: make(map[intervalKey]*IntervalReport),
+		dirty:     make(map[intervalKey]bool),
+		loaded:    make(map[intervalKey]bool),
+	}
+	if r.interval <= 0 {
+		r.interval = 15 * time.Minute
+	}
+	if r.retention <= 0 {
+		r.retention = 24 * time.Hour
+	}
+	return r
+}
+
+// Record adds a call record to its interval. Use it as an agent pool's OnCallRecord.
+func (r *Reporter) Record(record CallRecord) {
+	start := record.Enqueued.Truncate(r.interval)
+	key := intervalKey{record.Queue, start.UnixNano()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report, ok := r.intervals[key]
+	if !ok {
+		report = &IntervalReport{Queue: record.Queue, Start: start}
+		r.intervals[key] = report
+	}
+	report.Offered++
+	if record.Abandoned {
+		report.Abandoned++
+	} else {
+		report.Answered++
+		report.HandleTime += record.HandleTime()
+	}
+	r.dirty[key] = true
+}
+
+// Flush persists the intervals updated since the last flush and forgets intervals
+// older than the retention
+func (r *Reporter) Flush(ctx context.Context) error {
+	r.flushing.Lock()
+	defer r.flushing.Unlock()
+
+	r.mu.Lock()
+	var unloaded []intervalKey
+	for key := range r.dirty {
+		if !r.loaded[key] {
+			unloaded = append(unloaded, key)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, key := range unloaded {
+		start := time.Unix(0, key.start)
+		stored, err := r.store.Intervals(ctx, key.queue, start, start.Add(r.interval))
+		if err != nil {
+			return fmt.Errorf("failed to load interval reports: %w", err)
+		}
+		r.mu.Lock()
+		for _, existing := range stored {
+			if existing.Queue == key.queue && existing.Start.Equal(start) {
+				report := r.intervals[key]
+				report.Offered += existing.Offered
+				report.Answered += existing.Answered
+				report.Abandoned += existing.Abandoned
+				report.HandleTime += existing.HandleTime
+			}
+		}
+		r.loaded[key] = true
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	reports := make([]IntervalReport, 0, len(r.dirty))
+	for key := range r.dirty {
+		reports = append(reports, *r.intervals[key])
+	}
+	dirty := r.dirty
+	r.dirty = make(map[intervalKey]bool)
+
+	cutoff := time.Now().Add(-r.retention)
+	for key, report := range r.intervals {
+		if report.Start.Before(cutoff) && !dirty[key] {
+			delete(r.intervals, key)
+			delete(r.loaded, key)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(reports) == 0 {
+		return nil
+	}
+	if err := r.store.SaveIntervals(ctx, reports); err != nil {
+		// Keep the intervals dirty so the next flush retries them
+		r.mu.Lock()
+		for key := range dirty {
+			if _, ok := r.intervals[key]; ok {
+				r.dirty[key] = true
+			}
+		}
+		r.mu.Unlock()
+		return fmt.Errorf("failed to save interval reports: %w", err)
+	}
+	return nil
+}
+
+// Run flushes at the end of every interval until ctx is done, then flushes once more
+func (r *Reporter) Run(ctx context.Context) error {
+	for {
+		next := time.Now().Truncate(r.interval).Add(r.interval)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			if err := r.Flush(ctx); err != nil {
+				// Retried at the next interval
+				continue
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return r.Flush(flushCtx)
+		}
+	}
+}
+
+// Query returns the persisted reports of a queue, or of every queue when queue is empty,
+// for the intervals starting in [from, to)
+func (r *Reporter) Query(ctx context.Context, queue string, from, to time.Time) ([]IntervalReport, error) {
+	reports, err := r.store.Intervals(ctx, queue, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query interval reports: %w", err)
+	}
+	return reports, nil
+}
+
+// Summarize totals interval reports, e.g. to report a whole day
+func Summarize(reports []IntervalReport) IntervalReport {
+	var total IntervalReport
+	for i, report := range reports {
+		if i == 0 || report.Start.Before(total.Start) {
+			total.Start = report.Start
+		}
+		total.Offered += report.Offered
+		total.Answered += report.Answered
+		total.Abandoned += report.Abandoned
+		total.HandleTime += report.HandleTime
+	}
+	return total
+}
@@ -0,0 +1,111 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingReportStore fails every save
+type failingReportStore struct {
+	*MemoryReportStore
+}
+
+func (s failingReportStore) SaveIntervals(ctx context.Context, reports []IntervalReport) error {
+	return errors.New("database down")
+}
+
+func TestReporterAggregation(t *testing.T) {
+	store := NewMemoryReportStore()
+	reporter := NewReporter(store, nil)
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: start.Add(time.Minute), Answered: start.Add(2 * time.Minute),
+		Ended: start.Add(6 * time.Minute), WrapUp: time.Minute})
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: start.Add(14 * time.Minute), Answered: start.Add(15 * time.Minute),
+		Ended: start.Add(18 * time.Minute)})
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: start.Add(5 * time.Minute), Abandoned: true})
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: start.Add(16 * time.Minute), Abandoned: true})
+	reporter.Record(CallRecord{Queue: "support", Enqueued: start, Abandoned: true})
+
+	if err := reporter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	reports, err := reporter.Query(context.Background(), "sales", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 sales intervals, got %d", len(reports))
+	}
+	first := reports[0]
+	if !first.Start.Equal(start) || first.Offered != 3 || first.Answered != 2 || first.Abandoned != 1 {
+		t.Errorf("Unexpected first interval: %+v", first)
+	}
+	if aht := first.AHT(); aht != 4*time.Minute {
+		t.Errorf("Expected AHT of 4m, got %v", aht)
+	}
+
+	// A late record updates the saved interval
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: start.Add(3 * time.Minute), Abandoned: true})
+	reporter.Flush(context.Background())
+	reports, _ = reporter.Query(context.Background(), "", start, start.Add(15*time.Minute))
+	if len(reports) != 2 || reports[0].Offered != 4 || reports[1].Queue != "support" {
+		t.Errorf("Expected updated sales interval and support interval, got %+v", reports)
+	}
+
+	if total := Summarize(reports); total.Offered != 5 || total.Abandoned != 3 {
+		t.Errorf("Unexpected summary: %+v", total)
+	}
+}
+
+func TestReporterRetriesFailedSaves(t *testing.T) {
+	store := NewMemoryReportStore()
+	reporter := NewReporter(failingReportStore{store}, nil)
+	reporter.Record(CallRecord{Queue: "sales", Enqueued: time.Now(), Abandoned: true})
+	if err := reporter.Flush(context.Background()); err == nil {
+		t.Fatal("Expected flush error")
+	}
+
+	reporter.store = store
+	reporter.Flush(context.Background())
+	reports, _ := store.Intervals(context.Background(), "sales", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if len(reports) != 1 {
+		t.Errorf("Expected the failed interval to be saved on retry, got %d", len(reports))
+	}
+}
+
+func TestPoolCallRecords(t *testing.T) {
+	records := make(chan CallRecord, 4)
+	pool := NewAgentPool(&AgentPoolOptions{
+		WrapUp:       30 * time.Millisecond,
+		OnCallRecord: func(record CallRecord) { records <- record },
+	})
+	pool.AddAgent(&Agent{ID: "ann"}, AgentAvailable)
+	queue := pool.NewQueue(QueueOptions{Name: "sales"})
+
+	agent, _ := queue.Enqueue(context.Background(), &QueuedCall{ID: "call-1"})
+	time.Sleep(20 * time.Millisecond)
+	pool.Release(agent.ID)
+
+	select {
+	case record := <-records:
+		if record.CallID != "call-1" || record.AgentID != "ann" || record.Queue != "sales" {
+			t.Errorf("Unexpected record: %+v", record)
+		}
+		if record.WrapUp < 20*time.Millisecond || record.HandleTime() < 40*time.Millisecond {
+			t.Errorf("Expected handle time to include talk and wrap-up, got %+v", record)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a call record after wrap-up")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pool.SetState("ann", AgentOffline)
+	queue.Enqueue(ctx, &QueuedCall{ID: "call-2"})
+	if record := <-records; !record.Abandoned || record.CallID != "call-2" {
+		t.Errorf("Expected abandoned record for call-2, got %+v", record)
+	}
+}
@@ -0,0 +1,176 @@
+// Package rustpbxtest provides helpers for integration tests of applications built on the
+// RustPBX SDK, such as assertions on the sequence of events of a call:
+//
+//	events := rustpbxtest.Record(conn)
+//	conn.Invite(option)
+//	events.Expect(t, "ringing", "answer", "asr*", rustpbxtest.Expect{Event: "hangup", Timeout: time.Minute})
+package rustpbxtest
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rustpbx/go-sdk/rustpbx"
+)
+
+// DefaultTimeout bounds the wait for each expected event when the expectation has no timeout
+var DefaultTimeout = 5 * time.Second
+
+// Expect describes an expected event
+type Expect struct {
+	// Event is a path.Match pattern on the event type, e.g. "asr*"; "*" or empty match any event
+	Event string
+	// Match further checks the event when not nil
+	Match func(event *rustpbx.Event) bool
+	// Timeout bounds the wait since the previous expected event; DefaultTimeout when zero
+	Timeout time.Duration
+}
+
+// String describes the expectation in failure messages
+func (e Expect) String() string {
+	description := e.Event
+	if description == "" {
+		description = "*"
+	}
+	if e.Match != nil {
+		description += " (matching)"
+	}
+	return description
+}
+
+// matches reports whether an event meets the expectation
+func (e Expect) matches(event *rustpbx.Event) bool {
+	if e.Event != "" {
+		if ok, _ := path.Match(e.Event, event.Event); !ok {
+			return false
+		}
+	}
+	return e.Match == nil || e.Match(event)
+}
+
+// expectation converts an element of a sequence: an event type pattern, an Expect or a
+// func(*rustpbx.Event) bool
+func expectation(t testing.TB, element interface{}) Expect {
+	t.Helper()
+	switch e := element.(type) {
+	case string:
+		return Expect{Event: e}
+	case Expect:
+		return e
+	case func(event *rustpbx.Event) bool:
+		return Expect{Match: e}
+	default:
+		t.Fatalf("unsupported expectation %T", element)
+		return Expect{}
+	}
+}
+
+// Recorder records the events of a connection for assertions
+type Recorder struct {
+	conn *rustpbx.Connection
+	sub  *rustpbx.Subscription
+
+	mu     sync.Mutex
+	events []*rustpbx.Event
+	// next is the index of the first event not consumed by Expect
+	next int
+	// added is signalled when an event is recorded
+	added chan struct{}
+}
+
+// Record starts recording the events of a connection. Start it before the actions whose
+// events are expected, so none is missed.
+func Record(conn *rustpbx.Connection) *Recorder {
+	r := &Recorder{conn: conn, added: make(chan struct{}, 1)}
+	r.sub = conn.AddEventHandler(func(event *rustpbx.Event) {
+		r.mu.Lock()
+		r.events = append(r.events, event)
+		r.mu.Unlock()
+		select {
+		case r.added <- struct{}{}:
+		default:
+		}
+	})
+	return r
+}
+
+// Stop stops recording
+func (r *Recorder) Stop() {
+	r.conn.RemoveEventHandler(r.sub)
+}
+
+// Events returns the events recorded so far
+func (r *Recorder) Events() []*rustpbx.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*rustpbx.Event(nil), r.events...)
+}
+
+// Expect asserts that events matching the sequence occur in order, allowing other events
+// in between, and returns the matched events. Each element is an event type pattern such
+// as "answer" or "track*", an Expect, or a func(*rustpbx.Event) bool. Matching continues
+// after the last event matched by a previous call. The test fails at the first expected
+// event that does not occur within its timeout.
+func (r *Recorder) Expect(t testing.TB, sequence ...interface{}) []*rustpbx.Event {
+	t.Helper()
+	matched := make([]*rustpbx.Event, 0, len(sequence))
+	for step, element := range sequence {
+		expect := expectation(t, element)
+		event, skipped := r.await(expect)
+		if event == nil {
+			t.Fatalf("expected event %s (%d of %d) within %s, got [%s]", expect, step+1, len(sequence),
+				expect.timeout(), strings.Join(skipped, " "))
+			return matched
+		}
+		matched = append(matched, event)
+	}
+	return matched
+}
+
+// timeout returns the wait bound of the expectation
+func (e Expect) timeout() time.Duration {
+	if e.Timeout > 0 {
+		return e.Timeout
+	}
+	return DefaultTimeout
+}
+
+// await consumes events until one meets the expectation. It returns nil and the types of
+// the events seen meanwhile when the timeout expires first.
+func (r *Recorder) await(expect Expect) (*rustpbx.Event, []string) {
+	timer := time.NewTimer(expect.timeout())
+	defer timer.Stop()
+	var skipped []string
+	for {
+		r.mu.Lock()
+		for r.next < len(r.events) {
+			event := r.events[r.next]
+			r.next++
+			if expect.matches(event) {
+				r.mu.Unlock()
+				return event, nil
+			}
+			skipped = append(skipped, event.Event)
+		}
+		r.mu.Unlock()
+
+		select {
+		case <-r.added:
+		case <-timer.C:
+			return nil, skipped
+		}
+	}
+}
+
+// ExpectEvents records the events of a connection from now on and asserts that they
+// occur in the order of the sequence, see Recorder.Expect. Use Record instead when the
+// events may occur before ExpectEvents is called.
+func ExpectEvents(t testing.TB, conn *rustpbx.Connection, sequence ...interface{}) []*rustpbx.Event {
+	t.Helper()
+	r := Record(conn)
+	defer r.Stop()
+	return r.Expect(t, sequence...)
+}
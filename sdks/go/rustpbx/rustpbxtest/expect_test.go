@@ -0,0 +1,121 @@
+package rustpbxtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rustpbx/go-sdk/rustpbx"
+)
+
+// fakeT records the failure of an expectation instead of failing the test
+type fakeT struct {
+	testing.TB
+	failure string
+}
+
+// errFailed aborts an expectation after Fatalf
+var errFailed = errors.New("failed")
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.failure = fmt.Sprintf(format, args...)
+	panic(errFailed)
+}
+
+// expectFailure runs an expectation that should fail and returns its message
+func expectFailure(t *testing.T, run func(t testing.TB)) string {
+	t.Helper()
+	fake := &fakeT{TB: t}
+	func() {
+		defer func() {
+			if r := recover(); r != nil && r != errFailed {
+				panic(r)
+			}
+		}()
+		run(fake)
+	}()
+	if fake.failure == "" {
+		t.Fatal("Expected the expectation to fail")
+	}
+	return fake.failure
+}
+
+// callServer sends the events of a call once the client is ready
+func callServer(t *testing.T) string {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		for _, event := range []map[string]interface{}{
+			{"event": "ringing"},
+			{"event": "answer"},
+			{"event": "trackStart", "trackId": "tts"},
+			{"event": "asrDelta", "text": "hel"},
+			{"event": "asrFinal", "text": "hello"},
+			{"event": "dtmf", "digit": "5"},
+			{"event": "hangup"},
+		} {
+			conn.WriteJSON(event)
+		}
+		conn.ReadMessage()
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestExpectEvents(t *testing.T) {
+	conn, err := rustpbx.NewConnection(context.Background(), callServer(t))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	events := Record(conn)
+	defer events.Stop()
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	matched := events.Expect(t, "ringing", "answer", "asr*",
+		func(event *rustpbx.Event) bool { return event.Event == "asrFinal" && event.Text == "hello" },
+		Expect{Event: "dtmf", Match: func(event *rustpbx.Event) bool { return event.Digit == "5" }})
+	if len(matched) != 5 || matched[2].Event != "asrDelta" || matched[3].Text != "hello" {
+		t.Errorf("Unexpected matched events: %+v", matched)
+	}
+
+	failure := expectFailure(t, func(t testing.TB) {
+		events.Expect(t, Expect{Event: "answer", Timeout: 100 * time.Millisecond})
+	})
+	if !strings.Contains(failure, "expected event answer (1 of 1)") || !strings.Contains(failure, "[hangup]") {
+		t.Errorf("Unexpected failure message: %s", failure)
+	}
+	if len(events.Events()) != 7 {
+		t.Errorf("Expected 7 recorded events, got %d", len(events.Events()))
+	}
+}
+
+func TestExpectEventsTimesOut(t *testing.T) {
+	conn, err := rustpbx.NewConnection(context.Background(), callServer(t))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	failure := expectFailure(t, func(t testing.TB) {
+		ExpectEvents(t, conn, Expect{Event: "hangup", Timeout: 50 * time.Millisecond})
+	})
+	if !strings.Contains(failure, "within 50ms") {
+		t.Errorf("Unexpected failure message: %s", failure)
+	}
+}
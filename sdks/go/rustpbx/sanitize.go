@@ -0,0 +1,193 @@
+package rustpbx
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EmojiTreatment controls what the text sanitizer does with emojis
+type EmojiTreatment int
+
+const (
+	// EmojiKeep leaves emojis to the TTS provider
+	EmojiKeep EmojiTreatment = iota
+	// EmojiRemove drops emojis
+	EmojiRemove
+	// EmojiDescribe replaces emojis with their description, such as "thumbs up", and drops
+	// emojis without one
+	EmojiDescribe
+)
+
+// DefaultEmojiNames are the English descriptions used by EmojiDescribe
+var DefaultEmojiNames = map[string]string{
+	"😀": "grinning face",
+	"😃": "grinning face",
+	"😄": "grinning face",
+	"🙂": "smiling face",
+	"😊": "smiling face",
+	"😉": "winking face",
+	"😂": "face with tears of joy",
+	"😢": "crying face",
+	"😞": "disappointed face",
+	"🤔": "thinking face",
+	"👍": "thumbs up",
+	"👎": "thumbs down",
+	"👋": "waving hand",
+	"🙏": "folded hands",
+	"👏": "clapping hands",
+	"❤": "red heart",
+	"🎉": "party popper",
+	"✅": "check mark",
+	"✔": "check mark",
+	"❌": "cross mark",
+	"⚠": "warning",
+	"⭐": "star",
+	"🔥": "fire",
+	"🚀": "rocket",
+	"💡": "light bulb",
+	"📞": "telephone",
+	"☎": "telephone",
+	"📧": "e-mail",
+	"📅": "calendar",
+	"⏰": "alarm clock",
+}
+
+// TextSanitizer prepares text for TTS, since LLM output routinely contains formatting that
+// providers read aloud literally. The zero value only repairs invalid UTF-8 and drops
+// control characters.
+type TextSanitizer struct {
+	// StripMarkdown converts markdown formatting into prose, with the Markdown strategies
+	StripMarkdown bool
+	// Markdown holds the list, link and code strategies; the MarkdownProse defaults when nil
+	Markdown *MarkdownProse
+	Emoji    EmojiTreatment
+	// EmojiNames describes emojis for EmojiDescribe; DefaultEmojiNames when nil
+	EmojiNames map[string]string
+	// CollapseWhitespace turns runs of whitespace, including line breaks, into single spaces
+	CollapseWhitespace bool
+	// InvalidUTF8 replaces invalid UTF-8 sequences; they are dropped when empty
+	InvalidUTF8 string
+	// MaxLength splits texts longer than this many characters, preferably after sentences,
+	// into segments streamed in turn; unlimited when zero
+	MaxLength int
+}
+
+// DefaultTextSanitizer returns a sanitizer suited to LLM output: markdown stripped,
+// emojis removed and whitespace collapsed
+func DefaultTextSanitizer() *TextSanitizer {
+	return &TextSanitizer{
+		StripMarkdown:      true,
+		Emoji:              EmojiRemove,
+		CollapseWhitespace: true,
+	}
+}
+
+// Sanitize returns the text to speak
+func (s *TextSanitizer) Sanitize(text string) string {
+	text = strings.ToValidUTF8(text, s.InvalidUTF8)
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, text)
+
+	if s.StripMarkdown {
+		prose := s.Markdown
+		if prose == nil {
+			prose = &MarkdownProse{}
+		}
+		text = prose.Convert(text)
+	}
+	switch s.Emoji {
+	case EmojiRemove:
+		text = replaceEmojis(text, nil)
+	case EmojiDescribe:
+		names := s.EmojiNames
+		if names == nil {
+			names = DefaultEmojiNames
+		}
+		text = replaceEmojis(text, names)
+	}
+	if s.CollapseWhitespace {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	return text
+}
+
+// Split splits a sanitized text into segments of at most MaxLength characters
+func (s *TextSanitizer) Split(text string) []string {
+	if s.MaxLength <= 0 {
+		return []string{text}
+	}
+	var segments []string
+	for _, segment := range splitText(text, s.MaxLength, 0, func(rune) int { return 1 }) {
+		if strings.TrimSpace(segment) != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return []string{text}
+	}
+	return segments
+}
+
+// isEmoji reports whether a rune is an emoji or part of an emoji sequence
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		// Pictographs, emoticons, flags and skin tones
+	case r >= 0x2600 && r <= 0x27BF:
+		// Miscellaneous symbols and dingbats
+	case r >= 0x2B00 && r <= 0x2BFF, r == 0x231A, r == 0x231B, r >= 0x23E9 && r <= 0x23FA:
+	case r == 0x200D, r == 0xFE0F, r == 0x20E3, r >= 0xE0020 && r <= 0xE007F:
+		// Joiners, variation selectors, keycaps and tags
+	default:
+		return false
+	}
+	return true
+}
+
+// isEmojiModifier reports whether a rune only modifies the emoji before it
+func isEmojiModifier(r rune) bool {
+	return r == 0x200D || r == 0xFE0F || r == 0x20E3 || (r >= 0x1F3FB && r <= 0x1F3FF) || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+// replaceEmojis replaces emojis with their names, or drops them when names is nil or has
+// no entry. Names are separated from the surrounding words by spaces.
+func replaceEmojis(text string, names map[string]string) string {
+	var b strings.Builder
+	var previous rune
+	runes := []rune(text)
+	for i, r := range runes {
+		if !isEmoji(r) {
+			b.WriteRune(r)
+			previous = r
+			continue
+		}
+		if isEmojiModifier(r) {
+			continue
+		}
+		name, ok := names[string(r)]
+		if !ok {
+			continue
+		}
+		if previous != 0 && !unicode.IsSpace(previous) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(name)
+		previous = 'x'
+		// Separate the name from the next word
+		for _, next := range runes[i+1:] {
+			if isEmojiModifier(next) {
+				continue
+			}
+			if !unicode.IsSpace(next) && !unicode.IsPunct(next) {
+				b.WriteByte(' ')
+				previous = ' '
+			}
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeMarkdown(t *testing.T) {
+	text := "# Your options\n\nHere is **what** you can do:\n\n- Pay `online` at [our site](https://example.com)\n" +
+		"- Call _support_, open my_account_page\n\n---\n| Plan | Price |\n|------|------:|\n| Basic | $5 |\n\n```\ncode\n```"
+	expected := "Your options. Here is what you can do: Pay online at our site. " +
+		"Call support, open my_account_page. Plan, Price. Basic, $5. code"
+	if got := DefaultTextSanitizer().Sanitize(text); got != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, got)
+	}
+}
+
+func TestSanitizeEmoji(t *testing.T) {
+	tests := []struct {
+		treatment EmojiTreatment
+		text      string
+		expected  string
+	}{
+		{EmojiRemove, "Great 👍🏽 see you soon! 🎉", "Great see you soon!"},
+		{EmojiDescribe, "Done✅ and 🦄, thanks🙏!", "Done check mark and , thanks folded hands!"},
+		{EmojiKeep, "Hi 👋", "Hi 👋"},
+	}
+	for _, test := range tests {
+		sanitizer := &TextSanitizer{Emoji: test.treatment, CollapseWhitespace: true}
+		if got := sanitizer.Sanitize(test.text); got != test.expected {
+			t.Errorf("Expected '%s', got '%s'", test.expected, got)
+		}
+	}
+
+	sanitizer := &TextSanitizer{Emoji: EmojiDescribe, EmojiNames: map[string]string{"👍": "pulgar arriba"}}
+	if got := sanitizer.Sanitize("Listo 👍"); got != "Listo pulgar arriba" {
+		t.Errorf("Expected custom emoji names, got '%s'", got)
+	}
+}
+
+func TestSanitizeUTF8(t *testing.T) {
+	sanitizer := &TextSanitizer{InvalidUTF8: "?"}
+	if got := sanitizer.Sanitize("caf\xe9\x00 ok\x07"); got != "caf? ok" {
+		t.Errorf("Expected invalid UTF-8 replaced and control characters dropped, got %q", got)
+	}
+}
+
+func TestSanitizerSplit(t *testing.T) {
+	sanitizer := &TextSanitizer{MaxLength: 40}
+	text := "First sentence is here. Second one follows it. And a third that is quite a bit longer than forty."
+	segments := sanitizer.Split(text)
+	if len(segments) < 3 {
+		t.Fatalf("Expected at least 3 segments, got %v", segments)
+	}
+	if segments[0] != "First sentence is here. " {
+		t.Errorf("Expected the first segment to end on a sentence, got '%s'", segments[0])
+	}
+	for _, segment := range segments {
+		if len([]rune(segment)) > 40 {
+			t.Errorf("Segment exceeds 40 characters: '%s'", segment)
+		}
+	}
+}
+
+func TestTTSSanitized(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	sanitizer := DefaultTextSanitizer()
+	sanitizer.MaxLength = 30
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{TextSanitizer: sanitizer})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.TTSSimple("**Hello** there 😀"); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	if cmd := <-commands; cmd["text"] != "Hello there" || cmd["streaming"] == true {
+		t.Errorf("Expected a single sanitized TTS command, got %v", cmd)
+	}
+
+	if err := conn.TTSSimple("🎉"); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	if err := conn.TTSSimple("Thanks for calling. Your order has shipped today."); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	var spoken []string
+	for {
+		cmd := <-commands
+		if cmd["streaming"] != true {
+			t.Fatalf("Expected streamed segments, got %v", cmd)
+		}
+		spoken = append(spoken, cmd["text"].(string))
+		if cmd["end_of_stream"] == true {
+			break
+		}
+	}
+	if len(spoken) != 2 || strings.Join(spoken, "") != "Thanks for calling. Your order has shipped today." {
+		t.Errorf("Unexpected segments: %q", spoken)
+	}
+}
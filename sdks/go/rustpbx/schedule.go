@@ -0,0 +1,497 @@
+package rustpbx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleState is the state of a schedule at a point in time
+type ScheduleState string
+
+const (
+	ScheduleOpen    ScheduleState = "open"
+	ScheduleClosed  ScheduleState = "closed"
+	ScheduleHoliday ScheduleState = "holiday"
+)
+
+// TimeRange is a daily opening window in minutes since midnight.
+// A range whose end is not after its start runs past midnight.
+type TimeRange struct {
+	Start int
+	End   int
+}
+
+// Holiday is a full day on which the schedule is closed
+type Holiday struct {
+	Date string // YYYY-MM-DD in the schedule's time zone
+	Name string
+}
+
+// Closure closes the schedule for part of a day, such as a timed calendar event
+type Closure struct {
+	Start time.Time
+	End   time.Time
+	Name  string
+}
+
+// Schedule represents business hours and holidays in a time zone, used to
+// branch flows between live routing and after-hours handling
+type Schedule struct {
+	Location *time.Location
+	Hours    map[time.Weekday][]TimeRange
+	Holidays map[string]Holiday
+	// Closures are treated as holidays while they last
+	Closures []Closure
+}
+
+// NewSchedule creates an always-closed schedule in the named IANA time zone
+func NewSchedule(timezone string) (*Schedule, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load time zone %s: %w", timezone, err)
+	}
+	return &Schedule{
+		Location: loc,
+		Hours:    make(map[time.Weekday][]TimeRange),
+		Holidays: make(map[string]Holiday),
+	}, nil
+}
+
+// parseClock parses an HH:MM time of day into minutes since midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", clock, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// AddHours opens the schedule between start and end (HH:MM) on the given days
+func (s *Schedule) AddHours(days []time.Weekday, start, end string) error {
+	startMin, err := parseClock(start)
+	if err != nil {
+		return err
+	}
+	endMin, err := parseClock(end)
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		s.Hours[day] = append(s.Hours[day], TimeRange{Start: startMin, End: endMin})
+	}
+	return nil
+}
+
+// AddHoliday closes the schedule for a whole day (YYYY-MM-DD)
+func (s *Schedule) AddHoliday(date, name string) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return fmt.Errorf("invalid holiday date %q: %w", date, err)
+	}
+	s.Holidays[date] = Holiday{Date: date, Name: name}
+	return nil
+}
+
+// IsHoliday reports whether t falls on a holiday or within a closure
+func (s *Schedule) IsHoliday(t time.Time) (Holiday, bool) {
+	date := t.In(s.Location).Format("2006-01-02")
+	if h, ok := s.Holidays[date]; ok {
+		return h, true
+	}
+	for _, c := range s.Closures {
+		if !t.Before(c.Start) && t.Before(c.End) {
+			return Holiday{Date: date, Name: c.Name}, true
+		}
+	}
+	return Holiday{}, false
+}
+
+// IsOpen reports whether the schedule is open at t
+func (s *Schedule) IsOpen(t time.Time) bool {
+	return s.State(t) == ScheduleOpen
+}
+
+// State returns the state of the schedule at t
+func (s *Schedule) State(t time.Time) ScheduleState {
+	if _, ok := s.IsHoliday(t); ok {
+		return ScheduleHoliday
+	}
+
+	local := t.In(s.Location)
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, r := range s.Hours[local.Weekday()] {
+		if r.End > r.Start {
+			if minute >= r.Start && minute < r.End {
+				return ScheduleOpen
+			}
+		} else if minute >= r.Start {
+			return ScheduleOpen
+		}
+	}
+
+	// Overnight ranges from the previous day
+	for _, r := range s.Hours[(local.Weekday()+6)%7] {
+		if r.End <= r.Start && minute < r.End {
+			return ScheduleOpen
+		}
+	}
+
+	return ScheduleClosed
+}
+
+// NextOpen returns the first minute at or after t when the schedule is open,
+// searching up to two weeks ahead
+func (s *Schedule) NextOpen(t time.Time) (time.Time, bool) {
+	cursor := t.Truncate(time.Minute)
+	limit := cursor.Add(14 * 24 * time.Hour)
+	for cursor.Before(limit) {
+		if s.IsOpen(cursor) {
+			return cursor, true
+		}
+		cursor = s.nextChange(cursor)
+	}
+	return time.Time{}, false
+}
+
+// nextChange returns the first time after t at which the state may change: the start or
+// end of an opening range, the start or end of a closure, or midnight, when holidays start
+// and end
+func (s *Schedule) nextChange(t time.Time) time.Time {
+	local := t.In(s.Location)
+	year, month, day := local.Date()
+	next := time.Date(year, month, day+1, 0, 0, 0, 0, s.Location)
+	consider := func(candidate time.Time) {
+		if candidate.After(t) && candidate.Before(next) {
+			next = candidate
+		}
+	}
+	at := func(minute int) time.Time {
+		return time.Date(year, month, day, 0, minute, 0, 0, s.Location)
+	}
+	for _, r := range s.Hours[local.Weekday()] {
+		consider(at(r.Start))
+		consider(at(r.End))
+	}
+	// Overnight ranges from the previous day end today
+	for _, r := range s.Hours[(local.Weekday()+6)%7] {
+		if r.End <= r.Start {
+			consider(at(r.End))
+		}
+	}
+	for _, c := range s.Closures {
+		consider(c.Start)
+		consider(c.End)
+	}
+	return next
+}
+
+// LoadHolidayList reads holidays from lines of the form "YYYY-MM-DD Name".
+// Blank lines and lines starting with # are ignored.
+func (s *Schedule) LoadHolidayList(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date, name, _ := strings.Cut(line, " ")
+		if err := s.AddHoliday(date, strings.TrimSpace(name)); err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// icsRecurrenceYears bounds the expansion of recurring events without COUNT or UNTIL,
+// in years from now
+const icsRecurrenceYears = 5
+
+// LoadICS reads the events of an iCalendar file. All-day events are holidays; multi-day
+// events close every day from DTSTART up to, but excluding, DTEND. Timed events are
+// closures from DTSTART to DTEND, in UTC, their TZID or else the schedule's time zone.
+//
+// Recurring events are expanded for RRULEs with FREQ DAILY, WEEKLY, MONTHLY or YEARLY,
+// INTERVAL, COUNT, UNTIL and, for WEEKLY, BYDAY weekdays; occurrences listed in EXDATE
+// are skipped. Other rules, such as the nth weekday of a month, return an error rather
+// than leaving the schedule open on the holidays they describe. Rules without an end
+// are expanded up to icsRecurrenceYears from now.
+func (s *Schedule) LoadICS(r io.Reader) error {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return err
+	}
+	var inEvent bool
+	var event icsEvent
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Parameters such as DTSTART;TZID=Europe/Paris follow the name
+		name, params, _ := strings.Cut(name, ";")
+		name = strings.ToUpper(name)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			event = icsEvent{}
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if err := s.addICSEvent(&event); err != nil {
+				return err
+			}
+		case inEvent && name == "DTSTART":
+			event.start = icsValue{value: value, tzid: icsTZID(params)}
+		case inEvent && name == "DTEND":
+			event.end = icsValue{value: value, tzid: icsTZID(params)}
+		case inEvent && name == "SUMMARY":
+			event.summary = value
+		case inEvent && name == "RRULE":
+			event.rrule = value
+		case inEvent && name == "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				event.exdates = append(event.exdates, icsValue{value: date, tzid: icsTZID(params)})
+			}
+		}
+	}
+	return nil
+}
+
+// unfoldICS reads the lines of an iCalendar file, joining the continuation lines that
+// start with a space or a tab to the line they continue (RFC 5545, section 3.1)
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// icsEvent is the part of a VEVENT the schedule reads
+type icsEvent struct {
+	start, end icsValue
+	summary    string
+	rrule      string
+	exdates    []icsValue
+}
+
+// icsValue is a DTSTART, DTEND, EXDATE or UNTIL value with its time zone parameter
+type icsValue struct {
+	value string
+	tzid  string
+}
+
+// icsTZID returns the TZID parameter among the parameters of a property
+func icsTZID(params string) string {
+	for _, param := range strings.Split(params, ";") {
+		if name, value, _ := strings.Cut(param, "="); strings.EqualFold(name, "TZID") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// time parses a date or a date-time; timed is false for dates
+func (v icsValue) time(loc *time.Location) (t time.Time, timed bool, err error) {
+	date, clock, timed := strings.Cut(v.value, "T")
+	if !timed {
+		t, err = time.ParseInLocation("20060102", date, loc)
+		return t, false, err
+	}
+	if strings.HasSuffix(clock, "Z") {
+		loc = time.UTC
+	} else if v.tzid != "" {
+		if loc, err = time.LoadLocation(v.tzid); err != nil {
+			return time.Time{}, true, err
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", date+"T"+strings.TrimSuffix(clock, "Z"), loc)
+	return t, true, err
+}
+
+// addICSEvent adds the days covered by an all-day iCalendar event as holidays, or a
+// timed event as a closure, for every occurrence of a recurring event
+func (s *Schedule) addICSEvent(event *icsEvent) error {
+	if len(event.start.value) < 8 {
+		return fmt.Errorf("invalid DTSTART %q", event.start.value)
+	}
+	first, timed, err := event.start.time(s.Location)
+	if err != nil {
+		return fmt.Errorf("invalid DTSTART %q: %w", event.start.value, err)
+	}
+	occurrences := []time.Time{first}
+	if event.rrule != "" {
+		rule, err := parseICSRecurrence(event.rrule, first, s.Location)
+		if err != nil {
+			return fmt.Errorf("event %q: %w", event.summary, err)
+		}
+		occurrences = rule.expand(first, time.Now().AddDate(icsRecurrenceYears, 0, 0))
+	}
+	excluded := make(map[time.Time]bool)
+	for _, exdate := range event.exdates {
+		if t, _, err := exdate.time(first.Location()); err == nil {
+			excluded[t.UTC()] = true
+		}
+	}
+
+	if timed {
+		// An event without a valid end lasts no time
+		last, _, err := event.end.time(s.Location)
+		if err != nil || !last.After(first) {
+			return nil
+		}
+		for _, start := range occurrences {
+			if !excluded[start.UTC()] {
+				s.Closures = append(s.Closures, Closure{Start: start, End: start.Add(last.Sub(first)), Name: event.summary})
+			}
+		}
+		return nil
+	}
+
+	days := 1
+	if t, _, err := event.end.time(s.Location); err == nil && t.After(first) {
+		// Rounded, as a day across a DST change is not 24 hours long
+		days = int((t.Sub(first) + 12*time.Hour) / (24 * time.Hour))
+	}
+	for _, start := range occurrences {
+		if excluded[start.UTC()] {
+			continue
+		}
+		for i := 0; i < days; i++ {
+			date := start.AddDate(0, 0, i).Format("2006-01-02")
+			s.Holidays[date] = Holiday{Date: date, Name: event.summary}
+		}
+	}
+	return nil
+}
+
+// icsRecurrence is the part of an RRULE the schedule expands
+type icsRecurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	weekdays []time.Weekday
+}
+
+// icsWeekdays maps the BYDAY codes to weekdays
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseICSRecurrence parses an RRULE of an event starting at first, refusing the parts
+// the schedule cannot expand
+func parseICSRecurrence(rrule string, first time.Time, loc *time.Location) (*icsRecurrence, error) {
+	rule := &icsRecurrence{interval: 1}
+	for _, part := range strings.Split(rrule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(value)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(value)
+			if err == nil && rule.interval < 1 {
+				err = fmt.Errorf("must be positive")
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(value)
+		case "UNTIL":
+			rule.until, _, err = icsValue{value: value}.time(loc)
+		case "WKST":
+			// Weeks start on Monday, the default; other starts only matter with INTERVAL
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				weekday, ok := icsWeekdays[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE BYDAY %q", value)
+				}
+				rule.weekdays = append(rule.weekdays, weekday)
+			}
+		case "BYMONTH":
+			// Accepted when it only repeats the month of DTSTART
+			if value != strconv.Itoa(int(first.Month())) {
+				return nil, fmt.Errorf("unsupported RRULE %s", part)
+			}
+		case "BYMONTHDAY":
+			if value != strconv.Itoa(first.Day()) {
+				return nil, fmt.Errorf("unsupported RRULE %s", part)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE %s", part)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE %s: %w", part, err)
+		}
+	}
+	switch rule.freq {
+	case "DAILY", "MONTHLY", "YEARLY":
+		if len(rule.weekdays) > 0 {
+			return nil, fmt.Errorf("unsupported RRULE BYDAY with FREQ=%s", rule.freq)
+		}
+	case "WEEKLY":
+		if len(rule.weekdays) == 0 {
+			rule.weekdays = []time.Weekday{first.Weekday()}
+		}
+		// Occurrences are produced in the order of the week, which starts on Monday
+		sort.Slice(rule.weekdays, func(i, j int) bool {
+			return (rule.weekdays[i]+6)%7 < (rule.weekdays[j]+6)%7
+		})
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ %q", rule.freq)
+	}
+	return rule, nil
+}
+
+// expand returns the occurrences of the rule from first, ending at COUNT, UNTIL or
+// horizon, whichever comes first
+func (r *icsRecurrence) expand(first, horizon time.Time) []time.Time {
+	end := horizon
+	if !r.until.IsZero() && r.until.Before(end) {
+		// UNTIL is inclusive
+		end = r.until.Add(time.Nanosecond)
+	}
+	var occurrences []time.Time
+	for period := 0; ; period++ {
+		var candidates []time.Time
+		switch r.freq {
+		case "DAILY":
+			candidates = []time.Time{first.AddDate(0, 0, period*r.interval)}
+		case "WEEKLY":
+			monday := first.AddDate(0, 0, -int((first.Weekday()+6)%7)+7*period*r.interval)
+			for _, weekday := range r.weekdays {
+				candidates = append(candidates, monday.AddDate(0, 0, int((weekday+6)%7)))
+			}
+		case "MONTHLY":
+			candidates = []time.Time{first.AddDate(0, period*r.interval, 0)}
+		case "YEARLY":
+			candidates = []time.Time{first.AddDate(period*r.interval, 0, 0)}
+		}
+		for _, candidate := range candidates {
+			if !candidate.Before(end) {
+				return occurrences
+			}
+			// Days that do not exist in a month or year, such as February 29, are skipped
+			if candidate.Before(first) || (r.freq == "MONTHLY" || r.freq == "YEARLY") && candidate.Day() != first.Day() {
+				continue
+			}
+			occurrences = append(occurrences, candidate)
+			if r.count > 0 && len(occurrences) == r.count {
+				return occurrences
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package rustpbx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleState(t *testing.T) {
+	schedule, err := NewSchedule("America/New_York")
+	if err != nil {
+		t.Fatalf("NewSchedule failed: %v", err)
+	}
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	if err := schedule.AddHours(weekdays, "09:00", "17:00"); err != nil {
+		t.Fatalf("AddHours failed: %v", err)
+	}
+	if err := schedule.AddHours([]time.Weekday{time.Saturday}, "22:00", "02:00"); err != nil {
+		t.Fatalf("AddHours failed: %v", err)
+	}
+
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20251225\r\nDTEND;VALUE=DATE:20251227\r\nSUMMARY:Christmas\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if err := schedule.LoadICS(strings.NewReader(ics)); err != nil {
+		t.Fatalf("LoadICS failed: %v", err)
+	}
+
+	loc := schedule.Location
+	tests := []struct {
+		at       time.Time
+		expected ScheduleState
+	}{
+		{time.Date(2025, 12, 22, 10, 0, 0, 0, loc), ScheduleOpen},
+		{time.Date(2025, 12, 22, 17, 0, 0, 0, loc), ScheduleClosed},
+		{time.Date(2025, 12, 22, 15, 0, 0, 0, time.UTC), ScheduleOpen},
+		{time.Date(2025, 12, 25, 10, 0, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2025, 12, 26, 10, 0, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2025, 12, 20, 23, 0, 0, 0, loc), ScheduleOpen},
+		{time.Date(2025, 12, 21, 1, 0, 0, 0, loc), ScheduleOpen},
+		{time.Date(2025, 12, 21, 3, 0, 0, 0, loc), ScheduleClosed},
+	}
+	for _, test := range tests {
+		if state := schedule.State(test.at); state != test.expected {
+			t.Errorf("State(%s) = %s, expected %s", test.at, state, test.expected)
+		}
+	}
+
+	next, ok := schedule.NextOpen(time.Date(2025, 12, 24, 18, 0, 0, 0, loc))
+	expected := time.Date(2025, 12, 27, 22, 0, 0, 0, loc)
+	if !ok || !next.Equal(expected) {
+		t.Errorf("Expected next opening at %s, got %s", expected, next)
+	}
+}
+
+func TestLoadICSTimedEvents(t *testing.T) {
+	schedule, _ := NewSchedule("America/New_York")
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	schedule.AddHours(weekdays, "09:00", "17:00")
+
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;TZID=America/Los_Angeles:20260105T090000\r\n" +
+		"DTEND;TZID=America/Los_Angeles:20260105T100000\r\nSUMMARY:All hands\r\n  meeting\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20260106T140000Z\r\nDTEND:20260106T150000Z\r\nSUMMARY:Fire drill\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if err := schedule.LoadICS(strings.NewReader(ics)); err != nil {
+		t.Fatalf("LoadICS failed: %v", err)
+	}
+
+	loc := schedule.Location
+	tests := []struct {
+		at       time.Time
+		expected ScheduleState
+	}{
+		{time.Date(2026, 1, 5, 11, 0, 0, 0, loc), ScheduleOpen},
+		{time.Date(2026, 1, 5, 12, 30, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 5, 13, 0, 0, 0, loc), ScheduleOpen},
+		{time.Date(2026, 1, 6, 9, 30, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 6, 10, 0, 0, 0, loc), ScheduleOpen},
+	}
+	for _, test := range tests {
+		if state := schedule.State(test.at); state != test.expected {
+			t.Errorf("State(%s) = %s, expected %s", test.at, state, test.expected)
+		}
+	}
+	if holiday, _ := schedule.IsHoliday(time.Date(2026, 1, 5, 12, 30, 0, 0, loc)); holiday.Name != "All hands meeting" {
+		t.Errorf("Expected the unfolded summary, got %q", holiday.Name)
+	}
+}
+
+func TestLoadICSRecurringEvents(t *testing.T) {
+	schedule, _ := NewSchedule("Europe/Paris")
+	weekdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	schedule.AddHours(weekdays, "09:00", "17:00")
+
+	ics := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20201225\r\nRRULE:FREQ=YEARLY\r\nSUMMARY:Noël\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;TZID=Europe/Paris:20260105T100000\r\nDTEND;TZID=Europe/Paris:20260105T103000\r\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4\r\nEXDATE;TZID=Europe/Paris:20260107T100000\r\nSUMMARY:Stand-up\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if err := schedule.LoadICS(strings.NewReader(ics)); err != nil {
+		t.Fatalf("LoadICS failed: %v", err)
+	}
+
+	loc := schedule.Location
+	tests := []struct {
+		at       time.Time
+		expected ScheduleState
+	}{
+		{time.Date(2026, 12, 25, 10, 0, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 5, 10, 15, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 7, 10, 15, 0, 0, loc), ScheduleOpen},
+		{time.Date(2026, 1, 12, 10, 15, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 14, 10, 15, 0, 0, loc), ScheduleHoliday},
+		{time.Date(2026, 1, 19, 10, 15, 0, 0, loc), ScheduleOpen},
+	}
+	for _, test := range tests {
+		if state := schedule.State(test.at); state != test.expected {
+			t.Errorf("State(%s) = %s, expected %s", test.at, state, test.expected)
+		}
+	}
+
+	next, ok := schedule.NextOpen(time.Date(2026, 1, 12, 10, 0, 0, 0, loc))
+	if expected := time.Date(2026, 1, 12, 10, 30, 0, 0, loc); !ok || !next.Equal(expected) {
+		t.Errorf("Expected next opening at the end of the closure %s, got %s", expected, next)
+	}
+
+	// Rules the schedule cannot expand are refused rather than ignored
+	thanksgiving := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20261126\r\n" +
+		"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH\r\nSUMMARY:Thanksgiving\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if err := schedule.LoadICS(strings.NewReader(thanksgiving)); err == nil {
+		t.Error("Expected an error for an unsupported RRULE")
+	}
+}
+
+func TestLoadHolidayList(t *testing.T) {
+	schedule, _ := NewSchedule("UTC")
+	list := "# public holidays\n2026-01-01 New Year's Day\n\n2026-07-04 Independence Day\n"
+	if err := schedule.LoadHolidayList(strings.NewReader(list)); err != nil {
+		t.Fatalf("LoadHolidayList failed: %v", err)
+	}
+
+	holiday, ok := schedule.IsHoliday(time.Date(2026, 7, 4, 12, 0, 0, 0, time.UTC))
+	if !ok || holiday.Name != "Independence Day" {
+		t.Errorf("Expected Independence Day, got %+v", holiday)
+	}
+
+	if err := schedule.LoadHolidayList(strings.NewReader("not-a-date Holiday\n")); err == nil {
+		t.Error("Expected error for malformed holiday line")
+	}
+}
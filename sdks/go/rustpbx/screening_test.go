@@ -0,0 +1,103 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAttestationScore(t *testing.T) {
+	if AttestationScore("A") >= AttestationScore("C") {
+		t.Error("Expected full attestation to score lower than gateway attestation")
+	}
+	if AttestationScore("") != 0.5 {
+		t.Errorf("Expected unsigned calls to score 0.5, got %f", AttestationScore(""))
+	}
+}
+
+func TestScreeningRejectsSpam(t *testing.T) {
+	server, commands := newTestServer(t, func(conn *websocket.Conn) {
+		// Wait for the client to install its handler
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100", Attestation: "C"})
+	})
+
+	var signal *SpamSignal
+	client := NewClient(server.URL)
+	events := make(chan *Event, 4)
+	conn, err := client.ConnectSIP(context.Background(), &ConnectionOptions{
+		Screening: &ScreeningPolicy{
+			Scorer: func(ctx context.Context, s *SpamSignal) (float64, error) {
+				signal = s
+				return 0.95, nil
+			},
+			Action: ScreeningReject,
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case cmd := <-commands:
+		if cmd["command"] != "reject" || cmd["code"] != float64(603) {
+			t.Errorf("Expected reject with code 603, got %v", cmd)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected reject command")
+	}
+
+	event := <-events
+	if event.Event != "screened" {
+		t.Errorf("Expected 'screened' event instead of the incoming call, got '%s'", event.Event)
+	}
+	if signal == nil || signal.Attestation != "C" {
+		t.Errorf("Expected scorer to receive attestation 'C', got %+v", signal)
+	}
+}
+
+func TestScreeningDoesNotBlockEvents(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(Event{Event: "incoming", Caller: "+15550100"})
+		conn.WriteJSON(Event{Event: "dtmf", Digit: "1"})
+	})
+
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), &ConnectionOptions{
+		Screening: &ScreeningPolicy{
+			// A reputation lookup that never answers is abandoned at the timeout
+			Scorer: func(ctx context.Context, s *SpamSignal) (float64, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+			Timeout: 100 * time.Millisecond,
+			Action:  ScreeningReject,
+		},
+	})
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+
+	events := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) { events <- event })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	for _, expected := range []string{"dtmf", "error", "incoming"} {
+		select {
+		case event := <-events:
+			if event.Event != expected {
+				t.Fatalf("Expected %s, got %s", expected, event.Event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s", expected)
+		}
+	}
+}
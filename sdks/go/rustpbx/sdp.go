@@ -0,0 +1,211 @@
+package rustpbx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SDPError reports a problem of a session description, at a line counted from 1, or 0
+// when it concerns the description as a whole
+type SDPError struct {
+	Line   int
+	Reason string
+}
+
+func (e *SDPError) Error() string {
+	if e.Line == 0 {
+		return e.Reason
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// sdpMedia is a media section being checked
+type sdpMedia struct {
+	line       int
+	media      string
+	rtp        bool
+	formats    map[string]bool
+	connection bool
+	rtpmaps    map[string]bool
+}
+
+// ValidateSDP checks the structure of a session description (RFC 4566) before it is sent
+// as an offer or answer: the line syntax, the required session lines, the media lines
+// with their codec lines and the connection addresses. The server fails malformed ones
+// without saying why. It returns the problems joined, or nil.
+func ValidateSDP(sdp string) error {
+	var errs []error
+	fail := func(line int, format string, args ...interface{}) {
+		errs = append(errs, &SDPError{Line: line, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	lines := strings.Split(strings.ReplaceAll(sdp, "\r\n", "\n"), "\n")
+	// Trailing line breaks are fine
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return &SDPError{Reason: "empty session description"}
+	}
+
+	session := map[byte]bool{}
+	sessionConnection := false
+	var medias []*sdpMedia
+	var current *sdpMedia
+	for i, line := range lines {
+		number := i + 1
+		if line == "" {
+			fail(number, "empty line")
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			fail(number, "starts with whitespace; indented SDP, e.g. in a raw string literal, must be unindented")
+			continue
+		}
+		if len(line) < 2 || line[1] != '=' || line[0] < 'a' || line[0] > 'z' {
+			fail(number, "expected <type>=<value> with a lowercase type letter, got %q", truncateSDPLine(line))
+			continue
+		}
+		typ, value := line[0], line[2:]
+		if i == 0 && (typ != 'v' || value != "0") {
+			fail(number, "must be v=0, got %q", truncateSDPLine(line))
+		}
+		if current == nil && typ != 'm' {
+			session[typ] = true
+		}
+
+		switch typ {
+		case 'o':
+			if len(strings.Fields(value)) != 6 {
+				fail(number, "o= needs 6 fields: <username> <sess-id> <sess-version> IN IP4|IP6 <address>")
+			}
+		case 'c':
+			if err := checkSDPConnection(value); err != "" {
+				fail(number, "%s", err)
+			}
+			if current == nil {
+				sessionConnection = true
+			} else {
+				current.connection = true
+			}
+		case 'm':
+			fields := strings.Fields(value)
+			if len(fields) < 4 {
+				fail(number, "m= needs <media> <port> <proto> <fmt>..., got %q", truncateSDPLine(line))
+				current = &sdpMedia{line: number}
+				medias = append(medias, current)
+				continue
+			}
+			current = &sdpMedia{
+				line:    number,
+				media:   fields[0],
+				rtp:     strings.Contains(fields[2], "RTP/"),
+				formats: map[string]bool{},
+				rtpmaps: map[string]bool{},
+			}
+			medias = append(medias, current)
+			port, _, _ := strings.Cut(fields[1], "/")
+			if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+				fail(number, "invalid port %q", fields[1])
+			}
+			for _, format := range fields[3:] {
+				if n, err := strconv.Atoi(format); current.rtp && (err != nil || n < 0 || n > 127) {
+					fail(number, "invalid RTP payload type %q", format)
+				}
+				current.formats[format] = true
+			}
+		case 'a':
+			name, attr, _ := strings.Cut(value, ":")
+			if name != "rtpmap" && name != "fmtp" {
+				continue
+			}
+			if current == nil {
+				fail(number, "a=%s must be in a media section", name)
+				continue
+			}
+			payload, rest, _ := strings.Cut(attr, " ")
+			if current.formats != nil && !current.formats[payload] {
+				fail(number, "a=%s for payload type %q missing from the m= line at line %d", name, payload, current.line)
+			}
+			if name == "rtpmap" {
+				current.rtpmaps[payload] = true
+				if err := checkRTPMap(rest); err != "" {
+					fail(number, "%s", err)
+				}
+			}
+		}
+	}
+
+	for _, typ := range []byte{'o', 's', 't'} {
+		if !session[typ] {
+			fail(0, "missing %c= line before the first m= line", typ)
+		}
+	}
+	audio := false
+	for _, m := range medias {
+		if m.media == "audio" {
+			audio = true
+		}
+		if !m.connection && !sessionConnection && !strings.Contains(m.media, "application") {
+			fail(m.line, "%s media has no c= line, neither in its section nor at session level", m.media)
+		}
+		if !m.rtp {
+			continue
+		}
+		for format := range m.formats {
+			// Dynamic payload types have no static codec
+			if n, err := strconv.Atoi(format); err == nil && n >= 96 && !m.rtpmaps[format] {
+				fail(m.line, "dynamic payload type %s has no a=rtpmap line", format)
+			}
+		}
+	}
+	if !audio {
+		fail(0, "no m=audio section")
+	}
+	return errors.Join(errs...)
+}
+
+// checkSDPConnection checks the value of a c= line: IN IP4|IP6 <address>[/ttl]
+func checkSDPConnection(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) != 3 || fields[0] != "IN" || (fields[1] != "IP4" && fields[1] != "IP6") {
+		return fmt.Sprintf("c= must be IN IP4|IP6 <address>, got %q", value)
+	}
+	address, _, _ := strings.Cut(fields[2], "/")
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		// A domain name is allowed, but must not look like a broken address
+		if strings.ContainsAny(address, ":") || strings.Trim(address, "0123456789.") == "" {
+			return fmt.Sprintf("invalid connection address %q", address)
+		}
+	case fields[1] == "IP4" && ip.To4() == nil:
+		return fmt.Sprintf("connection address %s is not IPv4 but declared IP4", address)
+	case fields[1] == "IP6" && ip.To4() != nil:
+		return fmt.Sprintf("connection address %s is not IPv6 but declared IP6", address)
+	}
+	return ""
+}
+
+// checkRTPMap checks the codec of an a=rtpmap line: <encoding>/<clock rate>[/<channels>]
+func checkRTPMap(codec string) string {
+	parts := strings.Split(codec, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return fmt.Sprintf("a=rtpmap needs <payload type> <encoding>/<clock rate>, got %q", codec)
+	}
+	if rate, err := strconv.Atoi(parts[1]); err != nil || rate <= 0 {
+		return fmt.Sprintf("invalid clock rate %q in a=rtpmap", parts[1])
+	}
+	return ""
+}
+
+// truncateSDPLine shortens a line quoted in an error
+func truncateSDPLine(line string) string {
+	if len(line) > 40 {
+		return line[:40] + "..."
+	}
+	return line
+}
@@ -0,0 +1,66 @@
+package rustpbx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const validOffer = `v=0
+o=- 123456789 123456789 IN IP4 192.168.1.100
+s=-
+c=IN IP4 192.168.1.100
+t=0 0
+m=audio 54400 RTP/AVP 0 111
+a=rtpmap:0 PCMU/8000
+a=rtpmap:111 opus/48000/2
+a=fmtp:111 minptime=10
+a=sendrecv`
+
+func TestValidateSDP(t *testing.T) {
+	if err := ValidateSDP(validOffer); err != nil {
+		t.Errorf("Expected a valid offer, got %v", err)
+	}
+	if err := ValidateSDP(strings.ReplaceAll(validOffer, "\n", "\r\n") + "\r\n"); err != nil {
+		t.Errorf("Expected a valid CRLF offer, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sdp  string
+		want string
+	}{
+		{"empty", "", "empty session description"},
+		{"indented", strings.ReplaceAll(validOffer, "\ns=", "\n\ts="), "line 3: starts with whitespace"},
+		{"version", strings.Replace(validOffer, "v=0", "v=1", 1), "line 1: must be v=0"},
+		{"syntax", strings.Replace(validOffer, "s=-", "s -", 1), "line 3: expected <type>=<value>"},
+		{"missing time", strings.Replace(validOffer, "t=0 0\n", "", 1), "missing t= line"},
+		{"origin", strings.Replace(validOffer, "o=- 123456789 123456789 IN IP4", "o=- 1 IN IP4", 1), "line 2: o= needs 6 fields"},
+		{"connection", strings.Replace(validOffer, "c=IN IP4 192.168.1.100", "c=IN IP4 192.168.1.300", 1), "line 4: invalid connection address"},
+		{"family", strings.Replace(validOffer, "c=IN IP4 192.168.1.100", "c=IN IP6 192.168.1.100", 1), "not IPv6 but declared IP6"},
+		{"no connection", strings.Replace(validOffer, "c=IN IP4 192.168.1.100\n", "", 1), "line 5: audio media has no c= line"},
+		{"port", strings.Replace(validOffer, "54400", "70000", 1), `invalid port "70000"`},
+		{"payload", strings.Replace(validOffer, "RTP/AVP 0 111", "RTP/AVP 0 111 PCMU", 1), `invalid RTP payload type "PCMU"`},
+		{"rtpmap", strings.Replace(validOffer, "a=rtpmap:111 opus/48000/2\n", "", 1), "dynamic payload type 111 has no a=rtpmap line"},
+		{"unlisted", strings.Replace(validOffer, "a=rtpmap:0 PCMU/8000", "a=rtpmap:8 PCMA/8000", 1), `payload type "8" missing from the m= line at line 6`},
+		{"clock", strings.Replace(validOffer, "PCMU/8000", "PCMU/8k", 1), `invalid clock rate "8k"`},
+		{"no audio", strings.Replace(validOffer, "m=audio", "m=video", 1), "no m=audio section"},
+	}
+	for _, test := range tests {
+		err := ValidateSDP(test.sdp)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.want, err)
+		}
+	}
+}
+
+func TestCallOptionOfferValidation(t *testing.T) {
+	err := (&CallOption{Offer: strings.Replace(validOffer, "s=-", "s -", 1)}).Validate()
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "offer" || !strings.HasPrefix(fieldErr.Reason, "line 3:") {
+		t.Errorf("Expected an offer field error at line 3, got %v", err)
+	}
+	if err := (&CallOption{Offer: validOffer}).Validate(); err != nil {
+		t.Errorf("Expected a valid offer, got %v", err)
+	}
+}
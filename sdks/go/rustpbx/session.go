@@ -0,0 +1,26 @@
+package rustpbx
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxSessionIDLength is the longest session ID accepted by ValidateSessionID
+const maxSessionIDLength = 128
+
+// The server uses session IDs in file names, so only a safe character set is allowed
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidateSessionID checks that a session ID is safe to send to the server
+func ValidateSessionID(sessionID string) error {
+	if sessionID == "" {
+		return fmt.Errorf("session ID is empty")
+	}
+	if len(sessionID) > maxSessionIDLength {
+		return fmt.Errorf("session ID exceeds %d characters", maxSessionIDLength)
+	}
+	if !sessionIDPattern.MatchString(sessionID) {
+		return fmt.Errorf("session ID %q contains invalid characters", sessionID)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package rustpbx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSessionID(t *testing.T) {
+	tests := []struct {
+		sessionID string
+		valid     bool
+	}{
+		{"test-session", true},
+		{"a1b2.c3_d4", true},
+		{"", false},
+		{"../etc/passwd", false},
+		{"has space", false},
+		{strings.Repeat("a", maxSessionIDLength+1), false},
+	}
+
+	for _, test := range tests {
+		err := ValidateSessionID(test.sessionID)
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateSessionID(%q) = %v, expected valid=%t", test.sessionID, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestSigning(t *testing.T) {
+	signer := &RequestSigner{Secret: []byte("shared"), Header: "X-PBX-Signature"}
+	var verified int
+	server := httptest.NewServer(signer.VerifyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified++
+		w.Write([]byte(`{"calls":[]}`))
+	})))
+	defer server.Close()
+
+	client := NewClientWithOptions(server.URL, ClientOptions{Signer: signer})
+	if _, err := client.GetActiveCalls(context.Background()); err != nil {
+		t.Fatalf("Expected the signed request to be accepted, got %v", err)
+	}
+	if _, err := NewClient(server.URL).GetActiveCalls(context.Background()); err == nil {
+		t.Error("Expected an unsigned request to be rejected")
+	}
+	if verified != 1 {
+		t.Errorf("Expected one verified request, got %d", verified)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	sender := &RequestSigner{Secret: []byte("new")}
+	sign := func(body string) *http.Request {
+		req := httptest.NewRequest("POST", "/hooks/call?tenant=acme", strings.NewReader(body))
+		if err := sender.Sign(req); err != nil {
+			t.Fatalf("Sign failed: %v", err)
+		}
+		return req
+	}
+
+	receiver := &RequestSigner{Secret: []byte("other"), PreviousSecrets: [][]byte{[]byte("new")}}
+	req := sign(`{"event":"hangup"}`)
+	body, err := receiver.Verify(req)
+	if err != nil || string(body) != `{"event":"hangup"}` {
+		t.Fatalf("Expected the previous secret to verify, got %q, %v", body, err)
+	}
+	if again, _ := io.ReadAll(req.Body); string(again) != string(body) {
+		t.Errorf("Expected the body to stay readable, got %q", again)
+	}
+
+	tampered := sign(`{"event":"hangup"}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"event":"answer"}`))
+	if _, err := receiver.Verify(tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a tampered body to be rejected, got %v", err)
+	}
+
+	moved := sign(`{}`)
+	moved.URL.Path = "/hooks/admin"
+	if _, err := receiver.Verify(moved); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a replay to another path to be rejected, got %v", err)
+	}
+
+	stale := sign(`{}`)
+	stale.Header.Set("X-Signature", strings.Replace(stale.Header.Get("X-Signature"), "t=", "t=1", 1))
+	if _, err := receiver.Verify(stale); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a timestamp outside the tolerance to be rejected, got %v", err)
+	}
+
+	receiver.PreviousSecrets = nil
+	if _, err := receiver.Verify(sign(`{}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected another secret to be rejected, got %v", err)
+	}
+	receiver.Tolerance = time.Minute
+	if _, err := receiver.Verify(httptest.NewRequest("POST", "/", nil)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a missing signature to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,491 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlotType is the kind of value a slot holds
+type SlotType string
+
+const (
+	// SlotNumber values are int64
+	SlotNumber SlotType = "number"
+	// SlotDate values are time.Time at midnight
+	SlotDate SlotType = "date"
+	// SlotTime values are time.Time on the current day
+	SlotTime SlotType = "time"
+	// SlotDateTime values are time.Time
+	SlotDateTime SlotType = "datetime"
+)
+
+// Slot represents a structured value extracted from an utterance
+type Slot struct {
+	Name  string
+	Type  SlotType
+	Value interface{}
+	// Text is the part of the utterance the value was read from
+	Text       string
+	Confidence float64
+}
+
+// Time returns the value of a date or time slot
+func (s *Slot) Time() (time.Time, bool) {
+	t, ok := s.Value.(time.Time)
+	return t, ok
+}
+
+// Int returns the value of a number slot
+func (s *Slot) Int() (int64, bool) {
+	n, ok := s.Value.(int64)
+	return n, ok
+}
+
+// SlotExtractor turns an utterance into structured values
+type SlotExtractor interface {
+	Extract(ctx context.Context, utterance string) ([]Slot, error)
+}
+
+// GrammarExtractor extracts English dates, times and numbers with rules, such as
+// "next Tuesday at 3", "March 3rd", "in two weeks" or "forty two"
+type GrammarExtractor struct {
+	// Now returns the reference time for relative dates; time.Now when nil
+	Now func() time.Time
+}
+
+var (
+	grammarTimeRe     = regexp.MustCompile(`\b(at )?(\d{1,2})(?::(\d{2})| ([0-5]\d))?(?: ?(am|pm)| (o'?clock))?\b|\b(noon|midday|midnight)\b`)
+	grammarRelativeRe = regexp.MustCompile(`\b(day after tomorrow|today|tomorrow|tonight)\b`)
+	grammarWeekdayRe  = regexp.MustCompile(`\b(?:(this|next) )?(sunday|monday|tuesday|wednesday|thursday|friday|saturday)\b`)
+	grammarInRe       = regexp.MustCompile(`\bin (\d+) (days?|weeks?)\b`)
+	grammarMonthDayRe = regexp.MustCompile(`\b(january|february|march|april|may|june|july|august|september|october|november|december) (?:the )?(\d{1,2})(?:st|nd|rd|th)?\b`)
+	grammarDayMonthRe = regexp.MustCompile(`\b(?:the )?(\d{1,2})(?:st|nd|rd|th)(?: of (january|february|march|april|may|june|july|august|september|october|november|december))?\b`)
+	grammarNumberRe   = regexp.MustCompile(`\b\d+\b`)
+)
+
+var grammarMonths = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+	"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+	"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+}
+
+var grammarWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// Extract implements SlotExtractor. A date and a time in the same utterance are
+// combined into a single datetime slot.
+func (g *GrammarExtractor) Extract(ctx context.Context, utterance string) ([]Slot, error) {
+	now := time.Now()
+	if g.Now != nil {
+		now = g.Now()
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	text := normalizeSpokenNumbers(utterance)
+
+	var used [][]int
+	free := func(loc []int) bool {
+		for _, span := range used {
+			if loc[0] < span[1] && span[0] < loc[1] {
+				return false
+			}
+		}
+		return true
+	}
+
+	date, dateText, dateConfidence := g.extractDate(text, today, &used)
+
+	var clock *Slot
+	for _, m := range grammarTimeRe.FindAllStringSubmatchIndex(text, -1) {
+		if !free(m[:2]) {
+			continue
+		}
+		slot, ok := parseGrammarTime(text, m, today)
+		if !ok {
+			continue
+		}
+		if dateText == "tonight" {
+			if t, _ := slot.Time(); t.Hour() < 12 {
+				slot.Value = t.Add(12 * time.Hour)
+			}
+		}
+		used = append(used, m[:2])
+		clock = &slot
+		break
+	}
+
+	var slots []Slot
+	switch {
+	case date != nil && clock != nil:
+		t, _ := clock.Time()
+		slots = append(slots, Slot{
+			Name:       string(SlotDateTime),
+			Type:       SlotDateTime,
+			Value:      time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()),
+			Text:       dateText + " " + clock.Text,
+			Confidence: dateConfidence * clock.Confidence,
+		})
+	case date != nil:
+		slots = append(slots, Slot{Name: string(SlotDate), Type: SlotDate, Value: *date, Text: dateText, Confidence: dateConfidence})
+	case clock != nil:
+		slots = append(slots, *clock)
+	}
+
+	for _, loc := range grammarNumberRe.FindAllStringIndex(text, -1) {
+		if !free(loc) {
+			continue
+		}
+		n, err := strconv.ParseInt(text[loc[0]:loc[1]], 10, 64)
+		if err != nil {
+			continue
+		}
+		slots = append(slots, Slot{Name: string(SlotNumber), Type: SlotNumber, Value: n, Text: text[loc[0]:loc[1]], Confidence: 0.9})
+	}
+	return slots, nil
+}
+
+// extractDate finds the first date expression in normalized text and marks its span as used
+func (g *GrammarExtractor) extractDate(text string, today time.Time, used *[][]int) (*time.Time, string, float64) {
+	if m := grammarRelativeRe.FindStringSubmatchIndex(text); m != nil {
+		*used = append(*used, m[:2])
+		word := text[m[2]:m[3]]
+		date := today
+		switch word {
+		case "tomorrow":
+			date = today.AddDate(0, 0, 1)
+		case "day after tomorrow":
+			date = today.AddDate(0, 0, 2)
+		}
+		return &date, word, 0.95
+	}
+
+	if m := grammarWeekdayRe.FindStringSubmatchIndex(text); m != nil {
+		*used = append(*used, m[:2])
+		weekday := grammarWeekdays[text[m[4]:m[5]]]
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		confidence := 0.9
+		if days == 0 {
+			// "Tuesday" said on a Tuesday most likely means next week, unless "this Tuesday"
+			days = 7
+			if m[2] >= 0 && text[m[2]:m[3]] == "this" {
+				days = 0
+			}
+			confidence = 0.6
+		}
+		if m[2] >= 0 && text[m[2]:m[3]] == "next" {
+			// "next Tuesday" is ambiguous between the coming one and the one after
+			confidence = 0.7
+		}
+		date := today.AddDate(0, 0, days)
+		return &date, text[m[0]:m[1]], confidence
+	}
+
+	if m := grammarInRe.FindStringSubmatchIndex(text); m != nil {
+		*used = append(*used, m[:2])
+		n, _ := strconv.Atoi(text[m[2]:m[3]])
+		if strings.HasPrefix(text[m[4]:m[5]], "week") {
+			n *= 7
+		}
+		date := today.AddDate(0, 0, n)
+		return &date, text[m[0]:m[1]], 0.95
+	}
+
+	if m := grammarMonthDayRe.FindStringSubmatchIndex(text); m != nil {
+		day, _ := strconv.Atoi(text[m[4]:m[5]])
+		if date, ok := nextDate(today, grammarMonths[text[m[2]:m[3]]], day); ok {
+			*used = append(*used, m[:2])
+			return &date, text[m[0]:m[1]], 0.9
+		}
+	}
+
+	if m := grammarDayMonthRe.FindStringSubmatchIndex(text); m != nil {
+		day, _ := strconv.Atoi(text[m[2]:m[3]])
+		month, confidence := today.Month(), 0.75
+		if m[4] >= 0 {
+			month, confidence = grammarMonths[text[m[4]:m[5]]], 0.9
+		} else if day < today.Day() {
+			month = today.AddDate(0, 1, 0).Month()
+		}
+		if date, ok := nextDate(today, month, day); ok {
+			*used = append(*used, m[:2])
+			return &date, text[m[0]:m[1]], confidence
+		}
+	}
+
+	return nil, "", 0
+}
+
+// nextDate returns the first occurrence of month and day on or after today
+func nextDate(today time.Time, month time.Month, day int) (time.Time, bool) {
+	for year := today.Year(); year <= today.Year()+1; year++ {
+		date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+		if date.Day() != day {
+			// Not a valid day of the month
+			return time.Time{}, false
+		}
+		if !date.Before(today) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseGrammarTime reads a time of day from a grammarTimeRe match
+func parseGrammarTime(text string, m []int, today time.Time) (Slot, bool) {
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return text[m[2*i]:m[2*i+1]]
+	}
+
+	hour, minute, confidence := 0, 0, 0.95
+	switch group(7) {
+	case "noon", "midday":
+		hour = 12
+	case "midnight":
+		hour = 0
+	default:
+		at, colon, spoken, meridiem, oclock := group(1), group(3), group(4), group(5), group(6)
+		// A bare number is a time only with "at", minutes, am/pm or o'clock
+		if at == "" && colon == "" && meridiem == "" && oclock == "" {
+			return Slot{}, false
+		}
+		if spoken != "" && at == "" {
+			return Slot{}, false
+		}
+		hour, _ = strconv.Atoi(group(2))
+		if colon != "" {
+			minute, _ = strconv.Atoi(colon)
+		} else if spoken != "" {
+			minute, _ = strconv.Atoi(spoken)
+		}
+		if hour > 23 {
+			return Slot{}, false
+		}
+		switch {
+		case meridiem == "pm" && hour < 12:
+			hour += 12
+		case meridiem == "am" && hour == 12:
+			hour = 0
+		case meridiem == "" && hour >= 1 && hour <= 7:
+			// "at 3" most likely means the afternoon
+			hour += 12
+			confidence = 0.7
+		case meridiem == "" && hour <= 12:
+			confidence = 0.8
+		}
+	}
+
+	return Slot{
+		Name:       string(SlotTime),
+		Type:       SlotTime,
+		Value:      time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, today.Location()),
+		Text:       strings.TrimSpace(text[m[0]:m[1]]),
+		Confidence: confidence,
+	}, true
+}
+
+var spokenUnits = map[string]int64{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+	"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+	"seventeen": 17, "eighteen": 18, "nineteen": 19,
+}
+
+var spokenTens = map[string]int64{
+	"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50, "sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+var spokenOrdinals = map[string]int64{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6, "seventh": 7, "eighth": 8, "ninth": 9,
+	"tenth": 10, "eleventh": 11, "twelfth": 12, "thirteenth": 13, "fourteenth": 14, "fifteenth": 15, "sixteenth": 16,
+	"seventeenth": 17, "eighteenth": 18, "nineteenth": 19, "twentieth": 20, "thirtieth": 30,
+}
+
+// normalizeSpokenNumbers lower-cases an utterance and writes spelled-out numbers and
+// ordinals as digits, so "twenty third at three thirty" becomes "23rd at 3 30"
+func normalizeSpokenNumbers(utterance string) string {
+	text := strings.ToLower(utterance)
+	text = strings.NewReplacer("a.m.", "am", "p.m.", "pm", "-", " ", ",", " ", "?", " ", "!", " ").Replace(text)
+	text = strings.TrimRight(strings.TrimSpace(text), ".")
+
+	var out []string
+	var current, part int64
+	active := false
+	flush := func() {
+		if active {
+			out = append(out, strconv.FormatInt(current+part, 10))
+		}
+		current, part, active = 0, 0, false
+	}
+
+	for _, word := range strings.Fields(text) {
+		word = strings.TrimRight(word, ".")
+		if n, ok := spokenOrdinals[word]; ok {
+			// "twenty third": an ordinal ends the number
+			if active && part >= 20 && part%10 == 0 && n < 10 {
+				n += part
+				part = 0
+			} else {
+				flush()
+			}
+			n += current
+			current, active = 0, false
+			out = append(out, strconv.FormatInt(n, 10)+ordinalSuffix(n))
+			continue
+		}
+		if n, ok := spokenUnits[word]; ok {
+			switch {
+			case active && part == 0:
+				part = n
+			case active && n < 10 && part >= 20 && part%10 == 0:
+				part += n
+			default:
+				flush()
+				part, active = n, true
+			}
+			continue
+		}
+		if n, ok := spokenTens[word]; ok {
+			if !active || part != 0 {
+				flush()
+			}
+			part, active = n, true
+			continue
+		}
+		switch {
+		case word == "hundred" && active:
+			if part == 0 {
+				part = 1
+			}
+			current += part * 100
+			part = 0
+			continue
+		case word == "thousand" && active:
+			if current+part == 0 {
+				part = 1
+			}
+			current = (current + part) * 1000
+			part = 0
+			continue
+		case word == "and" && active && part == 0 && current > 0:
+			// "one hundred and five"
+			continue
+		}
+		flush()
+		out = append(out, word)
+	}
+	flush()
+	return strings.Join(out, " ")
+}
+
+// ordinalSuffix returns the English ordinal suffix of a number
+func ordinalSuffix(n int64) string {
+	s := englishOrdinal(int(n))
+	return strings.TrimLeft(s, "0123456789")
+}
+
+// SlotSpec describes a slot for the LLM extractor
+type SlotSpec struct {
+	Name        string
+	Type        SlotType
+	Description string
+}
+
+// slotExtractionPrompt asks the LLM to fill slots from an utterance
+const slotExtractionPrompt = `Extract the following values from the caller's utterance. The current time is %s.
+Reply with JSON only, in the form {"slots":[{"name":"...","value":"...","confidence":0.9}]}, leaving out values that were not mentioned.
+Numbers are written as digits, dates as YYYY-MM-DD, times as HH:MM (24 hours) and datetimes as YYYY-MM-DDTHH:MM.
+
+Values:
+%s`
+
+// LLMSlotExtractor extracts slots with an LLM, for values a grammar cannot capture
+type LLMSlotExtractor struct {
+	LLM   LLM
+	Slots []SlotSpec
+	// Now returns the reference time for relative dates; time.Now when nil
+	Now func() time.Time
+}
+
+// Extract implements SlotExtractor
+func (e *LLMSlotExtractor) Extract(ctx context.Context, utterance string) ([]Slot, error) {
+	now := time.Now()
+	if e.Now != nil {
+		now = e.Now()
+	}
+
+	var specs strings.Builder
+	types := make(map[string]SlotType, len(e.Slots))
+	for _, spec := range e.Slots {
+		fmt.Fprintf(&specs, "- %s (%s): %s\n", spec.Name, spec.Type, spec.Description)
+		types[spec.Name] = spec.Type
+	}
+
+	reply, err := e.LLM.Complete(ctx, []ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(slotExtractionPrompt, now.Format("Monday 2006-01-02 15:04"), specs.String())},
+		{Role: "user", Content: utterance},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract slots: %w", err)
+	}
+
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("failed to parse slots: no JSON in reply")
+	}
+	var extraction struct {
+		Slots []struct {
+			Name       string          `json:"name"`
+			Value      json.RawMessage `json:"value"`
+			Confidence float64         `json:"confidence"`
+		} `json:"slots"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &extraction); err != nil {
+		return nil, fmt.Errorf("failed to parse slots: %w", err)
+	}
+
+	var slots []Slot
+	for _, s := range extraction.Slots {
+		slotType, ok := types[s.Name]
+		if !ok {
+			continue
+		}
+		var raw string
+		if err := json.Unmarshal(s.Value, &raw); err != nil {
+			// Numbers may come unquoted
+			raw = string(s.Value)
+		}
+		value, err := parseSlotValue(slotType, raw, now)
+		if err != nil {
+			continue
+		}
+		slots = append(slots, Slot{Name: s.Name, Type: slotType, Value: value, Text: raw, Confidence: s.Confidence})
+	}
+	return slots, nil
+}
+
+// parseSlotValue converts an LLM slot value to the Go type of the slot
+func parseSlotValue(slotType SlotType, raw string, now time.Time) (interface{}, error) {
+	switch slotType {
+	case SlotNumber:
+		return strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	case SlotDate:
+		return time.ParseInLocation("2006-01-02", raw, now.Location())
+	case SlotTime:
+		t, err := time.Parse("15:04", raw)
+		if err != nil {
+			return nil, err
+		}
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location()), nil
+	case SlotDateTime:
+		return time.ParseInLocation("2006-01-02T15:04", raw, now.Location())
+	}
+	return raw, nil
+}
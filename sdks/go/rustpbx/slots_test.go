@@ -0,0 +1,91 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSpokenNumbers(t *testing.T) {
+	tests := map[string]string{
+		"Twenty-three people":                 "23 people",
+		"one hundred and five":                "105",
+		"on the twenty first at three thirty": "on the 21st at 3 30",
+		"two thousand twenty six":             "2026",
+		"table for two, at 7 p.m.":            "table for 2 at 7 pm",
+	}
+	for input, expected := range tests {
+		if text := normalizeSpokenNumbers(input); text != expected {
+			t.Errorf("normalizeSpokenNumbers(%q) = %q, expected %q", input, text, expected)
+		}
+	}
+}
+
+func TestGrammarExtractor(t *testing.T) {
+	// Monday, March 2nd 2026
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	extractor := &GrammarExtractor{Now: func() time.Time { return now }}
+
+	tests := []struct {
+		utterance string
+		slotType  SlotType
+		expected  time.Time
+	}{
+		{"next Tuesday at 3", SlotDateTime, time.Date(2026, 3, 3, 15, 0, 0, 0, time.UTC)},
+		{"tomorrow at ten thirty am", SlotDateTime, time.Date(2026, 3, 3, 10, 30, 0, 0, time.UTC)},
+		{"March the 1st", SlotDate, time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"how about the fifth of April", SlotDate, time.Date(2026, 4, 5, 0, 0, 0, 0, time.UTC)},
+		{"in two weeks", SlotDate, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"tonight at 8", SlotDateTime, time.Date(2026, 3, 2, 20, 0, 0, 0, time.UTC)},
+		{"around noon", SlotTime, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		slots, err := extractor.Extract(context.Background(), test.utterance)
+		if err != nil {
+			t.Fatalf("Extract(%q) failed: %v", test.utterance, err)
+		}
+		if len(slots) == 0 || slots[0].Type != test.slotType {
+			t.Errorf("Extract(%q): expected %s slot, got %+v", test.utterance, test.slotType, slots)
+			continue
+		}
+		if value, _ := slots[0].Time(); !value.Equal(test.expected) {
+			t.Errorf("Extract(%q): expected %s, got %s", test.utterance, test.expected, value)
+		}
+	}
+
+	slots, _ := extractor.Extract(context.Background(), "a table for four people on Friday")
+	if len(slots) != 2 || slots[1].Type != SlotNumber {
+		t.Fatalf("Expected date and number slots, got %+v", slots)
+	}
+	if n, _ := slots[1].Int(); n != 4 {
+		t.Errorf("Expected party size 4, got %d", n)
+	}
+}
+
+func TestLLMSlotExtractor(t *testing.T) {
+	extractor := &LLMSlotExtractor{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			return `{"slots":[{"name":"party","value":6,"confidence":0.9},{"name":"arrival","value":"2026-03-06T19:30","confidence":0.8},{"name":"other","value":"x"}]}`, nil
+		}),
+		Slots: []SlotSpec{
+			{Name: "party", Type: SlotNumber, Description: "number of guests"},
+			{Name: "arrival", Type: SlotDateTime, Description: "when the guests arrive"},
+		},
+		Now: func() time.Time { return time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC) },
+	}
+
+	slots, err := extractor.Extract(context.Background(), "six of us on Friday around half seven")
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(slots) != 2 {
+		t.Fatalf("Expected the two requested slots, got %+v", slots)
+	}
+	if n, _ := slots[0].Int(); n != 6 {
+		t.Errorf("Expected 6 guests, got %v", slots[0].Value)
+	}
+	if arrival, _ := slots[1].Time(); !arrival.Equal(time.Date(2026, 3, 6, 19, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected arrival: %v", arrival)
+	}
+}
@@ -0,0 +1,86 @@
+package rustpbx
+
+// ConnectionState is the state of the WebSocket connection of a Connection
+type ConnectionState string
+
+const (
+	// ConnectionConnecting connections are dialing the server
+	ConnectionConnecting ConnectionState = "connecting"
+	// ConnectionConnected connections exchange commands and events
+	ConnectionConnected ConnectionState = "connected"
+	// ConnectionReconnecting connections dropped and are re-dialing the session, see ReconnectPolicy
+	ConnectionReconnecting ConnectionState = "reconnecting"
+	// ConnectionClosed connections were closed or dropped for good; it is the final state
+	ConnectionClosed ConnectionState = "closed"
+)
+
+// State returns the state of the connection, e.g. for health checks
+func (c *Connection) State() ConnectionState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.state == "" {
+		return ConnectionConnecting
+	}
+	return c.state
+}
+
+// OnStateChange sets the handler of state transitions, replacing the previous one; a nil
+// handler removes it. It is called on the goroutine making the transition, without locks held.
+func (c *Connection) OnStateChange(handler func(old, new ConnectionState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stateHandler = handler
+}
+
+// setState moves the connection to a state; a closed connection stays closed
+func (c *Connection) setState(state ConnectionState) {
+	c.mu.Lock()
+	old := c.state
+	if old == "" {
+		old = ConnectionConnecting
+	}
+	if old == state || old == ConnectionClosed {
+		c.mu.Unlock()
+		return
+	}
+	c.state = state
+	handler := c.stateHandler
+	c.mu.Unlock()
+
+	c.log().Debug("connection state changed", "from", old, "to", state)
+	if handler != nil {
+		handler(old, state)
+	}
+}
+
+// OnDisconnect sets the handler called once when the connection is gone for good, after
+// its state changed to closed, replacing the previous one. err is nil when the connection
+// was closed with Close or by the server ending the session normally; otherwise it is
+// the drop, as returned by Err: a *CloseError with the code of the server's close frame,
+// or wrapping ErrConnectionDead when the keepalive found the server silent, e.g. to fail
+// a call over to another node. Unlike "error" events it is not reported while reconnecting.
+func (c *Connection) OnDisconnect(handler func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnectHandler = handler
+}
+
+// setDisconnectErr records why the connection dropped, keeping the first error
+func (c *Connection) setDisconnectErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disconnectErr == nil {
+		c.disconnectErr = err
+	}
+}
+
+// notifyDisconnect calls the disconnect handler when the read loop ends
+func (c *Connection) notifyDisconnect() {
+	c.mu.RLock()
+	handler, err := c.disconnectHandler, c.disconnectErr
+	c.mu.RUnlock()
+	c.log().Debug("disconnected", "error", err)
+	if handler != nil {
+		handler(err)
+	}
+}
@@ -0,0 +1,137 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestConnectionState(t *testing.T) {
+	server, _, _ := reconnectServer(t, 1)
+	transitions := make(chan [2]ConnectionState, 16)
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Reconnect: &ReconnectPolicy{InitialBackoff: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	if state := conn.State(); state != ConnectionConnected {
+		t.Errorf("Expected state '%s', got '%s'", ConnectionConnected, state)
+	}
+	conn.OnStateChange(func(old, new ConnectionState) { transitions <- [2]ConnectionState{old, new} })
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	expected := [][2]ConnectionState{
+		{ConnectionConnected, ConnectionReconnecting},
+		{ConnectionReconnecting, ConnectionConnected},
+		{ConnectionConnected, ConnectionClosed},
+	}
+	for i, want := range expected {
+		if i == len(expected)-1 {
+			conn.Close()
+		}
+		select {
+		case got := <-transitions:
+			if got != want {
+				t.Errorf("Expected transition %v, got %v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected transition %v", want)
+		}
+	}
+	if state := conn.State(); state != ConnectionClosed {
+		t.Errorf("Expected state '%s', got '%s'", ConnectionClosed, state)
+	}
+	select {
+	case got := <-transitions:
+		t.Errorf("Unexpected transition %v after close", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestConnectionStateDropped(t *testing.T) {
+	server, _, _ := reconnectServer(t, 1)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+
+	select {
+	case <-conn.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the read loop to end")
+	}
+	if state := conn.State(); state != ConnectionClosed {
+		t.Errorf("Expected state '%s' after the connection dropped, got '%s'", ConnectionClosed, state)
+	}
+}
+
+func TestOnDisconnectDead(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Never read, so pings go unanswered
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		Keepalive: &KeepalivePolicy{Interval: 20 * time.Millisecond, DeadAfter: 60 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+	disconnects := make(chan error, 2)
+	conn.OnDisconnect(func(err error) { disconnects <- err })
+
+	select {
+	case err := <-disconnects:
+		if !errors.Is(err, ErrConnectionDead) {
+			t.Errorf("Expected ErrConnectionDead, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a disconnect")
+	}
+	if state := conn.State(); state != ConnectionClosed {
+		t.Errorf("Expected state '%s', got '%s'", ConnectionClosed, state)
+	}
+	conn.Close()
+	select {
+	case err := <-disconnects:
+		t.Errorf("Unexpected second disconnect: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOnDisconnectClose(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	disconnects := make(chan error, 1)
+	conn.OnDisconnect(func(err error) { disconnects <- err })
+	conn.Close()
+
+	select {
+	case err := <-disconnects:
+		if err != nil {
+			t.Errorf("Expected no error after Close, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a disconnect")
+	}
+}
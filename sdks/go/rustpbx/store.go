@@ -0,0 +1,190 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionBackend persists session values beyond the connection, e.g. in Redis, so that
+// they survive a reconnect or can be read by another instance. Values are JSON encoded.
+type SessionBackend interface {
+	// Load returns a value and whether it exists and has not expired
+	Load(ctx context.Context, sessionID, key string) ([]byte, bool, error)
+	// Save stores a value for ttl; for the backend's own retention when zero
+	Save(ctx context.Context, sessionID, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, sessionID, key string) error
+}
+
+// storeEntry is a value held in memory by a session store
+type storeEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// expired reports whether the entry has expired at now
+func (e storeEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// SessionStore holds values scoped to a call, such as collected slots or the caller's
+// authentication status, so flow nodes and handlers can share them. Values are kept in
+// memory for the call and written through to the backend when one is configured; values
+// missing from memory, e.g. after a reconnect, are read from the backend.
+type SessionStore struct {
+	ctx       context.Context
+	sessionID string
+	backend   SessionBackend
+	mu        sync.Mutex
+	entries   map[string]storeEntry
+}
+
+// Store returns the key/value store of the call
+func (c *Connection) Store() *SessionStore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.store == nil {
+		c.store = &SessionStore{
+			ctx:     c.ctx,
+			backend: c.sessionBackend,
+			entries: make(map[string]storeEntry),
+		}
+		if c.callContext != nil {
+			c.store.sessionID = c.callContext.SessionID
+		}
+	}
+	return c.store
+}
+
+// Set stores a value for ttl; for the rest of the call when zero
+func (s *SessionStore) Set(key string, value interface{}, ttl time.Duration) error {
+	entry := storeEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	if s.backend != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode session value %q: %w", key, err)
+		}
+		if err := s.backend.Save(s.ctx, s.sessionID, key, data, ttl); err != nil {
+			return fmt.Errorf("failed to save session value %q: %w", key, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+	return nil
+}
+
+// Value returns a value held in memory and whether it exists and has not expired. Use
+// GetValue to read values of a known type, including values only in the backend.
+func (s *SessionStore) Value(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expired(time.Now()) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Delete removes a value
+func (s *SessionStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+
+	if s.backend != nil {
+		if err := s.backend.Delete(s.ctx, s.sessionID, key); err != nil {
+			return fmt.Errorf("failed to delete session value %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// GetValue returns a value of the store as a T and whether it exists and has not
+// expired. Values read from the backend are decoded from JSON; a value of another type
+// held in memory is an error.
+func GetValue[T any](s *SessionStore, key string) (T, bool, error) {
+	var typed T
+	if value, ok := s.Value(key); ok {
+		typed, ok := value.(T)
+		if !ok {
+			return typed, false, fmt.Errorf("session value %q is a %T, not a %T", key, value, typed)
+		}
+		return typed, true, nil
+	}
+	if s.backend == nil {
+		return typed, false, nil
+	}
+
+	data, ok, err := s.backend.Load(s.ctx, s.sessionID, key)
+	if err != nil {
+		return typed, false, fmt.Errorf("failed to load session value %q: %w", key, err)
+	}
+	if !ok {
+		return typed, false, nil
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return typed, false, fmt.Errorf("failed to decode session value %q: %w", key, err)
+	}
+	return typed, true, nil
+}
+
+// memoryKey identifies a value of a MemorySessionBackend
+type memoryKey struct {
+	sessionID string
+	key       string
+}
+
+// MemorySessionBackend keeps session values in memory, for tests and single-process
+// deployments where values must survive reconnects
+type MemorySessionBackend struct {
+	mu     sync.Mutex
+	values map[memoryKey]storeEntry
+}
+
+// NewMemorySessionBackend creates an empty in-memory session backend
+func NewMemorySessionBackend() *MemorySessionBackend {
+	return &MemorySessionBackend{values: make(map[memoryKey]storeEntry)}
+}
+
+// Load implements SessionBackend
+func (b *MemorySessionBackend) Load(ctx context.Context, sessionID, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.values[memoryKey{sessionID, key}]
+	if !ok || entry.expired(time.Now()) {
+		delete(b.values, memoryKey{sessionID, key})
+		return nil, false, nil
+	}
+	return entry.value.([]byte), true, nil
+}
+
+// Save implements SessionBackend; values saved without a ttl are kept until deleted
+func (b *MemorySessionBackend) Save(ctx context.Context, sessionID, key string, value []byte, ttl time.Duration) error {
+	entry := storeEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[memoryKey{sessionID, key}] = entry
+	return nil
+}
+
+// Delete implements SessionBackend
+func (b *MemorySessionBackend) Delete(ctx context.Context, sessionID, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.values, memoryKey{sessionID, key})
+	return nil
+}
@@ -0,0 +1,62 @@
+package rustpbx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type authStatus struct {
+	Verified bool   `json:"verified"`
+	Method   string `json:"method"`
+}
+
+func TestSessionStore(t *testing.T) {
+	conn := &Connection{ctx: context.Background(), callContext: &CallContext{SessionID: "call-1"}}
+	store := conn.Store()
+	if conn.Store() != store {
+		t.Error("Expected one store per call")
+	}
+
+	store.Set("auth", authStatus{Verified: true, Method: "pin"}, 0)
+	store.Set("otp", "123456", 10*time.Millisecond)
+
+	auth, ok, err := GetValue[authStatus](store, "auth")
+	if err != nil || !ok || !auth.Verified {
+		t.Errorf("Expected the auth status, got %+v %v %v", auth, ok, err)
+	}
+	if _, _, err := GetValue[int](store, "auth"); err == nil {
+		t.Error("Expected an error reading a value as another type")
+	}
+	if otp, ok, _ := GetValue[string](store, "otp"); !ok || otp != "123456" {
+		t.Errorf("Expected the otp, got '%s'", otp)
+	}
+	time.Sleep(15 * time.Millisecond)
+	if _, ok, _ := GetValue[string](store, "otp"); ok {
+		t.Error("Expected the otp to expire")
+	}
+	store.Delete("auth")
+	if _, ok := store.Value("auth"); ok {
+		t.Error("Expected the value to be deleted")
+	}
+}
+
+func TestSessionStoreBackend(t *testing.T) {
+	backend := NewMemorySessionBackend()
+	first := &Connection{ctx: context.Background(), callContext: &CallContext{SessionID: "call-1"}, sessionBackend: backend}
+	if err := first.Store().Set("slots", map[string]string{"city": "Paris"}, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// A reconnected session reads the values saved before
+	second := &Connection{ctx: context.Background(), callContext: &CallContext{SessionID: "call-1"}, sessionBackend: backend}
+	slots, ok, err := GetValue[map[string]string](second.Store(), "slots")
+	if err != nil || !ok || slots["city"] != "Paris" {
+		t.Errorf("Expected the slots from the backend, got %v %v %v", slots, ok, err)
+	}
+
+	other := &Connection{ctx: context.Background(), callContext: &CallContext{SessionID: "call-2"}, sessionBackend: backend}
+	if _, ok, _ := GetValue[map[string]string](other.Store(), "slots"); ok {
+		t.Error("Expected values to be scoped to the session")
+	}
+}
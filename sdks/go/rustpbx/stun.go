@@ -0,0 +1,196 @@
+package rustpbx
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// STUN message types (RFC 5389, RFC 5766)
+const (
+	stunBindingRequest  uint16 = 0x0001
+	stunBindingSuccess  uint16 = 0x0101
+	stunAllocateRequest uint16 = 0x0003
+	stunAllocateSuccess uint16 = 0x0103
+	stunAllocateError   uint16 = 0x0113
+	stunRefreshRequest  uint16 = 0x0004
+)
+
+// STUN attribute types
+const (
+	stunAttrMappedAddress      uint16 = 0x0001
+	stunAttrUsername           uint16 = 0x0006
+	stunAttrMessageIntegrity   uint16 = 0x0008
+	stunAttrErrorCode          uint16 = 0x0009
+	stunAttrLifetime           uint16 = 0x000D
+	stunAttrRealm              uint16 = 0x0014
+	stunAttrNonce              uint16 = 0x0015
+	stunAttrXORRelayedAddress  uint16 = 0x0016
+	stunAttrRequestedTransport uint16 = 0x0019
+	stunAttrXORMappedAddress   uint16 = 0x0020
+)
+
+const (
+	stunMagicCookie = 0x2112A442
+	stunHeaderSize  = 20
+)
+
+// stunAttribute is a type-length-value attribute of a STUN message
+type stunAttribute struct {
+	typ   uint16
+	value []byte
+}
+
+// stunMessage is a STUN request or response
+type stunMessage struct {
+	typ         uint16
+	transaction [12]byte
+	attributes  []stunAttribute
+}
+
+// newSTUNRequest returns a request with a random transaction ID
+func newSTUNRequest(typ uint16) *stunMessage {
+	m := &stunMessage{typ: typ}
+	rand.Read(m.transaction[:])
+	return m
+}
+
+// add appends an attribute
+func (m *stunMessage) add(typ uint16, value []byte) {
+	m.attributes = append(m.attributes, stunAttribute{typ: typ, value: value})
+}
+
+// get returns the value of the first attribute of a type
+func (m *stunMessage) get(typ uint16) ([]byte, bool) {
+	for _, a := range m.attributes {
+		if a.typ == typ {
+			return a.value, true
+		}
+	}
+	return nil, false
+}
+
+// encode serializes the message. With a key, a MESSAGE-INTEGRITY attribute computed over
+// the rest of the message is appended.
+func (m *stunMessage) encode(key []byte) []byte {
+	buf := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(buf[0:], m.typ)
+	binary.BigEndian.PutUint32(buf[4:], stunMagicCookie)
+	copy(buf[8:], m.transaction[:])
+	for _, a := range m.attributes {
+		buf = appendSTUNAttribute(buf, a.typ, a.value)
+	}
+	if key != nil {
+		// The length covers the integrity attribute, which is computed without it
+		binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)-stunHeaderSize+24))
+		mac := hmac.New(sha1.New, key)
+		mac.Write(buf)
+		buf = appendSTUNAttribute(buf, stunAttrMessageIntegrity, mac.Sum(nil))
+	}
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)-stunHeaderSize))
+	return buf
+}
+
+func appendSTUNAttribute(buf []byte, typ uint16, value []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, typ)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	// Values are padded to 4 bytes
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+var errNotSTUN = errors.New("not a STUN message")
+
+// decodeSTUN parses a STUN message
+func decodeSTUN(data []byte) (*stunMessage, error) {
+	if len(data) < stunHeaderSize || binary.BigEndian.Uint32(data[4:]) != stunMagicCookie {
+		return nil, errNotSTUN
+	}
+	length := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < stunHeaderSize+length {
+		return nil, fmt.Errorf("truncated STUN message: %d of %d bytes", len(data)-stunHeaderSize, length)
+	}
+	m := &stunMessage{typ: binary.BigEndian.Uint16(data[0:])}
+	copy(m.transaction[:], data[8:20])
+	body := data[stunHeaderSize : stunHeaderSize+length]
+	for len(body) >= 4 {
+		typ := binary.BigEndian.Uint16(body[0:])
+		size := int(binary.BigEndian.Uint16(body[2:]))
+		if len(body) < 4+size {
+			return nil, errors.New("truncated STUN attribute")
+		}
+		m.add(typ, body[4:4+size])
+		padded := 4 + (size+3)/4*4
+		if padded > len(body) {
+			padded = len(body)
+		}
+		body = body[padded:]
+	}
+	return m, nil
+}
+
+// readSTUN reads a STUN message from a stream transport, where messages are delimited by
+// the length of their header
+func readSTUN(r io.Reader) (*stunMessage, error) {
+	header := make([]byte, stunHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	data := make([]byte, stunHeaderSize+int(binary.BigEndian.Uint16(header[2:])))
+	copy(data, header)
+	if _, err := io.ReadFull(r, data[stunHeaderSize:]); err != nil {
+		return nil, err
+	}
+	return decodeSTUN(data)
+}
+
+// address decodes an (XOR-)MAPPED-ADDRESS style attribute
+func (m *stunMessage) address(typ uint16) (*net.UDPAddr, bool) {
+	value, ok := m.get(typ)
+	if !ok || len(value) < 8 {
+		return nil, false
+	}
+	port := binary.BigEndian.Uint16(value[2:])
+	ip := append(net.IP(nil), value[4:]...)
+	switch {
+	case value[1] == 0x01 && len(ip) == net.IPv4len:
+	case value[1] == 0x02 && len(ip) == net.IPv6len:
+	default:
+		return nil, false
+	}
+	if typ != stunAttrMappedAddress {
+		// XOR with the magic cookie, followed by the transaction ID for IPv6
+		port ^= stunMagicCookie >> 16
+		mask := make([]byte, 16)
+		binary.BigEndian.PutUint32(mask, stunMagicCookie)
+		copy(mask[4:], m.transaction[:])
+		for i := range ip {
+			ip[i] ^= mask[i]
+		}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}, true
+}
+
+// errorCode returns the code and reason of an ERROR-CODE attribute
+func (m *stunMessage) errorCode() (int, string) {
+	value, ok := m.get(stunAttrErrorCode)
+	if !ok || len(value) < 4 {
+		return 0, ""
+	}
+	return int(value[2]&0x07)*100 + int(value[3]), string(value[4:])
+}
+
+// turnKey returns the long-term credential key of TURN
+func turnKey(username, realm, password string) []byte {
+	sum := md5.Sum([]byte(username + ":" + realm + ":" + password))
+	return sum[:]
+}
@@ -0,0 +1,93 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// taskGroup tracks the goroutines started with Go and After
+type taskGroup struct {
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// taskContext returns the context of the call's goroutines, cancelled when the call
+// hangs up or the connection closes
+func (c *Connection) taskContext() context.Context {
+	c.tasks.once.Do(func() {
+		c.tasks.ctx, c.tasks.cancel = context.WithCancel(c.ctx)
+	})
+	return c.tasks.ctx
+}
+
+// cancelTasks cancels the goroutines of the call when it hangs up
+func (c *Connection) cancelTasks() {
+	c.taskContext()
+	c.tasks.cancel()
+}
+
+// Go runs fn in a goroutine tied to the call: its context is cancelled when the call
+// hangs up or the connection closes. A returned error or recovered panic is reported as
+// an "error" event with Sender "task" and returned by Wait, unless it only reports the
+// cancellation.
+func (c *Connection) Go(fn func(ctx context.Context) error) {
+	ctx := c.taskContext()
+	c.tasks.wg.Add(1)
+	go func() {
+		defer c.tasks.wg.Done()
+		err := runTask(ctx, fn)
+		if err == nil || (ctx.Err() != nil && errors.Is(err, ctx.Err())) {
+			return
+		}
+		c.tasks.mu.Lock()
+		c.tasks.errs = append(c.tasks.errs, err)
+		c.tasks.mu.Unlock()
+		c.dispatch(&Event{
+			Event:     "error",
+			Timestamp: time.Now().UnixMilli(),
+			Sender:    "task",
+			Error:     err.Error(),
+		})
+	}()
+}
+
+// runTask calls fn, turning a panic into an error
+func runTask(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("call goroutine panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// After runs fn after delay in a goroutine started with Go, unless the call ends first.
+// Use it rather than time.AfterFunc, whose functions outlive the call.
+func (c *Connection) After(delay time.Duration, fn func(ctx context.Context) error) {
+	c.Go(func(ctx context.Context) error {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fn(ctx)
+		}
+	})
+}
+
+// Wait waits for the goroutines started with Go and After and returns their errors, joined
+func (c *Connection) Wait() error {
+	c.tasks.wg.Wait()
+	c.tasks.mu.Lock()
+	defer c.tasks.mu.Unlock()
+	return errors.Join(c.tasks.errs...)
+}
@@ -0,0 +1,83 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCallGoroutines(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteJSON(map[string]interface{}{"event": "hangup", "reason": "caller"})
+	})
+	conn, err := NewConnection(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	reported := make(chan *Event, 4)
+	conn.OnEvent(func(event *Event) {
+		if event.Event == "error" && event.Sender == "task" {
+			reported <- event
+		}
+	})
+
+	failure := errors.New("lookup failed")
+	conn.Go(func(ctx context.Context) error { return failure })
+	conn.Go(func(ctx context.Context) error { panic("boom") })
+	cancelled := make(chan struct{})
+	conn.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	fired := make(chan struct{})
+	conn.After(time.Hour, func(ctx context.Context) error {
+		close(fired)
+		return nil
+	})
+
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the goroutines to be cancelled at hangup")
+	}
+
+	err = conn.Wait()
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Errorf("Expected the error and the panic, got %v", err)
+	}
+	if strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Expected cancellations not to be reported, got %v", err)
+	}
+	select {
+	case <-fired:
+		t.Error("Expected the delayed function not to run after hangup")
+	default:
+	}
+	if len(reported) != 2 {
+		t.Errorf("Expected 2 error events, got %d", len(reported))
+	}
+
+	ran := make(chan struct{})
+	conn.Go(func(ctx context.Context) error {
+		if ctx.Err() == nil {
+			close(ran)
+		}
+		return nil
+	})
+	conn.Wait()
+	select {
+	case <-ran:
+		t.Error("Expected goroutines started after hangup to start cancelled")
+	default:
+	}
+}
@@ -0,0 +1,117 @@
+package rustpbx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateProvider returns the client certificate presented on each handshake, so that
+// certificates can be rotated without restarting the application
+type CertificateProvider func(info *tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+// TLSOptions builds the TLS configuration of a client from files, for deployments with a
+// private CA or mutual TLS
+type TLSOptions struct {
+	// CAFile holds PEM certificates trusted in addition to the system roots
+	CAFile string
+	// CertFile and KeyFile hold the PEM client certificate and key for mutual TLS. They
+	// are reloaded when they change, see ReloadingCertificate.
+	CertFile string
+	KeyFile  string
+	// Certificate provides the client certificate for mutual TLS instead of CertFile and
+	// KeyFile, e.g. from a secrets manager or workload identity agent
+	Certificate CertificateProvider
+	// ServerName overrides the name used for SNI and certificate verification, e.g. when
+	// connecting by IP address
+	ServerName string
+	// InsecureSkipVerify disables certificate verification. Only use it in lab environments.
+	InsecureSkipVerify bool
+}
+
+// Config loads the files and returns the TLS configuration to set as ClientOptions.TLS
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	provider := o.Certificate
+	if provider == nil && (o.CertFile != "" || o.KeyFile != "") {
+		provider = ReloadingCertificate(o.CertFile, o.KeyFile)
+		// Fail now rather than on the first handshake
+		if _, err := provider(nil); err != nil {
+			return nil, err
+		}
+	}
+	if provider != nil {
+		config.GetClientCertificate = provider
+	}
+	return config, nil
+}
+
+// ReloadingCertificate provides the client certificate held in PEM files, loading it
+// again on the next handshake whenever either file is modified. The previous certificate
+// is kept while the files cannot be loaded, e.g. while they are being replaced.
+func ReloadingCertificate(certFile, keyFile string) CertificateProvider {
+	var (
+		mu       sync.Mutex
+		cert     *tls.Certificate
+		modified time.Time
+	)
+	return func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		latest, err := latestModTime(certFile, keyFile)
+		if err == nil && cert != nil && !latest.After(modified) {
+			return cert, nil
+		}
+		loaded, loadErr := tls.LoadX509KeyPair(certFile, keyFile)
+		if err == nil {
+			err = loadErr
+		}
+		if err != nil {
+			if cert != nil {
+				return cert, nil
+			}
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cert, modified = &loaded, latest
+		return cert, nil
+	}
+}
+
+// latestModTime returns the time the most recently modified of the files was modified
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,150 @@
+package rustpbx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientTLS(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.Write([]byte(`{"calls":[]}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// The test server certificate serves as CA and, for mutual TLS, as client certificate
+	dir := t.TempDir()
+	cert := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0o600)
+
+	config, err := (&TLSOptions{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "example.com"}).Config()
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+	client := NewClientWithOptions(server.URL, ClientOptions{TLS: config})
+	conn, err := client.ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Expected the wss handshake to succeed, got %v", err)
+	}
+	conn.Close()
+	if _, err := client.GetActiveCalls(context.Background()); err != nil {
+		t.Errorf("Expected the https request to succeed, got %v", err)
+	}
+
+	if _, err := NewClient(server.URL).ConnectCall(context.Background(), nil); err == nil {
+		t.Error("Expected the private CA to be rejected without TLS options")
+	}
+	if _, err := (&TLSOptions{CAFile: keyFile}).Config(); err == nil {
+		t.Error("Expected a CA file without certificates to be rejected")
+	}
+}
+
+// writeClientCert writes a self-signed client certificate with a common name to PEM files
+func writeClientCert(t *testing.T, certFile, keyFile, name string, modified time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+	os.Chtimes(certFile, modified, modified)
+	os.Chtimes(keyFile, modified, modified)
+}
+
+func TestClientCertificateRotation(t *testing.T) {
+	presented := make(chan string, 4)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented <- r.TLS.PeerCertificates[0].Subject.CommonName
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	issued := time.Now().Add(-time.Minute)
+	writeClientCert(t, certFile, keyFile, "first", issued)
+
+	config, err := (&TLSOptions{CertFile: certFile, KeyFile: keyFile, InsecureSkipVerify: true}).Config()
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+	client := NewClientWithOptions(server.URL, ClientOptions{TLS: config})
+	connect := func() string {
+		conn, err := client.ConnectCall(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Expected the handshake to succeed, got %v", err)
+		}
+		conn.Close()
+		return <-presented
+	}
+
+	if name := connect(); name != "first" {
+		t.Errorf("Expected the first certificate, got '%s'", name)
+	}
+	writeClientCert(t, certFile, keyFile, "rotated", issued.Add(30*time.Second))
+	if name := connect(); name != "rotated" {
+		t.Errorf("Expected the rotated certificate without a restart, got '%s'", name)
+	}
+
+	// A half-written rotation keeps the current certificate
+	os.WriteFile(keyFile, []byte("partial"), 0o600)
+	if name := connect(); name != "rotated" {
+		t.Errorf("Expected the current certificate to be kept, got '%s'", name)
+	}
+
+	if _, err := (&TLSOptions{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}).Config(); err == nil {
+		t.Error("Expected missing certificate files to be rejected")
+	}
+}
@@ -2,6 +2,7 @@ package rustpbx
 
 import (
 	"encoding/json"
+	"log/slog"
 	"time"
 )
 
@@ -48,6 +49,14 @@ const (
 	EOUTypeTencent EOUType = "tencent"
 )
 
+// PLCMode represents packet loss concealment modes
+type PLCMode string
+
+const (
+	PLCOn  PLCMode = "on"
+	PLCOff PLCMode = "off"
+)
+
 // TTSEmotion represents TTS emotion types
 type TTSEmotion string
 
@@ -81,7 +90,9 @@ type RecorderOption struct {
 // VADOption represents Voice Activity Detection configuration
 type VADOption struct {
 	Type           VADType `json:"type,omitempty"`
-	Aggressiveness int     `json:"aggressiveness,omitempty"`
+	// Aggressiveness is not a RustPBX VAD option: RustPBX ignores it and strict
+	// validation refuses it
+	Aggressiveness int `json:"aggressiveness,omitempty"`
 }
 
 // TranscriptionOption represents ASR configuration
@@ -141,6 +152,25 @@ type ReferOption struct {
 	AutoHangup bool   `json:"autoHangup,omitempty"`
 }
 
+// MediaOption represents media path tuning. A small jitter buffer lowers the latency of
+// bots on good networks; a large one with PLC smooths lossy mobile callers. It is a
+// proposal: RustPBX has no media tuning in its call option yet, so the option is
+// validated by the SDK but not sent.
+type MediaOption struct {
+	// JitterMinMs and JitterMaxMs bound the adaptive jitter buffer, in milliseconds
+	JitterMinMs int `json:"jitterMinMs,omitempty"`
+	JitterMaxMs int `json:"jitterMaxMs,omitempty"`
+	// PLC turns packet loss concealment on or off
+	PLC PLCMode `json:"plc,omitempty"`
+	// PTimeMs is the packetization time in milliseconds, a multiple of 10 up to 120, that
+	// MaxBitrate is checked at; 20ms when zero
+	PTimeMs int `json:"ptimeMs,omitempty"`
+	// MaxBitrate is the audio bandwidth of each direction in bits per second, headers
+	// included, that a constrained link offers. Invite and Accept refuse a codec that does
+	// not fit at the ptime, see AudioFormat.Bitrate; RustPBX does not enforce it.
+	MaxBitrate int `json:"maxBitrate,omitempty"`
+}
+
 // CallOption represents the main call configuration
 type CallOption struct {
 	Denoise          bool                     `json:"denoise,omitempty"`
@@ -157,6 +187,12 @@ type CallOption struct {
 	Extra            map[string]interface{}   `json:"extra,omitempty"`
 	Codec            Codec                    `json:"codec,omitempty"`
 	EOU              *EouOption               `json:"eou,omitempty"`
+	// Media is validated but not sent, see MediaOption
+	Media *MediaOption `json:"-"`
+	// AddressFamily selects the IP address families of the media. RustPBX only knows
+	// EnableIPv6, so it is not sent: EnableIPv6 is set from it and the candidates of the
+	// offer are filtered or reordered for it.
+	AddressFamily AddressFamily `json:"-"`
 }
 
 // TTSOptions represents TTS command options
@@ -206,7 +242,8 @@ type TTSCommand struct {
 	PlayID      string `json:"playId,omitempty"`
 	AutoHangup  bool   `json:"autoHangup,omitempty"`
 	Streaming   bool   `json:"streaming,omitempty"`
-	EndOfStream bool   `json:"endOfStream,omitempty"`
+	// EndOfStream is not renamed by RustPBX, unlike the other fields
+	EndOfStream bool `json:"end_of_stream,omitempty"`
 }
 
 // PlayCommand represents play command
@@ -270,6 +307,15 @@ type Event struct {
 	Error     string          `json:"error,omitempty"`
 	Code      int             `json:"code,omitempty"`
 	Data      json.RawMessage `json:"data,omitempty"`
+	// Attestation is the STIR/SHAKEN attestation level (A, B or C) when the server provides
+	// it. RustPBX does not send it, and the protocol schema does not include it.
+	Attestation string `json:"attestation,omitempty"`
+
+	// SpamScore is set on incoming events when call screening is enabled
+	SpamScore float64 `json:"-"`
+
+	// Context is the call context of the connection that delivered the event
+	Context *CallContext `json:"-"`
 }
 
 // Call represents an active call
@@ -278,6 +324,8 @@ type Call struct {
 	CallType  CallType    `json:"call_type"`
 	CreatedAt time.Time   `json:"created_at"`
 	Option    *CallOption `json:"option"`
+	// Encryption is the protection of the call's media, when the server reports it
+	Encryption MediaEncryption `json:"encryption,omitempty"`
 }
 
 // CallListResponse represents the response from /call/lists
@@ -294,8 +342,120 @@ type ICEServer struct {
 
 // ConnectionOptions represents WebSocket connection options
 type ConnectionOptions struct {
+	// SessionID names the call session; a UUID when empty. RustPBX does not check that
+	// it is unused: a session with the ID of an active call replaces that call in the
+	// active calls, so IDs must be unique.
 	SessionID string
 	Dump      bool
+	Tenant    string
+	Metadata  map[string]string
+
+	// Budget limits the resources a call may consume
+	Budget *CallBudget
+
+	// Screening scores incoming calls for spam and robocall likelihood
+	Screening *ScreeningPolicy
+
+	// Emergency controls dialing of emergency numbers; targets are not checked when nil
+	Emergency *EmergencyPolicy
+
+	// Profiler measures per-turn speech-to-speech latency from the connection's events
+	Profiler *LatencyProfiler
+	// Dispositions is the taxonomy accepted by SetDisposition; DefaultDispositionTaxonomy when nil
+	Dispositions DispositionTaxonomy
+
+	// Enricher looks up incoming callers before the incoming event is dispatched
+	Enricher *IdentityEnricher
+	// Memory loads the profile of incoming callers before the incoming event is dispatched
+	// and records the call in it
+	Memory *CallerMemory
+
+	// Admission limits the concurrent incoming calls admitted across connections
+	Admission *AdmissionController
+
+	// Degradation switches providers, falls back to the keypad or transfers the call when ASR or TTS keeps failing
+	Degradation *DegradationPolicy
+
+	// Faults injects control channel failures for resilience testing; never set it in production
+	Faults *FaultInjector
+
+	// MaxEventSize bounds event frames in bytes; 1 MiB when zero, unlimited when negative
+	MaxEventSize int
+	// Quarantine receives the event frames that could not be decoded, e.g. to log them for analysis
+	Quarantine func(frame []byte, err error)
+
+	// MaxInboundMessage bounds received messages in bytes, the read limit of the WebSocket;
+	// exceeding frames are reported with "frameRejected" events and handled according to
+	// OversizedFrames. 4 MiB when zero, unlimited when negative.
+	MaxInboundMessage int
+	// OversizedFrames drops the connection with ErrMessageTooLarge on frames exceeding
+	// MaxInboundMessage when zero, or skips them with OversizedSkip
+	OversizedFrames OversizedFrames
+	// MaxOutboundMessage bounds sent messages in bytes; 1 MiB when zero, unlimited when negative.
+	// Longer TTS texts are sent as a stream of chunks; other commands fail with ErrMessageTooLarge.
+	// WebSocket framing fragments large messages on the wire regardless.
+	MaxOutboundMessage int
+
+	// Reconnect re-dials the session as a new session when the connection drops, dialing an
+	// outbound call again if the policy allows it; the connection fails on drops when nil
+	Reconnect *ReconnectPolicy
+	// Keepalive pings the server and reports a silent connection with a "connectionStale"
+	// event; the connection is only bounded by a 60s read deadline when nil
+	Keepalive *KeepalivePolicy
+
+	// Flags evaluates feature flags for the call, for the assistant and for flows through FeatureEnabled
+	Flags *FeatureFlags
+	// Config supplies the prompts, flows, provider settings and routing rules of the call.
+	// The call keeps the config current when it connects; reloads apply to later calls.
+	Config *ConfigWatcher
+	// Registry tracks the session for administration; connecting fails with ErrDraining
+	// while the registry drains
+	Registry *SessionRegistry
+	// SessionBackend persists the values of the call's Store beyond the connection; they
+	// are only kept in memory when nil
+	SessionBackend SessionBackend
+
+	// TextSanitizer cleans up and splits the texts passed to TTS, such as LLM output with markdown
+	TextSanitizer *TextSanitizer
+
+	// Dispatcher runs the event handlers on its workers, in order per connection; they run
+	// on the goroutine reading the connection when nil
+	Dispatcher *EventDispatcher
+
+	// Logger logs dials, commands, events, read errors and reconnects, with secrets
+	// redacted; the logger of the client when nil, and nothing is logged without either
+	Logger *slog.Logger
+	// WireDump records every raw frame sent and received, unlike Dump which makes the
+	// server dump the session. Frames are dumped as is, secrets included.
+	WireDump *WireDump
+	// Validation checks the commands sent and the events received against the protocol
+	// schema, to detect drift from the server's protocol; off when zero
+	Validation ProtocolValidation
+	// AlignSampleRates sets the sample rates of the recorder, ASR and TTS of the call
+	// options sent with Invite and Accept to the rate of their codec. Mismatched rates are
+	// otherwise reported as "sampleRateMismatch" events and sent as is.
+	AlignSampleRates bool
+	// WriteQueue queues the commands sent, to bound bursts and let hangup and interrupt
+	// overtake them; commands are written directly by the sending goroutine when nil
+	WriteQueue *WriteQueuePolicy
+	// SkipOptionValidation sends the call options of Invite and Accept without checking
+	// them with CallOption.Validate, e.g. for fields a newer server accepts
+	SkipOptionValidation bool
+	// Recording locates the recordings of recorded calls once written, to emit
+	// "recordingFinalized" events; recordings are only reported started and stopped when nil
+	Recording *RecordingOptions
+	// Dial configures the WebSocket handshake, such as its timeout and proxy
+	Dial *DialOptions
+	// Metrics receives the commands sent, events received and reconnects as they happen;
+	// Connection.Metrics reports their totals regardless
+	Metrics MetricsRecorder
+	// MediaWatch reports one-way audio with "mediaAnomaly" events; it is not detected when nil
+	MediaWatch *MediaWatchPolicy
+	// RequireEncryption refuses calls whose media is not SRTP or DTLS-SRTP protected, or
+	// cannot be verified so: Invite and Accept fail with ErrUnencryptedMedia on a plain
+	// offer, and incoming calls and answers with plain SDP are ended and reported with an
+	// "unencryptedMedia" event instead of being delivered
+	RequireEncryption bool
 }
 
 // EventHandler represents an event handler function
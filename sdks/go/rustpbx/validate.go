@@ -0,0 +1,292 @@
+package rustpbx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidOption is wrapped by the errors of the Validate methods of the options
+var ErrInvalidOption = errors.New("invalid option")
+
+// FieldError reports an invalid field of an option, named by its JSON path such as
+// "asr.samplerate". The Validate methods join one per invalid field.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Unwrap makes the error match ErrInvalidOption
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidOption
+}
+
+// supportedSampleRates are the sample rates accepted by the media pipeline
+var supportedSampleRates = map[int]bool{8000: true, 16000: true, 22050: true, 24000: true, 32000: true, 44100: true, 48000: true}
+
+// optionErrors collects the field errors of an option
+type optionErrors []error
+
+// add records an invalid field
+func (e *optionErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, &FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// nest records the field errors of a nested option under its field
+func (e *optionErrors) nest(field string, err error) {
+	if err == nil {
+		return
+	}
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			err = &FieldError{Field: field + "." + fieldErr.Field, Reason: fieldErr.Reason}
+		}
+		*e = append(*e, err)
+	}
+}
+
+// sdp records the problems of a session description under its field
+func (e *optionErrors) sdp(field string, err error) {
+	if err == nil {
+		return
+	}
+	problems := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		problems = joined.Unwrap()
+	}
+	for _, problem := range problems {
+		e.add(field, "%v", problem)
+	}
+}
+
+// sampleRate records an unsupported sample rate; zero leaves the default
+func (e *optionErrors) sampleRate(field string, rate int) {
+	if rate != 0 && !supportedSampleRates[rate] {
+		e.add(field, "unsupported sample rate %d", rate)
+	}
+}
+
+// err returns the recorded errors joined, or nil
+func (e optionErrors) err() error {
+	return errors.Join(e...)
+}
+
+// Validate checks the fields of a call option and its nested options, as the server
+// would otherwise fail the call with a less descriptive error or ignore them
+func (o *CallOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	switch o.Codec {
+	case "", CodecPCMU, CodecPCMA, CodecG722, CodecPCM:
+	default:
+		errs.add("codec", "unknown codec %q, expected pcmu, pcma, g722 or pcm", o.Codec)
+	}
+	if o.HandshakeTimeout != "" {
+		if seconds, err := strconv.ParseUint(o.HandshakeTimeout, 10, 64); err != nil || seconds == 0 {
+			errs.add("handshakeTimeout", "must be a positive number of seconds such as \"30\", got %q", o.HandshakeTimeout)
+		}
+	}
+	switch o.AddressFamily {
+	case "", AddressDualStack, AddressPreferIPv6:
+	case AddressIPv4Only:
+		if o.EnableIPv6 {
+			errs.add("enableIpv6", "conflicts with address family %s", o.AddressFamily)
+		}
+	default:
+		errs.add("addressFamily", "unknown address family %q, expected ipv4, dualStack or preferIpv6", o.AddressFamily)
+	}
+	if o.Callee != "" && strings.TrimSpace(o.Callee) != o.Callee {
+		errs.add("callee", "must not have surrounding spaces")
+	}
+	if o.Offer != "" {
+		errs.sdp("offer", ValidateSDP(o.Offer))
+	}
+	errs.nest("recorder", o.Recorder.Validate())
+	errs.nest("vad", o.VAD.Validate())
+	errs.nest("asr", o.ASR.Validate())
+	errs.nest("tts", o.TTS.Validate())
+	errs.nest("eou", o.EOU.Validate())
+	if err := o.Media.Validate(); err != nil {
+		errs.nest("media", err)
+	} else {
+		errs.nest("media", o.Media.checkBitrate(o.Codec))
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of a recorder option
+func (o *RecorderOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	errs.sampleRate("samplerate", o.SampleRate)
+	if o.PTime != "" {
+		if ptime, err := time.ParseDuration(o.PTime); err != nil || ptime <= 0 {
+			errs.add("ptime", "must be a positive duration such as \"20ms\", got %q", o.PTime)
+		}
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of a VAD option
+func (o *VADOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	switch o.Type {
+	case "", VADTypeWebRTC, VADTypeSilero, VADTypeTen:
+	default:
+		errs.add("type", "unknown VAD type %q, expected webrtc, silero or ten", o.Type)
+	}
+	if o.Aggressiveness < 0 || o.Aggressiveness > 3 {
+		errs.add("aggressiveness", "must be between 0 and 3, got %d", o.Aggressiveness)
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of an ASR option. Missing credentials are not reported, as
+// the server may take them from its environment.
+func (o *TranscriptionOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	errs.sampleRate("samplerate", o.SampleRate)
+	if o.BufferSize < 0 {
+		errs.add("bufferSize", "must not be negative, got %d", o.BufferSize)
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of a TTS option
+func (o *SynthesisOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	errs.sampleRate("samplerate", o.SampleRate)
+	if o.Speed < 0 {
+		errs.add("speed", "must not be negative, got %g", o.Speed)
+	}
+	if o.Volume < 0 {
+		errs.add("volume", "must not be negative, got %d", o.Volume)
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of an end of utterance option
+func (o *EouOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	switch o.Type {
+	case "", EOUTypeTencent:
+	default:
+		errs.add("type", "unknown end of utterance type %q", o.Type)
+	}
+	if o.Timeout < 0 {
+		errs.add("timeout", "must not be negative, got %d", o.Timeout)
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of a media option
+func (o *MediaOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	if o.JitterMinMs < 0 {
+		errs.add("jitterMinMs", "must not be negative, got %d", o.JitterMinMs)
+	}
+	if o.JitterMaxMs < 0 {
+		errs.add("jitterMaxMs", "must not be negative, got %d", o.JitterMaxMs)
+	}
+	if o.JitterMaxMs > 0 && o.JitterMinMs > o.JitterMaxMs {
+		errs.add("jitterMaxMs", "must not be less than jitterMinMs %d, got %d", o.JitterMinMs, o.JitterMaxMs)
+	}
+	switch o.PLC {
+	case "", PLCOn, PLCOff:
+	default:
+		errs.add("plc", "unknown mode %q, expected on or off", o.PLC)
+	}
+	if o.PTimeMs != 0 && (o.PTimeMs < 10 || o.PTimeMs > 120 || o.PTimeMs%10 != 0) {
+		errs.add("ptimeMs", "must be a multiple of 10 from 10 to 120, got %d", o.PTimeMs)
+	}
+	if o.MaxBitrate < 0 {
+		errs.add("maxBitrate", "must not be negative, got %d", o.MaxBitrate)
+	}
+	return errs.err()
+}
+
+// checkBitrate checks that the audio of a codec fits the bitrate cap at the ptime
+func (o *MediaOption) checkBitrate(codec Codec) error {
+	if o == nil || o.MaxBitrate <= 0 {
+		return nil
+	}
+	var errs optionErrors
+	ptime := 20 * time.Millisecond
+	if o.PTimeMs > 0 {
+		ptime = time.Duration(o.PTimeMs) * time.Millisecond
+	}
+	format := AudioFormatFor(codec)
+	if needed := format.Bitrate(ptime); needed > o.MaxBitrate {
+		errs.add("maxBitrate", "%s audio in %s packets needs %d bps, over the cap of %d",
+			format.Codec, ptime, needed, o.MaxBitrate)
+	}
+	return errs.err()
+}
+
+// Validate checks the fields of a refer option
+func (o *ReferOption) Validate() error {
+	if o == nil {
+		return nil
+	}
+	var errs optionErrors
+	if o.Timeout < 0 {
+		errs.add("timeout", "must not be negative, got %d", o.Timeout)
+	}
+	return errs.err()
+}
+
+// validateCallOption validates the call option of an Invite or Accept, unless disabled
+// with SkipOptionValidation. SIP calls need a callee to dial.
+func (c *Connection) validateCallOption(option *CallOption, invite bool) error {
+	if c.skipOptionValidation {
+		return nil
+	}
+	var errs optionErrors
+	if invite && c.sipCall() && (option == nil || option.Callee == "") {
+		errs.add("callee", "is required for SIP calls")
+	}
+	errs = append(errs, option.Validate())
+	if err := errs.err(); err != nil {
+		return fmt.Errorf("invalid call option: %w", err)
+	}
+	return nil
+}
+
+// sipCall reports whether the connection serves a SIP call
+func (c *Connection) sipCall() bool {
+	path := c.wsURL
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.HasSuffix(path, "/call/sip")
+}
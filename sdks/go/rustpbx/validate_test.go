@@ -0,0 +1,123 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallOptionValidate(t *testing.T) {
+	valid := &CallOption{
+		Callee:           "sip:1000@example.com",
+		Codec:            CodecG722,
+		HandshakeTimeout: "30",
+		Recorder:         &RecorderOption{SampleRate: 16000, PTime: "20ms"},
+		VAD:              &VADOption{Type: VADTypeSilero},
+		ASR:              &TranscriptionOption{Provider: ProviderTencent, SampleRate: 16000},
+		TTS:              &SynthesisOption{Provider: ProviderTencent, Speed: 1},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid option, got %v", err)
+	}
+	var nilOption *CallOption
+	if err := nilOption.Validate(); err != nil {
+		t.Errorf("Expected a nil option to be valid, got %v", err)
+	}
+
+	invalid := &CallOption{
+		Codec:            "opus",
+		HandshakeTimeout: "30s",
+		Recorder:         &RecorderOption{PTime: "20"},
+		ASR:              &TranscriptionOption{SampleRate: 11000},
+		TTS:              &SynthesisOption{Volume: -1},
+		Media:            &MediaOption{JitterMinMs: 80, JitterMaxMs: 40, PLC: "auto"},
+	}
+	err := invalid.Validate()
+	if !errors.Is(err, ErrInvalidOption) {
+		t.Fatalf("Expected ErrInvalidOption, got %v", err)
+	}
+	var fields []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("Expected field errors, got %v", err)
+		}
+		fields = append(fields, fieldErr.Field)
+	}
+	expected := "codec,handshakeTimeout,recorder.ptime,asr.samplerate,tts.volume,media.jitterMaxMs,media.plc"
+	if got := strings.Join(fields, ","); got != expected {
+		t.Errorf("Expected invalid fields %s, got %s", expected, got)
+	}
+	if !strings.Contains(err.Error(), `asr.samplerate: unsupported sample rate 11000`) {
+		t.Errorf("Expected a descriptive error, got %v", err)
+	}
+}
+
+func TestMediaOptionBitrate(t *testing.T) {
+	if bitrate := AudioFormatFor(CodecPCMU).Bitrate(0); bitrate != 80000 {
+		t.Errorf("Expected 80000 bps for G.711 in 20ms packets, got %d", bitrate)
+	}
+	if bitrate := AudioFormatFor(CodecPCMA).Bitrate(60 * time.Millisecond); bitrate != 69334 {
+		t.Errorf("Expected 69334 bps for G.711 in 60ms packets, got %d", bitrate)
+	}
+
+	tests := []struct {
+		option *CallOption
+		field  string
+	}{
+		{&CallOption{Codec: CodecPCMU, Media: &MediaOption{PTimeMs: 40, MaxBitrate: 72000}}, ""},
+		{&CallOption{Codec: CodecPCMU, Media: &MediaOption{MaxBitrate: 72000}}, "media.maxBitrate"},
+		{&CallOption{Codec: CodecPCM, Media: &MediaOption{PTimeMs: 120, MaxBitrate: 100000}}, "media.maxBitrate"},
+		{&CallOption{Media: &MediaOption{PTimeMs: 25}}, "media.ptimeMs"},
+		{&CallOption{Media: &MediaOption{MaxBitrate: -1}}, "media.maxBitrate"},
+	}
+	for _, test := range tests {
+		err := test.option.Validate()
+		var fieldErr *FieldError
+		switch {
+		case test.field == "" && err != nil:
+			t.Errorf("%+v: expected a valid option, got %v", test.option.Media, err)
+		case test.field != "" && (!errors.As(err, &fieldErr) || fieldErr.Field != test.field):
+			t.Errorf("%+v: expected an invalid %s, got %v", test.option.Media, test.field, err)
+		}
+	}
+}
+
+func TestInviteValidation(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectSIP(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectSIP failed: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.Invite(&CallOption{Codec: "opus"})
+	if !errors.Is(err, ErrInvalidOption) || !strings.Contains(err.Error(), "callee: is required for SIP calls") ||
+		!strings.Contains(err.Error(), "codec: unknown codec") {
+		t.Errorf("Expected the missing callee and codec to be reported, got %v", err)
+	}
+	if err := conn.Accept(&CallOption{Codec: CodecPCMA}); err != nil {
+		t.Errorf("Expected accept without a callee, got %v", err)
+	}
+	if cmd := <-commands; cmd["command"] != "accept" {
+		t.Errorf("Expected only the accept to be sent, got %v", cmd)
+	}
+}
+
+func TestSkipOptionValidation(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{SkipOptionValidation: true})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Invite(&CallOption{Codec: "opus"}); err != nil {
+		t.Fatalf("Expected the option to be sent unchecked, got %v", err)
+	}
+	if cmd := <-commands; cmd["option"].(map[string]interface{})["codec"] != "opus" {
+		t.Errorf("Expected the codec to be sent as is, got %v", cmd)
+	}
+}
@@ -0,0 +1,171 @@
+package rustpbx
+
+import (
+	"strings"
+	"unicode"
+)
+
+// VerbosityPolicy bounds how much of a reply is spoken per turn, so long answers do not
+// turn into monologues. A longer reply is cut after a sentence and followed by the
+// continuation prompt; if the caller accepts, the rest is spoken without asking the LLM
+// again, and anything else starts a new turn.
+type VerbosityPolicy struct {
+	// MaxWords bounds the words spoken per turn; 60 when zero, about 25 seconds of speech
+	MaxWords int
+	// ContinuationPrompt follows a cut reply; "Would you like more detail?" when empty
+	ContinuationPrompt string
+	// Locale recognizes the caller accepting or declining; EnglishLocale when nil
+	Locale *LocaleBundle
+	// DeclineText is spoken when the caller declines; nothing when empty
+	DeclineText string
+}
+
+// maxWords returns the word limit per turn
+func (p *VerbosityPolicy) maxWords() int {
+	if p.MaxWords <= 0 {
+		return 60
+	}
+	return p.MaxWords
+}
+
+// prompt returns the continuation prompt
+func (p *VerbosityPolicy) prompt() string {
+	if p.ContinuationPrompt == "" {
+		return "Would you like more detail?"
+	}
+	return p.ContinuationPrompt
+}
+
+// locale returns the bundle recognizing the caller's answer
+func (p *VerbosityPolicy) locale() *LocaleBundle {
+	if p.Locale == nil {
+		return EnglishLocale
+	}
+	return p.Locale
+}
+
+// cut splits a reply into the part spoken now and the rest offered as more detail.
+// Whole sentences are kept while they fit; a first sentence longer than the limit is cut
+// between words. A rest shorter than a fifth of the limit is spoken rather than offered.
+func (p *VerbosityPolicy) cut(text string) (string, string) {
+	limit := p.maxWords()
+	if len(strings.Fields(text)) <= limit+limit/5 {
+		return text, ""
+	}
+
+	end, words := 0, 0
+	rest := text
+	for rest != "" {
+		n := len(firstSentence(rest))
+		count := len(strings.Fields(rest[:n]))
+		if words+count > limit {
+			break
+		}
+		words += count
+		end += n
+		rest = rest[n:]
+	}
+	if end == 0 {
+		// A single long sentence is cut between words
+		end = wordOffset(text, limit)
+	}
+	return strings.TrimSpace(text[:end]), strings.TrimSpace(text[end:])
+}
+
+// firstSentence returns the text up to and including the first sentence end and the
+// space after it, or the whole text if it has no sentence end
+func firstSentence(text string) string {
+	for i, r := range text {
+		switch r {
+		case '.', '!', '?':
+			if strings.HasPrefix(text[i+1:], " ") {
+				return text[:i+2]
+			}
+		case '。', '！', '？':
+			return text[:i+len(string(r))]
+		}
+	}
+	return text
+}
+
+// wordOffset returns the offset just after the first n words of text
+func wordOffset(text string, n int) int {
+	inWord := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if space && inWord {
+			if n--; n == 0 {
+				return i
+			}
+		}
+		inWord = !space
+	}
+	return len(text)
+}
+
+// governReply cuts a reply to the verbosity limit, keeping the rest for the caller to ask
+// for, and returns the text to speak; the caller must hold a.mu
+func (a *Assistant) governReply(text string) string {
+	policy := a.options.Verbosity
+	if policy == nil {
+		return text
+	}
+	spoken, rest := policy.cut(text)
+	a.more = rest
+	if rest == "" {
+		return spoken
+	}
+	return spoken + " " + policy.prompt()
+}
+
+// continueReply answers the continuation prompt of a cut reply. It returns false if the
+// utterance is not an answer to it and starts a new turn.
+func (a *Assistant) continueReply(text string) bool {
+	a.mu.Lock()
+	if a.more == "" || len(a.utterance) > 0 || (a.turn != nil && !a.turn.speculative) {
+		a.more = ""
+		a.mu.Unlock()
+		return false
+	}
+	policy := a.options.Verbosity
+	locale := policy.locale()
+
+	var reply string
+	switch {
+	case locale.IsYes(text):
+		reply = a.governReply(a.more)
+	case isExactly(text, locale.No):
+		a.more = ""
+		reply = policy.DeclineText
+	default:
+		a.more = ""
+		a.mu.Unlock()
+		return false
+	}
+	if a.turn != nil {
+		// A speculative reply to the answer is not needed
+		a.turn.stop()
+		a.turn = nil
+	}
+	speaker := a.speaker()
+	a.mu.Unlock()
+
+	a.conn.History("user", text)
+	if reply != "" {
+		a.speakAs(reply, speaker)
+		a.conn.History("assistant", reply)
+	}
+	return true
+}
+
+// isExactly reports whether the normalized utterance is one of the phrases, so that
+// "no" declines but "no, what about billing?" asks something else
+func isExactly(text string, phrases []string) bool {
+	text = normalizeUtterance(text)
+	for _, phrase := range phrases {
+		if text == normalizeUtterance(phrase) {
+			return true
+		}
+	}
+	return false
+}
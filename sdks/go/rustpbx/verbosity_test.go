@@ -0,0 +1,73 @@
+package rustpbx
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerbosityCut(t *testing.T) {
+	policy := &VerbosityPolicy{MaxWords: 10}
+	text := "Your plan includes calls. It also includes texts and data. Roaming costs extra in most countries outside the region."
+	spoken, rest := policy.cut(text)
+	if spoken != "Your plan includes calls. It also includes texts and data." {
+		t.Errorf("Expected whole sentences within the limit, got '%s'", spoken)
+	}
+	if rest != "Roaming costs extra in most countries outside the region." {
+		t.Errorf("Unexpected rest: '%s'", rest)
+	}
+
+	spoken, rest = policy.cut(strings.Repeat("word ", 30))
+	if len(strings.Fields(spoken)) != 10 || len(strings.Fields(rest)) != 20 {
+		t.Errorf("Expected a long sentence cut after 10 words, got '%s' / '%s'", spoken, rest)
+	}
+
+	if spoken, rest := policy.cut("One two three four five. Six seven eight nine ten eleven."); rest != "" || !strings.HasSuffix(spoken, "eleven.") {
+		t.Errorf("Expected a short overflow to be spoken, got '%s' / '%s'", spoken, rest)
+	}
+}
+
+func TestAssistantVerbosity(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	var calls atomic.Int32
+	assistant := NewAssistant(conn, &AssistantOptions{
+		LLM: LLMFunc(func(ctx context.Context, messages []ChatMessage) (string, error) {
+			calls.Add(1)
+			return "First part is here. Second part comes next. Third part ends it. Fourth part is extra.", nil
+		}),
+		Patience:  &PatiencePolicy{Min: 10 * time.Millisecond},
+		Verbosity: &VerbosityPolicy{MaxWords: 5, DeclineText: "Okay."},
+	})
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "tell me everything"})
+	if text := nextTTS(t, commands); text != "First part is here. Would you like more detail?" {
+		t.Errorf("Expected the reply to be cut, got '%s'", text)
+	}
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "yes please"})
+	if text := nextTTS(t, commands); text != "Second part comes next. Would you like more detail?" {
+		t.Errorf("Expected the next part, got '%s'", text)
+	}
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "No."})
+	if text := nextTTS(t, commands); text != "Okay." {
+		t.Errorf("Expected the decline text, got '%s'", text)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected the continuation to skip the LLM, got %d calls", n)
+	}
+
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "tell me again"})
+	nextTTS(t, commands)
+	assistant.HandleEvent(&Event{Event: "asrFinal", Text: "no, what about roaming"})
+	nextTTS(t, commands)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected a new question to reach the LLM, got %d calls", n)
+	}
+}
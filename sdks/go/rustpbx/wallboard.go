@@ -0,0 +1,145 @@
+package rustpbx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// QueueMetrics reports the live state and counters of a call queue
+type QueueMetrics struct {
+	Queue       string
+	Waiting     int
+	LongestWait time.Duration
+	Answered    int
+	Abandoned   int
+	// AverageWait is the mean time answered calls waited for an agent
+	AverageWait time.Duration
+	// ServiceLevel is the fraction of offered calls answered within the queue's target;
+	// abandoned calls count as missed. It is 1 before any call was offered.
+	ServiceLevel float64
+}
+
+// MarshalJSON encodes the metrics with durations in milliseconds for wallboard clients
+func (m QueueMetrics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"queue":         m.Queue,
+		"waiting":       m.Waiting,
+		"longestWaitMs": m.LongestWait.Milliseconds(),
+		"answered":      m.Answered,
+		"abandoned":     m.Abandoned,
+		"averageWaitMs": m.AverageWait.Milliseconds(),
+		"serviceLevel":  m.ServiceLevel,
+	})
+}
+
+// WallboardSnapshot reports the aggregated queue and agent metrics of a pool
+type WallboardSnapshot struct {
+	At     time.Time      `json:"at"`
+	Queues []QueueMetrics `json:"queues"`
+	// Agents counts the agents in each state
+	Agents map[AgentState]int `json:"agents"`
+	// ActiveAgents counts the agents that are not offline
+	ActiveAgents int `json:"activeAgents"`
+}
+
+// Snapshot returns the current wallboard metrics of the pool
+func (p *AgentPool) Snapshot() WallboardSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	snapshot := WallboardSnapshot{
+		At:     now,
+		Queues: make([]QueueMetrics, len(p.queues)),
+		Agents: make(map[AgentState]int),
+	}
+
+	index := make(map[*CallQueue]int, len(p.queues))
+	for i, q := range p.queues {
+		index[q] = i
+		metrics := QueueMetrics{
+			Queue:        q.options.Name,
+			Answered:     q.answered,
+			Abandoned:    q.abandoned,
+			ServiceLevel: 1,
+		}
+		if q.answered > 0 {
+			metrics.AverageWait = q.totalWait / time.Duration(q.answered)
+		}
+		if offered := q.answered + q.abandoned; offered > 0 {
+			metrics.ServiceLevel = float64(q.onTarget) / float64(offered)
+		}
+		snapshot.Queues[i] = metrics
+	}
+	for _, call := range p.waiting {
+		metrics := &snapshot.Queues[index[call.queue]]
+		metrics.Waiting++
+		if wait := now.Sub(call.enqueued); wait > metrics.LongestWait {
+			metrics.LongestWait = wait
+		}
+	}
+
+	for _, agent := range p.agents {
+		snapshot.Agents[agent.state]++
+		if agent.state != AgentOffline {
+			snapshot.ActiveAgents++
+		}
+	}
+	return snapshot
+}
+
+// Watch pushes a snapshot every interval until ctx is done. Snapshots are dropped
+// while the receiver is behind, so it always reads recent metrics.
+func (p *AgentPool) Watch(ctx context.Context, interval time.Duration) <-chan WallboardSnapshot {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	snapshots := make(chan WallboardSnapshot, 1)
+	go func() {
+		defer close(snapshots)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case snapshots <- p.Snapshot():
+			default:
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return snapshots
+}
+
+// WallboardHandler serves the pool's wallboard metrics over HTTP. Requests accepting
+// text/event-stream receive a snapshot every interval as server-sent events; other
+// requests receive the current snapshot as JSON.
+func WallboardHandler(pool *AgentPool, interval time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if r.Header.Get("Accept") != "text/event-stream" || !ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(pool.Snapshot())
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		for snapshot := range pool.Watch(r.Context(), interval) {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package rustpbx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWallboardSnapshot(t *testing.T) {
+	pool := NewAgentPool(nil)
+	pool.AddAgent(&Agent{ID: "ann"}, AgentAvailable)
+	pool.AddAgent(&Agent{ID: "bob"}, AgentOffline)
+	sales := pool.NewQueue(QueueOptions{Name: "sales", ServiceLevel: time.Minute})
+	support := pool.NewQueue(QueueOptions{Name: "support", Skills: []string{"support"}})
+
+	sales.Enqueue(context.Background(), &QueuedCall{ID: "1"})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	support.Enqueue(ctx, &QueuedCall{ID: "2"})
+	go support.Enqueue(context.Background(), &QueuedCall{ID: "3"})
+	time.Sleep(30 * time.Millisecond)
+
+	snapshot := pool.Snapshot()
+	if snapshot.ActiveAgents != 1 || snapshot.Agents[AgentBusy] != 1 || snapshot.Agents[AgentOffline] != 1 {
+		t.Errorf("Unexpected agent counts: %+v", snapshot.Agents)
+	}
+	if m := snapshot.Queues[0]; m.Queue != "sales" || m.Answered != 1 || m.ServiceLevel != 1 {
+		t.Errorf("Unexpected sales metrics: %+v", m)
+	}
+	m := snapshot.Queues[1]
+	if m.Waiting != 1 || m.Abandoned != 1 || m.ServiceLevel != 0 {
+		t.Errorf("Unexpected support metrics: %+v", m)
+	}
+	if m.LongestWait < 20*time.Millisecond {
+		t.Errorf("Expected longest wait of at least 20ms, got %v", m.LongestWait)
+	}
+}
+
+func TestWallboardHandler(t *testing.T) {
+	pool := NewAgentPool(nil)
+	pool.AddAgent(&Agent{ID: "ann"}, AgentAvailable)
+	pool.NewQueue(QueueOptions{Name: "sales"})
+	server := httptest.NewServer(WallboardHandler(pool, 10*time.Millisecond))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	var snapshot map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&snapshot)
+	resp.Body.Close()
+	if snapshot["activeAgents"] != float64(1) {
+		t.Errorf("Expected 1 active agent, got %v", snapshot["activeAgents"])
+	}
+	queue := snapshot["queues"].([]interface{})[0].(map[string]interface{})
+	if queue["queue"] != "sales" || queue["longestWaitMs"] != float64(0) {
+		t.Errorf("Unexpected queue metrics: %v", queue)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	for i := 0; i < 2; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil || !strings.HasPrefix(line, "data: {") {
+			t.Fatalf("Expected snapshot event, got %q (%v)", line, err)
+		}
+		reader.ReadString('\n')
+	}
+}
@@ -0,0 +1,154 @@
+package rustpbx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Wire dump directions
+const (
+	WireInbound  = "in"
+	WireOutbound = "out"
+)
+
+// WireRecord is a WebSocket frame in a wire dump
+type WireRecord struct {
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"sessionId,omitempty"`
+	// Direction is WireInbound or WireOutbound
+	Direction string `json:"direction"`
+	// Text holds a text frame as is; Binary holds a binary frame, base64 encoded in the dump
+	Text   string `json:"text,omitempty"`
+	Binary []byte `json:"binary,omitempty"`
+}
+
+// WireDump writes the raw frames of connections to a writer as JSON lines, one WireRecord
+// per frame, to replay and debug protocol issues. Share one dump between connections.
+type WireDump struct {
+	// Audio includes the binary audio frames, which are skipped by default
+	Audio bool
+
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	err    error
+}
+
+// NewWireDump creates a dump writing to w
+func NewWireDump(w io.Writer) *WireDump {
+	return &WireDump{w: w}
+}
+
+// OpenWireDump creates a dump appending to a JSONL file
+func OpenWireDump(path string) (*WireDump, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wire dump: %w", err)
+	}
+	return &WireDump{w: file, closer: file}, nil
+}
+
+// OpenEncryptedWireDump creates a dump writing to a file encrypted with cipher, replacing
+// it; read it with ReadWireDump from cipher.NewReader. Close the dump to complete the file.
+func OpenEncryptedWireDump(path string, cipher *ArtifactCipher) (*WireDump, error) {
+	w, err := cipher.CreateFile(path, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &WireDump{w: w, closer: w}, nil
+}
+
+// Close closes the file of a dump opened with OpenWireDump or OpenEncryptedWireDump;
+// later frames are not dumped
+func (d *WireDump) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.w = nil
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// Err returns the first error writing the dump; the dump stops at the first error
+func (d *WireDump) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// record writes a frame to the dump
+func (d *WireDump) record(sessionID, direction string, messageType int, data []byte) {
+	record := WireRecord{Time: time.Now().UTC(), SessionID: sessionID, Direction: direction}
+	switch messageType {
+	case websocket.TextMessage:
+		record.Text = string(data)
+	case websocket.BinaryMessage:
+		if !d.Audio {
+			return
+		}
+		record.Binary = data
+	default:
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.w == nil || d.err != nil {
+		return
+	}
+	if _, err := d.w.Write(append(line, '\n')); err != nil {
+		d.err = fmt.Errorf("failed to write wire dump: %w", err)
+	}
+}
+
+// ReadWireDump reads the records of a wire dump in order, e.g. to replay a session
+func ReadWireDump(r io.Reader) ([]WireRecord, error) {
+	var records []WireRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, defaultMaxInboundMessage*2)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record WireRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("failed to decode wire record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("failed to read wire dump: %w", err)
+	}
+	return records, nil
+}
+
+// dumpFrame records a frame in the wire dump of the connection, if any
+func (c *Connection) dumpFrame(direction string, messageType int, data []byte) {
+	if c.wireDump == nil {
+		return
+	}
+	c.wireDump.record(c.sessionID(), direction, messageType, data)
+}
+
+// sessionID returns the session ID of the connection, from its URL or call context
+func (c *Connection) sessionID() string {
+	if sessionID := sessionIDFromURL(c.wsURL); sessionID != "" {
+		return sessionID
+	}
+	if c.callContext != nil {
+		return c.callContext.SessionID
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package rustpbx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWireDump(t *testing.T) {
+	server, _ := newTestServer(t, func(conn *websocket.Conn) {
+		var ready map[string]interface{}
+		conn.ReadJSON(&ready)
+		conn.WriteMessage(websocket.BinaryMessage, []byte{1, 2})
+		conn.WriteJSON(map[string]interface{}{"event": "answer"})
+	})
+	path := filepath.Join(t.TempDir(), "wire.jsonl")
+	dump, err := OpenWireDump(path)
+	if err != nil {
+		t.Fatalf("OpenWireDump failed: %v", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "?id=call-9"
+	conn, err := newConnection(context.Background(), wsURL, nil, &ConnectionOptions{WireDump: dump}, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	answered := make(chan struct{})
+	conn.AddEventHandler(func(event *Event) {
+		if event.Event == "answer" {
+			close(answered)
+		}
+	})
+	conn.SendRawCommand(map[string]interface{}{"command": "ready"})
+	select {
+	case <-answered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the answer event")
+	}
+	conn.Close()
+	dump.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := ReadWireDump(file)
+	if err != nil {
+		t.Fatalf("ReadWireDump failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected the command and the event without audio, got %+v", records)
+	}
+	if r := records[0]; r.Direction != WireOutbound || r.SessionID != "call-9" || r.Text != `{"command":"ready"}` || r.Time.IsZero() {
+		t.Errorf("Unexpected outbound record: %+v", r)
+	}
+	if r := records[1]; r.Direction != WireInbound || !strings.Contains(r.Text, `"answer"`) {
+		t.Errorf("Unexpected inbound record: %+v", r)
+	}
+}
+
+func TestWireDumpAudio(t *testing.T) {
+	var out syncBuffer
+	dump := NewWireDump(&out)
+	dump.Audio = true
+	dump.record("call-1", WireInbound, websocket.BinaryMessage, []byte{0xff, 0x7f})
+	records, err := ReadWireDump(strings.NewReader(out.String()))
+	if err != nil || len(records) != 1 || string(records[0].Binary) != "\xff\x7f" {
+		t.Errorf("Expected the audio frame to round-trip, got %+v, %v", records, err)
+	}
+}
+
+func TestWireDumpEncrypted(t *testing.T) {
+	cipher, err := NewArtifactCipher(context.Background(), func(ctx context.Context) ([]byte, error) {
+		return make([]byte, 32), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "wire.jsonl.enc")
+	dump, err := OpenEncryptedWireDump(path, cipher)
+	if err != nil {
+		t.Fatalf("OpenEncryptedWireDump failed: %v", err)
+	}
+	dump.record("call-9", WireOutbound, websocket.TextMessage, []byte(`{"command":"ready"}`))
+	dump.record("call-9", WireInbound, websocket.TextMessage, []byte(`{"event":"answer"}`))
+	if err := dump.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "ready") {
+		t.Error("Expected the dump to be encrypted")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := ReadWireDump(cipher.NewReader(file))
+	if err != nil || len(records) != 2 || records[1].Text != `{"event":"answer"}` {
+		t.Errorf("Expected both records back, got %+v, %v", records, err)
+	}
+}
@@ -0,0 +1,180 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrQueueFull is returned for commands sent while the write queue is full
+var ErrQueueFull = errors.New("write queue full")
+
+// WriteQueuePolicy represents the outgoing command queue of a connection. Commands are
+// written in order by a single writer, except hangup and interrupt, which jump the queue
+// and are neither limited by Size nor by Rate.
+type WriteQueuePolicy struct {
+	// Size is the number of commands waiting to be written, beyond which sending fails
+	// with ErrQueueFull; 64 when zero, unlimited when negative
+	Size int
+	// Rate is the number of commands written per second; unlimited when zero
+	Rate float64
+	// Burst is the number of commands written at once within Rate; 1 when zero
+	Burst int
+}
+
+// queuedWrite is a command waiting in the write queue
+type queuedWrite struct {
+	ctx    context.Context
+	data   []byte
+	result chan error
+}
+
+// writeQueue orders and paces the commands of a connection
+type writeQueue struct {
+	policy WriteQueuePolicy
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	urgent  []*queuedWrite
+	pending []*queuedWrite
+	tokens  float64
+	last    time.Time
+}
+
+// newWriteQueue creates a write queue with the policy defaults applied
+func newWriteQueue(policy WriteQueuePolicy) *writeQueue {
+	if policy.Size == 0 {
+		policy.Size = 64
+	}
+	if policy.Burst <= 0 {
+		policy.Burst = 1
+	}
+	return &writeQueue{
+		policy: policy,
+		wake:   make(chan struct{}, 1),
+		tokens: float64(policy.Burst),
+		last:   time.Now(),
+	}
+}
+
+// urgentCommand reports whether a command jumps the write queue
+func urgentCommand(command interface{}) bool {
+	switch cmd := command.(type) {
+	case HangupCommand, *HangupCommand:
+		return true
+	case Command:
+		return cmd.Command == "interrupt"
+	case *Command:
+		return cmd.Command == "interrupt"
+	case map[string]interface{}:
+		return cmd["command"] == "hangup" || cmd["command"] == "interrupt"
+	}
+	return false
+}
+
+// QueuedWrites returns the number of commands waiting in the write queue
+func (c *Connection) QueuedWrites() int {
+	if c.writes == nil {
+		return 0
+	}
+	c.writes.mu.Lock()
+	defer c.writes.mu.Unlock()
+	return len(c.writes.urgent) + len(c.writes.pending)
+}
+
+// enqueueWrite queues an encoded command and waits until it is written
+func (c *Connection) enqueueWrite(ctx context.Context, data []byte, urgent bool) error {
+	q := c.writes
+	item := &queuedWrite{ctx: ctx, data: data, result: make(chan error, 1)}
+
+	q.mu.Lock()
+	if urgent {
+		q.urgent = append(q.urgent, item)
+	} else {
+		if q.policy.Size > 0 && len(q.pending) >= q.policy.Size {
+			q.mu.Unlock()
+			return fmt.Errorf("%w: %d commands waiting", ErrQueueFull, len(q.pending))
+		}
+		q.pending = append(q.pending, item)
+	}
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case err := <-item.result:
+		return err
+	case <-ctx.Done():
+		// The writer skips the command, unless it is being written
+		return classifyError(ctx.Err())
+	case <-c.done:
+		return ErrConnectionClosed
+	}
+}
+
+// next returns the command to write, or how long to wait for the rate limit; ok is false
+// when the queue is empty
+func (q *writeQueue) next() (item *queuedWrite, wait time.Duration, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.urgent) > 0 {
+		item, q.urgent = q.urgent[0], q.urgent[1:]
+		return item, 0, true
+	}
+	if len(q.pending) == 0 {
+		return nil, 0, false
+	}
+	if q.pending[0].ctx.Err() == nil && q.policy.Rate > 0 {
+		now := time.Now()
+		q.tokens += now.Sub(q.last).Seconds() * q.policy.Rate
+		q.last = now
+		if burst := float64(q.policy.Burst); q.tokens > burst {
+			q.tokens = burst
+		}
+		if q.tokens < 1 {
+			return nil, time.Duration((1 - q.tokens) / q.policy.Rate * float64(time.Second)), true
+		}
+		q.tokens--
+	}
+	item, q.pending = q.pending[0], q.pending[1:]
+	return item, 0, true
+}
+
+// writeLoop writes the queued commands until the read loop ends
+func (c *Connection) writeLoop() {
+	q := c.writes
+	for {
+		item, wait, ok := q.next()
+		if item != nil {
+			if err := item.ctx.Err(); err != nil {
+				item.result <- classifyError(err)
+				continue
+			}
+			item.result <- c.writeMessageContext(item.ctx, websocket.TextMessage, item.data)
+			continue
+		}
+
+		// Wait for a command, or for the rate limit to let the next one through
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if ok {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-q.wake:
+		case <-expired:
+		case <-c.done:
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package rustpbx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteQueue(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		WriteQueue: &WriteQueuePolicy{Size: 2, Rate: 5},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	if err := conn.TTS("a", "", "a", nil); err != nil {
+		t.Fatalf("TTS failed: %v", err)
+	}
+	results := make(chan error, 2)
+	for _, text := range []string{"b", "c"} {
+		queued := conn.QueuedWrites() + 1
+		go func() { results <- conn.TTS(text, "", text, nil) }()
+		for conn.QueuedWrites() < queued {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err := conn.TTS("d", "", "d", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	if err := conn.Hangup("normal_clearing", "caller"); err != nil {
+		t.Fatalf("Hangup failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			t.Errorf("Expected the queued TTS to be sent, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the queued TTS to be rate limited, took %v", elapsed)
+	}
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		cmd := <-commands
+		if cmd["command"] == "hangup" {
+			order = append(order, "hangup")
+		} else {
+			order = append(order, cmd["text"].(string))
+		}
+	}
+	if expected := []string{"a", "hangup", "b", "c"}; strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected commands %v, got %v", expected, order)
+	}
+}
+
+func TestWriteQueueCancel(t *testing.T) {
+	server, commands := newTestServer(t, nil)
+	conn, err := NewClient(server.URL).ConnectCall(context.Background(), &ConnectionOptions{
+		WriteQueue: &WriteQueuePolicy{Rate: 1},
+	})
+	if err != nil {
+		t.Fatalf("ConnectCall failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Mute("")
+	<-commands
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := conn.TTSContext(ctx, "late", "", "", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to expire in the queue, got %v", err)
+	}
+	if err := conn.Interrupt(); err != nil {
+		t.Fatalf("Interrupt failed: %v", err)
+	}
+	if cmd := <-commands; cmd["command"] != "interrupt" {
+		t.Errorf("Expected the interrupt without the cancelled TTS, got %v", cmd)
+	}
+}